@@ -227,11 +227,33 @@ func (d DirectoryURL) SetAccessControl(ctx context.Context, permissions BlobFSAc
 		acl = &permissions.ACL
 	}
 
-	// This does not yet have support for recursive updates. But then again, we don't really need it.
+	// leave the owner and group as they are, rather than sending them empty
+	var owner, group *string
+	if permissions.Owner != "" {
+		owner = &permissions.Owner
+	}
+	if permissions.Group != "" {
+		group = &permissions.Group
+	}
+
+	// See SetAccessControlRecursive for recursive updates.
 	return d.directoryClient.Update(ctx, PathUpdateActionSetAccessControl, d.filesystem, d.pathParameter,
 		nil, nil, nil, nil, nil,
 		nil, nil, nil, nil, nil,
-		nil, nil, &permissions.Owner, &permissions.Group, perms, acl,
+		nil, nil, owner, group, perms, acl,
 		nil, nil, nil, nil, &overrideHttpVerb,
 		nil, nil, nil, nil)
 }
+
+// SetAccessControlRecursive applies the ACL change to the directory and everything under it, up to maxRecords paths at a time.
+// Pass the continuation token of each response to the next call, until it's empty.
+// If continueOnFailure is set, paths that fail are listed in the response, rather than stopping the change.
+func (d DirectoryURL) SetAccessControlRecursive(ctx context.Context, mode PathSetAccessControlRecursiveMode, acl string, continuation string,
+	maxRecords int32, continueOnFailure bool) (*SetAccessControlRecursiveResponse, error) {
+	var maxRecordsParameter *int32
+	if maxRecords > 0 {
+		maxRecordsParameter = &maxRecords
+	}
+
+	return d.directoryClient.setAccessControlRecursive(ctx, d.filesystem, d.pathParameter, mode, acl, &continuation, maxRecordsParameter, &continueOnFailure)
+}
@@ -277,11 +277,20 @@ func (f FileURL) SetAccessControl(ctx context.Context, permissions BlobFSAccessC
 		acl = &permissions.ACL
 	}
 
+	// leave the owner and group as they are, rather than sending them empty
+	var owner, group *string
+	if permissions.Owner != "" {
+		owner = &permissions.Owner
+	}
+	if permissions.Group != "" {
+		group = &permissions.Group
+	}
+
 	// This does not yet have support for recursive updates. But then again, we don't really need it.
 	return f.fileClient.Update(ctx, PathUpdateActionSetAccessControl, f.fileSystemName, f.path,
 		nil, nil, nil, nil, nil,
 		nil, nil, nil, nil, nil,
-		nil, nil, &permissions.Owner, &permissions.Group, perms, acl,
+		nil, nil, owner, group, perms, acl,
 		nil, nil, nil, nil, &overrideHttpVerb,
 		nil, nil, nil, nil)
 }
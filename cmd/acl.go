@@ -0,0 +1,363 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	"github.com/spf13/cobra"
+)
+
+// acl command is used to encapsulate the sub-commands that get and change ADLS Gen2 ACLs
+// it is not runnable itself
+var aclCmd = &cobra.Command{
+	Use:     "acl",
+	Short:   aclCmdShortDescription,
+	Long:    aclCmdLongDescription,
+	Example: aclCmdExample,
+}
+
+var adlsACLPermissions = regexp.MustCompile(`^[r-][w-][xtT-]$`)
+
+// validateADLSACL checks the entries of an ACL given to set, modify or remove, so that mistakes are reported
+// before a job is started, rather than once for every path it changes
+func validateADLSACL(acl string, mode azbfs.PathSetAccessControlRecursiveMode) error {
+	if strings.TrimSpace(acl) == "" {
+		return errors.New("the --acl flag is required")
+	}
+
+	for _, entry := range strings.Split(acl, ",") {
+		parts := strings.Split(entry, ":")
+		isDefault := parts[0] == "default"
+		if isDefault {
+			parts = parts[1:]
+		}
+
+		if len(parts) == 0 {
+			return fmt.Errorf("invalid ACL entry %q", entry)
+		}
+		switch parts[0] {
+		case "user", "group", "mask", "other":
+		default:
+			return fmt.Errorf("invalid ACL entry %q: the type must be user, group, mask or other", entry)
+		}
+
+		if mode == azbfs.PathSetAccessControlRecursiveModeRemove {
+			if len(parts) > 3 || (len(parts) == 3 && parts[2] != "") {
+				return fmt.Errorf("invalid ACL entry %q: entries to remove don't have permissions", entry)
+			}
+			hasID := len(parts) > 1 && parts[1] != ""
+			if hasID && (parts[0] == "mask" || parts[0] == "other") {
+				return fmt.Errorf("invalid ACL entry %q: %s entries don't have an id", entry, parts[0])
+			}
+			if !hasID && !isDefault {
+				// the service won't remove the base entries of the access ACL, which every path must have,
+				// but any of the default ACL's entries can go, since a directory doesn't need a default ACL
+				return fmt.Errorf("invalid ACL entry %q: only entries for named users and groups, or default entries, can be removed", entry)
+			}
+			continue
+		}
+
+		if len(parts) != 3 || !adlsACLPermissions.MatchString(parts[2]) {
+			return fmt.Errorf("invalid ACL entry %q: expected [default:]type:[id]:permissions, e.g. user::rwx", entry)
+		}
+		if (parts[0] == "mask" || parts[0] == "other") && parts[1] != "" {
+			return fmt.Errorf("invalid ACL entry %q: %s entries don't have an id", entry, parts[0])
+		}
+	}
+
+	return nil
+}
+
+// setBfsAccessControl schedules one transfer for the source, or one for each entry of the list of files; like
+// removeBfsResources, there's no enumeration, since the service applies a recursive change itself
+func setBfsAccessControl(cca *CookedCopyCmdArgs) (err error) {
+	transferProcessor := setPropertiesTransferProcessor(cca, NumOfFilesPerDispatchJobPart, common.EFolderPropertiesOption.AllFolders())
+	transferProcessor.copyJobTemplate.BlobFSRecursiveDelete = cca.Recursive // apply the change under directories too
+
+	// return an error if the unsupported options are passed in
+	if len(cca.InitModularFilters()) > 0 {
+		return errors.New("filter options, such as include/exclude, are not supported for this command")
+	}
+
+	// patterns are not supported
+	if strings.Contains(cca.Source.Value, "*") {
+		return errors.New("pattern matches are not supported in this command")
+	}
+
+	sourceURL, err := cca.Source.FullURL()
+	if err != nil {
+		return errors.New("cannot parse source URL")
+	}
+	urlParts := azbfs.NewBfsURLParts(*sourceURL)
+
+	schedule := func(relativePath string) error {
+		if cca.dryrunMode {
+			target := common.GenerateFullPath(urlParts.DirectoryOrFilePath, relativePath)
+			glcm.Dryrun(func(_ common.OutputFormat) string {
+				if cca.Recursive {
+					return fmt.Sprintf("DRYRUN: change the ACL of %s, and of everything under it, with %q", target, cca.accessControlList)
+				}
+				return fmt.Sprintf("DRYRUN: change the ACL of %s with %q", target, cca.accessControlList)
+			})
+			return nil
+		}
+
+		return transferProcessor.scheduleCopyTransfer(newStoredObject(
+			nil,
+			path.Base(common.GenerateFullPath(urlParts.DirectoryOrFilePath, relativePath)),
+			relativePath,
+			common.EEntityType.File(), // the ste finds out whether it's a directory
+			time.Now(),
+			0,
+			noContentProps,
+			noContentProps,
+			nil,
+			"",
+		))
+	}
+
+	if cca.ListOfFilesChannel == nil {
+		if err := schedule(""); err != nil {
+			return err
+		}
+	} else {
+		// read from the list of files channel to find out what needs to be changed
+		childPath, ok := <-cca.ListOfFilesChannel
+		for ; ok; childPath, ok = <-cca.ListOfFilesChannel {
+			if err := schedule(childPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cca.dryrunMode {
+		return nil
+	}
+
+	_, err = transferProcessor.dispatchFinalPart()
+	return err
+}
+
+// newACLChangeCmd creates the set, modify and remove sub-commands, which all run as set-properties jobs
+func newACLChangeCmd(name string, flag common.SetPropertiesFlags, mode azbfs.PathSetAccessControlRecursiveMode, short string) *cobra.Command {
+	raw := rawCopyCmdArgs{}
+	acl := ""
+
+	cmd := &cobra.Command{
+		Use:   name + " [resourceURL]",
+		Short: short,
+		Long:  aclCmdLongDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("acl %s command only takes 1 argument (the resource URL). Passed %d argument(s)", name, len(args))
+			}
+
+			raw.src = args[0]
+			// ACLs are only reachable on the dfs endpoint, which is the opposite of what set-properties does
+			if InferArgumentLocation(raw.src) == common.ELocation.Blob() {
+				var err error
+				if raw.src, err = expandRemoteReference(raw.src); err != nil { // the endpoint can only be switched in the URL
+					return err
+				}
+				raw.src = strings.Replace(raw.src, ".blob", ".dfs", 1)
+				glcm.Info("Switching to use dfs endpoint on source account.")
+			}
+			if InferArgumentLocation(raw.src) != common.ELocation.BlobFS() {
+				return errors.New("ACLs can only be changed on ADLS Gen2 accounts")
+			}
+
+			raw.fromTo = common.EFromTo.BlobFSNone().String()
+			raw.setMandatoryDefaultsForSetProperties()
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			glcm.EnableInputWatcher()
+			if cancelFromStdin {
+				glcm.EnableCancelFromStdIn()
+			}
+
+			err := validateADLSACL(acl, mode)
+			if err != nil {
+				glcm.Error("failed to parse user input due to error: " + err.Error())
+			}
+
+			cooked, err := raw.cook()
+			if err != nil {
+				glcm.Error("failed to parse user input due to error: " + err.Error())
+			}
+			cooked.propertiesToTransfer = flag
+			cooked.accessControlList = acl
+
+			cooked.commandString = copyHandlerUtil{}.ConstructCommandStringFromArgs()
+			err = cooked.process()
+			if err != nil {
+				glcm.Error(fmt.Sprintf("failed to perform acl %s command due to error: %s", name, err.Error()))
+			}
+
+			if cooked.dryrunMode {
+				glcm.Exit(nil, common.EExitCode.Success())
+			}
+
+			glcm.SurrenderControl()
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&acl, "acl", "", "The ACL entries, separated by ','. For example: user::rwx,user:<objectId>:r-x,group::r-x,other::---")
+	cmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Change the ACL of everything under the directory as well.")
+	cmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of text file which has the list of only files and directories to be changed.")
+	cmd.PersistentFlags().BoolVar(&raw.dryrun, "dry-run", false, "Prints the paths that would be changed by this command. This flag does not affect the actual files.")
+	return cmd
+}
+
+// getBfsAccessControl prints the ACL of the resource, and, with recursive, of everything under it
+func getBfsAccessControl(resource string, recursive bool) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	if InferArgumentLocation(resource) == common.ELocation.Blob() {
+		var err error
+		if resource, err = expandRemoteReference(resource); err != nil {
+			return err
+		}
+		resource = strings.Replace(resource, ".blob", ".dfs", 1)
+	}
+	if InferArgumentLocation(resource) != common.ELocation.BlobFS() {
+		return errors.New("ACLs can only be read from ADLS Gen2 accounts")
+	}
+
+	source, err := SplitResourceString(resource, common.ELocation.BlobFS())
+	if err != nil {
+		return err
+	}
+	if err = addCredentialHelperSAS(ctx, &source, common.ELocation.BlobFS(), false); err != nil {
+		return err
+	}
+
+	credentialInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.BlobFS(), source.Value, source.SAS, true, common.CpkOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to obtain credential info: %s", err.Error())
+	}
+	if credentialInfo.CredentialType.IsAzureOAuth() {
+		uotm := GetUserOAuthTokenManagerInstance()
+		if tokenInfo, err := uotm.GetTokenInfo(ctx); err != nil {
+			return err
+		} else {
+			credentialInfo.OAuthTokenInfo = *tokenInfo
+		}
+	}
+
+	p, err := createBlobFSPipeline(ctx, credentialInfo, azcopyLogVerbosity.ToPipelineLogLevel())
+	if err != nil {
+		return err
+	}
+
+	sourceURL, err := source.FullURL()
+	if err != nil {
+		return errors.New("cannot parse source URL")
+	}
+	urlParts := azbfs.NewBfsURLParts(*sourceURL)
+
+	printAccessControl := func(name string) error {
+		urlParts.DirectoryOrFilePath = name
+		ac, err := azbfs.NewDirectoryURL(urlParts.URL(), p).GetAccessControl(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot get the ACL of %s: %w", name, err)
+		}
+
+		glcm.Info(fmt.Sprintf("%s; Owner: %s; Group: %s; Permissions: %s; ACL: %s", "/"+name, ac.Owner, ac.Group, ac.Permissions, ac.ACL))
+		return nil
+	}
+
+	root := urlParts.DirectoryOrFilePath
+	if err := printAccessControl(root); err != nil || !recursive {
+		return err
+	}
+
+	urlParts.DirectoryOrFilePath = root
+	directoryURL := azbfs.NewDirectoryURL(urlParts.URL(), p)
+	if !directoryURL.IsFileSystemRoot() {
+		if isDirectory, err := directoryURL.IsDirectory(ctx); err != nil || !isDirectory {
+			return err
+		}
+	}
+
+	marker := ""
+	for {
+		listResp, err := directoryURL.ListDirectorySegment(ctx, &marker, true)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range listResp.Paths {
+			if err := printAccessControl(*v.Name); err != nil {
+				return err
+			}
+		}
+
+		marker = listResp.XMsContinuation()
+		if marker == "" { // do-while pattern
+			break
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+
+	aclCmd.AddCommand(newACLChangeCmd("set", common.ESetPropertiesFlags.SetAccessControl(), azbfs.PathSetAccessControlRecursiveModeSet,
+		"Replace the ACL of an ADLS Gen2 file or directory"))
+	aclCmd.AddCommand(newACLChangeCmd("modify", common.ESetPropertiesFlags.ModifyAccessControl(), azbfs.PathSetAccessControlRecursiveModeModify,
+		"Add entries to the ACL of an ADLS Gen2 file or directory, or change the permissions of entries already there"))
+	aclCmd.AddCommand(newACLChangeCmd("remove", common.ESetPropertiesFlags.RemoveAccessControl(), azbfs.PathSetAccessControlRecursiveModeRemove,
+		"Remove entries from the ACL of an ADLS Gen2 file or directory"))
+
+	recursive := false
+	getCmd := &cobra.Command{
+		Use:   "get [resourceURL]",
+		Short: "Show the ACL of an ADLS Gen2 file or directory",
+		Long:  aclCmdLongDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("acl get command only takes 1 argument (the resource URL). Passed %d argument(s)", len(args))
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := getBfsAccessControl(args[0], recursive); err != nil {
+				glcm.Error("failed to perform acl get command due to error: " + err.Error())
+			}
+			glcm.Exit(nil, common.EExitCode.Success())
+		},
+	}
+	getCmd.PersistentFlags().BoolVar(&recursive, "recursive", false, "Show the ACL of everything under the directory as well.")
+	aclCmd.AddCommand(getCmd)
+}
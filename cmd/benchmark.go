@@ -30,6 +30,7 @@ import (
 
 	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
 	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/azure-storage-file-go/azfile"
 	"github.com/spf13/cobra"
@@ -41,24 +42,38 @@ type rawBenchmarkCmdArgs struct {
 	target string
 
 	// parameters controlling the auto-generated data
-	sizePerFile    string
-	fileCount      uint
-	deleteTestData bool
-	numOfFolders   uint
+	sizePerFile      string
+	sizeDistribution string
+	fileCount        uint
+	deleteTestData   bool
+	numOfFolders     uint
+
+	// the container or share in which to generate the data for an S2S benchmark
+	s2sSource string
 
 	// options from flags
-	blockSizeMB float64
-	putMd5      bool
-	checkLength bool
-	blobType    string
-	output      string
-	mode        string
+	blockSizeMB       float64
+	adaptiveBlockSize bool
+	putMd5            bool
+	checkLength       bool
+	blobType          string
+	output            string
+	mode              string
+
+	// how concurrency is tuned. Empty means it's decided by the environment variable (or the default)
+	tuningStrategy string
+
+	// where the results go
+	saveHistory bool
+	historyFile string
 }
 
 const (
 	maxBytesPerFile = 4.75 * 1024 * 1024 * 1024 * 1024
 
 	sizeStringDescription = "a number immediately followed by K, M or G. E.g. 12k or 200G"
+
+	benchmarkTuningStrategyFlag = "tuning-strategy"
 )
 
 func ParseSizeString(s string, name string) (int64, error) {
@@ -119,6 +134,17 @@ func (raw rawBenchmarkCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		return dummyCooked, errors.New("file size too big")
 	}
 
+	// check the distribution now, rather than when the data is generated
+	if _, err = newBenchmarkSizeDistribution(raw.sizeDistribution, bytesPerFile); err != nil {
+		return dummyCooked, fmt.Errorf("invalid %s: %w", common.SizeDistributionParam, err)
+	}
+
+	if raw.tuningStrategy != "" && jobsAdmin.JobsAdmin != nil {
+		if err = jobsAdmin.JobsAdmin.SetConcurrencyTuningStrategy(raw.tuningStrategy); err != nil {
+			return dummyCooked, fmt.Errorf("invalid %s: %w", benchmarkTuningStrategyFlag, err)
+		}
+	}
+
 	// transcribe everything to copy args
 	c := rawCopyCmdArgs{}
 	c.setMandatoryDefaults()
@@ -128,43 +154,64 @@ func (raw rawBenchmarkCmdArgs) cook() (CookedCopyCmdArgs, error) {
 	if err != nil {
 		return dummyCooked, err
 	}
-	downloadMode := benchMode == common.EBenchMarkMode.Download()
+	if (benchMode == common.EBenchMarkMode.S2S()) != (raw.s2sSource != "") {
+		return dummyCooked, errors.New("the s2s-source flag must be given for S2S benchmarks, and only for them")
+	}
 
-	if downloadMode {
+	// src must be string, but needs to indicate that its for benchmark and encode what we want
+	generatedSource := benchmarkSourceHelper{}.ToUrl(raw.fileCount, bytesPerFile, raw.numOfFolders, raw.sizeDistribution)
+
+	switch benchMode {
+	case common.EBenchMarkMode.Download():
 		//We to write to NULL device, so our measurements are not masked by disk perf
 		c.dst = os.DevNull
 		c.src = raw.target
-	} else { // Upload
-		// src must be string, but needs to indicate that its for benchmark and encode what we want
-		c.src = benchmarkSourceHelper{}.ToUrl(raw.fileCount, bytesPerFile, raw.numOfFolders)
+	case common.EBenchMarkMode.S2S():
+		// the data is generated by an upload beforehand, so that only the copy between the two is measured
+		c.src, err = raw.appendVirtualDir(raw.s2sSource, virtualDir)
+		if err != nil {
+			return dummyCooked, err
+		}
+		c.dst, err = raw.appendVirtualDir(raw.target, virtualDir)
+		if err != nil {
+			return dummyCooked, err
+		}
+	default: // Upload
+		c.src = generatedSource
 		c.dst, err = raw.appendVirtualDir(raw.target, virtualDir)
 		if err != nil {
 			return dummyCooked, err
 		}
 	}
 
-	c.recursive = true                                     // because source is directory-like, in which case recursive is required
-	c.internalOverrideStripTopDir = true                   // we don't want to append an extra strange name filled with meta characters at the destination
-	c.forceWrite = common.EOverwriteOption.True().String() // don't want the extra round trip (for overwrite check) when benchmarking
-
+	raw.setBenchmarkCopyDefaults(&c)
 	c.blockSizeMB = raw.blockSizeMB
-	c.putMd5 = raw.putMd5
+	c.adaptiveBlockSize = raw.adaptiveBlockSize
 	c.CheckLength = raw.checkLength
-	c.blobType = raw.blobType
-	c.output = raw.output
+	if benchMode != common.EBenchMarkMode.S2S() {
+		c.putMd5 = raw.putMd5 // for S2S, it applies to the generation of the source, and the hashes are copied from there
+	}
 
 	cooked, err := c.cook()
 	if err != nil {
 		return cooked, err
 	}
+	cooked.isBenchmark = true
+	if raw.saveHistory {
+		cooked.benchmarkRecorder = newBenchmarkRecorder(raw.historyFile, raw.describe(benchMode, cooked))
+	}
 
-	if downloadMode {
-		glcm.Info(fmt.Sprintf("Benchmarking downloads from %s.", cooked.Source.Value))
-	} else {
-		glcm.Info(fmt.Sprintf("Benchmarking uploads to %s.", cooked.Destination.Value))
+	switch benchMode {
+	case common.EBenchMarkMode.Download():
+		glcm.Info(fmt.Sprintf("Benchmarking downloads from %s.", cooked.Source.DisplayValue()))
+	case common.EBenchMarkMode.S2S():
+		glcm.Info(fmt.Sprintf("Benchmarking service-to-service copies from %s to %s. The data will be generated in the source first.",
+			cooked.Source.DisplayValue(), cooked.Destination.DisplayValue()))
+	default:
+		glcm.Info(fmt.Sprintf("Benchmarking uploads to %s.", cooked.Destination.DisplayValue()))
 	}
 
-	if !downloadMode && raw.deleteTestData {
+	if benchMode != common.EBenchMarkMode.Download() && raw.deleteTestData {
 		// set up automatic cleanup
 		cooked.followupJobArgs, err = raw.createCleanupJobArgs(cooked.Destination, logVerbosityRaw)
 		if err != nil {
@@ -172,10 +219,88 @@ func (raw rawBenchmarkCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		}
 	}
 
+	if benchMode == common.EBenchMarkMode.S2S() {
+		return raw.chainS2SSetupJob(generatedSource, cooked)
+	}
+
 	return cooked, nil
 }
 
+// describe gives the config of the benchmark, for its record in the history
+func (raw rawBenchmarkCmdArgs) describe(benchMode common.BenchMarkMode, cooked CookedCopyCmdArgs) benchmarkConfig {
+	config := benchmarkConfig{
+		Mode:              benchMode.String(),
+		FromTo:            cooked.FromTo.String(),
+		BlockSizeMB:       raw.blockSizeMB,
+		AdaptiveBlockSize: raw.adaptiveBlockSize,
+		BlobType:          raw.blobType,
+		PutMd5:            raw.putMd5,
+		CheckLength:       raw.checkLength,
+	}
+	config.TuningStrategy = raw.tuningStrategy
+	if config.TuningStrategy == "" {
+		config.TuningStrategy = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ConcurrencyTuningStrategy())
+	}
+	if benchMode != common.EBenchMarkMode.Download() {
+		config.FileCount = raw.fileCount
+		config.SizePerFile = raw.sizePerFile
+		config.SizeDistribution = raw.sizeDistribution
+		config.NumOfFolders = raw.numOfFolders
+	}
+	if benchMode != common.EBenchMarkMode.Upload() {
+		config.Source = cooked.Source.DisplayValue()
+	}
+	if benchMode != common.EBenchMarkMode.Download() {
+		config.Destination = cooked.Destination.DisplayValue()
+	}
+	return config
+}
+
+// setBenchmarkCopyDefaults sets the copy args that are the same for all the jobs of a benchmark
+func (raw rawBenchmarkCmdArgs) setBenchmarkCopyDefaults(c *rawCopyCmdArgs) {
+	c.recursive = true                                     // because source is directory-like, in which case recursive is required
+	c.internalOverrideStripTopDir = true                   // we don't want to append an extra strange name filled with meta characters at the destination
+	c.forceWrite = common.EOverwriteOption.True().String() // don't want the extra round trip (for overwrite check) when benchmarking
+
+	c.blobType = raw.blobType
+	c.output = raw.output
+}
+
+// chainS2SSetupJob puts a job that generates the source data of an S2S benchmark before the copy that is measured,
+// and, if the test data is to be deleted, the deletion of that source data after the deletion of the copy
+func (raw rawBenchmarkCmdArgs) chainS2SSetupJob(generatedSource string, s2sJob CookedCopyCmdArgs) (CookedCopyCmdArgs, error) {
+	c := rawCopyCmdArgs{}
+	c.setMandatoryDefaults()
+	c.src = generatedSource
+	u, _ := s2sJob.Source.FullURL() // don't check error, because it was parsed already for the S2S job
+	c.dst = u.String()
+	raw.setBenchmarkCopyDefaults(&c)
+	c.putMd5 = raw.putMd5
+
+	setupJob, err := c.cook()
+	if err != nil {
+		return setupJob, err
+	}
+	setupJob.isSetupJob = true
+
+	s2sJob.jobID = common.NewJobID() // the setup job has the ID that cook gives out
+	if raw.deleteTestData {
+		sourceCleanup, err := raw.createCleanupJobArgs(s2sJob.Source, logVerbosityRaw)
+		if err != nil {
+			return setupJob, err
+		}
+		s2sJob.followupJobArgs.followupJobArgs = sourceCleanup
+	}
+	setupJob.followupJobArgs = &s2sJob
+
+	return setupJob, nil
+}
+
 func (raw rawBenchmarkCmdArgs) appendVirtualDir(target, virtualDir string) (string, error) {
+	target, err := expandRemoteReference(target)
+	if err != nil {
+		return "", err
+	}
 
 	u, err := url.Parse(target)
 	if err != nil {
@@ -251,41 +376,47 @@ type benchmarkSourceHelper struct{}
 // you want a URL that can't possibly be a real one, so we'll use that
 const benchmarkSourceHost = "benchmark.invalid"
 
-func (h benchmarkSourceHelper) ToUrl(fileCount uint, bytesPerFile int64, numOfFolders uint) string {
-	return fmt.Sprintf("https://%s?fc=%d&bpf=%d&nf=%d", benchmarkSourceHost, fileCount, bytesPerFile, numOfFolders)
+func (h benchmarkSourceHelper) ToUrl(fileCount uint, bytesPerFile int64, numOfFolders uint, sizeDistribution string) string {
+	return fmt.Sprintf("https://%s?fc=%d&bpf=%d&nf=%d&sd=%s", benchmarkSourceHost, fileCount, bytesPerFile, numOfFolders, url.QueryEscape(sizeDistribution))
 }
 
-func (h benchmarkSourceHelper) FromUrl(s string) (fileCount uint, bytesPerFile int64, numOfFolders uint, err error) {
+func (h benchmarkSourceHelper) FromUrl(s string) (fileCount uint, bytesPerFile int64, numOfFolders uint, sizeDistribution string, err error) {
 	// TODO: consider replace with regex?
 
 	expectedPrefix := "https://" + benchmarkSourceHost + "?"
 	if !strings.HasPrefix(s, expectedPrefix) {
-		return 0, 0, 0, errors.New("invalid benchmark source string")
+		return 0, 0, 0, "", errors.New("invalid benchmark source string")
 	}
 	s = strings.TrimPrefix(s, expectedPrefix)
 	pieces := strings.Split(s, "&")
-	if len(pieces) != 3 ||
+	if len(pieces) != 4 ||
 		!strings.HasPrefix(pieces[0], "fc=") ||
 		!strings.HasPrefix(pieces[1], "bpf=") ||
-		!strings.HasPrefix(pieces[2], "nf=") {
-		return 0, 0, 0, errors.New("invalid benchmark source string")
+		!strings.HasPrefix(pieces[2], "nf=") ||
+		!strings.HasPrefix(pieces[3], "sd=") {
+		return 0, 0, 0, "", errors.New("invalid benchmark source string")
 	}
 	pieces[0] = strings.Split(pieces[0], "=")[1]
 	pieces[1] = strings.Split(pieces[1], "=")[1]
 	pieces[2] = strings.Split(pieces[2], "=")[1]
+	pieces[3] = strings.Split(pieces[3], "=")[1]
 	fc, err := strconv.ParseUint(pieces[0], 10, 32)
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, 0, "", err
 	}
 	bpf, err := strconv.ParseInt(pieces[1], 10, 64)
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, 0, "", err
 	}
 	nf, err := strconv.ParseUint(pieces[2], 10, 32)
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, 0, "", err
+	}
+	sd, err := url.QueryUnescape(pieces[3])
+	if err != nil {
+		return 0, 0, 0, "", err
 	}
-	return uint(fc), bpf, uint(nf), nil
+	return uint(fc), bpf, uint(nf), sd, nil
 }
 
 var benchCmd *cobra.Command
@@ -303,8 +434,6 @@ func init() {
 		Example:    benchCmdExample,
 		Args: func(cmd *cobra.Command, args []string) error {
 
-			// TODO: if/when we support benchmarking for S2S, note that the current code to set userAgent string in
-			//   jobPartMgr will need to be changed if we want it to still set the benchmarking suffix for S2S
 			if len(args) == 1 {
 				raw.target = args[0]
 			} else {
@@ -333,13 +462,21 @@ func init() {
 	rootCmd.AddCommand(benchCmd)
 
 	benchCmd.PersistentFlags().StringVar(&raw.sizePerFile, common.SizePerFileParam, "250M", "size of each auto-generated data file. Must be "+sizeStringDescription)
+	benchCmd.PersistentFlags().StringVar(&raw.sizeDistribution, common.SizeDistributionParam, "fixed", "how the sizes of the auto-generated data files vary. 'fixed' makes them all "+common.SizePerFileParam+" in size. 'lognormal:<sigma>' draws them from a lognormal distribution with a median of "+common.SizePerFileParam+", e.g. lognormal:1.5. 'histogram:<path>' draws them from a file with a line for each size, giving the size and its relative weight, e.g. '64K 30'")
 	benchCmd.PersistentFlags().UintVar(&raw.fileCount, common.FileCountParam, common.FileCountDefault, "number of auto-generated data files to use")
 	benchCmd.PersistentFlags().UintVar(&raw.numOfFolders, "number-of-folders", 0, "If larger than 0, create folders to divide up the data.")
 	benchCmd.PersistentFlags().BoolVar(&raw.deleteTestData, "delete-test-data", true, "if true, the benchmark data will be deleted at the end of the benchmark run.  Set it to false if you want to keep the data at the destination - e.g. to use it for manual tests outside benchmark mode")
 
 	benchCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "use this block size (specified in MiB). Default is automatically calculated based on file size. Decimal fractions are allowed - e.g. 0.25. Identical to the same-named parameter in the copy command")
+	benchCmd.PersistentFlags().BoolVar(&raw.adaptiveBlockSize, common.AdaptiveBlockSizeFlagName, false, "choose the block size of each file as its transfer starts, rather than using one size for all. Identical to the same-named parameter in the copy command")
 	benchCmd.PersistentFlags().StringVar(&raw.blobType, "blob-type", "Detect", "defines the type of blob at the destination. Used to allow benchmarking different blob types. Identical to the same-named parameter in the copy command")
 	benchCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob/file. (By default the hash is NOT created.) Identical to the same-named parameter in the copy command")
 	benchCmd.PersistentFlags().BoolVar(&raw.checkLength, "check-length", true, "Check the length of a file on the destination after the transfer. If there is a mismatch between source and destination, the transfer is marked as failed.")
-	benchCmd.PersistentFlags().StringVar(&raw.mode, "mode", "upload", "Defines if Azcopy should test uploads or downloads from this target. Valid values are 'upload' and 'download'. Defaulted option is 'upload'.")
+	benchCmd.PersistentFlags().StringVar(&raw.tuningStrategy, benchmarkTuningStrategyFlag, "", "how concurrency is tuned. 'hill-climbing' (the default) raises it for as long as that raises throughput. "+
+		"'latency[:<milliseconds>]' keeps the 95th percentile of request latency near a target. 'sweep[:<step>[:<max>]]' tries every multiple of step, up to max, "+
+		"and reports the throughput and latency of each. Overrides the "+common.EEnvironmentVariable.ConcurrencyTuningStrategy().Name+" environment variable")
+	benchCmd.PersistentFlags().StringVar(&raw.mode, "mode", "upload", "Defines if Azcopy should test uploads or downloads from this target, or service-to-service copies to it. Valid values are 'upload', 'download' and 's2s'. Defaulted option is 'upload'.")
+	benchCmd.PersistentFlags().BoolVar(&raw.saveHistory, "save-history", true, "add the results of the benchmark to the history, so they can be compared with those of other runs by 'azcopy bench compare'")
+	benchCmd.PersistentFlags().StringVar(&raw.historyFile, benchmarkHistoryFileFlag, "", "the file that holds the benchmark history. Defaults to "+benchmarkHistoryFileName+" in the folder of the log files")
+	benchCmd.PersistentFlags().StringVar(&raw.s2sSource, "s2s-source", "", "For S2S benchmarks, the blob container or file share in which the data is generated before it is copied to the target. Required with --mode=s2s.")
 }
@@ -0,0 +1,461 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
+	"github.com/spf13/cobra"
+)
+
+const (
+	benchmarkHistoryFileName = "benchmark-history.jsonl"
+	benchmarkHistoryFileFlag = "history-file"
+)
+
+// benchmarkResult is the record of one benchmark run in the history, which is a file with one of these, as JSON, per line
+type benchmarkResult struct {
+	JobID       common.JobID
+	Time        time.Time
+	Config      benchmarkConfig
+	Environment benchmarkEnvironment
+	Outcome     benchmarkOutcome
+}
+
+// benchmarkConfig is what the benchmark was asked to do
+type benchmarkConfig struct {
+	Mode              string
+	FromTo            string
+	Source            string `json:",omitempty"` // without SAS, and only when it isn't generated
+	Destination       string `json:",omitempty"` // without SAS, and only when it isn't the null device
+	FileCount         uint   `json:",omitempty"`
+	SizePerFile       string `json:",omitempty"`
+	SizeDistribution  string `json:",omitempty"`
+	NumOfFolders      uint   `json:",omitempty"`
+	BlockSizeMB       float64
+	AdaptiveBlockSize bool `json:",omitempty"`
+	BlobType          string
+	PutMd5            bool
+	CheckLength       bool
+	TuningStrategy    string `json:",omitempty"`
+}
+
+// benchmarkEnvironment is what the benchmark ran on
+type benchmarkEnvironment struct {
+	AzCopyVersion    string
+	OS               string
+	Arch             string
+	NumCPU           int
+	Hostname         string
+	ConcurrencyValue string `json:",omitempty"`
+	BufferGB         string `json:",omitempty"`
+	CapMbps          float64
+}
+
+// benchmarkOutcome is how the benchmark went
+type benchmarkOutcome struct {
+	JobStatus              string
+	ElapsedSeconds         float64
+	FileTransfers          uint32
+	TransfersFailed        uint32
+	BytesTransferred       uint64
+	Mbps                   float64 // of the successfully transferred bytes, over the whole job
+	MbpsAfterTuning        float64 // of all the bytes over the wire, once concurrency tuning was done
+	IOPS                   int
+	AverageE2EMilliseconds int
+	NetworkErrorPercentage float32
+	ServerBusyPercentage   float32
+	Retries                int64
+	TunerFinalReason       string
+	TunerFinalConcurrency  int
+	ConstraintSeconds      map[string]float64 // how long each PerfConstraint was the primary one
+	Advice                 []string           // the codes of the PerformanceAdvisor's advice
+}
+
+func newBenchmarkEnvironment() benchmarkEnvironment {
+	hostname, _ := os.Hostname()
+	return benchmarkEnvironment{
+		AzCopyVersion:    common.AzcopyVersion,
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		NumCPU:           runtime.NumCPU(),
+		Hostname:         hostname,
+		ConcurrencyValue: glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ConcurrencyValue()),
+		BufferGB:         glcm.GetEnvironmentVariable(common.EEnvironmentVariable.BufferGB()),
+		CapMbps:          cmdLineCapMegaBitsPerSecond,
+	}
+}
+
+// benchmarkRecorder follows a benchmark job while it runs, and adds its results to the history when it's done
+type benchmarkRecorder struct {
+	historyFile       string
+	config            benchmarkConfig
+	constraintSeconds map[string]float64
+	lastSampleTime    time.Time
+	recorded          bool
+}
+
+func newBenchmarkRecorder(historyFile string, config benchmarkConfig) *benchmarkRecorder {
+	if historyFile == "" {
+		historyFile = filepath.Join(azcopyLogPathFolder, benchmarkHistoryFileName)
+	}
+	return &benchmarkRecorder{historyFile: historyFile, config: config, constraintSeconds: make(map[string]float64)}
+}
+
+// sample attributes the time since the last sample to the constraint that is primary now
+func (r *benchmarkRecorder) sample(constraint common.PerfConstraint) {
+	now := time.Now()
+	if !r.lastSampleTime.IsZero() {
+		r.constraintSeconds[constraint.String()] += now.Sub(r.lastSampleTime).Seconds()
+	}
+	r.lastSampleTime = now
+}
+
+func (r *benchmarkRecorder) record(summary common.ListJobSummaryResponse, duration time.Duration) error {
+	if r.recorded {
+		return nil
+	}
+	r.recorded = true
+
+	outcome := benchmarkOutcome{
+		JobStatus:              summary.JobStatus.String(),
+		ElapsedSeconds:         duration.Seconds(),
+		FileTransfers:          summary.FileTransfers,
+		TransfersFailed:        summary.TransfersFailed,
+		BytesTransferred:       summary.TotalBytesTransferred,
+		IOPS:                   summary.AverageIOPS,
+		AverageE2EMilliseconds: summary.AverageE2EMilliseconds,
+		NetworkErrorPercentage: summary.NetworkErrorPercentage,
+		ServerBusyPercentage:   summary.ServerBusyPercentage,
+		ConstraintSeconds:      r.constraintSeconds,
+	}
+	if duration > 0 {
+		outcome.Mbps = 8 * float64(summary.TotalBytesTransferred) / duration.Seconds() / (1000 * 1000)
+	}
+	outcome.TunerFinalReason, outcome.TunerFinalConcurrency, outcome.MbpsAfterTuning = jobsAdmin.JobsAdmin.GetTuningResults()
+	if jm, exists := jobsAdmin.JobsAdmin.JobMgr(summary.JobID); exists && jm.PipelineNetworkStats() != nil {
+		outcome.Retries = jm.PipelineNetworkStats().GetTotalRetries()
+	}
+	for _, a := range summary.PerformanceAdvice {
+		outcome.Advice = append(outcome.Advice, a.Code)
+	}
+
+	return appendBenchmarkResult(r.historyFile, benchmarkResult{
+		JobID:       summary.JobID,
+		Time:        time.Now().UTC(),
+		Config:      r.config,
+		Environment: newBenchmarkEnvironment(),
+		Outcome:     outcome,
+	})
+}
+
+func appendBenchmarkResult(historyFile string, result benchmarkResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(historyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readBenchmarkHistory returns the results in the history, oldest first
+func readBenchmarkHistory(historyFile string) ([]benchmarkResult, error) {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make([]benchmarkResult, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var result benchmarkResult
+		if err = json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, fmt.Errorf("line %d of the benchmark history is not valid: %w", lineNumber, err)
+		}
+		results = append(results, result)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Time.Before(results[j].Time) })
+	return results, nil
+}
+
+// findBenchmarkResult finds the run with the given job ID, or with the only job ID that starts with it
+func findBenchmarkResult(results []benchmarkResult, id string) (benchmarkResult, error) {
+	var found []benchmarkResult
+	for _, r := range results {
+		if strings.HasPrefix(strings.ToLower(r.JobID.String()), strings.ToLower(id)) {
+			found = append(found, r)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return benchmarkResult{}, fmt.Errorf("no benchmark run in the history has job ID %s", id)
+	case 1:
+		return found[0], nil
+	default:
+		return benchmarkResult{}, fmt.Errorf("more than one benchmark run in the history has a job ID starting with %s", id)
+	}
+}
+
+// benchmarkMetric is one of the numbers that compare looks for regressions in
+type benchmarkMetric struct {
+	name           string
+	higherIsBetter bool
+	value          func(o benchmarkOutcome) float64
+}
+
+var benchmarkMetrics = []benchmarkMetric{
+	{"Mbps", true, func(o benchmarkOutcome) float64 { return o.Mbps }},
+	{"Mbps after tuning", true, func(o benchmarkOutcome) float64 { return o.MbpsAfterTuning }},
+	{"IOPS", true, func(o benchmarkOutcome) float64 { return float64(o.IOPS) }},
+	{"Elapsed seconds", false, func(o benchmarkOutcome) float64 { return o.ElapsedSeconds }},
+	{"End-to-end ms per request", false, func(o benchmarkOutcome) float64 { return float64(o.AverageE2EMilliseconds) }},
+	{"Network error %", false, func(o benchmarkOutcome) float64 { return float64(o.NetworkErrorPercentage) }},
+	{"Server busy %", false, func(o benchmarkOutcome) float64 { return float64(o.ServerBusyPercentage) }},
+	{"Retries", false, func(o benchmarkOutcome) float64 { return float64(o.Retries) }},
+	{"Failed transfers", false, func(o benchmarkOutcome) float64 { return float64(o.TransfersFailed) }},
+}
+
+type benchmarkMetricComparison struct {
+	Metric        string
+	Baseline      float64
+	Candidate     float64
+	ChangePercent *float64 `json:",omitempty"` // not set when the baseline is zero
+	Regressed     bool
+}
+
+type benchmarkComparison struct {
+	Baseline           common.JobID
+	Candidate          common.JobID
+	ThresholdPercent   float64
+	Metrics            []benchmarkMetricComparison
+	Regressions        int
+	ConfigDifferences  []string // the runs didn't do the same thing, so may not be comparable
+	EnvironmentChanges []string // what differed in where they ran, which is often the point of comparing them
+}
+
+// compareBenchmarkResults flags as regressions the metrics of the candidate that are worse than the baseline's by more than the threshold
+func compareBenchmarkResults(baseline, candidate benchmarkResult, thresholdPercent float64) benchmarkComparison {
+	c := benchmarkComparison{
+		Baseline:           baseline.JobID,
+		Candidate:          candidate.JobID,
+		ThresholdPercent:   thresholdPercent,
+		ConfigDifferences:  diffBenchmarkFields(baseline.Config, candidate.Config),
+		EnvironmentChanges: diffBenchmarkFields(baseline.Environment, candidate.Environment),
+	}
+
+	for _, m := range benchmarkMetrics {
+		mc := benchmarkMetricComparison{Metric: m.name, Baseline: m.value(baseline.Outcome), Candidate: m.value(candidate.Outcome)}
+		if mc.Baseline != 0 {
+			change := 100 * (mc.Candidate - mc.Baseline) / math.Abs(mc.Baseline)
+			mc.ChangePercent = &change
+			if m.higherIsBetter {
+				mc.Regressed = change < -thresholdPercent
+			} else {
+				mc.Regressed = change > thresholdPercent
+			}
+		}
+		if mc.Regressed {
+			c.Regressions++
+		}
+		c.Metrics = append(c.Metrics, mc)
+	}
+
+	return c
+}
+
+// diffBenchmarkFields describes the fields that differ between two structs of the same type
+func diffBenchmarkFields(a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	diffs := make([]string, 0)
+	for i := 0; i < va.NumField(); i++ {
+		x, y := va.Field(i).Interface(), vb.Field(i).Interface()
+		if !reflect.DeepEqual(x, y) {
+			diffs = append(diffs, fmt.Sprintf("%s: %v -> %v", va.Type().Field(i).Name, x, y))
+		}
+	}
+	return diffs
+}
+
+func (c benchmarkComparison) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Comparing benchmark run %s (baseline) with %s\n\n", c.Baseline, c.Candidate))
+	sb.WriteString(fmt.Sprintf("%-28s %14s %14s %10s\n", "Metric", "Baseline", "Candidate", "Change"))
+	for _, m := range c.Metrics {
+		change := "n/a"
+		if m.ChangePercent != nil {
+			change = fmt.Sprintf("%+.1f%%", *m.ChangePercent)
+		}
+		flag := ""
+		if m.Regressed {
+			flag = "  REGRESSION"
+		}
+		sb.WriteString(fmt.Sprintf("%-28s %14.2f %14.2f %10s%s\n", m.Metric, m.Baseline, m.Candidate, change, flag))
+	}
+
+	if len(c.ConfigDifferences) > 0 {
+		sb.WriteString("\nThe runs were configured differently, so may not be comparable:\n")
+		for _, d := range c.ConfigDifferences {
+			sb.WriteString("  " + d + "\n")
+		}
+	}
+	if len(c.EnvironmentChanges) > 0 {
+		sb.WriteString("\nThe environments differed in:\n")
+		for _, d := range c.EnvironmentChanges {
+			sb.WriteString("  " + d + "\n")
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d regression(s) of more than %.1f%%\n", c.Regressions, c.ThresholdPercent))
+	return sb.String()
+}
+
+// pickBenchmarkRuns picks the baseline and candidate from the history: the two given, the one given and the latest,
+// or, if none are given, the latest two
+func pickBenchmarkRuns(results []benchmarkResult, ids []string) (baseline, candidate benchmarkResult, err error) {
+	switch len(ids) {
+	case 0:
+		if len(results) < 2 {
+			return baseline, candidate, errors.New("the benchmark history must have at least two runs to compare")
+		}
+		return results[len(results)-2], results[len(results)-1], nil
+	case 1:
+		if len(results) == 0 {
+			return baseline, candidate, errors.New("the benchmark history is empty")
+		}
+		baseline, err = findBenchmarkResult(results, ids[0])
+		return baseline, results[len(results)-1], err
+	default:
+		if baseline, err = findBenchmarkResult(results, ids[0]); err != nil {
+			return
+		}
+		candidate, err = findBenchmarkResult(results, ids[1])
+		return
+	}
+}
+
+func init() {
+	historyFile := func() string {
+		f, _ := benchCmd.PersistentFlags().GetString(benchmarkHistoryFileFlag)
+		if f == "" {
+			f = filepath.Join(azcopyLogPathFolder, benchmarkHistoryFileName)
+		}
+		return f
+	}
+
+	thresholdPercent := float64(0)
+	compareCmd := &cobra.Command{
+		Use:   "compare [baseline job ID] [candidate job ID]",
+		Short: benchCompareCmdShortDescription,
+		Long:  benchCompareCmdLongDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 2 {
+				return errors.New("compare takes at most two job IDs")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			results, err := readBenchmarkHistory(historyFile())
+			if err != nil {
+				glcm.Error("failed to read the benchmark history due to error: " + err.Error())
+			}
+			baseline, candidate, err := pickBenchmarkRuns(results, args)
+			if err != nil {
+				glcm.Error(err.Error())
+			}
+
+			comparison := compareBenchmarkResults(baseline, candidate, thresholdPercent)
+			exitCode := common.EExitCode.Success()
+			if comparison.Regressions > 0 {
+				exitCode = common.EExitCode.Error() // so that scripts can stop on regressions
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(comparison)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				return comparison.String()
+			}, exitCode)
+		},
+	}
+	compareCmd.Flags().Float64Var(&thresholdPercent, "threshold", 10, "how much worse, in percent, a metric of the candidate run must be than that of the baseline to be flagged as a regression")
+	benchCmd.AddCommand(compareCmd)
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: benchHistoryCmdShortDescription,
+		Long:  benchHistoryCmdLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			results, err := readBenchmarkHistory(historyFile())
+			if err != nil {
+				glcm.Error("failed to read the benchmark history due to error: " + err.Error())
+			}
+
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(results)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+
+				var sb strings.Builder
+				sb.WriteString(fmt.Sprintf("%-36s  %-20s  %-8s  %-16s  %10s  %s\n", "JobId", "Time", "Mode", "FromTo", "Mbps", "Status"))
+				for _, r := range results {
+					sb.WriteString(fmt.Sprintf("%-36s  %-20s  %-8s  %-16s  %10.2f  %s\n", r.JobID, r.Time.Format("2006-01-02 15:04:05"),
+						r.Config.Mode, r.Config.FromTo, r.Outcome.Mbps, r.Outcome.JobStatus))
+				}
+				return sb.String()
+			}, common.EExitCode.Success())
+		},
+	}
+	benchCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,161 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	sizeDistributionFixed     = "fixed"
+	sizeDistributionLognormal = "lognormal"
+	sizeDistributionHistogram = "histogram"
+
+	// the sizes are random, but from a fixed seed, so that repeated runs of the same benchmark generate the same payload
+	sizeDistributionSeed = 1
+)
+
+// benchmarkSizeDistribution gives the sizes of the files that a benchmark generates
+type benchmarkSizeDistribution interface {
+	nextSize() int64
+}
+
+// newBenchmarkSizeDistribution parses the value of --size-distribution, which is one of
+//   - "fixed" (or nothing), for every file to be sizePerFile bytes
+//   - "lognormal:<sigma>", for a lognormal distribution with a median of sizePerFile
+//   - "histogram:<path>", for sizes drawn from a file with lines of "<size> <weight>", sizes being in bytes or as for --size-per-file
+func newBenchmarkSizeDistribution(spec string, sizePerFile int64) (benchmarkSizeDistribution, error) {
+	kind, arg := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, arg = spec[:i], spec[i+1:]
+	}
+
+	switch strings.ToLower(kind) {
+	case "", sizeDistributionFixed:
+		return &fixedSizeDistribution{size: sizePerFile}, nil
+	case sizeDistributionLognormal:
+		sigma, err := strconv.ParseFloat(arg, 64)
+		if err != nil || sigma <= 0 {
+			return nil, fmt.Errorf("%s must be given a positive sigma, e.g. %s:1.5", sizeDistributionLognormal, sizeDistributionLognormal)
+		}
+		return &lognormalSizeDistribution{median: float64(sizePerFile), sigma: sigma, rand: rand.New(rand.NewSource(sizeDistributionSeed))}, nil
+	case sizeDistributionHistogram:
+		if arg == "" {
+			return nil, fmt.Errorf("%s must be given the path of a file, e.g. %s:sizes.txt", sizeDistributionHistogram, sizeDistributionHistogram)
+		}
+		return newHistogramSizeDistribution(arg)
+	default:
+		return nil, fmt.Errorf("unknown size distribution '%s'. Valid values are %s, %s:<sigma> and %s:<path>",
+			kind, sizeDistributionFixed, sizeDistributionLognormal, sizeDistributionHistogram)
+	}
+}
+
+type fixedSizeDistribution struct {
+	size int64
+}
+
+func (d *fixedSizeDistribution) nextSize() int64 {
+	return d.size
+}
+
+// lognormalSizeDistribution matches the long tail of most real collections of files: many small ones and a few large ones
+type lognormalSizeDistribution struct {
+	median float64
+	sigma  float64
+	rand   *rand.Rand
+}
+
+func (d *lognormalSizeDistribution) nextSize() int64 {
+	size := d.median * math.Exp(d.sigma*d.rand.NormFloat64())
+	return int64(math.Max(1, math.Min(size, maxBytesPerFile)))
+}
+
+type histogramSizeDistribution struct {
+	sizes      []int64
+	cumulative []float64 // the running total of the weights, for the buckets up to and including each size
+	rand       *rand.Rand
+}
+
+func newHistogramSizeDistribution(path string) (*histogramSizeDistribution, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the size histogram: %w", err)
+	}
+	defer f.Close()
+
+	d := &histogramSizeDistribution{rand: rand.New(rand.NewSource(sizeDistributionSeed))}
+	total := float64(0)
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d of the size histogram must be a size and a weight", lineNumber)
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			size, err = ParseSizeString(fields[0], "the size on line "+strconv.Itoa(lineNumber)+" of the size histogram")
+			if err != nil {
+				return nil, err
+			}
+		}
+		if size < 0 || size > maxBytesPerFile {
+			return nil, fmt.Errorf("the size on line %d of the size histogram is out of range", lineNumber)
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("the weight on line %d of the size histogram must be a number that isn't negative", lineNumber)
+		}
+
+		total += weight
+		d.sizes = append(d.sizes, size)
+		d.cumulative = append(d.cumulative, total)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read the size histogram: %w", err)
+	}
+	if total <= 0 {
+		return nil, errors.New("the size histogram must have at least one size with a weight greater than zero")
+	}
+
+	return d, nil
+}
+
+func (d *histogramSizeDistribution) nextSize() int64 {
+	target := d.rand.Float64() * d.cumulative[len(d.cumulative)-1]
+	i := sort.Search(len(d.cumulative), func(i int) bool { return d.cumulative[i] > target })
+	if i == len(d.sizes) {
+		i-- // only when rounding has put the target at the very top
+	}
+	return d.sizes[i]
+}
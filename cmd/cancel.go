@@ -31,7 +31,8 @@ import (
 // TODO should this command be removed? Previously AzCopy was supposed to have an independent backend (out of proc)
 // TODO but that's not the plan anymore
 type rawCancelCmdArgs struct {
-	jobID string
+	jobID            string
+	distributedQueue string
 }
 
 func (raw rawCancelCmdArgs) cook() (cookedCancelCmdArgs, error) {
@@ -42,16 +43,28 @@ func (raw rawCancelCmdArgs) cook() (cookedCancelCmdArgs, error) {
 		return cookedCancelCmdArgs{}, fmt.Errorf("invalid jobId string passed: %q", raw.jobID)
 	}
 
-	return cookedCancelCmdArgs{jobID: jobID}, nil
+	return cookedCancelCmdArgs{jobID: jobID, distributedQueue: raw.distributedQueue}, nil
 }
 
 type cookedCancelCmdArgs struct {
-	jobID common.JobID
+	jobID            common.JobID
+	distributedQueue string
 }
 
 // handles the cancel command
-// dispatches the cancel Job order to the storage engine
+// dispatches the cancel Job order to the storage engine, or, for a distributed job, to its queue,
+// since the job's parts are executed by the workers rather than by this process
 func (cca cookedCancelCmdArgs) process() error {
+	if cca.distributedQueue != "" {
+		queue, err := newFSPartQueue(cca.distributedQueue)
+		if err != nil {
+			return err
+		}
+		Rpc = queue.rpc(Rpc)
+	} else if queue, ok := distributedQueueForJob(cca.jobID); ok {
+		Rpc = queue.rpc(Rpc)
+	}
+
 	var cancelJobResponse common.CancelPauseResumeResponse
 	Rpc(common.ERpcCmd.CancelJob(), cca.jobID, &cancelJobResponse)
 	if !cancelJobResponse.CancelledPauseResumed {
@@ -98,4 +111,6 @@ func init() {
 		Hidden: true,
 	}
 	rootCmd.AddCommand(cancelCmd)
+
+	cancelCmd.PersistentFlags().StringVar(&raw.distributedQueue, "distributed-queue", "", "Cancel a distributed job in the queue in this shared directory. Only needed on machines other than the job's coordinator.")
 }
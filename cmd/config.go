@@ -0,0 +1,218 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const profileFlagName = "profile"
+
+var azcopyConfig = &common.Config{}
+var azcopyProfileName string
+var azcopyProfile common.ConfigProfile
+
+// azcopyProfileEnvironment lists the environment variables that were set from the profile, rather than by the user.
+var azcopyProfileEnvironment []string
+
+// only registered so that cobra accepts the flag; it is actually read by LoadConfigProfile, before cobra runs
+var cmdLineProfile string
+
+// LoadConfigProfile loads the config file and applies the selected profile's environment variables.
+// It must be called before anything reads the environment (e.g. the log and plan locations), which is before cobra parses
+// the command line, so it picks --profile out of the raw args itself.
+func LoadConfigProfile(args []string) error {
+	config, err := common.LoadConfig(common.DefaultConfigPath())
+	if err != nil {
+		return err
+	}
+
+	name, profile, err := config.SelectProfile(profileFromArgs(args))
+	if err != nil {
+		return err
+	}
+
+	azcopyConfig = config
+	azcopyProfileName = name
+	azcopyProfile = profile
+	azcopyProfileEnvironment = profile.ApplyEnvironment()
+	common.SetConfiguredRemotes(profile.Remotes)
+	return nil
+}
+
+func profileFromArgs(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		} else if strings.HasPrefix(a, "--"+profileFlagName+"=") {
+			return strings.TrimPrefix(a, "--"+profileFlagName+"=")
+		} else if a == "--"+profileFlagName && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// commandKey is how a command is named in the flags section of a profile, e.g. "copy" or "jobs resume".
+func commandKey(cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+}
+
+// applyProfileToCommand sets the profile's default values for the flags the user did not pass.
+func applyProfileToCommand(cmd *cobra.Command) error {
+	key := commandKey(cmd)
+	for name, value := range azcopyProfile.FlagDefaults(key) {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			if _, forCommand := azcopyProfile.Flags[key][name]; forCommand {
+				return fmt.Errorf("profile %q sets --%s, which is not a flag of the %s command", azcopyProfileName, name, key)
+			}
+			continue // flags for all commands only apply to those that have them
+		}
+
+		if flag.Changed {
+			continue // the command line wins
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("profile %q has an invalid value for --%s: %w", azcopyProfileName, name, err)
+		}
+	}
+
+	return nil
+}
+
+// expandRemoteReference returns the URL that raw refers to, without any SAS, if raw is "name:path" for a remote of the profile.
+// Anything else is returned as-is. It is only needed where a URL is taken apart before it becomes a ResourceString;
+// SplitResourceString and InferArgumentLocation accept remote references themselves.
+func expandRemoteReference(raw string) (string, error) {
+	resolved, _, _, err := common.ResolveRemoteReference(raw)
+	return resolved, err
+}
+
+var configShowSensitive bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: configCmdShortDescription,
+	Long:  configCmdLongDescription,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: configShowCmdShortDescription,
+	Long:  configShowCmdLongDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		glcm.Info(describeEffectiveConfig(configShowSensitive))
+		glcm.Exit(nil, common.EExitCode.Success())
+	},
+}
+
+// describeEffectiveConfig shows the configuration after resolving the config file, profile and environment,
+// along with where each setting came from.
+func describeEffectiveConfig(showSensitive bool) string {
+	var sb strings.Builder
+
+	path := common.IffString(azcopyConfig.Path == "", common.DefaultConfigPath()+" (not found)", azcopyConfig.Path)
+	sb.WriteString(fmt.Sprintf("Config file: %s\nProfile: %s\n", path, azcopyProfileName))
+
+	fromProfile := make(map[string]bool)
+	for _, name := range azcopyProfileEnvironment {
+		fromProfile[name] = true
+	}
+
+	sb.WriteString("\nEnvironment variables:\n")
+	hidden := make(map[string]bool)
+	names := make([]string, 0)
+	for _, env := range common.VisibleEnvironmentVariables {
+		names = append(names, env.Name)
+		hidden[env.Name] = env.Hidden
+	}
+	for name := range azcopyProfile.Environment {
+		if _, known := hidden[name]; !known {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if hidden[name] && !showSensitive {
+			value = "REDACTED"
+		}
+		sb.WriteString(fmt.Sprintf("  %s=%s (%s)\n", name, value, common.IffString(fromProfile[name], "profile", "environment")))
+	}
+
+	sb.WriteString("\nFlag defaults:\n")
+	commands := make([]string, 0, len(azcopyProfile.Flags))
+	for command := range azcopyProfile.Flags {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		flags := make([]string, 0, len(azcopyProfile.Flags[command]))
+		for name, value := range azcopyProfile.Flags[command] {
+			flags = append(flags, fmt.Sprintf("--%s=%s", name, value))
+		}
+		sort.Strings(flags)
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", command, strings.Join(flags, " ")))
+	}
+
+	sb.WriteString("\nRemotes:\n")
+	remotes := make([]string, 0, len(azcopyProfile.Remotes))
+	for name := range azcopyProfile.Remotes {
+		remotes = append(remotes, name)
+	}
+	sort.Strings(remotes)
+	for _, name := range remotes {
+		remote := azcopyProfile.Remotes[name]
+		details := make([]string, 0)
+		if remote.Type != "" {
+			details = append(details, "type "+remote.Type)
+		}
+		if remote.Credential != "" {
+			details = append(details, "credential "+remote.Credential) // a reference, never the secret itself
+		}
+		line := fmt.Sprintf("  %s -> %s", name, remote.URL)
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return sb.String()
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cmdLineProfile, profileFlagName, "", "Name of the profile, from the config file (see 'azcopy config show'), that supplies default flag values, environment variables and endpoint aliases. Overrides AZCOPY_PROFILE.")
+
+	configShowCmd.PersistentFlags().BoolVar(&configShowSensitive, "show-sensitive", false, "Shows sensitive/secret environment variables.")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -62,6 +62,8 @@ const pipeLocation = "~pipe~"
 
 const PreservePermissionsFlag = "preserve-permissions"
 
+const MapToPOSIXACLsFlag = "map-to-posix-acls"
+
 // represents the raw copy command input from the user
 type rawCopyCmdArgs struct {
 	// from arguments
@@ -88,6 +90,11 @@ type rawCopyCmdArgs struct {
 
 	// Indicates the user wants to upload the symlink itself, not the file on the other end
 	preserveSymlinks bool
+	hardlinks        string
+	// Indicates the user wants FIFOs, device nodes and sockets recreated at the destination
+	preserveSpecialFiles bool
+	// Indicates the user wants the extended attributes of local files sent and restored
+	preserveXattrs bool
 
 	// filters from flags
 	listOfFilesToCopy string
@@ -101,6 +108,7 @@ type rawCopyCmdArgs struct {
 
 	// options from flags
 	blockSizeMB              float64
+	adaptiveBlockSize        bool
 	metadata                 string
 	contentType              string
 	contentEncoding          string
@@ -114,6 +122,7 @@ type rawCopyCmdArgs struct {
 	CheckLength              bool
 	deleteSnapshotsOption    string
 	dryrun                   bool
+	distributedQueue         string
 
 	blobTags string
 	// defines the type of the blob at the destination in case of upload / account to account copy
@@ -127,6 +136,7 @@ type rawCopyCmdArgs struct {
 	preserveSMBPermissions bool
 	preservePermissions    bool // Separate flag so that we don't get funkiness with two "flags" targeting the same boolean
 	preserveOwner          bool // works in conjunction with preserveSmbPermissions
+	mapToPOSIXACLs         bool // translates the permissions preserved by preservePermissions to and from POSIX ACLs
 	// Default true; false indicates that the destination is the target directory, rather than something we'd put a directory under (e.g. a container)
 	asSubdir bool
 	// Opt-in flag to persist additional SMB properties to Azure Files. Named ...info instead of ...properties
@@ -196,6 +206,14 @@ func (raw *rawCopyCmdArgs) parsePatterns(pattern string) (cookedPatterns []strin
 	return
 }
 
+// validateAdaptiveBlockSize rejects an explicit block size when the block size is to be chosen adaptively
+func validateAdaptiveBlockSize(adaptiveBlockSize bool, blockSize int64) error {
+	if adaptiveBlockSize && blockSize != 0 {
+		return fmt.Errorf("--%s cannot be combined with --block-size-mb, because it chooses the block size itself", common.AdaptiveBlockSizeFlagName)
+	}
+	return nil
+}
+
 // blocSizeInBytes converts a FLOATING POINT number of MiB, to a number of bytes
 // A non-nil error is returned if the conversion is not possible to do accurately (e.g. it comes out of a fractional number of bytes)
 // The purpose of using floating point is to allow specialist users (e.g. those who want small block sizes to tune their read IOPS)
@@ -222,7 +240,9 @@ func blockSizeInBytes(rawBlockSizeInMiB float64) (int64, error) {
 // returns result of stripping and if striptopdir is enabled
 // if nothing happens, the original source is returned
 func (raw rawCopyCmdArgs) stripTrailingWildcardOnRemoteSource(location common.Location) (result string, stripTopDir bool, err error) {
-	result = raw.src
+	if result, err = expandRemoteReference(raw.src); err != nil {
+		return
+	}
 	resourceURL, err := url.Parse(result)
 	gURLParts := common.NewGenericResourceURLParts(*resourceURL, location)
 
@@ -326,6 +346,23 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		return cooked, err
 	}
 
+	if err = cooked.hardlinks.Parse(raw.hardlinks); err != nil {
+		return cooked, err
+	}
+	if err = validateHardlinkHandlingMode(cooked.hardlinks, cooked.FromTo); err != nil {
+		return cooked, err
+	}
+
+	cooked.preserveSpecialFiles = raw.preserveSpecialFiles
+	if err = validatePreserveSpecialFiles(cooked.preserveSpecialFiles, cooked.FromTo); err != nil {
+		return cooked, err
+	}
+
+	cooked.preserveXattrs = raw.preserveXattrs
+	if err = validatePreserveXattrs(cooked.preserveXattrs, cooked.FromTo); err != nil {
+		return cooked, err
+	}
+
 	// copy&transform flags to type-safety
 	err = cooked.ForceWrite.Parse(raw.forceWrite)
 	if err != nil {
@@ -348,6 +385,10 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	cooked.adaptiveBlockSize = raw.adaptiveBlockSize
+	if err = validateAdaptiveBlockSize(cooked.adaptiveBlockSize, cooked.blockSize); err != nil {
+		return cooked, err
+	}
 
 	// parse the given blob type.
 	err = cooked.blobType.Parse(raw.blobType)
@@ -653,6 +694,12 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 	if cooked.preservePOSIXProperties && !areBothLocationsPOSIXAware(cooked.FromTo) {
 		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (Linux->Blob, Blob->Linux, Blob->Blob)")
 	}
+	if cooked.preservePOSIXProperties {
+		// the owner and group are mapped through the identity mapping file, if there is one
+		if _, err := common.GetIdentityMapping(); err != nil {
+			return cooked, err
+		}
+	}
 
 	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.FromTo, &cooked.ForceWrite, "preserve-smb-info"); err != nil {
 		return cooked, err
@@ -670,6 +717,12 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		return cooked, err
 	}
 	cooked.preservePermissions = common.NewPreservePermissionsOption(isUserPersistingPermissions, raw.preserveOwner, cooked.FromTo)
+	if err = validateMapToPOSIXACLs(raw.mapToPOSIXACLs, isUserPersistingPermissions, cooked.FromTo); err != nil {
+		return cooked, err
+	}
+	if raw.mapToPOSIXACLs {
+		cooked.preservePermissions = cooked.preservePermissions.MapToPOSIX()
+	}
 
 	// --as-subdir is OK on all sources and destinations, but additional verification has to be done down the line. (e.g. https://account.blob.core.windows.net is not a valid root)
 	cooked.asSubdir = raw.asSubdir
@@ -706,9 +759,6 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 			cooked.pageBlobTier != common.EPageBlobTier.None() {
 			return cooked, fmt.Errorf("blob-tier is not supported while uploading to ADLS Gen 2")
 		}
-		if cooked.preservePermissions.IsTruthy() {
-			return cooked, fmt.Errorf("preserve-smb-permissions is not supported while uploading to ADLS Gen 2")
-		}
 		if cooked.s2sPreserveProperties {
 			return cooked, fmt.Errorf("s2s-preserve-properties is not supported while uploading")
 		}
@@ -882,6 +932,15 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 
 	cooked.dryrunMode = raw.dryrun
 
+	cooked.distributedQueue = raw.distributedQueue
+	if cooked.distributedQueue != "" && (cooked.dryrunMode || cooked.isRedirection()) {
+		return cooked, errors.New("--distributed-queue cannot be used with --dry-run, or when piping")
+	}
+	if cooked.distributedQueue != "" && cooked.hardlinks == common.EHardlinkHandlingType.Preserve() && cooked.FromTo.IsDownload() {
+		// each worker runs its parts as jobs of their own, so a link can't wait for the part with the file it links to
+		return cooked, fmt.Errorf("--distributed-queue cannot be used with --%s=preserve when downloading", common.HardlinksFlagName)
+	}
+
 	if azcopyOutputVerbosity == common.EOutputVerbosity.Quiet() || azcopyOutputVerbosity == common.EOutputVerbosity.Essential() {
 		if cooked.ForceWrite == common.EOverwriteOption.Prompt() {
 			err = fmt.Errorf("cannot set output level '%s' with overwrite option '%s'", azcopyOutputVerbosity.String(), cooked.ForceWrite.String())
@@ -964,6 +1023,12 @@ func validatePreserveSMBPropertyOption(toPreserve bool, fromTo common.FromTo, ov
 	if toPreserve && flagName == PreservePermissionsFlag && (fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFSBlob() || fromTo == common.EFromTo.BlobBlobFS() || fromTo == common.EFromTo.BlobFSBlobFS()) {
 		// the user probably knows what they're doing if they're trying to persist permissions between blob-type endpoints.
 		return nil
+	} else if toPreserve && flagName == PreservePermissionsFlag && (fromTo == common.EFromTo.LocalBlobFS() || fromTo == common.EFromTo.BlobFSLocal()) {
+		// ADLS Gen2 ACLs are translated to and from POSIX ACLs, which only Linux has
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("%s is set but persistence of ADLS Gen 2 ACLs for up/downloads is supported only in Linux", flagName)
+		}
+		return validateIdentityMappingFile(flagName)
 	} else if toPreserve && !(fromTo == common.EFromTo.LocalFile() ||
 		fromTo == common.EFromTo.FileLocal() ||
 		fromTo == common.EFromTo.FileFile()) {
@@ -988,6 +1053,35 @@ func validatePreserveOwner(preserve bool, fromTo common.FromTo) error {
 	return nil
 }
 
+func validateMapToPOSIXACLs(mapToPOSIX, preservePermissions bool, fromTo common.FromTo) error {
+	if !mapToPOSIX {
+		return nil
+	}
+	if !preservePermissions {
+		return fmt.Errorf("flag --%s can only be used with --%s", MapToPOSIXACLsFlag, PreservePermissionsFlag)
+	}
+	if fromTo != common.EFromTo.FileLocal() && fromTo != common.EFromTo.LocalFile() {
+		return fmt.Errorf("flag --%s can only be used on Azure Files<->Local", MapToPOSIXACLsFlag)
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("flag --%s is only supported on Linux", MapToPOSIXACLsFlag)
+	}
+
+	return validateIdentityMappingFile(MapToPOSIXACLsFlag)
+}
+
+// validateIdentityMappingFile loads the identity mapping now, so that a mistake in it fails the command rather than every transfer.
+func validateIdentityMappingFile(flagName string) error {
+	mapping, err := common.GetIdentityMapping()
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		return fmt.Errorf("flag --%s requires the %s environment variable", flagName, common.EEnvironmentVariable.SIDMappingFile().Name)
+	}
+	return nil
+}
+
 func validateSymlinkHandlingMode(symlinkHandling common.SymlinkHandlingType, fromTo common.FromTo) error {
 	if symlinkHandling.Preserve() {
 		switch fromTo {
@@ -1003,6 +1097,54 @@ func validateSymlinkHandlingMode(symlinkHandling common.SymlinkHandlingType, fro
 	return nil // other older symlink handling modes can work on all OSes
 }
 
+func validateHardlinkHandlingMode(hardlinkHandling common.HardlinkHandlingType, fromTo common.FromTo) error {
+	if hardlinkHandling == common.EHardlinkHandlingType.Preserve() {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("--%s=preserve is only supported on Linux", common.HardlinksFlagName)
+		}
+		switch fromTo {
+		case common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal():
+			return nil
+		default:
+			return fmt.Errorf("--%s=preserve can only be used on Local<->Blob", common.HardlinksFlagName)
+		}
+	}
+
+	return nil
+}
+
+func validatePreserveSpecialFiles(preserveSpecialFiles bool, fromTo common.FromTo) error {
+	if preserveSpecialFiles {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("flag --%s is only supported on Linux", common.PreserveSpecialFilesFlagName)
+		}
+		switch fromTo {
+		case common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal():
+			return nil
+		default:
+			return fmt.Errorf("flag --%s can only be used on Local<->Blob", common.PreserveSpecialFilesFlagName)
+		}
+	}
+
+	return nil
+}
+
+func validatePreserveXattrs(preserveXattrs bool, fromTo common.FromTo) error {
+	if preserveXattrs {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("flag --%s is only supported on Linux", common.PreserveXattrsFlagName)
+		}
+		switch fromTo {
+		case common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal():
+			return nil
+		default:
+			return fmt.Errorf("flag --%s can only be used on Local<->Blob", common.PreserveXattrsFlagName)
+		}
+	}
+
+	return nil
+}
+
 func crossValidateSymlinksAndPermissions(symlinkHandling common.SymlinkHandlingType, preservePermissions bool) error {
 	if symlinkHandling != common.ESymlinkHandlingType.Skip() && preservePermissions {
 		return errors.New("cannot handle symlinks when preserving permissions (since the correct permission inheritance behaviour for symlink targets is undefined)")
@@ -1134,6 +1276,7 @@ type CookedCopyCmdArgs struct {
 	Recursive          bool
 	StripTopDir        bool
 	SymlinkHandling    common.SymlinkHandlingType
+	hardlinks          common.HardlinkHandlingType
 	ForceWrite         common.OverwriteOption // says whether we should try to overwrite
 	ForceIfReadOnly    bool                   // says whether we should _force_ any overwrites (triggered by forceWrite) to work on Azure Files objects that are set to read-only
 	IsSourceDir        bool
@@ -1141,7 +1284,8 @@ type CookedCopyCmdArgs struct {
 	autoDecompress bool
 
 	// options from flags
-	blockSize int64
+	blockSize         int64
+	adaptiveBlockSize bool
 	// list of blobTypes to exclude while enumerating the transfer
 	excludeBlobType []azblob.BlobType
 	blobType        common.BlobType
@@ -1191,6 +1335,10 @@ type CookedCopyCmdArgs struct {
 	preserveSMBInfo bool
 	// Whether the user wants to preserve the POSIX properties ...
 	preservePOSIXProperties bool
+	// Whether the user wants FIFOs, device nodes and sockets recreated rather than sent as empty files
+	preserveSpecialFiles bool
+	// Whether the user wants the extended attributes of local files sent and restored
+	preserveXattrs bool
 
 	// Whether to enable Windows special privileges
 	backupMode bool
@@ -1224,6 +1372,12 @@ type CookedCopyCmdArgs struct {
 	isCleanupJob      bool // triggers abbreviated status reporting, since we don't want full reporting for cleanup jobs
 	cleanupJobMessage string
 
+	// isBenchmark is set on the job that the bench command measures, isSetupJob on one that only generates the data for it
+	isBenchmark bool
+	isSetupJob  bool
+	// set when the results of a benchmark are to be added to the history
+	benchmarkRecorder *benchmarkRecorder
+
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	IncludeDirectoryStubs bool
 
@@ -1233,6 +1387,9 @@ type CookedCopyCmdArgs struct {
 	// specify if dry run mode on
 	dryrunMode bool
 
+	// the queue that the job's parts go to, for workers to execute, if the job is distributed
+	distributedQueue string
+
 	CpkOptions common.CpkOptions
 
 	// Optional flag that permanently deletes soft deleted blobs
@@ -1243,6 +1400,8 @@ type CookedCopyCmdArgs struct {
 
 	// Bitmasked uint checking which properties to transfer
 	propertiesToTransfer common.SetPropertiesFlags
+	// the ADLS Gen2 ACL entries that an acl set, modify or remove job applies
+	accessControlList string
 
 	trailingDot common.TrailingDotOption
 }
@@ -1275,6 +1434,12 @@ func (cca *CookedCopyCmdArgs) process() error {
 		// if no error, the operation is now complete
 		glcm.Exit(nil, common.EExitCode.Success())
 	}
+
+	if cca.distributedQueue != "" {
+		if err := useDistributedQueue(cca.jobID, cca.distributedQueue); err != nil {
+			return fmt.Errorf("cannot use the distributed queue: %w", err)
+		}
+	}
 	return cca.processCopyJobPartOrders()
 }
 
@@ -1302,6 +1467,9 @@ func (cca *CookedCopyCmdArgs) processRedirectionDownload(blobResource common.Res
 	// The isPublic flag is useful in S2S transfers but doesn't much matter for download. Fortunately, no S2S happens here.
 	// This means that if there's auth, there's auth. We're happy and can move on.
 	// GetCredentialInfoForLocation also populates oauth token fields... so, it's very easy.
+	if err = addCredentialHelperSAS(ctx, &blobResource, common.ELocation.Blob(), true); err != nil {
+		return err
+	}
 	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.Blob(), blobResource.Value, blobResource.SAS, true, cca.CpkOptions)
 
 	if err != nil {
@@ -1352,6 +1520,9 @@ func (cca *CookedCopyCmdArgs) processRedirectionUpload(blobResource common.Resou
 	}
 
 	// GetCredentialInfoForLocation populates oauth token fields... so, it's very easy.
+	if err := addCredentialHelperSAS(ctx, &blobResource, common.ELocation.Blob(), false); err != nil {
+		return err
+	}
 	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.Blob(), blobResource.Value, blobResource.SAS, false, cca.CpkOptions)
 
 	if err != nil {
@@ -1463,6 +1634,14 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		return fmt.Errorf("failed to resolve destination: %w", err)
 	}
 
+	// deletions and property changes need write access to the source, which public access never gives
+	if err = addCredentialHelperSAS(ctx, &cca.Source, cca.FromTo.From(), !cca.FromTo.IsDelete() && !cca.FromTo.IsSetProperties()); err != nil {
+		return err
+	}
+	if err = addCredentialHelperSAS(ctx, &cca.Destination, cca.FromTo.To(), false); err != nil {
+		return err
+	}
+
 	// Note: credential info here is only used by remove at the moment.
 	// TODO: Get the entirety of remove into the new copyEnumeratorInit script so we can remove this
 	//       and stop having two places in copy that we get credential info
@@ -1507,6 +1686,7 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		BlobAttributes: common.BlobTransferAttributes{
 			BlobType:                 cca.blobType,
 			BlockSizeInBytes:         cca.blockSize,
+			AdaptiveBlockSize:        cca.adaptiveBlockSize,
 			ContentType:              cca.contentType,
 			ContentEncoding:          cca.contentEncoding,
 			ContentLanguage:          cca.contentLanguage,
@@ -1528,6 +1708,7 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		FileAttributes: common.FileTransferAttributes{
 			TrailingDot: cca.trailingDot,
 		},
+		IsBenchmark: cca.isBenchmark,
 	}
 
 	from := cca.FromTo.From()
@@ -1582,6 +1763,12 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		}
 
 	case cca.FromTo.IsSetProperties():
+		if cca.propertiesToTransfer.ShouldTransferAccessControl() {
+			// like dfs deletes, ACL changes are applied recursively by the service
+			err = setBfsAccessControl(cca)
+			break
+		}
+
 		// Set properties as well
 		e, createErr := setPropertiesEnumerator(cca)
 		if createErr != nil {
@@ -1667,6 +1854,10 @@ func (cca *CookedCopyCmdArgs) hasFollowup() bool {
 func (cca *CookedCopyCmdArgs) launchFollowup(priorJobExitCode common.ExitCode) {
 	go func() {
 		glcm.AllowReinitiateProgressReporting()
+		if cca.isSetupJob {
+			// the job that follows is the one being measured, so its tuning results and performance advice mustn't include this one
+			jobsAdmin.JobsAdmin.ResetConcurrencyTuning()
+		}
 		cca.followupJobArgs.priorJobExitCode = &priorJobExitCode
 		err := cca.followupJobArgs.process()
 		if err == NothingToRemoveError {
@@ -1695,6 +1886,9 @@ func (cca *CookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &glcm)
 	})
 	summary.IsCleanupJob = cca.isCleanupJob // only FE knows this, so we can only set it here
+	if cca.benchmarkRecorder != nil {
+		cca.benchmarkRecorder.sample(summary.PerfConstraint)
+	}
 	cleanupStatusString := fmt.Sprintf("Cleanup %v/%v", summary.TransfersCompleted, summary.TotalTransfers)
 
 	jobDone := summary.JobStatus.IsJobDone()
@@ -1740,8 +1934,7 @@ func (cca *CookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			}
 
 			// indicate whether constrained by disk or not
-			isBenchmark := cca.FromTo.From() == common.ELocation.Benchmark()
-			perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, isBenchmark)
+			perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, cca.isBenchmark)
 			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s, %s%s%s",
 				summary.PercentComplete,
 				summary.TransfersCompleted,
@@ -1757,13 +1950,26 @@ func (cca *CookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			exitCode = common.EExitCode.Error()
 		}
 
+		if cca.benchmarkRecorder != nil {
+			if err := cca.benchmarkRecorder.record(summary, duration); err != nil {
+				glcm.Info("Failed to add the results to the benchmark history due to error: " + err.Error())
+			} else {
+				glcm.Info("The results were added to the benchmark history in " + cca.benchmarkRecorder.historyFile)
+			}
+		}
+
 		builder := func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
 				jsonOutput, err := json.Marshal(summary)
 				common.PanicIfErr(err)
 				return string(jsonOutput)
 			} else {
-				screenStats, logStats := formatExtraStats(cca.FromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
+				perfAdvice := summary.PerformanceAdvice
+				if cca.isSetupJob {
+					perfAdvice = nil // the results that matter are those of the job that follows
+				}
+
+				screenStats, logStats := formatExtraStats(cca.isBenchmark, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage, summary.RequestLatencies)
 
 				output := fmt.Sprintf(
 					`
@@ -1798,7 +2004,7 @@ Final Job Status: %v%s%s
 					summary.TotalBytesTransferred,
 					summary.JobStatus,
 					screenStats,
-					formatPerfAdvice(summary.PerformanceAdvice))
+					formatPerfAdvice(perfAdvice))
 
 				// abbreviated output for cleanup jobs
 				if cca.isCleanupJob {
@@ -1855,7 +2061,7 @@ func formatPerfAdvice(advice []common.PerformanceAdvice) string {
 
 // format extra stats to include in the log.  If benchmarking, also output them on screen (but not to screen in normal
 // usage because too cluttered)
-func formatExtraStats(fromTo common.FromTo, avgIOPS int, avgE2EMilliseconds int, networkErrorPercent float32, serverBusyPercent float32) (screenStats, logStats string) {
+func formatExtraStats(isBenchmark bool, avgIOPS int, avgE2EMilliseconds int, networkErrorPercent float32, serverBusyPercent float32, latencies []common.RequestLatencySummary) (screenStats, logStats string) {
 	logStats = fmt.Sprintf(
 		`
 
@@ -1864,9 +2070,9 @@ IOPS: %v
 End-to-end ms per request: %v
 Network Errors: %.2f%%
 Server Busy: %.2f%%`,
-		avgIOPS, avgE2EMilliseconds, networkErrorPercent, serverBusyPercent)
+		avgIOPS, avgE2EMilliseconds, networkErrorPercent, serverBusyPercent) + formatRequestLatencies(latencies)
 
-	if fromTo.From() == common.ELocation.Benchmark() {
+	if isBenchmark {
 		screenStats = logStats
 		logStats = "" // since will display in the screen stats, and they get logged too
 	}
@@ -1874,6 +2080,33 @@ Server Busy: %.2f%%`,
 	return
 }
 
+// format the latency percentiles of each type of request, followed by the slowest requests of each type,
+// with the IDs that are needed to find them in the logs of the service
+func formatRequestLatencies(latencies []common.RequestLatencySummary) string {
+	if len(latencies) == 0 {
+		return ""
+	}
+	b := strings.Builder{}
+	b.WriteString("\n\nRequest latencies (ms):\n")
+	b.WriteString(fmt.Sprintf("%-26s %10s %8s %8s %8s %8s", "Operation", "Count", "p50", "p95", "p99", "Max"))
+	for _, l := range latencies {
+		b.WriteString(fmt.Sprintf("\n%-26s %10d %8d %8d %8d %8d", l.Operation, l.Count, l.P50Milliseconds, l.P95Milliseconds, l.P99Milliseconds, l.MaxMilliseconds))
+	}
+
+	b.WriteString("\n\nSlowest requests:")
+	for _, l := range latencies {
+		for _, r := range l.SlowestRequests {
+			status := "no response"
+			if r.StatusCode != 0 {
+				status = fmt.Sprintf("status %d", r.StatusCode)
+			}
+			b.WriteString(fmt.Sprintf("\n%s: %d ms at %s, %s, client request ID %s, service request ID %s, %s",
+				l.Operation, r.Milliseconds, r.StartTime.UTC().Format(time.RFC3339), status, r.ClientRequestID, r.ServiceRequestID, r.URL))
+		}
+	}
+	return b.String()
+}
+
 // Is disk speed looking like a constraint on throughput?  Ignore the first little-while,
 // to give an (arbitrary) amount of time for things to reach steady-state.
 func getPerfDisplayText(perfDiagnosticStrings []string, constraint common.PerfConstraint, durationOfJob time.Duration, isBench bool) (perfString string, diskString string) {
@@ -2004,6 +2237,7 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.excludeBlobType, "exclude-blob-type", "", "Optionally specifies the type of blob (BlockBlob/ PageBlob/ AppendBlob) to exclude when copying blobs from the container "+
 		"or the account. Use of this flag is not applicable for copying data from non azure-service to service. More than one blob should be separated by ';'. ")
 	// options change how the transfers are performed
+	cpCmd.PersistentFlags().BoolVar(&raw.adaptiveBlockSize, common.AdaptiveBlockSizeFlagName, false, "Choose the block size of each file as its transfer starts, from the size of the file, the throughput of earlier requests and the limits of the service, instead of using one size for all files. For downloads, bigger ranges are used when requests have high latency but the service is not busy. Cannot be combined with --block-size-mb.")
 	cpCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage, and downloading from Azure Storage. The default value is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
 	cpCmd.PersistentFlags().StringVar(&raw.blobType, "blob-type", "Detect", "Defines the type of blob at the destination. This is used for uploading blobs and when copying between accounts (default 'Detect'). Valid values include 'Detect', 'BlockBlob', 'PageBlob', and 'AppendBlob'. "+
 		"When copying between accounts, a value of 'Detect' causes AzCopy to use the type of source blob to determine the type of the destination blob. When uploading a file, 'Detect' determines if the file is a VHD or a VHDX file based on the file extension. If the file is either a VHD or VHDX file, AzCopy treats the file as a page blob.")
@@ -2018,11 +2252,15 @@ func init() {
 	cpCmd.PersistentFlags().BoolVar(&raw.noGuessMimeType, "no-guess-mime-type", false, "Prevents AzCopy from detecting the content-type based on the extension or content of the file.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveLastModifiedTime, "preserve-last-modified-time", false, "Only available when destination is file system.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBPermissions, "preserve-smb-permissions", false, "False by default. Preserves SMB ACLs between aware resources (Windows and Azure Files). For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
+	cpCmd.PersistentFlags().BoolVar(&raw.mapToPOSIXACLs, MapToPOSIXACLsFlag, false, "False by default. Only has an effect with --preserve-permissions, between Azure Files and a Linux file system without SMB security descriptors (e.g. ext4 or XFS). Translates the SMB permissions to the POSIX ACL (system.posix_acl_access) of each file and folder in downloads, and back in uploads, using the SID to uid/gid mapping file named by the AZCOPY_SID_MAPPING_FILE environment variable. Permissions that can't be translated, such as deny ACEs or SIDs missing from the mapping, are listed in the log.")
 	cpCmd.PersistentFlags().BoolVar(&raw.asSubdir, "as-subdir", true, "True by default. Places folder sources as subdirectories under the destination.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBInfo, "preserve-smb-info", (runtime.GOOS == "windows"), "Preserves SMB property info (last write time, creation time, attribute bits) between SMB-aware resources (Windows and Azure Files). On windows, this flag will be set to true by default. If the source or destination is a volume mounted on Linux using SMB protocol, this flag will have to be explicitly set to true. Only the attribute bits supported by Azure Files will be transferred; any others will be ignored. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is never preserved for folders.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preservePOSIXProperties, "preserve-posix-properties", false, "'Preserves' property info gleaned from stat or statx into object metadata.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSymlinks, common.PreserveSymlinkFlagName, false, "If enabled, symlink destinations are preserved as the blob content, rather than uploading the file/folder on the other end of the symlink")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveXattrs, common.PreserveXattrsFlagName, false, "Linux only, for Local<->Blob. False by default. Preserves the user.* and security.* extended attributes of files. They are stored in the blob's metadata, or, if they take more than 4 KiB, in a sidecar blob with the suffix '.azcopy-xattrs' next to it, and are restored when downloading. Sidecar blobs are not transferred as files of their own when this flag is used, and are deleted along with their blobs, or when the extended attributes fit in the metadata again.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveSpecialFiles, common.PreserveSpecialFilesFlagName, false, "Linux only, for Local<->Blob. False by default. Uploads FIFOs, character and block devices and sockets as empty blobs that record the type, mode and device number in their metadata, and recreates them when downloading. Creating device nodes requires the privileges to do so (e.g. root). Without this flag, such files are uploaded as empty blobs and downloaded as empty files.")
+	cpCmd.PersistentFlags().StringVar(&raw.hardlinks, common.HardlinksFlagName, "follow", "Linux only, for Local<->Blob. 'follow' (default) transfers every name of a hard-linked file as a file of its own. 'preserve' uploads the data once, with the other names as empty blobs that refer to it, and recreates the hard links when downloading (which can't be combined with --distributed-queue).")
 	cpCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
 	cpCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 	cpCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
@@ -2043,6 +2281,7 @@ func init() {
 	cpCmd.PersistentFlags().BoolVar(&raw.includeDirectoryStubs, "include-directory-stub", false, "False by default to ignore directory stubs. Directory stubs are blobs with metadata 'hdi_isfolder:true'. Setting value to true will preserve directory stubs during transfers.")
 	cpCmd.PersistentFlags().BoolVar(&raw.disableAutoDecoding, "disable-auto-decoding", false, "False by default to enable automatic decoding of illegal chars on Windows. Can be set to true to disable automatic decoding.")
 	cpCmd.PersistentFlags().BoolVar(&raw.dryrun, "dry-run", false, "Prints the file paths that would be copied by this command. This flag does not copy the actual files.")
+	cpCmd.PersistentFlags().StringVar(&raw.distributedQueue, "distributed-queue", "", "Put the parts of the job into the queue in this shared directory, to be executed by 'azcopy worker' on other machines, rather than executing them. Local paths must be the same on every machine.")
 	// s2sGetPropertiesInBackend is an optional flag for controlling whether S3 object's or Azure file's full properties are get during enumerating in frontend or
 	// right before transferring in ste(backend).
 	// The traditional behavior of all existing enumerator is to get full properties during enumerating(more specifically listing),
@@ -2078,5 +2317,5 @@ func init() {
 
 	// Deprecate the old persist-smb-permissions flag
 	_ = cpCmd.PersistentFlags().MarkHidden("preserve-smb-permissions")
-	cpCmd.PersistentFlags().BoolVar(&raw.preservePermissions, PreservePermissionsFlag, false, "False by default. Preserves ACLs between aware resources (Windows and Azure Files, or ADLS Gen 2 to ADLS Gen 2). For Hierarchical Namespace accounts, you will need a container SAS or OAuth token with Modify Ownership and Modify Permissions permissions. For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
+	cpCmd.PersistentFlags().BoolVar(&raw.preservePermissions, PreservePermissionsFlag, false, "False by default. Preserves ACLs between aware resources (Windows and Azure Files, ADLS Gen 2 to ADLS Gen 2, or Linux and ADLS Gen 2, where POSIX ACLs are translated using the uid/gid to AAD object ID mapping file named by the AZCOPY_SID_MAPPING_FILE environment variable). For Hierarchical Namespace accounts, you will need a container SAS or OAuth token with Modify Ownership and Modify Permissions permissions. For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
 }
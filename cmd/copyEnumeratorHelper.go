@@ -19,20 +19,9 @@ func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer
 	// we do this so that in the case of large transfer, the transfer engine can get started
 	// while the frontend is still gathering more transfers
 	if len(e.Transfers.List) == NumOfFilesPerDispatchJobPart {
-		shuffleTransfers(e.Transfers.List)
-		resp := common.CopyJobPartOrderResponse{}
-
-		Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
-
-		if !resp.JobStarted {
-			return fmt.Errorf("copy job part order with JobId %s and part number %d failed because %s", e.JobID, e.PartNum, resp.ErrorMsg)
-		}
-		// if the current part order sent to engine is 0, then start fetching the Job Progress summary.
-		if e.PartNum == 0 {
-			cca.waitUntilJobCompletion(false)
+		if err := dispatchPart(e, cca); err != nil {
+			return err
 		}
-		e.Transfers = common.Transfers{}
-		e.PartNum++
 	}
 
 	// only append the transfer after we've checked and dispatched a part
@@ -42,7 +31,7 @@ func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer
 		e.Transfers.List = append(e.Transfers.List, transfer)
 		e.Transfers.TotalSizeInBytes += uint64(transfer.SourceSize)
 		switch transfer.EntityType {
-		case common.EEntityType.File():
+		case common.EEntityType.File(), common.EEntityType.Hardlink(), common.EEntityType.SpecialFile():
 			e.Transfers.FileTransferCount++
 		case common.EEntityType.Folder():
 			e.Transfers.FolderTransferCount++
@@ -54,6 +43,25 @@ func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer
 	return nil
 }
 
+// dispatchPart sends the transfers gathered so far as a job part, and starts a new one
+func dispatchPart(e *common.CopyJobPartOrderRequest, cca *CookedCopyCmdArgs) error {
+	shuffleTransfers(e.Transfers.List)
+	resp := common.CopyJobPartOrderResponse{}
+
+	Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
+
+	if !resp.JobStarted {
+		return fmt.Errorf("copy job part order with JobId %s and part number %d failed because %s", e.JobID, e.PartNum, resp.ErrorMsg)
+	}
+	// if the current part order sent to engine is 0, then start fetching the Job Progress summary.
+	if e.PartNum == 0 {
+		cca.waitUntilJobCompletion(false)
+	}
+	e.Transfers = common.Transfers{}
+	e.PartNum++
+	return nil
+}
+
 // this function shuffles the transfers before they are dispatched
 // this is done to avoid hitting the same partition continuously in an append only pattern
 // TODO this should probably be removed after the high throughput block blob feature is implemented on the service side
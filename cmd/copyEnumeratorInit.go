@@ -57,6 +57,7 @@ func (cca *CookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 	jobPartOrder.PreserveSMBInfo = cca.preserveSMBInfo
 	// We set preservePOSIXProperties if the customer has explicitly asked for this in transfer or if it is just a Posix-property only transfer
 	jobPartOrder.PreservePOSIXProperties = cca.preservePOSIXProperties || (cca.ForceWrite == common.EOverwriteOption.PosixProperties())
+	jobPartOrder.PreserveXattrs = cca.preserveXattrs
 
 	// Infer on download so that we get LMT and MD5 on files download
 	// On S2S transfers the following rules apply:
@@ -83,6 +84,12 @@ func (cca *CookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		return nil, err
 	}
 
+	// with --preserve-special-files, FIFOs, device nodes and sockets are recreated rather than sent as empty files
+	traverser = withSpecialFileHandling(traverser, cca.preserveSpecialFiles, cca.FromTo, cca.Source.ValueLocal())
+	// with --hardlinks=preserve, further names of a file go to the destination as links to it
+	traverser = withHardlinkTracking(traverser, newHardlinkTracker(cca.hardlinks, cca.FromTo, cca.Source.ValueLocal()))
+	var hardlinks deferredHardlinks
+
 	// Check if the destination is a directory to correctly decide where our files land
 	isDestDir := cca.isDestDirectory(cca.Destination, &ctx)
 	if cca.ListOfVersionIDs != nil && (!(cca.FromTo == common.EFromTo.BlobLocal() || cca.FromTo == common.EFromTo.BlobTrash()) || cca.IsSourceDir || !isDestDir) {
@@ -318,11 +325,28 @@ func (cca *CookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		}
 
 		if shouldSendToSte {
+			if transfer.EntityType == common.EEntityType.Hardlink() && cca.FromTo.IsDownload() {
+				// the files they link to have to be downloaded first, so the links go in parts of their own, at the end
+				return hardlinks.add(transfer)
+			}
 			return addTransfer(&jobPartOrder, transfer, cca)
 		}
 		return nil
 	}
 	finalizer := func() error {
+		if !hardlinks.empty() {
+			if len(jobPartOrder.Transfers.List) > 0 {
+				if err := dispatchPart(&jobPartOrder, cca); err != nil {
+					return err
+				}
+			}
+			err := hardlinks.release(func(transfer common.CopyTransfer) error {
+				return addTransfer(&jobPartOrder, transfer, cca)
+			})
+			if err != nil {
+				return err
+			}
+		}
 		return dispatchFinalPart(&jobPartOrder, cca)
 	}
 
@@ -417,6 +441,10 @@ func (cca *CookedCopyCmdArgs) InitModularFilters() []ObjectFilter {
 		}
 	}
 
+	if cca.preserveXattrs {
+		filters = append(filters, &excludeXattrsSidecarFilter{})
+	}
+
 	switch cca.permanentDeleteOption {
 	case common.EPermanentDeleteOption.Snapshots():
 		filters = append(filters, &permDeleteFilter{deleteSnapshots: true})
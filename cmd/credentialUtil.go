@@ -86,7 +86,7 @@ func GetOAuthTokenManagerInstance() (*common.UserOAuthTokenManager, error) {
 			return
 		}
 
-		if autoLoginType != "SPN" && autoLoginType != "MSI" && autoLoginType != "DEVICE" {
+		if autoLoginType != "SPN" && autoLoginType != "MSI" && autoLoginType != "DEVICE" && autoLoginType != "HELPER" && autoLoginType != "WORKLOAD" {
 			glcm.Error("Invalid Auto-login type specified.")
 			return
 		}
@@ -116,6 +116,14 @@ func GetOAuthTokenManagerInstance() (*common.UserOAuthTokenManager, error) {
 
 		case "DEVICE":
 			lca.identity = false
+
+		case "HELPER":
+			lca.credentialHelper = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.CredentialHelper())
+
+		case "WORKLOAD":
+			lca.workloadIdentity = true
+			lca.applicationID = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ApplicationID())
+			lca.federatedTokenFile = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.FederatedTokenFile())
 		}
 
 		lca.persistToken = false
@@ -207,58 +215,8 @@ func getBlobCredentialType(ctx context.Context, blobResourceURL string, canBePub
 		return common.ECredentialType.Anonymous(), false, nil
 	}
 
-	checkPublic := func() (isPublicResource bool) {
-		if !canBePublic { // Cannot possibly be public - like say a destination EP
-			return false
-		}
-		p := azblob.NewPipeline(
-			azblob.NewAnonymousCredential(),
-			azblob.PipelineOptions{
-				Retry: azblob.RetryOptions{
-					Policy:        azblob.RetryPolicyExponential,
-					MaxTries:      ste.UploadMaxTries,
-					TryTimeout:    ste.UploadTryTimeout,
-					RetryDelay:    ste.UploadRetryDelay,
-					MaxRetryDelay: ste.UploadMaxRetryDelay,
-				},
-				RequestLog: azblob.RequestLogOptions{
-					SyslogDisabled: common.IsForceLoggingDisabled(),
-				},
-			})
-
-		isContainer := copyHandlerUtil{}.urlIsContainerOrVirtualDirectory(resourceURL)
-		isPublicResource = false
-
-		// Scenario 1: When resourceURL points to a container
-		// Scenario 2: When resourceURL points to a virtual directory.
-		// Check if the virtual directory is accessible by doing GetProperties on container.
-		// Virtual directory can be accessed/scanned only when its parent container is public.
-		bURLParts := azblob.NewBlobURLParts(*resourceURL)
-		bURLParts.BlobName = ""
-		containerURL := azblob.NewContainerURL(bURLParts.URL(), p)
-
-		if bURLParts.ContainerName == "" || strings.Contains(bURLParts.ContainerName, "*") {
-			// Service level searches can't possibly be public.
-			return false
-		}
-
-		if _, err := containerURL.GetProperties(ctx, azblob.LeaseAccessConditions{}); err == nil {
-			return true
-		}
-
-		if !isContainer {
-			clientProvidedKey := azblob.ClientProvidedKeyOptions{}
-			if cpkOptions.IsSourceEncrypted {
-				clientProvidedKey = common.GetClientProvidedKey(cpkOptions)
-			}
-			// Scenario 3: When resourceURL points to a blob
-			blobURL := azblob.NewBlobURL(*resourceURL, p)
-			if _, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, clientProvidedKey); err == nil {
-				return true
-			}
-		}
-
-		return
+	checkPublic := func() bool {
+		return canBePublic && isPublicBlobResource(ctx, *resourceURL, cpkOptions)
 	}
 
 	// If SAS token doesn't exist, it could be using OAuth token or the resource is public.
@@ -287,6 +245,58 @@ func getBlobCredentialType(ctx context.Context, blobResourceURL string, canBePub
 	}
 }
 
+// isPublicBlobResource checks whether the container, virtual directory or blob can be read without any credential.
+func isPublicBlobResource(ctx context.Context, resourceURL url.URL, cpkOptions common.CpkOptions) (isPublicResource bool) {
+	p := azblob.NewPipeline(
+		azblob.NewAnonymousCredential(),
+		azblob.PipelineOptions{
+			Retry: azblob.RetryOptions{
+				Policy:        azblob.RetryPolicyExponential,
+				MaxTries:      ste.UploadMaxTries,
+				TryTimeout:    ste.UploadTryTimeout,
+				RetryDelay:    ste.UploadRetryDelay,
+				MaxRetryDelay: ste.UploadMaxRetryDelay,
+			},
+			RequestLog: azblob.RequestLogOptions{
+				SyslogDisabled: common.IsForceLoggingDisabled(),
+			},
+		})
+
+	isContainer := copyHandlerUtil{}.urlIsContainerOrVirtualDirectory(&resourceURL)
+	isPublicResource = false
+
+	// Scenario 1: When resourceURL points to a container
+	// Scenario 2: When resourceURL points to a virtual directory.
+	// Check if the virtual directory is accessible by doing GetProperties on container.
+	// Virtual directory can be accessed/scanned only when its parent container is public.
+	bURLParts := azblob.NewBlobURLParts(resourceURL)
+	bURLParts.BlobName = ""
+	containerURL := azblob.NewContainerURL(bURLParts.URL(), p)
+
+	if bURLParts.ContainerName == "" || strings.Contains(bURLParts.ContainerName, "*") {
+		// Service level searches can't possibly be public.
+		return false
+	}
+
+	if _, err := containerURL.GetProperties(ctx, azblob.LeaseAccessConditions{}); err == nil {
+		return true
+	}
+
+	if !isContainer {
+		clientProvidedKey := azblob.ClientProvidedKeyOptions{}
+		if cpkOptions.IsSourceEncrypted {
+			clientProvidedKey = common.GetClientProvidedKey(cpkOptions)
+		}
+		// Scenario 3: When resourceURL points to a blob
+		blobURL := azblob.NewBlobURL(resourceURL, p)
+		if _, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, clientProvidedKey); err == nil {
+			return true
+		}
+	}
+
+	return
+}
+
 // getBlobFSCredentialType is used to get BlobFS's credential type when user wishes to use OAuth session mode.
 // The verification logic follows following rules:
 // 1. Check if there is a SAS query appended to the URL
@@ -357,6 +367,58 @@ func getAzureFileCredentialType() (common.CredentialType, error) {
 	return common.ECredentialType.Anonymous(), nil
 }
 
+// getCredentialHelperSAS asks the configured credential helper for a SAS for an Azure resource that was given without one,
+// but only when no other credential applies: no credential type is forced, there is no OAuth token (or shared key, for BlobFS),
+// and, where canBePublic, the resource cannot be read anonymously.
+// When the auto login type is HELPER, the helper is used for OAuth tokens instead, and no SAS is requested.
+func getCredentialHelperSAS(ctx context.Context, resource string, location common.Location, canBePublic bool) (string, error) {
+	if location != common.ELocation.Blob() && location != common.ELocation.BlobFS() && location != common.ELocation.File() {
+		return "", nil
+	}
+
+	helper := common.GetCredentialHelperFromEnvVar()
+	if helper.IsEmpty() || strings.EqualFold(glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AutoLoginType()), "HELPER") {
+		return "", nil
+	}
+
+	if GetCredTypeFromEnvVar() != common.ECredentialType.Unknown() {
+		return "", nil
+	}
+	switch location {
+	case common.ELocation.Blob():
+		if oAuthTokenExists() {
+			return "", nil
+		}
+		if resourceURL, err := url.Parse(resource); err == nil && canBePublic && !strings.HasPrefix(resourceURL.Host, "md-") &&
+			isPublicBlobResource(ctx, *resourceURL, common.CpkOptions{}) {
+			return "", nil
+		}
+	case common.ELocation.BlobFS():
+		if oAuthTokenExists() ||
+			(glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AccountName()) != "" && glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AccountKey()) != "") {
+			return "", nil
+		}
+	}
+
+	resp, err := helper.Get(ctx, common.CredentialHelperRequest{
+		Kind:     common.ECredentialHelperKind.SAS(),
+		Resource: resource,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain a SAS for %s from the credential helper: %w", resource, err)
+	}
+
+	return resp.SAS, nil
+}
+
+// addCredentialHelperSAS fills in the SAS of a resource that was given without one from the credential helper, if it applies.
+func addCredentialHelperSAS(ctx context.Context, resource *common.ResourceString, location common.Location, canBePublic bool) (err error) {
+	if resource.SAS == "" {
+		resource.SAS, err = getCredentialHelperSAS(ctx, resource.Value, location, canBePublic)
+	}
+	return err
+}
+
 var stashedEnvCredType = ""
 
 // GetCredTypeFromEnvVar tries to get credential type from environment variable defined by envVarCredentialType.
@@ -545,6 +607,10 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 		}
 	}
 
+	if err = prepareSASRenewal(location, resource, resourceSAS, isSource); err != nil {
+		return common.ECredentialType.Unknown(), false, err
+	}
+
 	if resourceSAS != "" && !mdAccount {
 		credType = common.ECredentialType.Anonymous()
 	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() || location == common.ELocation.GCP() {
@@ -0,0 +1,596 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// A distributed job is enumerated by a coordinator ("azcopy copy --distributed-queue=DIR"), which puts each job part
+// into a queue rather than executing it, and executed by "azcopy worker --queue=DIR" processes on any number of machines.
+// Each worker claims a part, runs it as a job of its own in its STE, and reports the outcome back to the queue,
+// from which the coordinator (and "jobs show") aggregates the progress of the whole job.
+//
+// The queue is a directory that all the machines share. Each job is laid out as:
+//
+//	DIR/<jobID>/job.json                     the job's description and totals, written by the coordinator
+//	DIR/<jobID>/pending/<part>.json          parts waiting for a worker
+//	DIR/<jobID>/claimed/<part>.json.<worker> parts being executed; the file's modification time is the worker's lease
+//	DIR/<jobID>/progress/<part>.json         the latest progress of claimed parts
+//	DIR/<jobID>/done/<part>.json             the final summaries of executed parts
+//
+// Parts are claimed by renaming them, which is atomic, so only one worker gets each part. A worker renews its lease
+// while it executes the part; if the worker dies, its lease expires and another worker claims the part again.
+// A worker that finds an expired lease renames the part to its own name before checking the lease again, and gives
+// the part back if its owner renewed the lease in the meantime.
+// Queued parts hold no SAS tokens or OAuth tokens: workers supply their own credentials.
+
+const (
+	distributedJobFileName = "job.json"
+	distributedPending     = "pending"
+	distributedClaimed     = "claimed"
+	distributedProgress    = "progress"
+	distributedDone        = "done"
+
+	// distributedQueueMarkerSuffix names the file, in the plan folder, that records where the coordinator queued a job
+	distributedQueueMarkerSuffix = ".queue"
+)
+
+var errLeaseLost = errors.New("the lease on the job part expired, and another worker claimed it")
+
+// leaseRecheckAttempts and leaseRecheckInterval say how long a worker looks for its part's file before concluding that
+// it lost the lease, since another worker that is checking the lease moves the file away for a moment.
+const (
+	leaseRecheckAttempts = 3
+	leaseRecheckInterval = 100 * time.Millisecond
+)
+
+// distributedJob is the content of job.json.
+type distributedJob struct {
+	JobID              common.JobID
+	FromTo             common.FromTo
+	CommandString      string
+	Source             string
+	Destination        string
+	PartsQueued        uint32
+	CompleteJobOrdered bool
+	Cancelled          bool
+
+	// totals of the parts queued so far
+	TotalTransfers          uint32
+	FileTransfers           uint32
+	FolderPropertyTransfers uint32
+	SymlinkTransfers        uint32
+	TotalBytesEnumerated    uint64
+}
+
+// distributedPartReport is the progress, or the final summary, of a part executed by a worker.
+type distributedPartReport struct {
+	Worker     string
+	LocalJobID common.JobID // the worker's job, whose log and plan files are on the worker's machine
+	Summary    common.ListJobSummaryResponse
+}
+
+// fsPartQueue is a queue of job parts in a directory shared by the coordinator and the workers.
+type fsPartQueue struct {
+	root string
+}
+
+func newFSPartQueue(root string) (*fsPartQueue, error) {
+	if root == "" {
+		return nil, errors.New("the queue directory must be specified")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &fsPartQueue{root: abs}, nil
+}
+
+func (q *fsPartQueue) jobDir(jobID common.JobID) string {
+	return filepath.Join(q.root, jobID.String())
+}
+
+func partFileName(partNum common.PartNumber) string {
+	return fmt.Sprintf("%05d.json", partNum)
+}
+
+// writeJSONFile writes v to a temporary name first, so that readers never see a partially written file.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (q *fsPartQueue) readJob(jobID common.JobID) (distributedJob, error) {
+	var job distributedJob
+	err := readJSONFile(filepath.Join(q.jobDir(jobID), distributedJobFileName), &job)
+	if os.IsNotExist(err) {
+		return job, fmt.Errorf("no job with JobId %v exists in the queue %s", jobID, q.root)
+	}
+	return job, err
+}
+
+// EnqueuePart adds a part to the queue, without its secrets. Only the coordinator of the job may call it.
+func (q *fsPartQueue) EnqueuePart(order common.CopyJobPartOrderRequest) error {
+	dir := q.jobDir(order.JobID)
+	var job distributedJob
+	if order.PartNum != 0 {
+		// the job was set up with its first part; it must not be reset, e.g. by a failure to read it
+		var err error
+		if job, err = q.readJob(order.JobID); err != nil {
+			return err
+		}
+	} else {
+		for _, sub := range []string{distributedPending, distributedClaimed, distributedProgress, distributedDone} {
+			if err := os.MkdirAll(filepath.Join(dir, sub), common.DEFAULT_FILE_PERM|0111); err != nil {
+				return err
+			}
+		}
+		job = distributedJob{
+			JobID:         order.JobID,
+			FromTo:        order.FromTo,
+			CommandString: order.CommandString,
+			Source:        order.SourceRoot.Value,
+			Destination:   order.DestinationRoot.Value,
+		}
+	}
+
+	if len(order.Transfers.List) > 0 {
+		order.SourceRoot.SAS = ""
+		order.DestinationRoot.SAS = ""
+		order.CredentialInfo.OAuthTokenInfo = common.OAuthTokenInfo{}
+		order.CredentialInfo.SourceBlobToken = nil
+		if err := writeJSONFile(filepath.Join(dir, distributedPending, partFileName(order.PartNum)), order); err != nil {
+			return err
+		}
+
+		job.PartsQueued++
+		job.TotalTransfers += uint32(len(order.Transfers.List))
+		job.FileTransfers += order.Transfers.FileTransferCount
+		job.FolderPropertyTransfers += order.Transfers.FolderTransferCount
+		job.SymlinkTransfers += order.Transfers.SymlinkTransferCount
+		job.TotalBytesEnumerated += order.Transfers.TotalSizeInBytes
+	}
+	job.CompleteJobOrdered = order.IsFinalPart
+
+	return writeJSONFile(filepath.Join(dir, distributedJobFileName), job)
+}
+
+// CancelJob stops workers from claiming the job's parts, and tells the workers executing them to cancel.
+func (q *fsPartQueue) CancelJob(jobID common.JobID) error {
+	job, err := q.readJob(jobID)
+	if err != nil {
+		return err
+	}
+	job.Cancelled = true
+	return writeJSONFile(filepath.Join(q.jobDir(jobID), distributedJobFileName), job)
+}
+
+// claimedPart is a part that a worker holds the lease on.
+type claimedPart struct {
+	queue   *fsPartQueue
+	path    string
+	lease   time.Duration
+	renewed time.Time // when the lease was last renewed
+	JobID   common.JobID
+	PartNum common.PartNumber
+	Job     distributedJob
+	Order   common.CopyJobPartOrderRequest
+}
+
+var nonWorkerNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// distributedWorkerName identifies this process in the names of the parts that it claims.
+func distributedWorkerName() string {
+	host, _ := os.Hostname()
+	return nonWorkerNameChars.ReplaceAllString(fmt.Sprintf("%s-%d", host, os.Getpid()), "_")
+}
+
+func sortedDirEntries(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// ClaimPart claims a pending part, or a part whose lease has expired, for the given worker.
+// It returns nil if there is nothing to claim.
+func (q *fsPartQueue) ClaimPart(worker string, lease time.Duration) (*claimedPart, error) {
+	jobs, err := sortedDirEntries(q.root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, jobEntry := range jobs {
+		jobID, err := common.ParseJobID(jobEntry.Name())
+		if err != nil || !jobEntry.IsDir() {
+			continue
+		}
+		job, err := q.readJob(jobID)
+		if err != nil || job.Cancelled {
+			continue
+		}
+		dir := q.jobDir(jobID)
+		claimedDir := filepath.Join(dir, distributedClaimed)
+
+		pending, err := sortedDirEntries(filepath.Join(dir, distributedPending))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range pending {
+			if !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			target := filepath.Join(claimedDir, entry.Name()+"."+worker)
+			if os.Rename(filepath.Join(dir, distributedPending, entry.Name()), target) != nil {
+				continue // another worker got there first
+			}
+			// the lease starts now, rather than when the part was queued
+			if err := os.Chtimes(target, time.Now(), time.Now()); err != nil {
+				return nil, err
+			}
+			return q.loadClaimedPart(job, target, lease)
+		}
+
+		claimed, err := sortedDirEntries(claimedDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range claimed {
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < lease {
+				continue
+			}
+			target, err := takeOverExpiredLease(claimedDir, entry.Name(), worker, lease)
+			if err != nil {
+				return nil, err
+			}
+			if target != "" {
+				return q.loadClaimedPart(job, target, lease)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// takeOverExpiredLease claims a part whose lease looked expired, and returns its new path. It returns "" if another
+// worker claimed the part first, or if its owner has renewed the lease since it was looked at.
+func takeOverExpiredLease(claimedDir, name, worker string, lease time.Duration) (string, error) {
+	// Only one worker can rename the part, and once it's renamed its owner can't renew the lease any more,
+	// so the lease can be checked again without another worker or the owner changing it.
+	source := filepath.Join(claimedDir, name)
+	partName := name[:strings.Index(name, ".json")+len(".json")]
+	target := filepath.Join(claimedDir, partName+"."+worker)
+	if os.Rename(source, target) != nil {
+		return "", nil
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if time.Since(info.ModTime()) < lease {
+		// the owner renewed the lease after all, so the part is still its
+		return "", os.Rename(target, source)
+	}
+	if err := os.Chtimes(target, time.Now(), time.Now()); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (q *fsPartQueue) loadClaimedPart(job distributedJob, path string, lease time.Duration) (*claimedPart, error) {
+	part := &claimedPart{queue: q, path: path, lease: lease, renewed: time.Now(), JobID: job.JobID, Job: job}
+	if err := readJSONFile(path, &part.Order); err != nil {
+		return nil, fmt.Errorf("cannot read job part %s: %w", path, err)
+	}
+	part.PartNum = part.Order.PartNum
+	return part, nil
+}
+
+func (p *claimedPart) reportPath(kind string) string {
+	return filepath.Join(p.queue.jobDir(p.JobID), kind, partFileName(p.PartNum))
+}
+
+// touch renews the lease. While another worker is checking the lease, the part's file is briefly under its name,
+// so the file only counts as gone once it has been missing for a few attempts.
+func (p *claimedPart) touch() (err error) {
+	for attempt := 0; attempt < leaseRecheckAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(leaseRecheckInterval)
+		}
+		now := time.Now()
+		if err = os.Chtimes(p.path, now, now); !os.IsNotExist(err) {
+			break
+		}
+	}
+	if err == nil {
+		p.renewed = time.Now()
+	}
+	return err
+}
+
+// Renew extends the worker's lease on the part and records its progress. It also says whether the job was cancelled.
+// It returns errLeaseLost only if another worker may have claimed the part; other errors may be transient.
+func (p *claimedPart) Renew(progress distributedPartReport) (cancelled bool, err error) {
+	if err := p.touch(); os.IsNotExist(err) {
+		return false, errLeaseLost
+	} else if err != nil {
+		if time.Since(p.renewed) >= p.lease {
+			// the lease may have expired while the file system was failing, so another worker may have the part now
+			return false, errLeaseLost
+		}
+		return false, err
+	}
+	if err := writeJSONFile(p.reportPath(distributedProgress), progress); err != nil {
+		return false, err
+	}
+
+	job, err := p.queue.readJob(p.JobID)
+	return job.Cancelled, err
+}
+
+// Complete records the final summary of the part, and gives up the lease.
+func (p *claimedPart) Complete(report distributedPartReport) error {
+	if err := p.touch(); os.IsNotExist(err) {
+		return errLeaseLost
+	} else if err != nil {
+		return err
+	}
+	if err := writeJSONFile(p.reportPath(distributedDone), report); err != nil {
+		return err
+	}
+	_ = os.Remove(p.reportPath(distributedProgress))
+	return os.Remove(p.path)
+}
+
+// Release puts the part back in the queue, for another worker to claim.
+func (p *claimedPart) Release() error {
+	_ = os.Remove(p.reportPath(distributedProgress))
+	return os.Rename(p.path, p.reportPath(distributedPending))
+}
+
+// partReports returns the latest report of each part: its final summary if it has been executed, or else its progress.
+func (q *fsPartQueue) partReports(jobID common.JobID) (reports map[string]distributedPartReport, done int, err error) {
+	reports = make(map[string]distributedPartReport)
+	for _, kind := range []string{distributedDone, distributedProgress} {
+		entries, err := sortedDirEntries(filepath.Join(q.jobDir(jobID), kind))
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, entry := range entries {
+			if _, exists := reports[entry.Name()]; exists || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			var report distributedPartReport
+			if err := readJSONFile(filepath.Join(q.jobDir(jobID), kind, entry.Name()), &report); err != nil {
+				if os.IsNotExist(err) {
+					continue // the part finished while we were looking
+				}
+				return nil, 0, err
+			}
+			reports[entry.Name()] = report
+			if kind == distributedDone {
+				done++
+			}
+		}
+	}
+	return reports, done, nil
+}
+
+// JobSummary aggregates the progress of the job, from the totals queued by the coordinator and the reports of the workers.
+func (q *fsPartQueue) JobSummary(jobID common.JobID) (common.ListJobSummaryResponse, error) {
+	job, err := q.readJob(jobID)
+	if err != nil {
+		return common.ListJobSummaryResponse{}, err
+	}
+	reports, done, err := q.partReports(jobID)
+	if err != nil {
+		return common.ListJobSummaryResponse{}, err
+	}
+
+	js := common.ListJobSummaryResponse{
+		Timestamp:               time.Now().UTC(),
+		JobID:                   jobID,
+		CompleteJobOrdered:      job.CompleteJobOrdered,
+		JobStatus:               common.EJobStatus.InProgress(),
+		TotalTransfers:          job.TotalTransfers,
+		FileTransfers:           job.FileTransfers,
+		FolderPropertyTransfers: job.FolderPropertyTransfers,
+		SymlinkTransfers:        job.SymlinkTransfers,
+		TotalBytesEnumerated:    job.TotalBytesEnumerated,
+		TotalBytesExpected:      job.TotalBytesEnumerated,
+	}
+
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := reports[name].Summary
+		js.ActiveConnections += s.ActiveConnections
+		js.FoldersCompleted += s.FoldersCompleted
+		js.TransfersCompleted += s.TransfersCompleted
+		js.FoldersFailed += s.FoldersFailed
+		js.TransfersFailed += s.TransfersFailed
+		js.FoldersSkipped += s.FoldersSkipped
+		js.TransfersSkipped += s.TransfersSkipped
+		js.BytesOverWire += s.BytesOverWire
+		js.TotalBytesTransferred += s.TotalBytesTransferred
+		// the bytes of skipped and failed transfers are no longer expected
+		js.TotalBytesExpected -= s.TotalBytesEnumerated - s.TotalBytesExpected
+		js.FailedTransfers = append(js.FailedTransfers, s.FailedTransfers...)
+		js.SkippedTransfers = append(js.SkippedTransfers, s.SkippedTransfers...)
+	}
+
+	if js.TotalBytesExpected == 0 {
+		js.PercentComplete = 100
+	} else {
+		js.PercentComplete = 100 * float32(js.TotalBytesTransferred) / float32(js.TotalBytesExpected)
+	}
+
+	allPartsDone := job.CompleteJobOrdered && uint32(done) == job.PartsQueued
+	claimed, err := sortedDirEntries(filepath.Join(q.jobDir(jobID), distributedClaimed))
+	if err != nil {
+		return js, err
+	}
+	switch {
+	case job.Cancelled && len(claimed) == 0:
+		js.JobStatus = common.EJobStatus.Cancelled()
+	case job.Cancelled:
+		js.JobStatus = common.EJobStatus.Cancelling()
+	case allPartsDone && js.TransfersFailed > 0 && js.TransfersSkipped > 0:
+		js.JobStatus = common.EJobStatus.CompletedWithErrorsAndSkipped()
+	case allPartsDone && js.TransfersFailed > 0:
+		js.JobStatus = common.EJobStatus.CompletedWithErrors()
+	case allPartsDone && js.TransfersSkipped > 0:
+		js.JobStatus = common.EJobStatus.CompletedWithSkipped()
+	case allPartsDone:
+		js.JobStatus = common.EJobStatus.Completed()
+	}
+	return js, nil
+}
+
+// JobTransfers lists the job's transfers of the given status. Workers only report the transfers that failed or were skipped.
+func (q *fsPartQueue) JobTransfers(jobID common.JobID, status common.TransferStatus) (common.ListJobTransfersResponse, error) {
+	resp := common.ListJobTransfersResponse{JobID: jobID}
+	if status != common.ETransferStatus.All() && status >= common.ETransferStatus.NotStarted() {
+		return resp, errors.New("distributed jobs only list the transfers that failed or were skipped")
+	}
+	summary, err := q.JobSummary(jobID)
+	if err != nil {
+		return resp, err
+	}
+
+	for _, details := range [][]common.TransferDetail{summary.FailedTransfers, summary.SkippedTransfers} {
+		for _, d := range details {
+			if transferMatchesStatus(d.TransferStatus, status) {
+				resp.Details = append(resp.Details, d)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// rpc handles the requests about the queue's jobs, and passes everything else on to next.
+func (q *fsPartQueue) rpc(next func(common.RpcCmd, interface{}, interface{})) func(common.RpcCmd, interface{}, interface{}) {
+	notSupported := "this is a distributed job, whose parts are executed by workers; this request is not supported for it"
+
+	return func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		switch cmd {
+		case common.ERpcCmd.CopyJobPartOrder():
+			order := request.(*common.CopyJobPartOrderRequest)
+			resp := response.(*common.CopyJobPartOrderResponse)
+			*resp = common.CopyJobPartOrderResponse{JobStarted: true}
+			if order.PartNum == 0 && order.IsFinalPart && len(order.Transfers.List) == 0 {
+				*resp = common.CopyJobPartOrderResponse{ErrorMsg: common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr()}
+			} else if err := q.EnqueuePart(*order); err != nil {
+				*resp = common.CopyJobPartOrderResponse{ErrorMsg: common.CopyJobPartOrderErrorType(err.Error())}
+			}
+
+		case common.ERpcCmd.ListJobSummary():
+			summary, err := q.JobSummary(*request.(*common.JobID))
+			if err != nil {
+				summary.ErrorMsg = err.Error()
+			}
+			*(response.(*common.ListJobSummaryResponse)) = summary
+
+		case common.ERpcCmd.ListJobTransfers():
+			transfers, err := q.JobTransfers(request.(common.ListJobTransfersRequest).JobID, request.(common.ListJobTransfersRequest).OfStatus)
+			if err != nil {
+				transfers.ErrorMsg = err.Error()
+			}
+			*(response.(*common.ListJobTransfersResponse)) = transfers
+
+		case common.ERpcCmd.CancelJob():
+			resp := common.CancelPauseResumeResponse{CancelledPauseResumed: true}
+			if err := q.CancelJob(request.(common.JobID)); err != nil {
+				resp = common.CancelPauseResumeResponse{ErrorMsg: err.Error()}
+			}
+			*(response.(*common.CancelPauseResumeResponse)) = resp
+
+		case common.ERpcCmd.PauseJob(), common.ERpcCmd.ResumeJob():
+			*(response.(*common.CancelPauseResumeResponse)) = common.CancelPauseResumeResponse{ErrorMsg: notSupported}
+
+		default:
+			next(cmd, request, response)
+		}
+	}
+}
+
+func distributedQueueMarkerPath(jobID common.JobID) string {
+	return filepath.Join(common.AzcopyJobPlanFolder, jobID.String()+distributedQueueMarkerSuffix)
+}
+
+// useDistributedQueue makes this process the coordinator of the job: its parts go to the queue, and its progress comes from it.
+func useDistributedQueue(jobID common.JobID, root string) error {
+	q, err := newFSPartQueue(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(q.root, common.DEFAULT_FILE_PERM|0111); err != nil {
+		return err
+	}
+	// remember where the job went, for "jobs show"
+	if err := os.WriteFile(distributedQueueMarkerPath(jobID), []byte(q.root), common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	Rpc = q.rpc(Rpc)
+	return nil
+}
+
+// distributedQueueForJob returns the queue that this machine coordinated the job through, if it was a distributed job.
+// Other machines can name the queue themselves, e.g. with "cancel --distributed-queue".
+func distributedQueueForJob(jobID common.JobID) (*fsPartQueue, bool) {
+	root, err := os.ReadFile(distributedQueueMarkerPath(jobID))
+	if err != nil {
+		return nil, false
+	}
+	return &fsPartQueue{root: string(root)}, true
+}
@@ -34,6 +34,12 @@ var envCmd = &cobra.Command{
 				val = "REDACTED"
 			}
 
+			for _, fromProfile := range azcopyProfileEnvironment {
+				if fromProfile == env.Name {
+					val += fmt.Sprintf(" (from profile %s)", azcopyProfileName)
+				}
+			}
+
 			glcm.Info(fmt.Sprintf("Name: %s\nCurrent Value: %s\nDescription: %s\n",
 				env.Name, val, env.Description))
 		}
@@ -0,0 +1,176 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// hardlinkInode identifies the file behind a local name
+type hardlinkInode struct {
+	dev uint64
+	ino uint64
+}
+
+// hardlinkTracker finds, during enumeration, the files that are further names of a file that was already seen,
+// so that with --hardlinks=preserve the data is transferred only once. A nil tracker leaves every object alone.
+type hardlinkTracker struct {
+	isUpload  bool
+	localRoot string
+
+	lock sync.Mutex
+	seen map[hardlinkInode]string // the relative path of the first name seen of each file
+}
+
+func newHardlinkTracker(handling common.HardlinkHandlingType, fromTo common.FromTo, localRoot string) *hardlinkTracker {
+	if handling != common.EHardlinkHandlingType.Preserve() {
+		return nil
+	}
+
+	return &hardlinkTracker{
+		isUpload:  fromTo.IsUpload(),
+		localRoot: localTraversalRoot(localRoot),
+		seen:      make(map[hardlinkInode]string),
+	}
+}
+
+// classify marks the object as a hard link if its data is going to be transferred under another name.
+// When uploading, that's any further name of a local file already seen. When downloading, it's any blob written
+// as a link by an upload, which refers to the blob that holds the data.
+func (t *hardlinkTracker) classify(object *StoredObject) {
+	if t == nil || object.entityType != common.EEntityType.File() {
+		return
+	}
+
+	if !t.isUpload {
+		if _, ok := object.Metadata[common.POSIXHardlinkMeta]; ok {
+			object.entityType = common.EEntityType.Hardlink()
+		}
+		return
+	}
+
+	if object.relativePath == "" {
+		return // a single file, which can't have another name in the job
+	}
+
+	inode, nlink, ok := localHardlinkInode(common.GenerateFullPath(t.localRoot, object.relativePath))
+	if !ok || nlink < 2 {
+		return
+	}
+
+	t.lock.Lock()
+	first, seen := t.seen[inode]
+	if !seen {
+		t.seen[inode] = object.relativePath
+	}
+	t.lock.Unlock()
+	if !seen {
+		return
+	}
+
+	ref, err := filepath.Rel(path.Dir(object.relativePath), first)
+	if err != nil {
+		return // upload it as a file of its own
+	}
+
+	metadata := common.Metadata{}
+	for k, v := range object.Metadata {
+		metadata[k] = v
+	}
+	metadata[common.POSIXHardlinkMeta] = (&url.URL{Path: filepath.ToSlash(ref)}).EscapedPath()
+
+	object.entityType = common.EEntityType.Hardlink()
+	object.size = 0
+	object.Metadata = metadata
+}
+
+// withHardlinkTracking wraps the source traverser if hard links are being preserved
+func withHardlinkTracking(traverser ResourceTraverser, tracker *hardlinkTracker) ResourceTraverser {
+	if tracker == nil {
+		return traverser
+	}
+	return &classifyingTraverser{ResourceTraverser: traverser, classify: tracker.classify}
+}
+
+// deferredHardlinks holds the hard links of a download until every file they could link to has been scheduled.
+// There can be any number of them, so they're written to a temporary file rather than kept in memory.
+type deferredHardlinks struct {
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+}
+
+func (d *deferredHardlinks) add(transfer common.CopyTransfer) error {
+	if d.file == nil {
+		file, err := os.CreateTemp("", "azcopy-hardlinks-*")
+		if err != nil {
+			return err
+		}
+		// hard links are only preserved on Linux, where the open file outlives its name; so it's gone when we are, however we exit
+		_ = os.Remove(file.Name())
+		d.file, d.writer = file, bufio.NewWriter(file)
+	}
+
+	d.count++
+	return json.NewEncoder(d.writer).Encode(transfer)
+}
+
+func (d *deferredHardlinks) empty() bool {
+	return d.count == 0
+}
+
+// release calls schedule for each hard link, in the order they were added, and then forgets them.
+func (d *deferredHardlinks) release(schedule func(common.CopyTransfer) error) error {
+	if d.file == nil {
+		return nil
+	}
+	file, writer := d.file, d.writer
+	defer file.Close()
+	*d = deferredHardlinks{}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var transfer common.CopyTransfer
+		if err := decoder.Decode(&transfer); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := schedule(transfer); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// localHardlinkInode returns the inode of the file at the given path, and how many names it has
+func localHardlinkInode(fullPath string) (hardlinkInode, uint64, bool) {
+	fi, err := os.Lstat(fullPath)
+	if err != nil || !fi.Mode().IsRegular() {
+		return hardlinkInode{}, 0, false
+	}
+
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return hardlinkInode{}, 0, false
+	}
+
+	return hardlinkInode{dev: uint64(stat.Dev), ino: stat.Ino}, uint64(stat.Nlink), true
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package cmd
+
+// localHardlinkInode is only implemented on Linux, where --hardlinks=preserve is supported
+func localHardlinkInode(fullPath string) (hardlinkInode, uint64, bool) {
+	return hardlinkInode{}, 0, false
+}
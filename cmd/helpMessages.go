@@ -212,6 +212,46 @@ const envCmdLongDescription = `Shows the environment variables that you can use
 
 ` + environmentVariableNotice
 
+// ===================================== CONFIG COMMAND ===================================== //
+const configCmdShortDescription = "Sub-commands related to the AzCopy config file"
+
+const configCmdLongDescription = `Sub-commands related to the AzCopy config file.
+
+The config file (by default config.yaml in the .azcopy folder of your home directory, or the file named by AZCOPY_CONFIG_FILE)
+holds named profiles and remotes. Each profile can set environment variables, default values for the flags of each command,
+and aliases that stand for endpoint URLs. A remote is a named endpoint, with an optional location type and a reference to
+where its SAS comes from (env:NAME, file:PATH or helper), so that every command accepts "name:path" in place of a URL,
+and logs show the name rather than the URL. Select a profile with --profile or AZCOPY_PROFILE; otherwise the profile named by
+the file's top-level "profile" setting, or "default", is used.
+
+Flags given on the command line take precedence over environment variables, which take precedence over the profile.
+
+  profile: prod
+  remotes:
+    backup:
+      url: https://backup.blob.core.windows.net
+      credential: env:BACKUP_SAS
+  profiles:
+    prod:
+      environment:
+        AZCOPY_CONCURRENCY_VALUE: 64
+      flags:
+        "*":
+          log-level: WARNING
+        copy:
+          block-size-mb: 16
+          recursive: true
+      aliases:
+        prod-data: https://proddata.blob.core.windows.net
+
+With the config above, "azcopy copy ./dir prod-data:container" copies to https://proddata.blob.core.windows.net/container,
+and "azcopy sync ./dir backup:container" syncs to the backup account, using the SAS in the BACKUP_SAS environment variable.`
+
+const configShowCmdShortDescription = "Shows the effective configuration, after resolving the config file, profile and environment."
+
+const configShowCmdLongDescription = `Shows the effective configuration: the config file and profile in use, the environment variables that are set
+(and whether each came from the environment or the profile), and the profile's flag defaults and aliases.`
+
 // ===================================== JOBS COMMAND ===================================== //
 const jobsCmdShortDescription = "Sub-commands related to managing jobs"
 
@@ -245,6 +285,42 @@ Note that you can customize the location where log and plan files are saved. See
 
 const removeJobsCmdExample = "  azcopy jobs rm e52247de-0323-b14d-4cc8-76e0be2e2d44"
 
+const inspectJobsCmdShortDescription = "Decode the plan files of the given job ID"
+
+const inspectJobsCmdLongDescription = `
+Decode every plan file of the given job ID, without loading the job, into JSON Lines or CSV.
+
+JSON Lines output has a line for the header of each job part (roots, options and destination settings), followed by a line for each of its transfers.
+CSV output has a row for each transfer. Each transfer includes its source and destination (relative to the part's roots), size, status and error code.
+
+Note that you can customize the location where log and plan files are saved. See the env command to learn more.`
+
+const inspectJobsCmdExample = `  azcopy jobs inspect e52247de-0323-b14d-4cc8-76e0be2e2d44 --with-status=Failed
+  azcopy jobs inspect e52247de-0323-b14d-4cc8-76e0be2e2d44 --format=csv --output-file=transfers.csv`
+
+const exportJobsCmdShortDescription = "Package the plan files of the given job ID into a bundle, so it can be continued on another machine"
+
+const exportJobsCmdLongDescription = `
+Package the plan files of the given job ID into a tar file, along with a manifest that describes the job and any other files
+the job needs to resume, such as the progress of unfinished recursive ACL changes.
+Jobs queued with --distributed-queue cannot be exported, since their state lives in the queue directory.
+The bundle can be imported with 'azcopy jobs import' on another machine, where the job can then be resumed.
+Plan files do not contain SAS tokens or other credentials, so neither does the bundle; they must be supplied again when the job is resumed.
+The job should not be running while it is exported.`
+
+const exportJobsCmdExample = "  azcopy jobs export e52247de-0323-b14d-4cc8-76e0be2e2d44 job.tar"
+
+const importJobsCmdShortDescription = "Add a job exported from another machine to this machine's jobs, so it can be resumed"
+
+const importJobsCmdLongDescription = `
+Add the plan files, and the other job files, of a bundle created by 'azcopy jobs export' to the plan folder of this machine.
+If the job's source or destination is a local directory, it can be moved to a different location with --source-root or --destination-root,
+for example when the data is mounted somewhere else on this machine. Once imported, the job can be resumed with 'azcopy jobs resume'.`
+
+const importJobsCmdExample = `  azcopy jobs import job.tar
+  azcopy jobs import job.tar --source-root=/mnt/data
+  azcopy jobs resume e52247de-0323-b14d-4cc8-76e0be2e2d44 --destination-sas="?sv=..."`
+
 const cleanJobsCmdShortDescription = "Remove all log and plan files for all jobs"
 
 const cleanJobsCmdLongDescription = `
@@ -310,6 +386,14 @@ Set the environment variable AZCOPY_SPA_CERT_PASSWORD to the certificate's passw
    Please treat /path/to/my/cert as a path to a PEM or PKCS12 file-- AzCopy does not reach into the system cert store to obtain your certificate.
    --certificate-path is mandatory when doing cert-based service principal auth.
 
+Log in by using workload identity federation, e.g. in a Kubernetes pod or CI runner that has AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE set:
+
+   - azcopy login --workload-identity
+
+Log in by using an external credential helper, which receives a JSON request on stdin and prints {"access_token": "...", "expires_on": ...} to stdout:
+
+   - azcopy login --credential-helper "/path/to/my/helper --audience storage"
+
 Subcommand for login to check the login status of your current session.
 	- azcopy login status 
 `
@@ -473,29 +557,35 @@ const benchCmdShortDescription = "Performs a performance benchmark"
 // TODO: document whether we delete the uploaded data
 
 const benchCmdLongDescription = `
-Runs a performance benchmark by uploading or downloading test data to or from a specified destination. 
-For uploads, the test data is automatically generated.
+Runs a performance benchmark by uploading or downloading test data to or from a specified destination, or by copying 
+it there from another container or share, service to service. For uploads and service-to-service copies, the test data is 
+automatically generated.
 
 The benchmark command runs the same process as 'copy', except that: 
 
   - Instead of requiring both source and destination parameters, benchmark takes just one. This is the 
     blob container, Azure Files Share, or ADLS Gen 2 File System that you want to upload to or download from.
 
-  - The 'mode' parameter describes whether AzCopy should test uploads to or downloads from given target. Valid values are 'Upload'
-    and 'Download'. Default value is 'Upload'.
+  - The 'mode' parameter describes whether AzCopy should test uploads to, downloads from, or service-to-service copies to the given 
+    target. Valid values are 'Upload', 'Download' and 'S2S'. Default value is 'Upload'.
 
   - For upload benchmarks, the payload is described by command line parameters, which control how many files are auto-generated and 
-    how big they are. The generation process takes place entirely in memory. Disk is not used.
+    how big they are. The generation process takes place entirely in memory. Disk is not used. The sizes can all be the same, or
+    be drawn from a lognormal distribution or from a histogram of sizes, using the 'size-distribution' parameter, so that the
+    payload can resemble real data.
 
-  - For downloads, the payload consists of whichever files already exist at the source. (See example below about how to generate
-    test files if needed).
+  - For S2S benchmarks, the payload is generated in the same way, and uploaded to the blob container or file share given by the 
+    's2s-source' parameter. Only the copy from there to the target (Blob to Blob, Blob to Azure Files, and so on) is measured.
+
+  - For downloads, the payload consists of whichever files already exist at the source, which may be a blob container, Azure Files
+    share, or ADLS Gen 2 File System. (See example below about how to generate test files if needed).
   
   - Only a few of the optional parameters that are available to the copy command are supported.
   
   - Additional diagnostics are measured and reported.
   
-  - For uploads, the default behaviour is to delete the transferred data at the end of the test run.  For downloads, the data
-    is never actually saved locally.
+  - For uploads and S2S copies, the default behaviour is to delete the transferred data (and for S2S, the generated source data)
+    at the end of the test run.  For downloads, the data is never actually saved locally.
 
 Benchmark mode will automatically tune itself to the number of parallel TCP connections that gives 
 the maximum throughput. It will display that number at the end. To prevent auto-tuning, set the 
@@ -525,11 +615,50 @@ Run a benchmark test that downloads existing files from a target
 
    - azcopy bench --mode='Download' "https://[account].blob.core.windows.net/[container]?<SAS?"
 
+Run a benchmark test that downloads existing files from an Azure Files share
+
+   - azcopy bench --mode='Download' "https://[account].file.core.windows.net/[share]?<SAS>"
+
+Run a benchmark test of service-to-service copies from one blob container to another, for 1000 files whose sizes have a 
+median of 1 MiB and follow a lognormal distribution:
+
+   - azcopy bench --mode='S2S' --s2s-source "https://[account].blob.core.windows.net/[source_container]?<SAS>" "https://[account].blob.core.windows.net/[container]?<SAS>" --file-count 1000 --size-per-file 1M --size-distribution lognormal:1.5
+
+Run an upload benchmark where the sizes of the files follow the histogram in sizes.txt, which has lines such as '4K 60' and '100M 1'
+
+   - azcopy bench "https://[account].blob.core.windows.net/[container]?<SAS>" --size-distribution histogram:sizes.txt
+
 Run an upload that does not delete the transferred files. (These files can then serve as the payload for a download test)
 
    - azcopy bench "https://[account].blob.core.windows.net/[container]?<SAS>" --file-count 100 --delete-test-data=false
 `
 
+const benchCompareCmdShortDescription = "Compares the results of two benchmark runs"
+
+const benchCompareCmdLongDescription = `
+Compares the results of two benchmark runs from the benchmark history, to which each run of the bench command adds its results 
+(unless --save-history=false is given).
+
+The runs are named by their job IDs, or by enough of the start of them to be unique. The first is the baseline, and the second 
+the candidate. If only one is given, the candidate is the latest run. If none are given, the latest run is compared with the one 
+before it.
+
+Each metric of the candidate (throughput, IOPS, elapsed time, request latency, errors and retries) is compared with that of the 
+baseline, and flagged as a regression if it is worse by more than the threshold. Differences in how the runs were configured, 
+and in the environments they ran in (such as the AzCopy version and the number of CPUs), are listed too. 
+
+The exit code is non-zero if there are any regressions, so that scripts can stop on them. Use 'azcopy bench history' to list
+the runs in the history.
+`
+
+const benchHistoryCmdShortDescription = "Lists the benchmark runs in the benchmark history"
+
+const benchHistoryCmdLongDescription = `
+Lists the benchmark runs in the benchmark history, oldest first, with their job IDs, for use with 'azcopy bench compare'. 
+With --output-type=json, the full record of each run is given: its configuration, its environment, and its results, including
+the final state of the concurrency tuner and how long each performance constraint was the primary one.
+`
+
 // ===================================== SET-PROPERTIES COMMAND ===================================== //
 
 const setPropertiesCmdShortDescription = "(Preview) Given a location, change all the valid system properties of that storage (blob or file)"
@@ -573,3 +702,59 @@ Clear all existing blob-tags of blob:
 	- azcopy set-properties "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --blob-tags=clear
 	- While setting tags on the blobs, there are additional permissions('t' for tags) in SAS without which the service will give authorization error back.
 `
+
+// ===================================== ACL COMMAND ===================================== //
+
+const aclCmdShortDescription = "Sub-commands related to the ACLs of ADLS Gen2 files and directories"
+
+const aclCmdLongDescription = `
+Get and change the POSIX access control lists (ACLs) of files and directories in ADLS Gen2 (hierarchical namespace) accounts.
+
+An ACL is a comma-separated list of entries of the form [default:]user|group|mask|other:[id]:permissions, where the id is the object ID
+of an Azure AD user, group or service principal, or empty for the owning user and group. For example: user::rwx,user:<objectId>:r-x,group::r-x,other::---
+The entries given to remove have no permissions. Any entry of the default ACL can be removed, but only the named user and group
+entries of the access ACL can, since every path must keep its user::, group:: and other:: entries.
+
+With --recursive, set, modify and remove change everything under a directory, using the service's recursive ACL API. The change runs as a job,
+so its progress is reported, and failures are logged, in the same way as for remove and set-properties; 'azcopy jobs resume' carries on from
+the last batch of paths that the service had finished. Paths that couldn't be changed don't stop the job, and are listed in its log.
+`
+
+const aclCmdExample = `
+Show the ACL of a directory, and of everything under it:
+	- azcopy acl get "https://[account].dfs.core.windows.net/[filesystem]/[path/to/dir]" --recursive
+
+Replace the ACL of everything under a directory:
+	- azcopy acl set "https://[account].dfs.core.windows.net/[filesystem]/[path/to/dir]" --acl="user::rwx,group::r-x,other::---" --recursive
+
+Give a user read access to everything under a directory, and to what is created in it later:
+	- azcopy acl modify "https://[account].dfs.core.windows.net/[filesystem]/[path/to/dir]" --acl="user:[objectId]:r-x,default:user:[objectId]:r-x" --recursive
+
+Take those entries away again (note that remove takes no permissions):
+	- azcopy acl remove "https://[account].dfs.core.windows.net/[filesystem]/[path/to/dir]" --acl="user:[objectId],default:user:[objectId]" --recursive
+`
+
+// ===================================== WORKER COMMAND ===================================== //
+const workerCmdShortDescription = "Execute the parts of distributed jobs, from a queue shared with other machines"
+
+const workerCmdLongDescription = `
+Execute the parts of distributed jobs. A distributed job is enumerated by a coordinator, which is a copy command run with
+--distributed-queue. Rather than executing the job itself, the coordinator puts each part of the job into the queue, which is a directory
+shared by all the machines involved, and reports on the progress of the whole job as workers execute its parts.
+
+Each worker claims one part at a time, and runs it as a job of its own, with its own log and plan files on the worker's machine.
+While it runs the part, the worker renews its lease on it. If a worker stops, its parts are claimed by other workers once their leases expire.
+
+The queue holds no SAS tokens or OAuth tokens, so workers supply their own credentials, in the same way as when a job is resumed:
+with --source-sas and --destination-sas, with the credential helper, or by logging in.
+
+Run 'azcopy jobs show' on the coordinator's machine to see the progress of a distributed job, even after the coordinator has exited.
+Run 'azcopy cancel [jobID]' there to cancel it (or, on any other machine, 'azcopy cancel [jobID] --distributed-queue=[queue]'):
+workers stop claiming its parts, and cancel the parts they are executing.`
+
+const workerCmdExample = `Coordinator:
+  - azcopy copy "/data" "https://[account].blob.core.windows.net/[container]?[SAS]" --recursive --distributed-queue=/mnt/shared/queue
+
+Workers:
+  - azcopy worker --queue=/mnt/shared/queue --destination-sas="[SAS]"
+  - azcopy worker --queue=/mnt/shared/queue --destination-sas="[SAS]" --exit-when-idle`
@@ -0,0 +1,335 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	"github.com/spf13/cobra"
+)
+
+// A job bundle is a tar file holding a manifest, followed by the job's plan files and the other per-job files the STE reads on resume.
+// Plan files never hold SAS tokens or other credentials, so neither does the bundle; they are supplied again on resume.
+const (
+	jobBundleManifestName = "manifest.json"
+	jobBundleVersion      = 1
+)
+
+type jobBundleManifest struct {
+	BundleVersion     int
+	AzCopyVersion     string
+	JobID             common.JobID
+	DataSchemaVersion common.Version
+	FromTo            string
+	SourceRoot        string
+	DestinationRoot   string
+	PlanFiles         []string
+	SidecarFiles      []string `json:",omitempty"` // e.g. the continuation tokens of unfinished recursive ACL changes
+	ExportedAt        time.Time
+}
+
+type jobsImportArgs struct {
+	bundle          string
+	sourceRoot      string
+	destinationRoot string
+}
+
+func init() {
+	var exportJobID common.JobID
+
+	jobsExportCmd := &cobra.Command{
+		Use:     "export [jobID] [bundle]",
+		Short:   exportJobsCmdShortDescription,
+		Long:    exportJobsCmdLongDescription,
+		Example: exportJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("export job command requires the JobID and the path of the bundle to create")
+			}
+			jobId, err := common.ParseJobID(args[0])
+			if err != nil {
+				return errors.New("invalid jobId given " + args[0])
+			}
+			exportJobID = jobId
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := exportJob(exportJobID, args[1])
+			if err != nil {
+				glcm.Error(fmt.Sprintf("Failed to export job %s due to error: %s.", exportJobID, err))
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return fmt.Sprintf("Exported the %d plan file(s) of job %s to %s.", len(manifest.PlanFiles), exportJobID, args[1])
+			}, common.EExitCode.Success())
+		},
+	}
+
+	importArgs := jobsImportArgs{}
+
+	jobsImportCmd := &cobra.Command{
+		Use:     "import [bundle]",
+		Short:   importJobsCmdShortDescription,
+		Long:    importJobsCmdLongDescription,
+		Example: importJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("import job command requires the path of the bundle")
+			}
+			importArgs.bundle = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := importJob(importArgs)
+			if err != nil {
+				glcm.Error(fmt.Sprintf("Failed to import job from %s due to error: %s.", importArgs.bundle, err))
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return fmt.Sprintf("Imported job %s. Run 'azcopy jobs resume %s' to continue it, supplying any SAS tokens it needs.", manifest.JobID, manifest.JobID)
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsExportCmd)
+	jobsCmd.AddCommand(jobsImportCmd)
+
+	jobsImportCmd.PersistentFlags().StringVar(&importArgs.sourceRoot, "source-root", "", "Replace the job's local source directory with this one.")
+	jobsImportCmd.PersistentFlags().StringVar(&importArgs.destinationRoot, "destination-root", "", "Replace the job's local destination directory with this one.")
+}
+
+// exportJob writes the plan files of the job, and their sidecar files, to a bundle at bundlePath.
+// The job shouldn't be running, since the files are copied as they are at this point in time.
+func exportJob(jobID common.JobID, bundlePath string) (manifest jobBundleManifest, err error) {
+	planFiles, err := jobPlanFiles(jobID)
+	if err != nil {
+		return manifest, err
+	}
+
+	// the parts of a distributed job, and their progress, live in the queue rather than in the plan folder
+	if _, err := os.Stat(distributedQueueMarkerPath(jobID)); err == nil {
+		return manifest, fmt.Errorf("job %s was queued with --distributed-queue, so its state cannot be carried to another machine in a bundle", jobID)
+	}
+	sidecarFiles, err := filepath.Glob(filepath.Join(common.AzcopyJobPlanFolder, jobID.String()+"--*"+ste.AccessControlContinuationSuffix))
+	if err != nil {
+		return manifest, err
+	}
+
+	mmf, err := planFiles[0].MapReadOnly()
+	if err != nil {
+		return manifest, err
+	}
+	plan := mmf.Plan()
+	manifest = jobBundleManifest{
+		BundleVersion:     jobBundleVersion,
+		AzCopyVersion:     common.AzcopyVersion,
+		JobID:             jobID,
+		DataSchemaVersion: ste.DataSchemaVersion,
+		FromTo:            plan.FromTo.String(),
+		SourceRoot:        plan.SourceRoot(),
+		DestinationRoot:   plan.DestinationRoot(),
+		ExportedAt:        time.Now().UTC(),
+	}
+	mmf.Unmap()
+	for _, planFile := range planFiles {
+		manifest.PlanFiles = append(manifest.PlanFiles, string(planFile))
+	}
+	for _, sidecarFile := range sidecarFiles {
+		manifest.SidecarFiles = append(manifest.SidecarFiles, filepath.Base(sidecarFile))
+	}
+
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(bundlePath)
+		}
+	}()
+
+	tw := tar.NewWriter(file)
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err = writeBundleEntry(tw, jobBundleManifestName, manifestJSON); err != nil {
+		return manifest, err
+	}
+	for _, planFile := range planFiles {
+		data, err := os.ReadFile(planFile.GetJobPartPlanPath())
+		if err != nil {
+			return manifest, err
+		}
+		if err = writeBundleEntry(tw, string(planFile), data); err != nil {
+			return manifest, err
+		}
+	}
+	for i, sidecarFile := range sidecarFiles {
+		data, err := os.ReadFile(sidecarFile)
+		if err != nil {
+			return manifest, err
+		}
+		if err = writeBundleEntry(tw, manifest.SidecarFiles[i], data); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, tw.Close()
+}
+
+func writeBundleEntry(tw *tar.Writer, name string, data []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     int64(common.DEFAULT_FILE_PERM),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// importJob adds the plan and sidecar files of a bundle to the plan folder, re-rooting their local source or destination if asked to.
+func importJob(args jobsImportArgs) (manifest jobBundleManifest, err error) {
+	file, err := os.Open(args.bundle)
+	if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	header, err := tr.Next()
+	if err != nil {
+		return manifest, fmt.Errorf("cannot read the bundle: %w", err)
+	} else if header.Name != jobBundleManifestName {
+		return manifest, fmt.Errorf("the bundle does not start with a %s, so it wasn't created by 'azcopy jobs export'", jobBundleManifestName)
+	}
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("cannot read the bundle's manifest: %w", err)
+	}
+	if manifest.BundleVersion != jobBundleVersion {
+		return manifest, fmt.Errorf("the bundle has version %d, which this version of AzCopy does not support", manifest.BundleVersion)
+	}
+
+	var fromTo common.FromTo
+	if err = fromTo.Parse(manifest.FromTo); err != nil {
+		return manifest, err
+	}
+	sourceRoot, err := importedLocalRoot(args.sourceRoot, fromTo.From(), "source")
+	if err != nil {
+		return manifest, err
+	}
+	destinationRoot, err := importedLocalRoot(args.destinationRoot, fromTo.To(), "destination")
+	if err != nil {
+		return manifest, err
+	}
+	if (sourceRoot != "" || destinationRoot != "") && manifest.DataSchemaVersion != ste.DataSchemaVersion {
+		return manifest, fmt.Errorf("the bundle's plan files were written by a different version of AzCopy (%s), so they cannot be re-rooted by this one", manifest.AzCopyVersion)
+	}
+
+	if existing, _ := filepath.Glob(filepath.Join(common.AzcopyJobPlanFolder, manifest.JobID.String()+"--*.steV*")); len(existing) > 0 {
+		return manifest, fmt.Errorf("job %s already exists on this machine; remove it with 'azcopy jobs rm %s' first", manifest.JobID, manifest.JobID)
+	}
+
+	expected := make(map[string]bool, len(manifest.PlanFiles)+len(manifest.SidecarFiles))
+	for _, name := range manifest.PlanFiles {
+		expected[name] = true
+	}
+	sidecars := make(map[string]bool, len(manifest.SidecarFiles))
+	for _, name := range manifest.SidecarFiles {
+		expected[name] = true
+		sidecars[name] = true
+	}
+
+	// write the files to temporary names, so that a partially imported job is never picked up
+	var imported []string
+	defer func() {
+		for _, tmp := range imported {
+			_ = os.Remove(tmp)
+		}
+	}()
+	for {
+		header, err = tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return manifest, fmt.Errorf("cannot read the bundle: %w", err)
+		}
+		if !expected[header.Name] || filepath.Base(header.Name) != header.Name {
+			return manifest, fmt.Errorf("unexpected file %q in the bundle", header.Name)
+		}
+		delete(expected, header.Name)
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return manifest, err
+		}
+		if !sidecars[header.Name] && (sourceRoot != "" || destinationRoot != "") {
+			if data, err = ste.RerootJobPartPlan(data, sourceRoot, destinationRoot); err != nil {
+				return manifest, fmt.Errorf("failed to re-root %s: %w", header.Name, err)
+			}
+		}
+
+		tmp := filepath.Join(common.AzcopyJobPlanFolder, header.Name+".importing")
+		if err = os.WriteFile(tmp, data, common.DEFAULT_FILE_PERM); err != nil {
+			return manifest, err
+		}
+		imported = append(imported, tmp)
+	}
+	if len(expected) > 0 {
+		return manifest, fmt.Errorf("the bundle is missing %d of the files listed in its manifest", len(expected))
+	}
+
+	for _, tmp := range imported {
+		if err = os.Rename(tmp, tmp[:len(tmp)-len(".importing")]); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
+
+// importedLocalRoot validates and normalizes a root given on import. Only local roots can be replaced,
+// since remote ones are the same from any machine.
+func importedLocalRoot(root string, location common.Location, name string) (string, error) {
+	if root == "" {
+		return "", nil
+	} else if location != common.ELocation.Local() {
+		return "", fmt.Errorf("the job's %s is %s rather than a local directory, so it cannot be re-rooted", name, location)
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	return common.ToExtendedPath(cleanLocalPath(abs)), nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
 )
 
 func init() {
@@ -114,7 +115,7 @@ func handleCleanJobsCommand(givenStatus common.JobStatus) error {
 func blindDeleteAllJobFiles() (int, error) {
 	// get rid of the job plan files
 	numPlanFilesRemoved, err := removeFilesWithPredicate(common.AzcopyJobPlanFolder, func(s string) bool {
-		if strings.Contains(s, ".steV") {
+		if strings.Contains(s, ".steV") || strings.HasSuffix(s, distributedQueueMarkerSuffix) || strings.HasSuffix(s, ste.AccessControlContinuationSuffix) {
 			return true
 		}
 		return false
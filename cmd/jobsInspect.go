@@ -0,0 +1,238 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	"github.com/spf13/cobra"
+)
+
+const (
+	inspectFormatJSONLines = "jsonl"
+	inspectFormatCSV       = "csv"
+)
+
+type jobsInspectArgs struct {
+	jobID      common.JobID
+	format     string
+	withStatus string
+	outputFile string
+}
+
+// inspectPartRecord and inspectTransferRecord are the lines of JSON Lines output, told apart by Record.
+type inspectPartRecord struct {
+	Record string // always "part"
+	ste.JobPartPlanInfo
+}
+
+type inspectTransferRecord struct {
+	Record string // always "transfer"
+	ste.JobPartPlanTransferInfo
+}
+
+var inspectCSVHeader = []string{"PartNum", "TransferIndex", "Status", "ErrorCode", "EntityType", "Source", "Destination",
+	"SourceSize", "ModifiedTime", "CompletionTime", "BlobType", "BlobTier", "ContentType", "ContentMD5"}
+
+func init() {
+	commandLineInput := jobsInspectArgs{}
+
+	jobsInspectCmd := &cobra.Command{
+		Use:     "inspect [jobID]",
+		Short:   inspectJobsCmdShortDescription,
+		Long:    inspectJobsCmdLongDescription,
+		Example: inspectJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("inspect job command requires the JobID")
+			}
+			jobId, err := common.ParseJobID(args[0])
+			if err != nil {
+				return errors.New("invalid jobId given " + args[0])
+			}
+			commandLineInput.jobID = jobId
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := handleInspectJob(commandLineInput); err != nil {
+				glcm.Error(fmt.Sprintf("Failed to inspect the plan files of job %s due to error: %s.", commandLineInput.jobID, err))
+			}
+			glcm.Exit(nil, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsInspectCmd)
+
+	jobsInspectCmd.PersistentFlags().StringVar(&commandLineInput.format, "format", inspectFormatJSONLines, "Output format: jsonl (a line for each part's header, followed by a line for each of its transfers) or csv (transfers only).")
+	jobsInspectCmd.PersistentFlags().StringVar(&commandLineInput.withStatus, "with-status", "", "Only include the transfers with this status, available values: All, NotStarted, Started, Success, Failed, SkippedEntityAlreadyExists, etc. Failed includes every kind of failure.")
+	jobsInspectCmd.PersistentFlags().StringVar(&commandLineInput.outputFile, "output-file", "", "Write to this file instead of the standard output.")
+}
+
+// jobPlanFiles returns the plan files of the given job, ordered by part number.
+func jobPlanFiles(jobID common.JobID) ([]ste.JobPartPlanFileName, error) {
+	matches, err := filepath.Glob(filepath.Join(common.AzcopyJobPlanFolder, jobID.String()+"--*.steV*"))
+	if err != nil {
+		return nil, err
+	}
+
+	current := fmt.Sprintf(".steV%d", ste.DataSchemaVersion)
+	result := make([]ste.JobPartPlanFileName, 0, len(matches))
+	for _, m := range matches {
+		if name := filepath.Base(m); strings.HasSuffix(name, current) {
+			result = append(result, ste.JobPartPlanFileName(name))
+		}
+	}
+	if len(result) == 0 && len(matches) > 0 {
+		return nil, fmt.Errorf("the job's plan files were written by a different version of AzCopy; resuming the job with this version migrates them")
+	} else if len(result) == 0 {
+		return nil, fmt.Errorf("no plan files were found for the job in %s", common.AzcopyJobPlanFolder)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] }) // the part number is zero-padded
+	return result, nil
+}
+
+// transferMatchesStatus applies the same rule as "jobs show --with-status": Failed also matches the more specific failures.
+func transferMatchesStatus(status, wanted common.TransferStatus) bool {
+	return wanted == common.ETransferStatus.All() || status == wanted ||
+		(wanted == common.ETransferStatus.Failed() && status <= common.ETransferStatus.Failed())
+}
+
+func handleInspectJob(args jobsInspectArgs) error {
+	wanted := common.ETransferStatus.All()
+	if args.withStatus != "" {
+		if err := wanted.Parse(args.withStatus); err != nil {
+			return fmt.Errorf("cannot parse the given transfer status %s", args.withStatus)
+		}
+	}
+	if args.format != inspectFormatJSONLines && args.format != inspectFormatCSV {
+		return fmt.Errorf("unsupported format %q, use %s or %s", args.format, inspectFormatJSONLines, inspectFormatCSV)
+	}
+
+	planFiles, err := jobPlanFiles(args.jobID)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if args.outputFile != "" {
+		f, err := os.Create(args.outputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return inspectPlanFiles(planFiles, args.format, wanted, out)
+}
+
+func inspectPlanFiles(planFiles []ste.JobPartPlanFileName, format string, wanted common.TransferStatus, out io.Writer) error {
+	var csvWriter *csv.Writer
+	jsonEncoder := json.NewEncoder(out)
+	if format == inspectFormatCSV {
+		csvWriter = csv.NewWriter(out)
+		if err := csvWriter.Write(inspectCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	for _, planFile := range planFiles {
+		mmf, err := planFile.MapReadOnly()
+		if err != nil {
+			return fmt.Errorf("failed to open plan file %s: %w", planFile, err)
+		}
+
+		err = func() error {
+			defer mmf.Unmap()
+			plan := mmf.Plan()
+
+			if csvWriter == nil {
+				if err := jsonEncoder.Encode(inspectPartRecord{Record: "part", JobPartPlanInfo: plan.Info()}); err != nil {
+					return err
+				}
+			}
+
+			for t := uint32(0); t < plan.NumTransfers; t++ {
+				if !transferMatchesStatus(plan.Transfer(t).TransferStatus(), wanted) {
+					continue
+				}
+
+				transfer := plan.TransferInfo(t)
+				if csvWriter != nil {
+					if err := csvWriter.Write(transferCSVRow(transfer)); err != nil {
+						return err
+					}
+				} else if err := jsonEncoder.Encode(inspectTransferRecord{Record: "transfer", JobPartPlanTransferInfo: transfer}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+func transferCSVRow(t ste.JobPartPlanTransferInfo) []string {
+	formatTime := func(tm time.Time) string {
+		if tm.IsZero() {
+			return ""
+		}
+		return tm.Format(time.RFC3339Nano)
+	}
+
+	return []string{
+		strconv.Itoa(int(t.PartNum)),
+		strconv.FormatUint(uint64(t.TransferIndex), 10),
+		t.Status.String(),
+		strconv.Itoa(int(t.ErrorCode)),
+		t.EntityType,
+		t.Source,
+		t.Destination,
+		strconv.FormatInt(t.SourceSize, 10),
+		formatTime(t.ModifiedTime),
+		formatTime(t.CompletionTime),
+		t.BlobType,
+		t.BlobTier,
+		t.ContentType,
+		base64.StdEncoding.EncodeToString(t.ContentMD5),
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
 	"github.com/spf13/cobra"
 )
 
@@ -75,7 +76,8 @@ func init() {
 func handleRemoveSingleJob(jobID common.JobID) error {
 	// get rid of the job plan files
 	numPlanFileRemoved, err := removeFilesWithPredicate(common.AzcopyJobPlanFolder, func(s string) bool {
-		if strings.Contains(s, jobID.String()) && strings.Contains(s, ".steV") {
+		if strings.Contains(s, jobID.String()) && (strings.Contains(s, ".steV") || strings.HasSuffix(s, distributedQueueMarkerSuffix) ||
+			strings.HasSuffix(s, ste.AccessControlContinuationSuffix)) {
 			return true
 		}
 		return false
@@ -302,6 +302,19 @@ func (rca resumeCmdArgs) process() error {
 	}
 
 	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// SAS tokens are not persisted in the job plan, so fetch fresh ones from the credential helper, if one is configured.
+	if rca.SourceSAS == "" {
+		if rca.SourceSAS, err = getCredentialHelperSAS(ctx, getJobFromToResponse.Source, getJobFromToResponse.FromTo.From(), true); err != nil {
+			return err
+		}
+	}
+	if rca.DestinationSAS == "" {
+		if rca.DestinationSAS, err = getCredentialHelperSAS(ctx, getJobFromToResponse.Destination, getJobFromToResponse.FromTo.To(), false); err != nil {
+			return err
+		}
+	}
+
 	// Initialize credential info.
 	credentialInfo := common.CredentialInfo{}
 	// TODO: Replace context with root context
@@ -79,6 +79,11 @@ func init() {
 // handles the list command
 // dispatches the list order to the transfer engine
 func HandleShowCommand(listRequest common.ListRequest) error {
+	// the progress of a distributed job comes from its queue, rather than from plan files
+	if queue, ok := distributedQueueForJob(listRequest.JobID); ok {
+		Rpc = queue.rpc(Rpc)
+	}
+
 	if listRequest.OfStatus == "" {
 		resp := common.ListJobSummaryResponse{}
 		rpcCmd := common.ERpcCmd.ListJobSummary()
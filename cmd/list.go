@@ -213,10 +213,14 @@ func (cooked cookedListCmdArgs) HandleListContainerCommand() (err error) {
 		return err
 	}
 
-	if err := common.VerifyIsURLResolvable(raw.sourcePath); cooked.location.IsRemote() && err != nil {
+	if err := common.VerifyIsURLResolvable(source.Value); cooked.location.IsRemote() && err != nil {
 		return fmt.Errorf("failed to resolve target: %w", err)
 	}
 
+	if err = addCredentialHelperSAS(ctx, &source, cooked.location, true); err != nil {
+		return err
+	}
+
 	level, err := DetermineLocationLevel(source.Value, cooked.location, true)
 
 	if err != nil {
@@ -80,6 +80,14 @@ func init() {
 	//login with SPN
 	lgCmd.PersistentFlags().StringVar(&loginCmdArg.applicationID, "application-id", "", "Application ID of user-assigned identity. Required for service principal auth.")
 	lgCmd.PersistentFlags().StringVar(&loginCmdArg.certPath, "certificate-path", "", "Path to certificate for SPN authentication. Required for certificate-based service principal auth.")
+
+	// login with workload identity federation
+	lgCmd.PersistentFlags().BoolVar(&loginCmdArg.workloadIdentity, "workload-identity", false, "Log in using workload identity federation, by exchanging a federated token (such as a Kubernetes service account token) for an Azure AD token. "+
+		"The application ID, tenant ID and token file default to the AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE environment variables.")
+	lgCmd.PersistentFlags().StringVar(&loginCmdArg.federatedTokenFile, "federated-token-file", "", "Path to the federated token file used for workload identity federation. The file is read again whenever the token is refreshed.")
+
+	// login with a credential helper
+	lgCmd.PersistentFlags().StringVar(&loginCmdArg.credentialHelper, "credential-helper", "", "Log in using an external command that prints an OAuth token as JSON. The command is invoked again whenever the token nears expiry, including when jobs are resumed.")
 }
 
 type loginCmdArgs struct {
@@ -103,11 +111,34 @@ type loginCmdArgs struct {
 	certPass      string
 	clientSecret  string
 	persistToken  bool
+
+	// Command line of an external credential helper.
+	credentialHelper string
+
+	// Workload identity federation; applicationID and tenantID are shared with SPN auth.
+	workloadIdentity   bool
+	federatedTokenFile string
 }
 
 func (lca loginCmdArgs) validate() error {
 	// Only support one kind of oauth login at same time.
 	switch {
+	case lca.workloadIdentity:
+		if lca.identity || lca.servicePrincipal || lca.credentialHelper != "" {
+			return errors.New("you can only log in with one type of auth at once")
+		}
+
+		if lca.certPath != "" || lca.identityClientID != "" || lca.identityObjectID != "" || lca.identityResourceID != "" {
+			return errors.New("certificate path and identity client/object/resource IDs are not compatible with workload identity federation")
+		}
+	case lca.credentialHelper != "":
+		if lca.identity || lca.servicePrincipal {
+			return errors.New("you can only log in with one type of auth at once")
+		}
+
+		if lca.tenantID != "" || lca.applicationID != "" || lca.certPath != "" {
+			return errors.New("tenant ID/application ID/cert path cannot be used with a credential helper")
+		}
 	case lca.identity:
 		if lca.servicePrincipal {
 			return errors.New("you can only log in with one type of auth at once")
@@ -140,6 +171,10 @@ func (lca loginCmdArgs) validate() error {
 			return errors.New("application ID and certificate paths are exclusive to service principal auth and are not compatible with OAuth")
 		}
 
+		if lca.federatedTokenFile != "" {
+			return errors.New("federated token file is exclusive to workload identity federation and is not compatible with OAuth")
+		}
+
 		if lca.identityClientID != "" || lca.identityObjectID != "" || lca.identityResourceID != "" {
 			return errors.New("identity client/object/resource IDs are exclusive to managed service identity auth and are not compatible with OAuth")
 		}
@@ -158,6 +193,24 @@ func (lca loginCmdArgs) process() error {
 	// Persist the token to cache, if login fulfilled successfully.
 
 	switch {
+	case lca.workloadIdentity:
+		// Fall back to the variables injected by the Azure workload identity webhook.
+		applicationID := common.IffString(lca.applicationID != "", lca.applicationID, glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AzureClientID()))
+		tenantID := common.IffString(lca.tenantID != "", lca.tenantID, glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AzureTenantID()))
+		aadEndpoint := common.IffString(lca.aadEndpoint != "", lca.aadEndpoint, glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AzureAuthorityHost()))
+		tokenFile := common.IffString(lca.federatedTokenFile != "", lca.federatedTokenFile, glcm.GetEnvironmentVariable(common.EEnvironmentVariable.FederatedTokenFile()))
+
+		if _, err := uotm.WorkloadIdentityLogin(context.TODO(), tenantID, aadEndpoint, applicationID, tokenFile, lca.persistToken); err != nil {
+			return err
+		}
+
+		glcm.Info("Login with workload identity federation succeeded.")
+	case lca.credentialHelper != "":
+		if _, err := uotm.CredentialHelperLogin(context.TODO(), common.CredentialHelperInfo{Command: lca.credentialHelper}, lca.persistToken); err != nil {
+			return err
+		}
+
+		glcm.Info("Login with credential helper succeeded.")
 	case lca.servicePrincipal:
 
 		if lca.certPath != "" {
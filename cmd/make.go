@@ -45,7 +45,11 @@ type rawMakeCmdArgs struct {
 
 // parse raw input
 func (raw rawMakeCmdArgs) cook() (cookedMakeCmdArgs, error) {
-	parsedURL, err := url.Parse(raw.resourceToCreate)
+	resource, err := expandRemoteReference(raw.resourceToCreate)
+	if err != nil {
+		return cookedMakeCmdArgs{}, err
+	}
+	parsedURL, err := url.Parse(resource)
 	if err != nil {
 		return cookedMakeCmdArgs{}, err
 	}
@@ -81,6 +85,10 @@ func (cookedArgs cookedMakeCmdArgs) process() (err error) {
 		return fmt.Errorf("failed to resolve target: %w", err)
 	}
 
+	if err = addCredentialHelperSAS(ctx, &resourceStringParts, cookedArgs.resourceLocation, false); err != nil {
+		return err
+	}
+
 	credentialInfo, _, err := GetCredentialInfoForLocation(ctx, cookedArgs.resourceLocation, resourceStringParts.Value, resourceStringParts.SAS, false, common.CpkOptions{})
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
@@ -184,16 +185,46 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 }
 
 func SplitResourceString(raw string, loc common.Location) (common.ResourceString, error) {
+	// both "name:path" and the URLs under a remote get the SAS that the remote's credential reference refers to
+	raw, err := expandRemoteReference(raw)
+	if err != nil {
+		return common.ResourceString{}, err
+	}
+	remote, _, underRemote := common.RemoteForURL(raw)
+	underRemote = underRemote && loc.IsRemote()
+	if underRemote {
+		if raw, err = addRemoteSAS(raw, remote); err != nil {
+			return common.ResourceString{}, err
+		}
+	}
+
 	sasless, sas, err := splitAuthTokenFromResource(raw, loc)
 	if err != nil {
 		return common.ResourceString{}, nil
 	}
 	main, query := splitQueryFromSaslessResource(sasless, loc)
-	return common.ResourceString{
+	result := common.ResourceString{
 		Value:      main,
 		SAS:        sas,
 		ExtraQuery: query,
-	}, nil
+	}
+	if underRemote {
+		result.Remote = remote.Name
+	}
+	return result, nil
+}
+
+// addRemoteSAS adds the SAS for remote to raw, unless raw already has a signature.
+func addRemoteSAS(raw string, remote common.Remote) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Query().Get("sig") != "" {
+		return raw, nil // leave errors to the location-specific parsing
+	}
+	sas, err := remote.SAS(context.TODO())
+	if err != nil || sas == "" {
+		return raw, err
+	}
+	return raw + common.IffString(u.RawQuery == "", "?", "&") + sas, nil
 }
 
 // resourceBase will always be returned regardless of the location.
@@ -0,0 +1,269 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
+)
+
+// sasExpiryWarningWindow is how far ahead of a SAS's expiry we warn the user that their job may not finish in time.
+const sasExpiryWarningWindow = time.Hour
+
+// maxUserDelegationSASLifetime is the longest lifetime the service allows for a user delegation key.
+const maxUserDelegationSASLifetime = 7 * 24 * time.Hour
+
+var sasExpiryWarningsAlreadyLogged = &sync.Map{}
+
+func getSASRenewalMode() (common.SASRenewalMode, error) {
+	var mode common.SASRenewalMode
+	if err := mode.Parse(glcm.GetEnvironmentVariable(common.EEnvironmentVariable.SASRenewal())); err != nil {
+		return mode, fmt.Errorf("invalid value for %s: %w", common.EEnvironmentVariable.SASRenewal().Name, err)
+	}
+	return mode, nil
+}
+
+// prepareSASRenewal warns about a SAS that will expire soon, and registers a way to renew it according to AZCOPY_SAS_RENEWAL,
+// so that the STE can swap the replacement into in-flight requests.
+func prepareSASRenewal(location common.Location, resource, resourceSAS string, isSource bool) error {
+	if resourceSAS == "" {
+		return nil
+	}
+	if location != common.ELocation.Blob() && location != common.ELocation.BlobFS() && location != common.ELocation.File() {
+		return nil
+	}
+
+	mode, err := getSASRenewalMode()
+	if err != nil {
+		return err
+	}
+
+	var renew common.SASRenewer
+	switch mode {
+	case common.ESASRenewalMode.CredentialHelper():
+		helper := common.GetCredentialHelperFromEnvVar()
+		if helper.IsEmpty() {
+			return fmt.Errorf("SAS renewal via the credential helper requires %s to be set", common.EEnvironmentVariable.CredentialHelper().Name)
+		}
+		renew = func(ctx context.Context) (string, error) {
+			resp, err := helper.Get(ctx, common.CredentialHelperRequest{Kind: common.ECredentialHelperKind.SAS(), Resource: resource})
+			if err != nil {
+				return "", err
+			}
+			return resp.SAS, nil
+		}
+	case common.ESASRenewalMode.UserDelegation():
+		if location == common.ELocation.File() {
+			return errors.New("user delegation SAS renewal is not supported for Azure Files")
+		}
+		if _, _, err := userDelegationSASValuesLike(resource, resourceSAS); err != nil {
+			return fmt.Errorf("cannot renew the %s SAS: %w", sasRole(isSource), err)
+		}
+		renew = func(ctx context.Context) (string, error) {
+			return newUserDelegationSASLike(ctx, resource, resourceSAS)
+		}
+	}
+
+	if renew != nil {
+		common.RegisterSASRenewer(resourceSAS, renew)
+	}
+
+	warnIfSASExpiresSoon(resourceSAS, isSource, renew != nil)
+	return nil
+}
+
+func warnIfSASExpiresSoon(sas string, isSource, renewable bool) {
+	expiry := common.SASExpiry(sas)
+	if expiry.IsZero() || time.Until(expiry) > sasExpiryWarningWindow {
+		return
+	}
+
+	message := fmt.Sprintf("The SAS token for the %s expires at %s.", sasRole(isSource), expiry.Format(time.RFC3339))
+	if renewable {
+		message += " It will be renewed before it expires."
+	} else {
+		message = "WARNING: " + message + fmt.Sprintf(" Transfers still running at that time will fail. Set %s to renew it automatically.", common.EEnvironmentVariable.SASRenewal().Name)
+	}
+
+	if _, exists := sasExpiryWarningsAlreadyLogged.LoadOrStore(message, struct{}{}); !exists {
+		if jobsAdmin.JobsAdmin != nil {
+			jobsAdmin.JobsAdmin.LogToJobLog(message, pipeline.LogWarning)
+		}
+		glcm.Info(message)
+	}
+}
+
+func sasRole(isSource bool) string {
+	if isSource {
+		return "source"
+	}
+	return "destination"
+}
+
+// newUserDelegationSASLike signs a user delegation SAS for resource, with the same permissions, scope and lifetime as sas,
+// using the current Azure AD session.
+func newUserDelegationSASLike(ctx context.Context, resource, sas string) (string, error) {
+	values, lifetime, err := userDelegationSASValuesLike(resource, sas)
+	if err != nil {
+		return "", err
+	}
+
+	tokenInfo, err := GetUserOAuthTokenManagerInstance().GetTokenInfo(ctx)
+	if err != nil {
+		return "", fmt.Errorf("user delegation SAS renewal requires an Azure AD login: %w", err)
+	}
+	token, err := tokenInfo.Refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(resource)
+	if err != nil {
+		return "", err
+	}
+	// ADLS Gen2 accounts hand out user delegation keys on their blob endpoint
+	u.Host = strings.Replace(u.Host, ".dfs.", ".blob.", 1)
+
+	values.StartTime = time.Now().UTC().Add(-5 * time.Minute) // allow for clock skew
+	values.ExpiryTime = values.StartTime.Add(lifetime)
+
+	serviceURL := azblob.NewServiceURL(url.URL{Scheme: u.Scheme, Host: u.Host},
+		azblob.NewPipeline(azblob.NewTokenCredential(token.AccessToken, nil), azblob.PipelineOptions{}))
+	udc, err := serviceURL.GetUserDelegationCredential(ctx, azblob.NewKeyInfo(values.StartTime, values.ExpiryTime), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain a user delegation key: %w", err)
+	}
+
+	qp, err := values.NewSASQueryParameters(udc)
+	if err != nil {
+		return "", err
+	}
+	return qp.Encode(), nil
+}
+
+// userDelegationSASValuesLike works out the signature values (other than the start and expiry times) and the lifetime of a
+// user delegation SAS that grants exactly what sas grants on resource.
+// It fails, rather than widening access, if the scope or permissions of sas can't be expressed as a user delegation SAS.
+func userDelegationSASValuesLike(resource, sas string) (azblob.BlobSASSignatureValues, time.Duration, error) {
+	var values azblob.BlobSASSignatureValues
+
+	u, err := url.Parse(resource)
+	if err != nil {
+		return values, 0, err
+	}
+	parts := azblob.NewBlobURLParts(*u)
+	original, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+	if err != nil {
+		return values, 0, err
+	}
+
+	if original.Get("si") != "" {
+		return values, 0, errors.New("a SAS that refers to a stored access policy cannot be renewed as a user delegation SAS")
+	}
+	if parts.ContainerName == "" {
+		return values, 0, errors.New("a SAS for a whole account cannot be renewed as a user delegation SAS, which is limited to one container")
+	}
+	values.ContainerName = parts.ContainerName
+
+	blobName := strings.TrimSuffix(parts.BlobName, "/")
+	switch sr := original.Get("sr"); sr {
+	case "c":
+	case "b":
+		if blobName == "" {
+			return values, 0, errors.New("the SAS is scoped to a single blob, but the resource is not a blob")
+		}
+		values.BlobName = blobName
+	case "d":
+		depth, err := strconv.Atoi(original.Get("sdd"))
+		if err != nil || depth < 1 {
+			return values, 0, fmt.Errorf("the directory SAS has an invalid depth %q", original.Get("sdd"))
+		}
+		// the SAS covers the directory at that depth, and the resource is that directory or something beneath it
+		segments := strings.Split(blobName, "/")
+		if blobName == "" || len(segments) < depth {
+			return values, 0, fmt.Errorf("the directory SAS has a depth of %d, which is deeper than the resource", depth)
+		}
+		values.Directory = strings.Join(segments[:depth], "/")
+		if strings.ContainsAny(values.Directory, "*?") {
+			return values, 0, errors.New("the directory of the SAS cannot be determined from a resource containing wildcards")
+		}
+	case "":
+		// an account SAS; a container SAS for the resource's container grants no more than it did
+		if original.Get("ss") == "" {
+			return values, 0, errors.New("the SAS does not specify its scope")
+		}
+		if !strings.Contains(original.Get("ss"), "b") || !strings.Contains(original.Get("srt"), "c") || !strings.Contains(original.Get("srt"), "o") {
+			return values, 0, errors.New("the account SAS does not grant access to both containers and blobs, so a user delegation SAS would grant more")
+		}
+	default:
+		return values, 0, fmt.Errorf("a SAS with signed resource %q cannot be renewed as a user delegation SAS", sr)
+	}
+
+	// check the permissions the same way signing will, so that a SAS that can't be renewed is reported before the job starts
+	values.Permissions = original.Get("sp")
+	if values.BlobName != "" || values.Directory != "" {
+		err = (&azblob.BlobSASPermissions{}).Parse(values.Permissions)
+	} else {
+		err = (&azblob.ContainerSASPermissions{}).Parse(values.Permissions)
+	}
+	if err != nil {
+		return values, 0, fmt.Errorf("the SAS permissions %q cannot be granted by a user delegation SAS: %w", values.Permissions, err)
+	}
+
+	values.Protocol = azblob.SASProtocol(original.Get("spr"))
+	if values.Protocol == "" {
+		values.Protocol = azblob.SASProtocolHTTPS
+	}
+	if sip := original.Get("sip"); sip != "" {
+		start, end, _ := strings.Cut(sip, "-")
+		values.IPRange.Start = net.ParseIP(start)
+		if end != "" {
+			values.IPRange.End = net.ParseIP(end)
+		}
+		if values.IPRange.Start == nil || (end != "" && values.IPRange.End == nil) {
+			return values, 0, fmt.Errorf("the SAS has an invalid IP range %q", sip)
+		}
+	}
+
+	lifetime := time.Hour
+	if start, err := time.Parse(time.RFC3339, original.Get("st")); err == nil {
+		if expiry := common.SASExpiry(sas); expiry.After(start) {
+			lifetime = expiry.Sub(start)
+		}
+	}
+	if lifetime > maxUserDelegationSASLifetime {
+		lifetime = maxUserDelegationSASLifetime
+	}
+	return values, lifetime, nil
+}
@@ -102,6 +102,10 @@ func init() {
 			raw.src = args[0]
 			// We support DFS by using blob end-point of the account. We replace dfs by blob in src and dst
 			if src := InferArgumentLocation(raw.src); src == common.ELocation.BlobFS() {
+				var err error
+				if raw.src, err = expandRemoteReference(raw.src); err != nil { // the endpoint can only be switched in the URL
+					return err
+				}
 				raw.src = strings.Replace(raw.src, ".dfs", ".blob", 1)
 				glcm.Info("Switching to use blob endpoint on source account.")
 			}
@@ -43,6 +43,7 @@ func setPropertiesTransferProcessor(cca *CookedCopyCmdArgs, numOfTransfersPerPar
 			Metadata:          cca.metadata,
 			BlobTagsString:    cca.blobTags.ToString(),
 			RehydratePriority: cca.rehydratePriority,
+			AccessControlList: cca.accessControlList,
 		},
 		SetPropertiesFlags: cca.propertiesToTransfer,
 		FileAttributes: common.FileTransferAttributes{
@@ -0,0 +1,84 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// the metadata that an upload with --preserve-special-files writes on the blobs of special files
+var specialFileMetadataKeys = []string{
+	common.POSIXFIFOMeta,
+	common.POSIXCharDeviceMeta,
+	common.POSIXBlockDeviceMeta,
+	common.POSIXSocketMeta,
+}
+
+// specialFileClassifier finds, during enumeration, the FIFOs, device nodes and sockets, so that with
+// --preserve-special-files they are recreated at the destination rather than sent as empty files.
+type specialFileClassifier struct {
+	isUpload  bool
+	localRoot string
+}
+
+// classify marks the object as a special file. When uploading, that's a local file whose mode says it is one.
+// When downloading, it's any blob written for one by an upload.
+func (c *specialFileClassifier) classify(object *StoredObject) {
+	if object.entityType != common.EEntityType.File() {
+		return
+	}
+
+	if !c.isUpload {
+		if hasSpecialFileMetadata(object.Metadata) {
+			object.entityType = common.EEntityType.SpecialFile()
+		}
+		return
+	}
+
+	info, err := os.Stat(common.GenerateFullPath(c.localRoot, object.relativePath))
+	if err != nil || info.Mode()&(os.ModeNamedPipe|os.ModeSocket|os.ModeDevice) == 0 {
+		return
+	}
+
+	object.entityType = common.EEntityType.SpecialFile()
+	object.size = 0
+}
+
+// hasSpecialFileMetadata tells whether a blob was written for a special file
+func hasSpecialFileMetadata(metadata common.Metadata) bool {
+	for _, key := range specialFileMetadataKeys {
+		if _, ok := metadata[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withSpecialFileHandling wraps the source traverser if special files are being preserved
+func withSpecialFileHandling(traverser ResourceTraverser, preserveSpecialFiles bool, fromTo common.FromTo, localRoot string) ResourceTraverser {
+	if !preserveSpecialFiles {
+		return traverser
+	}
+	classifier := &specialFileClassifier{isUpload: fromTo.IsUpload(), localRoot: localTraversalRoot(localRoot)}
+	return &classifyingTraverser{ResourceTraverser: traverser, classify: classifier.classify}
+}
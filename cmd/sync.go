@@ -47,6 +47,7 @@ type rawSyncCmdArgs struct {
 
 	// options from flags
 	blockSizeMB           float64
+	adaptiveBlockSize     bool
 	include               string
 	exclude               string
 	excludePath           string
@@ -66,6 +67,9 @@ type rawSyncCmdArgs struct {
 	preservePOSIXProperties bool
 	followSymlinks          bool
 	preserveSymlinks        bool
+	hardlinks               string
+	preserveSpecialFiles    bool
+	preserveXattrs          bool
 	backupMode              bool
 	putMd5                  bool
 	md5ValidationOption     string
@@ -210,6 +214,10 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	cooked.adaptiveBlockSize = raw.adaptiveBlockSize
+	if err = validateAdaptiveBlockSize(cooked.adaptiveBlockSize, cooked.blockSize); err != nil {
+		return cooked, err
+	}
 
 	if err = cooked.symlinkHandling.Determine(raw.followSymlinks, raw.preserveSymlinks); err != nil {
 		return cooked, err
@@ -217,6 +225,20 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if err = crossValidateSymlinksAndPermissions(cooked.symlinkHandling, true /* replace with real value when available */); err != nil {
 		return cooked, err
 	}
+	if err = cooked.hardlinks.Parse(raw.hardlinks); err != nil {
+		return cooked, err
+	}
+	if err = validateHardlinkHandlingMode(cooked.hardlinks, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	cooked.preserveSpecialFiles = raw.preserveSpecialFiles
+	if err = validatePreserveSpecialFiles(cooked.preserveSpecialFiles, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	cooked.preserveXattrs = raw.preserveXattrs
+	if err = validatePreserveXattrs(cooked.preserveXattrs, cooked.fromTo); err != nil {
+		return cooked, err
+	}
 	cooked.recursive = raw.recursive
 	cooked.forceIfReadOnly = raw.forceIfReadOnly
 	if err = validateForceIfReadOnly(cooked.forceIfReadOnly, cooked.fromTo); err != nil {
@@ -275,6 +297,12 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if cooked.preservePOSIXProperties && !areBothLocationsPOSIXAware(cooked.fromTo) {
 		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (valid pairings are Linux->Blob, Blob->Linux, Blob->Blob)")
 	}
+	if cooked.preservePOSIXProperties {
+		// the owner and group are mapped through the identity mapping file, if there is one
+		if _, err := common.GetIdentityMapping(); err != nil {
+			return cooked, err
+		}
+	}
 
 	if err = cooked.compareHash.Parse(raw.compareHash); err != nil {
 		return cooked, err
@@ -392,6 +420,9 @@ type cookedSyncCmdArgs struct {
 	// filters
 	recursive             bool
 	symlinkHandling       common.SymlinkHandlingType
+	hardlinks             common.HardlinkHandlingType
+	preserveSpecialFiles  bool
+	preserveXattrs        bool
 	includePatterns       []string
 	excludePatterns       []string
 	excludePaths          []string
@@ -408,6 +439,7 @@ type cookedSyncCmdArgs struct {
 	putMd5                  bool
 	md5ValidationOption     common.HashValidationOption
 	blockSize               int64
+	adaptiveBlockSize       bool
 	forceIfReadOnly         bool
 	backupMode              bool
 
@@ -620,7 +652,7 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			if format == common.EOutputFormat.Json() {
 				return cca.getJsonOfSyncJobSummary(summary)
 			}
-			screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
+			screenStats, logStats := formatExtraStats(false, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage, summary.RequestLatencies)
 
 			output := fmt.Sprintf(
 				`
@@ -682,6 +714,13 @@ func (cca *cookedSyncCmdArgs) process() (err error) {
 		return fmt.Errorf("failed to resolve destination: %w", err)
 	}
 
+	if err = addCredentialHelperSAS(ctx, &cca.source, cca.fromTo.From(), true); err != nil {
+		return err
+	}
+	if err = addCredentialHelperSAS(ctx, &cca.destination, cca.fromTo.To(), false); err != nil {
+		return err
+	}
+
 	// Verifies credential type and initializes credential info.
 	// Note that this is for the destination.
 	cca.credentialInfo, _, err = GetCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false, cca.cpkOptions)
@@ -787,12 +826,16 @@ func init() {
 		"will be transferred; any others will be ignored. This flag applies to both files and folders, unless a file-only filter is specified "+
 		"(e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is never preserved for folders.")
 	syncCmd.PersistentFlags().BoolVar(&raw.preservePOSIXProperties, "preserve-posix-properties", false, "'Preserves' property info gleaned from stat or statx into object metadata.")
+	syncCmd.PersistentFlags().BoolVar(&raw.preserveXattrs, common.PreserveXattrsFlagName, false, "Linux only, for Local<->Blob. False by default. Preserves the user.* and security.* extended attributes of files. They are stored in the blob's metadata, or, if they take more than 4 KiB, in a sidecar blob with the suffix '.azcopy-xattrs' next to it, and are restored when downloading. Sidecar blobs are not transferred as files of their own when this flag is used, and are deleted along with their blobs, or when the extended attributes fit in the metadata again. Files are re-sent when their extended attributes change, even if they are otherwise unchanged.")
+	syncCmd.PersistentFlags().BoolVar(&raw.preserveSpecialFiles, common.PreserveSpecialFilesFlagName, false, "Linux only, for Local<->Blob. False by default. Uploads FIFOs, character and block devices and sockets as empty blobs that record the type, mode and device number in their metadata, and recreates them when downloading. Creating device nodes requires the privileges to do so (e.g. root). Without this flag, such files are uploaded as empty blobs and downloaded as empty files. Blobs are re-sent when a file becomes a special file.")
+	syncCmd.PersistentFlags().StringVar(&raw.hardlinks, common.HardlinksFlagName, "follow", "Linux only, for Local<->Blob. 'follow' (default) transfers every name of a hard-linked file as a file of its own. 'preserve' uploads the data once, with the other names as empty blobs that refer to it, and recreates the hard links when downloading. Blobs are re-sent when a name stops or starts being a link.")
 
 	// TODO: enable when we support local <-> File
 	syncCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
 	// syncCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
 	// syncCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 
+	syncCmd.PersistentFlags().BoolVar(&raw.adaptiveBlockSize, common.AdaptiveBlockSizeFlagName, false, "Choose the block size of each file as its transfer starts, from the size of the file, the throughput of earlier requests and the limits of the service, instead of using one size for all files. For downloads, bigger ranges are used when requests have high latency but the service is not busy. Cannot be combined with --block-size-mb.")
 	syncCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage or downloading from Azure Storage. Default is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
 	syncCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
 	syncCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
@@ -837,5 +880,5 @@ func init() {
 
 	// Deprecate the old persist-smb-permissions flag
 	_ = syncCmd.PersistentFlags().MarkHidden("preserve-smb-permissions")
-	syncCmd.PersistentFlags().BoolVar(&raw.preservePermissions, PreservePermissionsFlag, false, "False by default. Preserves ACLs between aware resources (Windows and Azure Files, or ADLS Gen 2 to ADLS Gen 2). For Hierarchical Namespace accounts, you will need a container SAS or OAuth token with Modify Ownership and Modify Permissions permissions. For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
+	syncCmd.PersistentFlags().BoolVar(&raw.preservePermissions, PreservePermissionsFlag, false, "False by default. Preserves ACLs between aware resources (Windows and Azure Files, ADLS Gen 2 to ADLS Gen 2, or Linux and ADLS Gen 2, where POSIX ACLs are translated using the uid/gid to AAD object ID mapping file named by the AZCOPY_SID_MAPPING_FILE environment variable). For Hierarchical Namespace accounts, you will need a container SAS or OAuth token with Modify Ownership and Modify Permissions permissions. For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
 }
@@ -34,6 +34,9 @@ const (
 	syncSkipReasonSameHash = "the source has the same hash"
 	syncOverwriteReasonNewerHash = "the source has a differing hash"
 	syncOverwriteResaonNewerLMT = "the source is more recent than the destination"
+	syncOverwriteReasonHardlink = "the source and destination differ in which file they are a hard link to"
+	syncOverwriteReasonSpecialFile = "the source is a special file but the destination is not"
+	syncOverwriteReasonXattrs = "the source and destination have different extended attributes"
 	syncStatusSkipped = "skipped"
 	syncStatusOverwritten = "overwritten"
 )
@@ -67,10 +70,11 @@ type syncDestinationComparator struct {
 
   	preferSMBTime     bool
 	disableComparison bool
+	compareXattrs     bool
 }
 
-func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison bool) *syncDestinationComparator {
-	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType}
+func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison, compareXattrs bool) *syncDestinationComparator {
+	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, preferSMBTime: preferSMBTime, disableComparison: disableComparison, compareXattrs: compareXattrs, comparisonHashType: comparisonHashType}
 }
 
 // it will only schedule transfers for destination objects that are present in the indexer but stale compared to the entry in the map
@@ -93,6 +97,25 @@ func (f *syncDestinationComparator) processIfNecessary(destinationObject StoredO
 			return f.copyTransferScheduler(sourceObjectInMap)
 		}
 
+		// a name that became, or stopped being, a hard link to another file is sent again whatever its time
+		if sourceObjectInMap.Metadata[common.POSIXHardlinkMeta] != destinationObject.Metadata[common.POSIXHardlinkMeta] {
+			syncComparatorLog(sourceObjectInMap.relativePath, syncStatusOverwritten, syncOverwriteReasonHardlink, false)
+			return f.copyTransferScheduler(sourceObjectInMap)
+		}
+
+		// likewise for a name that became a FIFO, device node or socket (one that stopped being one is newer anyway)
+		if sourceObjectInMap.entityType == common.EEntityType.SpecialFile() && !hasSpecialFileMetadata(destinationObject.Metadata) {
+			syncComparatorLog(sourceObjectInMap.relativePath, syncStatusOverwritten, syncOverwriteReasonSpecialFile, false)
+			return f.copyTransferScheduler(sourceObjectInMap)
+		}
+
+		// changing extended attributes doesn't change the last modified time, so their hashes are compared instead
+		if f.compareXattrs && sourceObjectInMap.entityType == common.EEntityType.File() &&
+			sourceObjectInMap.Metadata[common.POSIXXattrsHashMeta] != destinationObject.Metadata[common.POSIXXattrsHashMeta] {
+			syncComparatorLog(sourceObjectInMap.relativePath, syncStatusOverwritten, syncOverwriteReasonXattrs, false)
+			return f.copyTransferScheduler(sourceObjectInMap)
+		}
+
 		if f.comparisonHashType != common.ESyncHashType.None() && sourceObjectInMap.entityType == common.EEntityType.File() {
 			switch f.comparisonHashType {
 			case common.ESyncHashType.MD5():
@@ -141,10 +164,11 @@ type syncSourceComparator struct {
 
   preferSMBTime     bool
 	disableComparison bool
+	compareXattrs     bool
 }
 
-func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison bool) *syncSourceComparator {
-	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType}
+func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison, compareXattrs bool) *syncSourceComparator {
+	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, preferSMBTime: preferSMBTime, disableComparison: disableComparison, compareXattrs: compareXattrs, comparisonHashType: comparisonHashType}
 }
 
 // it will only transfer source items that are:
@@ -170,6 +194,23 @@ func (f *syncSourceComparator) processIfNecessary(sourceObject StoredObject) err
 			return f.copyTransferScheduler(sourceObject)
 		}
 
+		// whether the local file is already linked is only known once the file it links to is in place
+		if sourceObject.entityType == common.EEntityType.Hardlink() {
+			return f.copyTransferScheduler(sourceObject)
+		}
+
+		// whether the local file is the same kind of special file isn't known from the local listing, and they are cheap to recreate
+		if sourceObject.entityType == common.EEntityType.SpecialFile() {
+			return f.copyTransferScheduler(sourceObject)
+		}
+
+		// changing extended attributes doesn't change the last modified time, so their hashes are compared instead
+		if f.compareXattrs && sourceObject.entityType == common.EEntityType.File() &&
+			sourceObject.Metadata[common.POSIXXattrsHashMeta] != destinationObjectInMap.Metadata[common.POSIXXattrsHashMeta] {
+			syncComparatorLog(sourceObject.relativePath, syncStatusOverwritten, syncOverwriteReasonXattrs, false)
+			return f.copyTransferScheduler(sourceObject)
+		}
+
 		if f.comparisonHashType != common.ESyncHashType.None() && sourceObject.entityType == common.EEntityType.File() {
 			switch f.comparisonHashType {
 			case common.ESyncHashType.MD5():
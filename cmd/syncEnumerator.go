@@ -71,6 +71,13 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		return nil, err
 	}
 
+	// with --preserve-special-files, FIFOs, device nodes and sockets are recreated rather than sent as empty files
+	sourceTraverser = withSpecialFileHandling(sourceTraverser, cca.preserveSpecialFiles, cca.fromTo, cca.source.ValueLocal())
+	// with --hardlinks=preserve, further names of a file go to the destination as links to it
+	sourceTraverser = withHardlinkTracking(sourceTraverser, newHardlinkTracker(cca.hardlinks, cca.fromTo, cca.source.ValueLocal()))
+	// with --preserve-xattrs, changes to extended attributes count as changes to the file
+	sourceTraverser = withXattrHashes(sourceTraverser, cca.preserveXattrs, cca.fromTo.From(), cca.source.ValueLocal())
+
 	// Because we can't trust cca.credinfo, given that it's for the overall job, not the individual traversers, we get cred info again here.
 	dstCredInfo, _, err := GetCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value,
 		cca.destination.SAS, false, cca.cpkOptions)
@@ -90,6 +97,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	if err != nil {
 		return nil, err
 	}
+	destinationTraverser = withXattrHashes(destinationTraverser, cca.preserveXattrs, cca.fromTo.To(), cca.destination.ValueLocal())
 
 	// verify that the traversers are targeting the same type of resources
 	sourceIsDir, _ := sourceTraverser.IsDirectory(true)
@@ -121,6 +129,11 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	filters = append(filters, buildRegexFilters(cca.includeRegex, true)...)
 	filters = append(filters, buildRegexFilters(cca.excludeRegex, false)...)
 
+	// with --preserve-xattrs, sidecar blobs are neither transferred nor deleted as extra files
+	if cca.preserveXattrs {
+		filters = append(filters, &excludeXattrsSidecarFilter{})
+	}
+
 	// after making all filters, log any search prefix computed from them
 	if jobsAdmin.JobsAdmin != nil {
 		if prefixFilter := FilterSet(filters).GetEnumerationPreFilter(cca.recursive); prefixFilter != "" {
@@ -159,7 +172,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		// we ALREADY have available a complete map of everything that exists locally
 		// so as soon as we see a remote destination object we can know whether it exists in the local source
 
-		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode).processIfNecessary
+		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode, cca.preserveXattrs).processIfNecessary
 		finalize = func() error {
 			// schedule every local file that doesn't exist at the destination
 			err = indexer.traverse(transferScheduler.scheduleCopyTransfer, filters)
@@ -183,7 +196,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		indexer.isDestinationCaseInsensitive = IsDestinationCaseInsensitive(cca.fromTo)
 		// in all other cases (download and S2S), the destination is scanned/indexed first
 		// then the source is scanned and filtered based on what the destination contains
-		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode).processIfNecessary
+		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode, cca.preserveXattrs).processIfNecessary
 
 		finalize = func() error {
 			// remove the extra files at the destination that were not present at the source
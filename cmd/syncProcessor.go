@@ -54,13 +54,15 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 			PreserveLastModifiedTime: cca.preserveSMBInfo, // true by default for sync so that future syncs have this information available
 			PutMd5:                   cca.putMd5,
 			MD5ValidationOption:      cca.md5ValidationOption,
-			BlockSizeInBytes:         cca.blockSize},
+			BlockSizeInBytes:         cca.blockSize,
+			AdaptiveBlockSize:        cca.adaptiveBlockSize},
 		ForceWrite:                     common.EOverwriteOption.True(), // once we decide to transfer for a sync operation, we overwrite the destination regardless
 		ForceIfReadOnly:                cca.forceIfReadOnly,
 		LogLevel:                       azcopyLogVerbosity,
 		PreserveSMBPermissions:         cca.preservePermissions,
 		PreserveSMBInfo:                cca.preserveSMBInfo,
 		PreservePOSIXProperties:        cca.preservePOSIXProperties,
+		PreserveXattrs:                 cca.preserveXattrs,
 		S2SSourceChangeValidation:      true,
 		DestLengthValidation:           true,
 		S2SGetPropertiesInBackend:      true,
@@ -349,6 +351,14 @@ func (b *remoteResourceDeleter) delete(object StoredObject) error {
 			blobURLParts.BlobName = path.Join(blobURLParts.BlobName, object.relativePath)
 			blobURL := azblob.NewBlobURL(blobURLParts.URL(), b.p)
 			_, err = blobURL.Delete(b.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+			// the sidecar holding extended attributes too big for the metadata goes with its blob
+			if _, hasSidecar := object.Metadata[common.POSIXXattrsSidecarMeta]; hasSidecar && err == nil {
+				blobURLParts.BlobName += common.XattrsSidecarSuffix
+				_, err = azblob.NewBlobURL(blobURLParts.URL(), b.p).Delete(b.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+				if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+					err = nil
+				}
+			}
 		case common.ELocation.File():
 			fileURLParts := azfile.NewFileURLParts(*b.rootURL)
 			fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, object.relativePath)
@@ -89,7 +89,7 @@ func inferFromTo(src, dst string) common.FromTo {
 	srcLocation := InferArgumentLocation(src)
 	if srcLocation == srcLocation.Unknown() {
 		glcm.Info("Cannot infer source location of " +
-			common.URLStringExtension(src).RedactSecretQueryParamForLogging() +
+			common.DisplayStringForURL(src) +
 			". Please specify the --from-to switch. " + fromToHelpText)
 		return common.EFromTo.Unknown()
 	}
@@ -97,7 +97,7 @@ func inferFromTo(src, dst string) common.FromTo {
 	dstLocation := InferArgumentLocation(dst)
 	if dstLocation == dstLocation.Unknown() {
 		glcm.Info("Cannot infer destination location of " +
-			common.URLStringExtension(dst).RedactSecretQueryParamForLogging() +
+			common.DisplayStringForURL(dst) +
 			". Please specify the --from-to switch. " + fromToHelpText)
 		return common.EFromTo.Unknown()
 	}
@@ -121,8 +121,8 @@ func inferFromTo(src, dst string) common.FromTo {
 	}
 
 	glcm.Info("The parameters you supplied were " +
-		"Source: '" + common.URLStringExtension(src).RedactSecretQueryParamForLogging() + "' of type " + srcLocation.String() +
-		", and Destination: '" + common.URLStringExtension(dst).RedactSecretQueryParamForLogging() + "' of type " + dstLocation.String())
+		"Source: '" + common.DisplayStringForURL(src) + "' of type " + srcLocation.String() +
+		", and Destination: '" + common.DisplayStringForURL(dst) + "' of type " + dstLocation.String())
 	glcm.Info("Based on the parameters supplied, a valid source-destination combination could not " +
 		"automatically be found. Please check the parameters you supplied.  If they are correct, please " +
 		"specify an exact source and destination type using the --from-to switch. " + fromToHelpText)
@@ -136,6 +136,12 @@ func InferArgumentLocation(arg string) common.Location {
 	if arg == pipeLocation {
 		return common.ELocation.Pipe()
 	}
+	if resolved, _, ok, _ := common.ResolveRemoteReference(arg); ok {
+		arg = resolved // a reference to a remote is wherever the remote's URL is
+	}
+	if remote, _, ok := common.RemoteForURL(arg); ok && remote.Location != common.ELocation.Unknown() {
+		return remote.Location // the remote's type was given explicitly, e.g. for an emulator whose URL says nothing
+	}
 	if startsWith(arg, "http") {
 		// Let's try to parse the argument as a URL
 		u, err := url.Parse(arg)
@@ -0,0 +1,219 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	"github.com/spf13/cobra"
+)
+
+// workerReportInterval is how often a worker renews its lease, and reports the progress of the part it's executing.
+const workerReportInterval = 2 * time.Second
+
+type workerArgs struct {
+	queue          string
+	sourceSAS      string
+	destinationSAS string
+	lease          time.Duration
+	pollInterval   time.Duration
+	exitWhenIdle   bool
+}
+
+// distributedWorker claims job parts from a queue and executes them, one at a time.
+type distributedWorker struct {
+	args  workerArgs
+	queue *fsPartQueue
+	name  string
+
+	// credentials for each of the jobs in the queue, worked out when the worker claims the job's first part
+	credentials map[common.JobID]workerCredentials
+}
+
+type workerCredentials struct {
+	sourceSAS      string
+	destinationSAS string
+	info           common.CredentialInfo
+}
+
+func init() {
+	args := workerArgs{}
+
+	workerCmd := &cobra.Command{
+		Use:     "worker",
+		Short:   workerCmdShortDescription,
+		Long:    workerCmdLongDescription,
+		Example: workerCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("worker command does not take any arguments")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, _ []string) {
+			queue, err := newFSPartQueue(args.queue)
+			if err != nil {
+				glcm.Error("failed to open the queue due to error: " + err.Error())
+			}
+			if args.lease < 3*workerReportInterval {
+				glcm.Error(fmt.Sprintf("the lease duration must be at least %v", 3*workerReportInterval))
+			}
+
+			w := &distributedWorker{args: args, queue: queue, name: distributedWorkerName(), credentials: map[common.JobID]workerCredentials{}}
+			if err := w.run(); err != nil {
+				glcm.Error("worker stopped due to error: " + err.Error())
+			}
+			glcm.Exit(nil, common.EExitCode.Success())
+		},
+	}
+	rootCmd.AddCommand(workerCmd)
+
+	workerCmd.PersistentFlags().StringVar(&args.queue, "queue", "", "The directory, shared with the coordinator and the other workers, that holds the queue of job parts.")
+	workerCmd.PersistentFlags().StringVar(&args.sourceSAS, "source-sas", "", "SAS token for the source of the jobs in the queue.")
+	workerCmd.PersistentFlags().StringVar(&args.destinationSAS, "destination-sas", "", "SAS token for the destination of the jobs in the queue.")
+	workerCmd.PersistentFlags().DurationVar(&args.lease, "lease-duration", time.Minute, "How long a job part stays claimed by a worker that stopped reporting on it, before another worker may claim it.")
+	workerCmd.PersistentFlags().DurationVar(&args.pollInterval, "poll-interval", 10*time.Second, "How often to look for job parts, when there are none to claim.")
+	workerCmd.PersistentFlags().BoolVar(&args.exitWhenIdle, "exit-when-idle", false, "Exit when there are no job parts to claim, rather than wait for more.")
+}
+
+func (w *distributedWorker) run() error {
+	glcm.Info(fmt.Sprintf("Worker %s is waiting for job parts in %s", w.name, w.queue.root))
+	for {
+		part, err := w.queue.ClaimPart(w.name, w.args.lease)
+		if err != nil {
+			return err
+		}
+		if part == nil {
+			if w.args.exitWhenIdle {
+				return nil
+			}
+			time.Sleep(w.args.pollInterval)
+			continue
+		}
+
+		glcm.Info(fmt.Sprintf("Executing part %d of job %s", part.PartNum, part.JobID))
+		if err := w.execute(part); errors.Is(err, errLeaseLost) {
+			glcm.Info(fmt.Sprintf("Gave up part %d of job %s: %s", part.PartNum, part.JobID, err))
+		} else if err != nil {
+			// give the part to another worker, which may have what this one was missing
+			glcm.Info(fmt.Sprintf("Failed to execute part %d of job %s: %s", part.PartNum, part.JobID, err))
+			if err := part.Release(); err != nil {
+				return err
+			}
+			time.Sleep(w.args.pollInterval)
+		}
+	}
+}
+
+// credentialsFor works out the credentials for a job in the same way as "jobs resume", since the queue holds none.
+func (w *distributedWorker) credentialsFor(ctx context.Context, job distributedJob, order common.CopyJobPartOrderRequest) (workerCredentials, error) {
+	if creds, ok := w.credentials[job.JobID]; ok {
+		return creds, nil
+	}
+
+	var err error
+	creds := workerCredentials{sourceSAS: w.args.sourceSAS, destinationSAS: w.args.destinationSAS}
+	if creds.sourceSAS == "" {
+		if creds.sourceSAS, err = getCredentialHelperSAS(ctx, job.Source, job.FromTo.From(), true); err != nil {
+			return creds, err
+		}
+	}
+	if creds.destinationSAS == "" {
+		if creds.destinationSAS, err = getCredentialHelperSAS(ctx, job.Destination, job.FromTo.To(), false); err != nil {
+			return creds, err
+		}
+	}
+
+	if creds.info.CredentialType, err = getCredentialType(ctx, rawFromToInfo{
+		fromTo:         job.FromTo,
+		source:         job.Source,
+		destination:    job.Destination,
+		sourceSAS:      creds.sourceSAS,
+		destinationSAS: creds.destinationSAS,
+	}, order.CpkOptions); err != nil {
+		return creds, err
+	}
+	if creds.info.CredentialType.IsAzureOAuth() || order.S2SSourceCredentialType.IsAzureOAuth() {
+		tokenInfo, err := GetUserOAuthTokenManagerInstance().GetTokenInfo(ctx)
+		if err != nil {
+			return creds, err
+		}
+		creds.info.OAuthTokenInfo = *tokenInfo
+	}
+
+	w.credentials[job.JobID] = creds
+	return creds, nil
+}
+
+// execute runs the part as a single-part job in this process's STE, and reports on it until it's done.
+func (w *distributedWorker) execute(part *claimedPart) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+	creds, err := w.credentialsFor(ctx, part.Job, part.Order)
+	if err != nil {
+		return err
+	}
+
+	order := part.Order
+	order.JobID = common.NewJobID()
+	order.PartNum = 0
+	order.IsFinalPart = true
+	order.SourceRoot.SAS = creds.sourceSAS
+	order.DestinationRoot.SAS = creds.destinationSAS
+	order.CredentialInfo.CredentialType = creds.info.CredentialType
+	order.CredentialInfo.OAuthTokenInfo = creds.info.OAuthTokenInfo
+
+	var resp common.CopyJobPartOrderResponse
+	Rpc(common.ERpcCmd.CopyJobPartOrder(), &order, &resp)
+	if !resp.JobStarted {
+		return fmt.Errorf("the STE did not start the job part: %s", resp.ErrorMsg)
+	}
+
+	cancelled := false
+	for {
+		var summary common.ListJobSummaryResponse
+		Rpc(common.ERpcCmd.ListJobSummary(), &order.JobID, &summary)
+		report := distributedPartReport{Worker: w.name, LocalJobID: order.JobID, Summary: summary}
+		if summary.JobStatus.IsJobDone() {
+			return part.Complete(report)
+		}
+
+		jobCancelled, err := part.Renew(report)
+		leaseLost := errors.Is(err, errLeaseLost)
+		if (leaseLost || jobCancelled) && !cancelled {
+			var cancelResp common.CancelPauseResumeResponse
+			Rpc(common.ERpcCmd.CancelJob(), order.JobID, &cancelResp)
+			cancelled = true
+		}
+		if leaseLost {
+			return err
+		} else if err != nil {
+			// the lease lasts for several report intervals, so the next renewal can still keep it
+			glcm.Info(fmt.Sprintf("Failed to renew the lease on part %d of job %s, will try again: %s", part.PartNum, part.JobID, err))
+		}
+
+		time.Sleep(workerReportInterval)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// excludeXattrsSidecarFilter leaves out the sidecar blobs that hold extended attributes too big for metadata.
+// They're read by the transfer of the blob they belong to, rather than being transferred themselves.
+type excludeXattrsSidecarFilter struct{}
+
+func (f *excludeXattrsSidecarFilter) DoesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *excludeXattrsSidecarFilter) AppliesOnlyToFiles() bool {
+	return false // folders pass, as they never have the suffix, so this mustn't stop folder properties being transferred
+}
+
+func (f *excludeXattrsSidecarFilter) DoesPass(storedObject StoredObject) bool {
+	return !strings.HasSuffix(storedObject.name, common.XattrsSidecarSuffix)
+}
+
+// xattrHasher records, in the metadata of local files, a hash of the extended attributes that --preserve-xattrs
+// transfers. Changing them doesn't change the last modified time, so sync compares the hashes too.
+type xattrHasher struct {
+	localRoot string
+}
+
+func (h *xattrHasher) classify(object *StoredObject) {
+	if object.entityType != common.EEntityType.File() {
+		return
+	}
+
+	xattrs, err := common.GetXattrs(common.GenerateFullPath(h.localRoot, object.relativePath))
+	if err != nil {
+		return // the transfer will report it, if there is one
+	}
+	hash, err := xattrs.Hash()
+	if err != nil || hash == "" {
+		return
+	}
+
+	metadata := common.Metadata{}
+	for k, v := range object.Metadata {
+		metadata[k] = v
+	}
+	metadata[common.POSIXXattrsHashMeta] = hash
+	object.Metadata = metadata
+}
+
+// withXattrHashes wraps a local traverser of sync if extended attributes are being preserved
+func withXattrHashes(traverser ResourceTraverser, preserveXattrs bool, location common.Location, localRoot string) ResourceTraverser {
+	if !preserveXattrs || location != common.ELocation.Local() {
+		return traverser
+	}
+	hasher := &xattrHasher{localRoot: localTraversalRoot(localRoot)}
+	return &classifyingTraverser{ResourceTraverser: traverser, classify: hasher.classify}
+}
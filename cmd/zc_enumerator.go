@@ -119,8 +119,8 @@ func (s *StoredObject) isSourceRootFolder() bool {
 // do not pass through that routine.  So we need to make the filtering available in a separate function
 // so that the sync deletion code path(s) can access it.
 func (s *StoredObject) isCompatibleWithEntitySettings(fpo common.FolderPropertyOption, sht common.SymlinkHandlingType) bool {
-	if s.entityType == common.EEntityType.File() {
-		return true
+	if s.entityType == common.EEntityType.File() || s.entityType == common.EEntityType.Hardlink() || s.entityType == common.EEntityType.SpecialFile() {
+		return true // hard links and special files are only ever classified as such when they are being preserved
 	} else if s.entityType == common.EEntityType.Folder() {
 		switch fpo {
 		case common.EFolderPropertiesOption.NoFolders():
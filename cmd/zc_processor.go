@@ -49,6 +49,10 @@ type copyTransferProcessor struct {
 	folderPropertiesOption common.FolderPropertyOption
 	symlinkHandlingType    common.SymlinkHandlingType
 	dryrunMode             bool
+
+	// hard links being downloaded wait for the final part, so that the files they link to are in earlier parts
+	deferredHardlinks deferredHardlinks
+	hardlinksReleased bool
 }
 
 func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, numOfTransfersPerPart int,
@@ -69,7 +73,6 @@ func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, n
 }
 
 func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject StoredObject) (err error) {
-
 	// Escape paths on destinations where the characters are invalid
 	// And re-encode them where the characters are valid.
 	srcRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, false, true)
@@ -154,6 +157,15 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject StoredObject)
 		return nil
 	}
 
+	if copyTransfer.EntityType == common.EEntityType.Hardlink() && s.copyJobTemplate.FromTo.IsDownload() && !s.hardlinksReleased {
+		return s.deferredHardlinks.add(copyTransfer)
+	}
+
+	return s.addTransfer(copyTransfer)
+}
+
+// addTransfer adds the transfer to the part being built, dispatching that part first if it's full.
+func (s *copyTransferProcessor) addTransfer(copyTransfer common.CopyTransfer) error {
 	if len(s.copyJobTemplate.Transfers.List) == s.numOfTransfersPerPart {
 		resp := s.sendPartToSte()
 
@@ -173,7 +185,7 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject StoredObject)
 	s.copyJobTemplate.Transfers.TotalSizeInBytes += uint64(copyTransfer.SourceSize)
 
 	switch copyTransfer.EntityType {
-	case common.EEntityType.File():
+	case common.EEntityType.File(), common.EEntityType.Hardlink(), common.EEntityType.SpecialFile():
 		s.copyJobTemplate.Transfers.FileTransferCount++
 	case common.EEntityType.Folder():
 		s.copyJobTemplate.Transfers.FolderTransferCount++
@@ -188,6 +200,23 @@ var NothingScheduledError = errors.New("no transfers were scheduled because no f
 var FinalPartCreatedMessage = "Final job part has been created"
 
 func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err error) {
+	if !s.deferredHardlinks.empty() && !s.hardlinksReleased {
+		s.hardlinksReleased = true
+		if len(s.copyJobTemplate.Transfers.List) > 0 && !s.dryrunMode {
+			resp := s.sendPartToSte()
+			if resp.ErrorMsg != "" {
+				return false, errors.New(string(resp.ErrorMsg))
+			}
+
+			s.copyJobTemplate.Transfers = common.Transfers{}
+			s.copyJobTemplate.PartNum++
+		}
+
+		if err = s.deferredHardlinks.release(s.addTransfer); err != nil {
+			return false, err
+		}
+	}
+
 	var resp common.CopyJobPartOrderResponse
 	s.copyJobTemplate.IsFinalPart = true
 	resp = s.sendPartToSte()
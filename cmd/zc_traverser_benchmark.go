@@ -27,19 +27,23 @@ import (
 
 type benchmarkTraverser struct {
 	fileCount                   uint
-	bytesPerFile                int64
+	sizes                       benchmarkSizeDistribution
 	numOfFolders                uint
 	incrementEnumerationCounter enumerationCounterFunc
 }
 
 func newBenchmarkTraverser(source string, incrementEnumerationCounter enumerationCounterFunc) (*benchmarkTraverser, error) {
-	fc, bpf, nf, err := benchmarkSourceHelper{}.FromUrl(source)
+	fc, bpf, nf, sd, err := benchmarkSourceHelper{}.FromUrl(source)
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := newBenchmarkSizeDistribution(sd, bpf)
 	if err != nil {
 		return nil, err
 	}
 	return &benchmarkTraverser{
 			fileCount:                   fc,
-			bytesPerFile:                bpf,
+			sizes:                       sizes,
 			numOfFolders:                nf,
 			incrementEnumerationCounter: incrementEnumerationCounter},
 		nil
@@ -86,7 +90,7 @@ func (t *benchmarkTraverser) Traverse(preprocessor objectMorpher, processor obje
 			relativePath,
 			common.EEntityType.File(),
 			common.BenchmarkLmt,
-			t.bytesPerFile,
+			t.sizes.nextSize(),
 			noContentProps,
 			noBlobProps,
 			noMetdata,
@@ -0,0 +1,49 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// classifyingTraverser changes the objects of the traverser it wraps as they come through, for the kinds of object
+// that some options transfer as something other than a plain file (e.g. hard links, or special files)
+type classifyingTraverser struct {
+	ResourceTraverser
+	classify func(object *StoredObject)
+}
+
+func (c *classifyingTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	return c.ResourceTraverser.Traverse(preprocessor, func(object StoredObject) error {
+		c.classify(&object)
+		return processor(object)
+	}, filters)
+}
+
+// localTraversalRoot returns the folder that the relative paths of a local source are relative to
+func localTraversalRoot(source string) string {
+	// a wildcard can only be in the last segment of a local source
+	if strings.Contains(filepath.Base(source), "*") {
+		return filepath.Dir(source)
+	}
+	return source
+}
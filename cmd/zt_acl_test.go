@@ -0,0 +1,63 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
+	chk "gopkg.in/check.v1"
+)
+
+type aclSuite struct{}
+
+var _ = chk.Suite(&aclSuite{})
+
+func (s *aclSuite) TestValidateACLToSet(c *chk.C) {
+	for _, mode := range []azbfs.PathSetAccessControlRecursiveMode{azbfs.PathSetAccessControlRecursiveModeSet, azbfs.PathSetAccessControlRecursiveModeModify} {
+		c.Assert(validateADLSACL("user::rwx,group::r-x,other::---,mask::r-x", mode), chk.IsNil)
+		c.Assert(validateADLSACL("user:1234:rw-,default:group:5678:r-t", mode), chk.IsNil)
+
+		c.Assert(validateADLSACL("", mode), chk.NotNil)
+		c.Assert(validateADLSACL("user::rwxx", mode), chk.NotNil)
+		c.Assert(validateADLSACL("user:1234", mode), chk.NotNil)
+		c.Assert(validateADLSACL("owner::rwx", mode), chk.NotNil)
+		c.Assert(validateADLSACL("mask:1234:rwx", mode), chk.NotNil)
+	}
+}
+
+func (s *aclSuite) TestValidateACLToRemove(c *chk.C) {
+	remove := azbfs.PathSetAccessControlRecursiveModeRemove
+
+	// named entries can be removed from either ACL
+	c.Assert(validateADLSACL("user:1234,group:5678,default:user:1234,default:group:5678", remove), chk.IsNil)
+
+	// as can the base entries of the default ACL, with or without the trailing colon
+	c.Assert(validateADLSACL("default:user::,default:group::,default:mask::,default:other::", remove), chk.IsNil)
+	c.Assert(validateADLSACL("default:user,default:group,default:mask,default:other", remove), chk.IsNil)
+
+	// but not those of the access ACL
+	for _, entry := range []string{"user::", "group:", "mask", "other::"} {
+		c.Assert(validateADLSACL(entry, remove), chk.ErrorMatches, ".*only entries for named users and groups, or default entries, can be removed", chk.Commentf(entry))
+	}
+
+	c.Assert(validateADLSACL("user:1234:rwx", remove), chk.ErrorMatches, ".*entries to remove don't have permissions")
+	c.Assert(validateADLSACL("default:mask:1234", remove), chk.ErrorMatches, ".*mask entries don't have an id")
+	c.Assert(validateADLSACL("default:owner:1234", remove), chk.NotNil)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type benchmarkHistorySuite struct{}
+
+var _ = chk.Suite(&benchmarkHistorySuite{})
+
+func (s *benchmarkHistorySuite) TestHistoryRoundTrip(c *chk.C) {
+	historyFile := filepath.Join(c.MkDir(), benchmarkHistoryFileName)
+	later := benchmarkResult{JobID: common.NewJobID(), Time: time.Now().UTC(), Outcome: benchmarkOutcome{Mbps: 900}}
+	earlier := benchmarkResult{JobID: common.NewJobID(), Time: later.Time.Add(-time.Hour), Outcome: benchmarkOutcome{Mbps: 1000}}
+	c.Assert(appendBenchmarkResult(historyFile, later), chk.IsNil)
+	c.Assert(appendBenchmarkResult(historyFile, earlier), chk.IsNil)
+
+	results, err := readBenchmarkHistory(historyFile)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(results), chk.Equals, 2)
+	c.Assert(results[0].JobID, chk.Equals, earlier.JobID) // oldest first
+
+	baseline, candidate, err := pickBenchmarkRuns(results, nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(baseline.JobID, chk.Equals, earlier.JobID)
+	c.Assert(candidate.JobID, chk.Equals, later.JobID)
+
+	baseline, candidate, err = pickBenchmarkRuns(results, []string{later.JobID.String()[:8], earlier.JobID.String()})
+	c.Assert(err, chk.IsNil)
+	c.Assert(baseline.JobID, chk.Equals, later.JobID)
+	c.Assert(candidate.JobID, chk.Equals, earlier.JobID)
+
+	_, _, err = pickBenchmarkRuns(results[:1], nil)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *benchmarkHistorySuite) TestCompareFlagsRegressions(c *chk.C) {
+	baseline := benchmarkResult{
+		Config:      benchmarkConfig{Mode: "Upload", FileCount: 100},
+		Environment: benchmarkEnvironment{AzCopyVersion: "10.18.0"},
+		Outcome:     benchmarkOutcome{Mbps: 1000, IOPS: 500, AverageE2EMilliseconds: 100},
+	}
+	candidate := benchmarkResult{
+		Config:      benchmarkConfig{Mode: "Upload", FileCount: 100},
+		Environment: benchmarkEnvironment{AzCopyVersion: "10.19.0"},
+		Outcome:     benchmarkOutcome{Mbps: 950, IOPS: 300, AverageE2EMilliseconds: 150, Retries: 3},
+	}
+
+	comparison := compareBenchmarkResults(baseline, candidate, 10)
+
+	regressed := map[string]bool{}
+	for _, m := range comparison.Metrics {
+		regressed[m.Metric] = m.Regressed
+	}
+	c.Assert(regressed["Mbps"], chk.Equals, false) // only 5% worse
+	c.Assert(regressed["IOPS"], chk.Equals, true)
+	c.Assert(regressed["End-to-end ms per request"], chk.Equals, true)
+	c.Assert(regressed["Retries"], chk.Equals, false) // no baseline to compare with
+	c.Assert(comparison.Regressions, chk.Equals, 2)
+	c.Assert(comparison.ConfigDifferences, chk.HasLen, 0)
+	c.Assert(comparison.EnvironmentChanges, chk.DeepEquals, []string{"AzCopyVersion: 10.18.0 -> 10.19.0"})
+}
+
+func (s *benchmarkHistorySuite) TestRecorderConstraintTime(c *chk.C) {
+	r := newBenchmarkRecorder("unused", benchmarkConfig{})
+	r.sample(common.EPerfConstraint.Disk())
+	r.lastSampleTime = r.lastSampleTime.Add(-2 * time.Second)
+	r.sample(common.EPerfConstraint.Service())
+	c.Assert(r.constraintSeconds[common.EPerfConstraint.Service().String()] >= 2, chk.Equals, true)
+	c.Assert(r.constraintSeconds[common.EPerfConstraint.Disk().String()], chk.Equals, float64(0))
+}
@@ -0,0 +1,88 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	chk "gopkg.in/check.v1"
+)
+
+type benchmarkSizeDistributionSuite struct{}
+
+var _ = chk.Suite(&benchmarkSizeDistributionSuite{})
+
+func (s *benchmarkSizeDistributionSuite) TestSourceUrlRoundTrip(c *chk.C) {
+	u := benchmarkSourceHelper{}.ToUrl(10, 1024, 2, "histogram:C:\\sizes & such.txt")
+	fc, bpf, nf, sd, err := benchmarkSourceHelper{}.FromUrl(u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(fc, chk.Equals, uint(10))
+	c.Assert(bpf, chk.Equals, int64(1024))
+	c.Assert(nf, chk.Equals, uint(2))
+	c.Assert(sd, chk.Equals, "histogram:C:\\sizes & such.txt")
+}
+
+func (s *benchmarkSizeDistributionSuite) TestFixedAndLognormal(c *chk.C) {
+	fixed, err := newBenchmarkSizeDistribution("", 100)
+	c.Assert(err, chk.IsNil)
+	c.Assert(fixed.nextSize(), chk.Equals, int64(100))
+
+	lognormal, err := newBenchmarkSizeDistribution("lognormal:1.5", 1024*1024)
+	c.Assert(err, chk.IsNil)
+	sizes := make([]int, 1001)
+	for i := range sizes {
+		sizes[i] = int(lognormal.nextSize())
+		c.Assert(sizes[i] > 0, chk.Equals, true)
+	}
+	sort.Ints(sizes)
+	median := sizes[len(sizes)/2]
+	c.Assert(median > 768*1024 && median < 1280*1024, chk.Equals, true, chk.Commentf("median was %d", median))
+
+	// the same sizes come out every time
+	again, _ := newBenchmarkSizeDistribution("lognormal:1.5", 1024*1024)
+	first, _ := newBenchmarkSizeDistribution("lognormal:1.5", 1024*1024)
+	c.Assert(again.nextSize(), chk.Equals, first.nextSize())
+
+	_, err = newBenchmarkSizeDistribution("lognormal", 100)
+	c.Assert(err, chk.NotNil)
+	_, err = newBenchmarkSizeDistribution("uniform", 100)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *benchmarkSizeDistributionSuite) TestHistogram(c *chk.C) {
+	path := filepath.Join(c.MkDir(), "sizes.txt")
+	c.Assert(os.WriteFile(path, []byte("# size weight\n4K 3\n\n100 1\n8M 0\n"), 0644), chk.IsNil)
+
+	d, err := newBenchmarkSizeDistribution("histogram:"+path, 1)
+	c.Assert(err, chk.IsNil)
+	counts := map[int64]int{}
+	for i := 0; i < 4000; i++ {
+		counts[d.nextSize()]++
+	}
+	c.Assert(len(counts), chk.Equals, 2) // nothing of weight zero
+	c.Assert(counts[4096] > 2700 && counts[4096] < 3300, chk.Equals, true, chk.Commentf("got %v", counts))
+
+	c.Assert(os.WriteFile(path, []byte("4K\n"), 0644), chk.IsNil)
+	_, err = newBenchmarkSizeDistribution("histogram:"+path, 1)
+	c.Assert(err, chk.NotNil)
+}
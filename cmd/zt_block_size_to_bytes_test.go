@@ -52,3 +52,9 @@ func (s *genericFilterSuite) TestConversions(c *chk.C) {
 		}
 	}
 }
+
+func (s *blockSizeFilterSuite) TestAdaptiveBlockSizeExcludesExplicitSize(c *chk.C) {
+	c.Check(validateAdaptiveBlockSize(true, 0), chk.IsNil)
+	c.Check(validateAdaptiveBlockSize(false, 8*1024*1024), chk.IsNil)
+	c.Check(validateAdaptiveBlockSize(true, 8*1024*1024), chk.NotNil)
+}
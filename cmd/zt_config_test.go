@@ -0,0 +1,177 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/spf13/cobra"
+	chk "gopkg.in/check.v1"
+)
+
+type configSuite struct{}
+
+var _ = chk.Suite(&configSuite{})
+
+const testConfig = `
+profile: prod
+profiles:
+  prod:
+    flags:
+      "*":
+        log-level: WARNING
+      copy:
+        block-size-mb: 16
+        recursive: true
+    aliases:
+      prod-data: https://proddata.blob.core.windows.net/
+  empty: {}
+`
+
+func (s *configSuite) loadConfig(c *chk.C, content string) *common.Config {
+	path := filepath.Join(c.MkDir(), "config.yaml")
+	c.Assert(os.WriteFile(path, []byte(content), 0600), chk.IsNil)
+
+	config, err := common.LoadConfig(path)
+	c.Assert(err, chk.IsNil)
+	return config
+}
+
+func (s *configSuite) TestProfileSelection(c *chk.C) {
+	config := s.loadConfig(c, testConfig)
+
+	name, profile, err := config.SelectProfile("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(name, chk.Equals, "prod")
+	c.Assert(profile.FlagDefaults("copy"), chk.DeepEquals, map[string]string{"log-level": "WARNING", "block-size-mb": "16", "recursive": "true"})
+	c.Assert(profile.FlagDefaults("sync"), chk.DeepEquals, map[string]string{"log-level": "WARNING"})
+
+	name, _, err = config.SelectProfile("empty")
+	c.Assert(err, chk.IsNil)
+	c.Assert(name, chk.Equals, "empty")
+
+	_, _, err = config.SelectProfile("missing")
+	c.Assert(err, chk.NotNil)
+
+	// a missing file is just an empty config
+	config, err = common.LoadConfig(filepath.Join(c.MkDir(), "none.yaml"))
+	c.Assert(err, chk.IsNil)
+	name, _, err = config.SelectProfile("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(name, chk.Equals, common.DefaultConfigProfileName)
+
+	// unknown settings are rejected, rather than silently ignored
+	path := filepath.Join(c.MkDir(), "config.yaml")
+	c.Assert(os.WriteFile(path, []byte("profiles:\n  p:\n    flag:\n      copy: {}\n"), 0600), chk.IsNil)
+	_, err = common.LoadConfig(path)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *configSuite) TestProfileFromArgs(c *chk.C) {
+	c.Assert(profileFromArgs([]string{"copy", "a", "b", "--profile", "prod"}), chk.Equals, "prod")
+	c.Assert(profileFromArgs([]string{"--profile=test", "sync"}), chk.Equals, "test")
+	c.Assert(profileFromArgs([]string{"copy", "--", "--profile=x"}), chk.Equals, "")
+	c.Assert(profileFromArgs([]string{"copy", "--profile"}), chk.Equals, "")
+}
+
+func (s *configSuite) TestRemoteReferences(c *chk.C) {
+	config := s.loadConfig(c, testConfig+`
+remotes:
+  backup:
+    url: http://127.0.0.1:10000/devstoreaccount1
+    type: blob
+    credential: env:AZCOPY_TEST_BACKUP_SAS
+`)
+	_, profile, err := config.SelectProfile("")
+	c.Assert(err, chk.IsNil)
+	common.SetConfiguredRemotes(profile.Remotes)
+	defer common.SetConfiguredRemotes(nil)
+
+	// aliases are remotes with nothing but a URL
+	c.Assert(InferArgumentLocation("prod-data:container/dir"), chk.Equals, common.ELocation.Blob())
+	resource, err := SplitResourceString("prod-data:container/dir", common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(resource.Value, chk.Equals, "https://proddata.blob.core.windows.net/container/dir")
+	c.Assert(resource.SAS, chk.Equals, "")
+	c.Assert(resource.Remote, chk.Equals, "prod-data")
+	c.Assert(resource.DisplayValue(), chk.Equals, "prod-data:container/dir")
+	stripped, stripTopDir, err := rawCopyCmdArgs{src: "prod-data:container/dir/*"}.stripTrailingWildcardOnRemoteSource(common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(stripTopDir, chk.Equals, true)
+	c.Assert(stripped, chk.Equals, "https://proddata.blob.core.windows.net/container/dir")
+	expanded, err := expandRemoteReference("prod-data:")
+	c.Assert(err, chk.IsNil)
+	c.Assert(expanded, chk.Equals, "https://proddata.blob.core.windows.net")
+
+	// the SAS comes from the credential reference, and must be there
+	_, err = SplitResourceString("backup:c", common.ELocation.Blob())
+	c.Assert(err, chk.NotNil)
+	c.Assert(os.Setenv("AZCOPY_TEST_BACKUP_SAS", "?sv=2020-10-02&sig=secret"), chk.IsNil)
+	defer os.Unsetenv("AZCOPY_TEST_BACKUP_SAS")
+
+	// the explicit type is used where the URL says nothing, and the remote's name is shown instead of the URL
+	c.Assert(InferArgumentLocation("backup:c/blob"), chk.Equals, common.ELocation.Blob())
+	resource, err = SplitResourceString("backup:c/blob", common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(resource.Value, chk.Equals, "http://127.0.0.1:10000/devstoreaccount1/c/blob")
+	c.Assert(resource.SAS, chk.Equals, "sig=secret&sv=2020-10-02")
+	c.Assert(resource.DisplayValue(), chk.Equals, "backup:c/blob")
+
+	// a URL under the remote (e.g. once a trailing wildcard is stripped) is treated the same way
+	resource, err = SplitResourceString("http://127.0.0.1:10000/devstoreaccount1/c/dir", common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(resource.SAS, chk.Equals, "sig=secret&sv=2020-10-02")
+	c.Assert(resource.DisplayValue(), chk.Equals, "backup:c/dir")
+	c.Assert(common.DisplayStringForURL("http://127.0.0.1:10000/devstoreaccount1/c/blob?sig=secret"), chk.Equals, "backup:c/blob")
+
+	for _, raw := range []string{"https://acct.blob.core.windows.net/c", `C:\dir`, "other:c", "plain/path"} {
+		expanded, err = expandRemoteReference(raw)
+		c.Assert(err, chk.IsNil)
+		c.Assert(expanded, chk.Equals, raw)
+	}
+}
+
+func (s *configSuite) TestApplyProfileToCommand(c *chk.C) {
+	_, profile, err := s.loadConfig(c, testConfig).SelectProfile("")
+	c.Assert(err, chk.IsNil)
+	oldProfile := azcopyProfile
+	azcopyProfile = profile
+	defer func() { azcopyProfile = oldProfile }()
+
+	var blockSize float64
+	var recursive bool
+	root := &cobra.Command{Use: "azcopy"}
+	copyCmd := &cobra.Command{Use: "copy"}
+	copyCmd.Flags().Float64Var(&blockSize, "block-size-mb", 0, "")
+	copyCmd.Flags().BoolVar(&recursive, "recursive", false, "")
+	root.AddCommand(copyCmd)
+
+	// flags on the command line win over the profile
+	c.Assert(copyCmd.ParseFlags([]string{"--recursive=false"}), chk.IsNil)
+	c.Assert(applyProfileToCommand(copyCmd), chk.IsNil)
+	c.Assert(blockSize, chk.Equals, float64(16))
+	c.Assert(recursive, chk.Equals, false)
+
+	// a command-specific flag that the command doesn't have is a mistake in the profile
+	profile.Flags["copy"]["no-such-flag"] = "1"
+	c.Assert(applyProfileToCommand(copyCmd), chk.NotNil)
+}
@@ -22,6 +22,10 @@ package cmd
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
 	"strings"
 
 	"github.com/Azure/azure-storage-azcopy/v10/common"
@@ -115,3 +119,50 @@ func (s *credentialUtilSuite) TestCheckAuthSafeForTargetIsCalledWhenGettingAuthT
 	c.Assert(strings.Contains(err.Error(), "If this URL is in fact an Azure service, you can enable Azure authentication to notblob.example.com."),
 		chk.Equals, true)
 }
+
+func (s *credentialUtilSuite) TestCredentialHelperSASOnlyWhenNothingElseApplies(c *chk.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("the credential helper is a shell command")
+	}
+	// the helper always fails, so any call to it shows up as an error
+	os.Setenv(common.EEnvironmentVariable.CredentialHelper().Name, "/bin/sh -c false")
+	defer os.Unsetenv(common.EEnvironmentVariable.CredentialHelper().Name)
+	if common.AzcopyJobPlanFolder == "" {
+		common.AzcopyJobPlanFolder = c.MkDir() // where a cached OAuth token would be looked for
+		defer func() { common.AzcopyJobPlanFolder = "" }()
+	}
+
+	public := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if public {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	resource := server.URL + "/devstoreaccount1/container"
+
+	// a public source is read anonymously
+	sas, err := getCredentialHelperSAS(context.Background(), resource, common.ELocation.Blob(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(sas, chk.Equals, "")
+
+	// but a destination needs a credential even when it is public
+	_, err = getCredentialHelperSAS(context.Background(), resource, common.ELocation.Blob(), false)
+	c.Assert(err, chk.NotNil)
+
+	public = false
+	_, err = getCredentialHelperSAS(context.Background(), resource, common.ELocation.Blob(), true)
+	c.Assert(err, chk.NotNil)
+
+	// a SAS given by the user is never replaced
+	given := common.ResourceString{Value: resource, SAS: "sv=2020-10-02&sig=given"}
+	c.Assert(addCredentialHelperSAS(context.Background(), &given, common.ELocation.Blob(), false), chk.IsNil)
+	c.Assert(given.SAS, chk.Equals, "sv=2020-10-02&sig=given")
+
+	// local paths have nothing to ask the helper for
+	sas, err = getCredentialHelperSAS(context.Background(), c.MkDir(), common.ELocation.Local(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(sas, chk.Equals, "")
+}
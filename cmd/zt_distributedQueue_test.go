@@ -0,0 +1,321 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/go-autorest/autorest/adal"
+	chk "gopkg.in/check.v1"
+)
+
+type distributedQueueSuite struct {
+	oldRpc func(common.RpcCmd, interface{}, interface{})
+}
+
+var _ = chk.Suite(&distributedQueueSuite{})
+
+func (s *distributedQueueSuite) SetUpTest(c *chk.C) {
+	s.oldRpc = Rpc
+}
+
+func (s *distributedQueueSuite) TearDownTest(c *chk.C) {
+	Rpc = s.oldRpc
+}
+
+func distributedTestOrder(jobID common.JobID, partNum common.PartNumber, isFinal bool, sources ...string) common.CopyJobPartOrderRequest {
+	order := common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		PartNum:         partNum,
+		IsFinalPart:     isFinal,
+		FromTo:          common.EFromTo.LocalBlob(),
+		SourceRoot:      common.ResourceString{Value: "/data"},
+		DestinationRoot: common.ResourceString{Value: "https://acct.blob.core.windows.net/container", SAS: "sv=2020-10-02&sig=secret"},
+		CredentialInfo:  common.CredentialInfo{OAuthTokenInfo: common.OAuthTokenInfo{Token: adal.Token{AccessToken: "secret-token"}}},
+	}
+	for _, src := range sources {
+		order.Transfers.List = append(order.Transfers.List, common.CopyTransfer{Source: src, Destination: src, SourceSize: 10})
+		order.Transfers.FileTransferCount++
+		order.Transfers.TotalSizeInBytes += 10
+	}
+	return order
+}
+
+func (s *distributedQueueSuite) TestClaimAndComplete(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	jobID := common.NewJobID()
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, false, "/a", "/b")), chk.IsNil)
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 1, true, "/c")), chk.IsNil)
+
+	// the queue holds no secrets
+	data, err := os.ReadFile(filepath.Join(q.jobDir(jobID), distributedPending, partFileName(0)))
+	c.Assert(err, chk.IsNil)
+	c.Assert(strings.Contains(string(data), "sig=secret"), chk.Equals, false)
+	c.Assert(strings.Contains(string(data), "secret-token"), chk.Equals, false)
+
+	// each part goes to one worker
+	first, err := q.ClaimPart("worker1", time.Minute)
+	c.Assert(err, chk.IsNil)
+	second, err := q.ClaimPart("worker2", time.Minute)
+	c.Assert(err, chk.IsNil)
+	c.Assert(first.PartNum, chk.Equals, common.PartNumber(0))
+	c.Assert(second.PartNum, chk.Equals, common.PartNumber(1))
+	c.Assert(first.Order.Transfers.List, chk.HasLen, 2)
+	none, err := q.ClaimPart("worker3", time.Minute)
+	c.Assert(err, chk.IsNil)
+	c.Assert(none, chk.IsNil)
+
+	// progress is aggregated from the workers' reports
+	cancelled, err := first.Renew(distributedPartReport{Worker: "worker1", Summary: common.ListJobSummaryResponse{TransfersCompleted: 1, TotalBytesTransferred: 10, TotalBytesEnumerated: 20, TotalBytesExpected: 20}})
+	c.Assert(err, chk.IsNil)
+	c.Assert(cancelled, chk.Equals, false)
+	summary, err := q.JobSummary(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(summary.TotalTransfers, chk.Equals, uint32(3))
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint32(1))
+	c.Assert(summary.TotalBytesEnumerated, chk.Equals, uint64(30))
+	c.Assert(summary.CompleteJobOrdered, chk.Equals, true)
+	c.Assert(summary.JobStatus, chk.Equals, common.EJobStatus.InProgress())
+
+	c.Assert(first.Complete(distributedPartReport{Worker: "worker1", Summary: common.ListJobSummaryResponse{TransfersCompleted: 2, TotalBytesTransferred: 20, TotalBytesEnumerated: 20, TotalBytesExpected: 20}}), chk.IsNil)
+	c.Assert(second.Complete(distributedPartReport{Worker: "worker2", Summary: common.ListJobSummaryResponse{TransfersFailed: 1, TotalBytesEnumerated: 10,
+		FailedTransfers: []common.TransferDetail{{Src: "/data/c", TransferStatus: common.ETransferStatus.Failed()}}}}), chk.IsNil)
+
+	summary, err = q.JobSummary(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint32(2))
+	c.Assert(summary.TransfersFailed, chk.Equals, uint32(1))
+	c.Assert(summary.PercentComplete, chk.Equals, float32(100))
+	c.Assert(summary.JobStatus, chk.Equals, common.EJobStatus.CompletedWithErrors())
+
+	transfers, err := q.JobTransfers(jobID, common.ETransferStatus.Failed())
+	c.Assert(err, chk.IsNil)
+	c.Assert(transfers.Details, chk.HasLen, 1)
+	_, err = q.JobTransfers(jobID, common.ETransferStatus.Success())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *distributedQueueSuite) TestExpiredLease(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	jobID := common.NewJobID()
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, true, "/a")), chk.IsNil)
+
+	dead, err := q.ClaimPart("dead", time.Minute)
+	c.Assert(err, chk.IsNil)
+
+	// the lease is still held
+	none, err := q.ClaimPart("alive", time.Minute)
+	c.Assert(err, chk.IsNil)
+	c.Assert(none, chk.IsNil)
+
+	// until it expires
+	expired := time.Now().Add(-2 * time.Minute)
+	c.Assert(os.Chtimes(dead.path, expired, expired), chk.IsNil)
+	alive, err := q.ClaimPart("alive", time.Minute)
+	c.Assert(err, chk.IsNil)
+	c.Assert(alive, chk.NotNil)
+	c.Assert(alive.PartNum, chk.Equals, common.PartNumber(0))
+
+	// the worker that lost the lease finds out
+	_, err = dead.Renew(distributedPartReport{})
+	c.Assert(err, chk.Equals, errLeaseLost)
+	c.Assert(dead.Complete(distributedPartReport{}), chk.Equals, errLeaseLost)
+	c.Assert(alive.Complete(distributedPartReport{}), chk.IsNil)
+}
+
+func (s *distributedQueueSuite) TestRenewedLeaseIsNotTakenOver(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	jobID := common.NewJobID()
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, true, "/a")), chk.IsNil)
+	owner, err := q.ClaimPart("owner", time.Minute)
+	c.Assert(err, chk.IsNil)
+
+	// another worker saw the lease as expired, but the owner renewed it before the other worker could take the part
+	expired := time.Now().Add(-2 * time.Minute)
+	c.Assert(os.Chtimes(owner.path, expired, expired), chk.IsNil)
+	_, err = owner.Renew(distributedPartReport{})
+	c.Assert(err, chk.IsNil)
+	claimedDir := filepath.Join(q.jobDir(jobID), distributedClaimed)
+	target, err := takeOverExpiredLease(claimedDir, filepath.Base(owner.path), "other", time.Minute)
+	c.Assert(err, chk.IsNil)
+	c.Assert(target, chk.Equals, "")
+
+	// so the part is still the owner's
+	_, err = owner.Renew(distributedPartReport{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(owner.Complete(distributedPartReport{}), chk.IsNil)
+}
+
+func (s *distributedQueueSuite) TestRenewFailureIsNotLeaseLoss(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	jobID := common.NewJobID()
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, true, "/a")), chk.IsNil)
+	part, err := q.ClaimPart("worker", time.Minute)
+	c.Assert(err, chk.IsNil)
+
+	// the progress can't be written, but the lease is still held
+	progressDir := filepath.Join(q.jobDir(jobID), distributedProgress)
+	c.Assert(os.Remove(progressDir), chk.IsNil)
+	_, err = part.Renew(distributedPartReport{})
+	c.Assert(err, chk.NotNil)
+	c.Assert(errors.Is(err, errLeaseLost), chk.Equals, false)
+
+	c.Assert(os.Mkdir(progressDir, common.DEFAULT_FILE_PERM|0111), chk.IsNil)
+	_, err = part.Renew(distributedPartReport{})
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *distributedQueueSuite) TestCoordinatorAndWorker(c *chk.C) {
+	var executed []common.CopyJobPartOrderRequest
+	Rpc = func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		switch cmd {
+		case common.ERpcCmd.CopyJobPartOrder():
+			executed = append(executed, *request.(*common.CopyJobPartOrderRequest))
+			*(response.(*common.CopyJobPartOrderResponse)) = common.CopyJobPartOrderResponse{JobStarted: true}
+		case common.ERpcCmd.ListJobSummary():
+			*(response.(*common.ListJobSummaryResponse)) = common.ListJobSummaryResponse{JobStatus: common.EJobStatus.Completed(), TransfersCompleted: 1}
+		default:
+			c.Fatalf("unexpected request %v", cmd)
+		}
+	}
+	worker := Rpc
+
+	oldPlanFolder := common.AzcopyJobPlanFolder
+	common.AzcopyJobPlanFolder = c.MkDir()
+	defer func() { common.AzcopyJobPlanFolder = oldPlanFolder }()
+
+	// the coordinator queues the parts, rather than executing them
+	jobID := common.NewJobID()
+	queueDir := c.MkDir()
+	c.Assert(useDistributedQueue(jobID, queueDir), chk.IsNil)
+	var resp common.CopyJobPartOrderResponse
+	order := distributedTestOrder(jobID, 0, true, "/a")
+	Rpc(common.ERpcCmd.CopyJobPartOrder(), &order, &resp)
+	c.Assert(resp.JobStarted, chk.Equals, true)
+	c.Assert(executed, chk.HasLen, 0)
+
+	// the worker executes them, with its own credentials
+	Rpc = worker
+	q, err := newFSPartQueue(queueDir)
+	c.Assert(err, chk.IsNil)
+	w := &distributedWorker{args: workerArgs{destinationSAS: "sv=2020-10-02&sig=worker", lease: time.Minute, exitWhenIdle: true},
+		queue: q, name: "worker", credentials: map[common.JobID]workerCredentials{}}
+	c.Assert(w.run(), chk.IsNil)
+	c.Assert(executed, chk.HasLen, 1)
+	c.Assert(executed[0].JobID, chk.Not(chk.Equals), jobID)
+	c.Assert(executed[0].IsFinalPart, chk.Equals, true)
+	c.Assert(executed[0].DestinationRoot.SAS, chk.Equals, "sv=2020-10-02&sig=worker")
+
+	// and "jobs show" on the coordinator finds the job's progress in the queue
+	shown, ok := distributedQueueForJob(jobID)
+	c.Assert(ok, chk.Equals, true)
+	Rpc = shown.rpc(worker)
+	var summary common.ListJobSummaryResponse
+	Rpc(common.ERpcCmd.ListJobSummary(), &jobID, &summary)
+	c.Assert(summary.ErrorMsg, chk.Equals, "")
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint32(1))
+	c.Assert(summary.JobStatus, chk.Equals, common.EJobStatus.Completed())
+}
+
+func (s *distributedQueueSuite) TestNothingToQueue(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	rpc := q.rpc(nil)
+
+	var resp common.CopyJobPartOrderResponse
+	order := distributedTestOrder(common.NewJobID(), 0, true)
+	rpc(common.ERpcCmd.CopyJobPartOrder(), &order, &resp)
+	c.Assert(resp.JobStarted, chk.Equals, false)
+	c.Assert(resp.ErrorMsg, chk.Equals, common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr())
+}
+
+func (s *distributedQueueSuite) TestEnqueueDoesNotResetJob(c *chk.C) {
+	q, err := newFSPartQueue(c.MkDir())
+	c.Assert(err, chk.IsNil)
+	jobID := common.NewJobID()
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, false, "/a", "/b")), chk.IsNil)
+	c.Assert(q.CancelJob(jobID), chk.IsNil)
+
+	// a job record that can't be read is an error for later parts, rather than a reason to start the job over
+	jobFile := filepath.Join(q.jobDir(jobID), distributedJobFileName)
+	good, err := os.ReadFile(jobFile)
+	c.Assert(err, chk.IsNil)
+	c.Assert(os.WriteFile(jobFile, good[:len(good)/2], common.DEFAULT_FILE_PERM), chk.IsNil)
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 1, true, "/c")), chk.NotNil)
+
+	c.Assert(os.WriteFile(jobFile, good, common.DEFAULT_FILE_PERM), chk.IsNil)
+	c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 1, true, "/c")), chk.IsNil)
+	job, err := q.readJob(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(job.TotalTransfers, chk.Equals, uint32(3))
+	c.Assert(job.Cancelled, chk.Equals, true)
+}
+
+func (s *distributedQueueSuite) TestCancelFromAnotherProcess(c *chk.C) {
+	oldPlanFolder := common.AzcopyJobPlanFolder
+	common.AzcopyJobPlanFolder = c.MkDir()
+	defer func() { common.AzcopyJobPlanFolder = oldPlanFolder }()
+
+	// the local STE doesn't know the job, since its parts are executed by workers
+	localSTE := func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		*(response.(*common.CancelPauseResumeResponse)) = common.CancelPauseResumeResponse{ErrorMsg: "no such job"}
+	}
+
+	for _, fromCoordinatorMachine := range []bool{true, false} {
+		queueDir := c.MkDir()
+		q, err := newFSPartQueue(queueDir)
+		c.Assert(err, chk.IsNil)
+		jobID := common.NewJobID()
+		c.Assert(q.EnqueuePart(distributedTestOrder(jobID, 0, true, "/a", "/b")), chk.IsNil)
+		part, err := q.ClaimPart("worker", time.Minute)
+		c.Assert(err, chk.IsNil)
+
+		cooked := cookedCancelCmdArgs{jobID: jobID}
+		if fromCoordinatorMachine {
+			c.Assert(os.WriteFile(distributedQueueMarkerPath(jobID), []byte(q.root), common.DEFAULT_FILE_PERM), chk.IsNil)
+		} else {
+			cooked.distributedQueue = queueDir
+		}
+		Rpc = localSTE
+		c.Assert(cooked.process(), chk.IsNil)
+
+		// the worker finds out when it next renews its lease
+		cancelled, err := part.Renew(distributedPartReport{})
+		c.Assert(err, chk.IsNil)
+		c.Assert(cancelled, chk.Equals, true)
+		summary, err := q.JobSummary(jobID)
+		c.Assert(err, chk.IsNil)
+		c.Assert(summary.JobStatus, chk.Equals, common.EJobStatus.Cancelling())
+	}
+
+	// other jobs still go to the STE
+	Rpc = localSTE
+	c.Assert(cookedCancelCmdArgs{jobID: common.NewJobID()}.process(), chk.NotNil)
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type hardlinkSuite struct{}
+
+var _ = chk.Suite(&hardlinkSuite{})
+
+func (s *hardlinkSuite) TestHardlinkTrackerUpload(c *chk.C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "data"), []byte("hello"), 0644), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "single"), []byte("hello"), 0644), chk.IsNil)
+	c.Assert(os.Mkdir(filepath.Join(dir, "sub dir"), 0755), chk.IsNil)
+	c.Assert(os.Link(filepath.Join(dir, "data"), filepath.Join(dir, "link")), chk.IsNil)
+	c.Assert(os.Link(filepath.Join(dir, "data"), filepath.Join(dir, "sub dir", "link")), chk.IsNil)
+
+	tracker := newHardlinkTracker(common.EHardlinkHandlingType.Preserve(), common.EFromTo.LocalBlob(), dir)
+	classify := func(relativePath string) StoredObject {
+		object := StoredObject{relativePath: relativePath, entityType: common.EEntityType.File(), size: 5}
+		tracker.classify(&object)
+		return object
+	}
+
+	// the first name seen holds the data
+	data := classify("data")
+	c.Assert(data.entityType, chk.Equals, common.EEntityType.File())
+	c.Assert(data.size, chk.Equals, int64(5))
+
+	single := classify("single")
+	c.Assert(single.entityType, chk.Equals, common.EEntityType.File())
+
+	link := classify("link")
+	c.Assert(link.entityType, chk.Equals, common.EEntityType.Hardlink())
+	c.Assert(link.size, chk.Equals, int64(0))
+	c.Assert(link.Metadata[common.POSIXHardlinkMeta], chk.Equals, "data")
+
+	// references are relative to the link's folder, and escaped
+	subLink := classify("sub dir/link")
+	c.Assert(subLink.entityType, chk.Equals, common.EEntityType.Hardlink())
+	c.Assert(subLink.Metadata[common.POSIXHardlinkMeta], chk.Equals, "../data")
+
+	// nothing is tracked when following
+	c.Assert(newHardlinkTracker(common.EHardlinkHandlingType.Follow(), common.EFromTo.LocalBlob(), dir), chk.IsNil)
+}
+
+func (s *hardlinkSuite) TestHardlinkTrackerDownload(c *chk.C) {
+	tracker := newHardlinkTracker(common.EHardlinkHandlingType.Preserve(), common.EFromTo.BlobLocal(), "")
+
+	link := StoredObject{relativePath: "sub/link", entityType: common.EEntityType.File(), Metadata: common.Metadata{common.POSIXHardlinkMeta: "../data"}}
+	tracker.classify(&link)
+	c.Assert(link.entityType, chk.Equals, common.EEntityType.Hardlink())
+
+	data := StoredObject{relativePath: "data", entityType: common.EEntityType.File()}
+	tracker.classify(&data)
+	c.Assert(data.entityType, chk.Equals, common.EEntityType.File())
+}
+
+func (s *hardlinkSuite) TestSyncDestinationComparatorHardlinkChange(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	indexer := newObjectIndexer()
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, false, false)
+
+	// the source is now a file of its own, but the destination is still a link, and newer
+	source := StoredObject{name: "link", relativePath: "link", entityType: common.EEntityType.File(), lastModifiedTime: time.Now().Add(-time.Hour)}
+	c.Assert(indexer.store(source), chk.IsNil)
+	destination := StoredObject{name: "link", relativePath: "link", lastModifiedTime: time.Now(), Metadata: common.Metadata{common.POSIXHardlinkMeta: "data"}}
+	c.Assert(destinationComparator.processIfNecessary(destination), chk.IsNil)
+
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+}
+
+func (s *hardlinkSuite) TestDeferredHardlinks(c *chk.C) {
+	var hardlinks deferredHardlinks
+	c.Assert(hardlinks.empty(), chk.Equals, true)
+
+	for _, name := range []string{"a", "b", "c"} {
+		c.Assert(hardlinks.add(common.CopyTransfer{
+			Source:      "/" + name,
+			Destination: "/" + name,
+			EntityType:  common.EEntityType.Hardlink(),
+			Metadata:    common.Metadata{common.POSIXHardlinkMeta: "data"},
+		}), chk.IsNil)
+	}
+	c.Assert(hardlinks.empty(), chk.Equals, false)
+
+	// they come back in the order they were deferred, with everything the STE needs
+	var released []common.CopyTransfer
+	c.Assert(hardlinks.release(func(transfer common.CopyTransfer) error {
+		released = append(released, transfer)
+		return nil
+	}), chk.IsNil)
+	c.Assert(released, chk.HasLen, 3)
+	c.Assert(released[2].Source, chk.Equals, "/c")
+	c.Assert(released[2].EntityType, chk.Equals, common.EEntityType.Hardlink())
+	c.Assert(released[2].Metadata[common.POSIXHardlinkMeta], chk.Equals, "data")
+	c.Assert(hardlinks.empty(), chk.Equals, true)
+}
+
+func (s *hardlinkSuite) TestPreserveRejectedForDistributedDownload(c *chk.C) {
+	raw := getDefaultCopyRawInput("https://acct.blob.core.windows.net/container", c.MkDir())
+	raw.fromTo = common.EFromTo.BlobLocal().String()
+	raw.recursive = true
+	raw.hardlinks = common.EHardlinkHandlingType.Preserve().String()
+	raw.distributedQueue = c.MkDir()
+
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Matches, "--distributed-queue cannot be used with --hardlinks=preserve.*")
+}
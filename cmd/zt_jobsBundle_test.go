@@ -0,0 +1,136 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsBundleSuite struct {
+	oldPlanFolder string
+}
+
+var _ = chk.Suite(&jobsBundleSuite{})
+
+func (s *jobsBundleSuite) SetUpTest(c *chk.C) {
+	s.oldPlanFolder = common.AzcopyJobPlanFolder
+	common.AzcopyJobPlanFolder = c.MkDir()
+}
+
+func (s *jobsBundleSuite) TearDownTest(c *chk.C) {
+	common.AzcopyJobPlanFolder = s.oldPlanFolder
+}
+
+// moveToNewMachine exports the job, then empties the plan folder, as if the bundle had been copied to another machine.
+func (s *jobsBundleSuite) moveToNewMachine(c *chk.C, jobID common.JobID) string {
+	bundle := filepath.Join(c.MkDir(), "job.tar")
+	manifest, err := exportJob(jobID, bundle)
+	c.Assert(err, chk.IsNil)
+	c.Assert(manifest.PlanFiles, chk.HasLen, 1)
+	c.Assert(manifest.FromTo, chk.Equals, "BlobLocal")
+
+	common.AzcopyJobPlanFolder = c.MkDir()
+	return bundle
+}
+
+func (s *jobsBundleSuite) TestExportImport(c *chk.C) {
+	jobID := (&jobsInspectSuite{}).createTestPlan(c)
+	bundle := s.moveToNewMachine(c, jobID)
+
+	newRoot := c.MkDir()
+	manifest, err := importJob(jobsImportArgs{bundle: bundle, destinationRoot: newRoot})
+	c.Assert(err, chk.IsNil)
+	c.Assert(manifest.JobID, chk.Equals, jobID)
+
+	planFiles, err := jobPlanFiles(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(planFiles, chk.HasLen, 1)
+	mmf := planFiles[0].Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+
+	// the local destination moved, but the remote source and the transfers' progress did not
+	c.Assert(plan.SourceRoot(), chk.Equals, "https://acct.blob.core.windows.net/container")
+	c.Assert(plan.DestinationRoot(), chk.Equals, common.ToExtendedPath(cleanLocalPath(newRoot)))
+	c.Assert(plan.NumTransfers, chk.Equals, uint32(3))
+	src, dst, _ := plan.TransferSrcDstStrings(2)
+	c.Assert(src, chk.Equals, "https://acct.blob.core.windows.net/container/c.txt")
+	c.Assert(dst, chk.Equals, common.GenerateFullPath(common.ToExtendedPath(cleanLocalPath(newRoot)), "/c.txt"))
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+	c.Assert(plan.Transfer(1).ErrorCode(), chk.Equals, int32(409))
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(common.AzcopyJobPlanFolder)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+}
+
+func (s *jobsBundleSuite) TestImportRejections(c *chk.C) {
+	jobID := (&jobsInspectSuite{}).createTestPlan(c)
+	bundle := s.moveToNewMachine(c, jobID)
+
+	// the source is remote, so it cannot be re-rooted
+	_, err := importJob(jobsImportArgs{bundle: bundle, sourceRoot: c.MkDir()})
+	c.Assert(err, chk.NotNil)
+
+	// the job can only be imported once
+	_, err = importJob(jobsImportArgs{bundle: bundle})
+	c.Assert(err, chk.IsNil)
+	_, err = importJob(jobsImportArgs{bundle: bundle})
+	c.Assert(err, chk.NotNil)
+
+	// a bundle needs a manifest
+	notABundle := filepath.Join(c.MkDir(), "plan.tar")
+	c.Assert(os.WriteFile(notABundle, []byte("not a tar file"), common.DEFAULT_FILE_PERM), chk.IsNil)
+	_, err = importJob(jobsImportArgs{bundle: notABundle})
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *jobsBundleSuite) TestExportCarriesSidecarFiles(c *chk.C) {
+	jobID := (&jobsInspectSuite{}).createTestPlan(c)
+	continuation := ste.AccessControlContinuationFile(jobID, 0, 2)
+	c.Assert(os.WriteFile(continuation, []byte("token"), common.DEFAULT_FILE_PERM), chk.IsNil)
+
+	bundle := s.moveToNewMachine(c, jobID)
+	_, err := importJob(jobsImportArgs{bundle: bundle, destinationRoot: c.MkDir()})
+	c.Assert(err, chk.IsNil)
+
+	// the continuation token is carried over as it was, even though the plan was re-rooted
+	data, err := os.ReadFile(ste.AccessControlContinuationFile(jobID, 0, 2))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "token")
+}
+
+func (s *jobsBundleSuite) TestExportRefusesDistributedJob(c *chk.C) {
+	jobID := (&jobsInspectSuite{}).createTestPlan(c)
+	c.Assert(os.WriteFile(distributedQueueMarkerPath(jobID), []byte(c.MkDir()), common.DEFAULT_FILE_PERM), chk.IsNil)
+
+	bundle := filepath.Join(c.MkDir(), "job.tar")
+	_, err := exportJob(jobID, bundle)
+	c.Assert(err, chk.NotNil)
+	_, err = os.Stat(bundle)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
@@ -0,0 +1,159 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsInspectSuite struct {
+	oldPlanFolder string
+}
+
+var _ = chk.Suite(&jobsInspectSuite{})
+
+// createTestPlan writes a single-part plan with a successful, a failed and a pending transfer.
+func (s *jobsInspectSuite) createTestPlan(c *chk.C) common.JobID {
+	jobID := common.NewJobID()
+	modified := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		IsFinalPart:     true,
+		FromTo:          common.EFromTo.BlobLocal(),
+		Fpo:             common.EFolderPropertiesOption.NoFolders(),
+		SourceRoot:      common.ResourceString{Value: "https://acct.blob.core.windows.net/container"},
+		DestinationRoot: common.ResourceString{Value: "/data"},
+		CommandString:   "copy https://acct.blob.core.windows.net/container /data --recursive",
+		Transfers: common.Transfers{List: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", EntityType: common.EEntityType.File(), SourceSize: 10, LastModifiedTime: modified, BlobType: azblob.BlobBlockBlob},
+			{Source: "/b.txt", Destination: "/b.txt", EntityType: common.EEntityType.File(), SourceSize: 20, LastModifiedTime: modified},
+			{Source: "/c.txt", Destination: "/c.txt", EntityType: common.EEntityType.File(), SourceSize: 30, LastModifiedTime: modified},
+		}},
+	}
+
+	planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion))
+	planFile.Create(order)
+
+	mmf := planFile.Map()
+	mmf.Plan().Transfer(0).SetTransferStatus(common.ETransferStatus.Success(), true)
+	mmf.Plan().Transfer(1).SetTransferStatus(common.ETransferStatus.SkippedEntityAlreadyExists(), true)
+	mmf.Plan().Transfer(1).SetTransferStatus(common.ETransferStatus.BlobTierFailure(), true)
+	mmf.Plan().Transfer(1).SetErrorCode(409, true)
+	mmf.Unmap()
+
+	return jobID
+}
+
+func (s *jobsInspectSuite) SetUpTest(c *chk.C) {
+	s.oldPlanFolder = common.AzcopyJobPlanFolder
+	common.AzcopyJobPlanFolder = c.MkDir()
+}
+
+func (s *jobsInspectSuite) TearDownTest(c *chk.C) {
+	common.AzcopyJobPlanFolder = s.oldPlanFolder
+}
+
+func (s *jobsInspectSuite) TestInspectJSONLines(c *chk.C) {
+	jobID := s.createTestPlan(c)
+	planFiles, err := jobPlanFiles(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(planFiles, chk.HasLen, 1)
+
+	var out bytes.Buffer
+	c.Assert(inspectPlanFiles(planFiles, inspectFormatJSONLines, common.ETransferStatus.All(), &out), chk.IsNil)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	c.Assert(lines, chk.HasLen, 4)
+
+	var part inspectPartRecord
+	c.Assert(json.Unmarshal([]byte(lines[0]), &part), chk.IsNil)
+	c.Assert(part.Record, chk.Equals, "part")
+	c.Assert(part.JobID, chk.Equals, jobID)
+	c.Assert(part.SourceRoot, chk.Equals, "https://acct.blob.core.windows.net/container")
+	c.Assert(part.FromTo, chk.Equals, "BlobLocal")
+	c.Assert(part.NumTransfers, chk.Equals, uint32(3))
+	c.Assert(part.CommandString, chk.Equals, "copy https://acct.blob.core.windows.net/container /data --recursive")
+
+	var transfer inspectTransferRecord
+	c.Assert(json.Unmarshal([]byte(lines[1]), &transfer), chk.IsNil)
+	c.Assert(transfer.Record, chk.Equals, "transfer")
+	c.Assert(transfer.Source, chk.Equals, "/a.txt")
+	c.Assert(transfer.SourceSize, chk.Equals, int64(10))
+	c.Assert(transfer.Status, chk.Equals, common.ETransferStatus.Success())
+	c.Assert(transfer.ModifiedTime.Equal(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)), chk.Equals, true)
+
+	// Failed includes the more specific failures, just like jobs show
+	out.Reset()
+	c.Assert(inspectPlanFiles(planFiles, inspectFormatJSONLines, common.ETransferStatus.Failed(), &out), chk.IsNil)
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	c.Assert(lines, chk.HasLen, 2)
+	c.Assert(json.Unmarshal([]byte(lines[1]), &transfer), chk.IsNil)
+	c.Assert(transfer.Source, chk.Equals, "/b.txt")
+	c.Assert(transfer.ErrorCode, chk.Equals, int32(409))
+}
+
+func (s *jobsInspectSuite) TestInspectCSV(c *chk.C) {
+	jobID := s.createTestPlan(c)
+	planFiles, err := jobPlanFiles(jobID)
+	c.Assert(err, chk.IsNil)
+
+	var out bytes.Buffer
+	c.Assert(inspectPlanFiles(planFiles, inspectFormatCSV, common.ETransferStatus.Started(), &out), chk.IsNil)
+	rows, err := csv.NewReader(&out).ReadAll()
+	c.Assert(err, chk.IsNil)
+	c.Assert(rows, chk.HasLen, 2)
+	c.Assert(rows[0], chk.DeepEquals, inspectCSVHeader)
+	c.Assert(rows[1][:8], chk.DeepEquals, []string{"0", "2", "Started", "0", "File", "/c.txt", "/c.txt", "30"})
+}
+
+func (s *jobsInspectSuite) TestInspectMissingJob(c *chk.C) {
+	_, err := jobPlanFiles(common.NewJobID())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *jobsInspectSuite) TestInspectTruncatedPlan(c *chk.C) {
+	jobID := s.createTestPlan(c)
+	planFiles, err := jobPlanFiles(jobID)
+	c.Assert(err, chk.IsNil)
+	path := planFiles[0].GetJobPartPlanPath()
+	info, err := os.Stat(path)
+	c.Assert(err, chk.IsNil)
+
+	// cut off the strings of the last transfer, as if the file were still being written
+	c.Assert(os.Truncate(path, info.Size()-1), chk.IsNil)
+	var out bytes.Buffer
+	c.Assert(inspectPlanFiles(planFiles, inspectFormatJSONLines, common.ETransferStatus.All(), &out), chk.ErrorMatches, ".*truncated or corrupt: the strings of transfer 2.*")
+
+	// and then into the transfer table
+	c.Assert(os.Truncate(path, info.Size()-int64(unsafe.Sizeof(ste.JobPartPlanTransfer{}))), chk.IsNil)
+	c.Assert(inspectPlanFiles(planFiles, inspectFormatJSONLines, common.ETransferStatus.All(), &out), chk.ErrorMatches, ".*truncated or corrupt: its 3 transfers need.*")
+	c.Assert(out.Len(), chk.Equals, 0)
+}
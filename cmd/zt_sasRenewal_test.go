@@ -0,0 +1,81 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type sasRenewalSuite struct{}
+
+var _ = chk.Suite(&sasRenewalSuite{})
+
+func (s *sasRenewalSuite) TestUserDelegationSASKeepsScope(c *chk.C) {
+	const account = "https://myaccount.blob.core.windows.net"
+	tests := []struct {
+		resource, sas        string
+		container, blob, dir string
+	}{
+		{account + "/cont", "sr=c&sp=rl&st=2030-01-01T00:00:00Z&se=2030-01-01T02:00:00Z", "cont", "", ""},
+		{account + "/cont/a/b.txt", "sr=b&sp=r", "cont", "a/b.txt", ""},
+		{account + "/cont/a/b/c/", "sr=d&sdd=2&sp=rl", "cont", "", "a/b"},
+		{account + "/cont/a/b/c.txt", "sr=d&sdd=1&sp=rl", "cont", "", "a"},
+		{"https://myaccount.dfs.core.windows.net/fs/dir", "sr=d&sdd=1&sp=racwdl", "fs", "", "dir"},
+		{account + "/cont/x", "ss=b&srt=sco&sp=rl", "cont", "", ""},
+	}
+
+	for _, t := range tests {
+		values, _, err := userDelegationSASValuesLike(t.resource, "?"+t.sas)
+		c.Assert(err, chk.IsNil, chk.Commentf(t.sas))
+		c.Check(values.ContainerName, chk.Equals, t.container, chk.Commentf(t.sas))
+		c.Check(values.BlobName, chk.Equals, t.blob, chk.Commentf(t.sas))
+		c.Check(values.Directory, chk.Equals, t.dir, chk.Commentf(t.sas))
+	}
+
+	_, lifetime, err := userDelegationSASValuesLike(tests[0].resource, tests[0].sas)
+	c.Assert(err, chk.IsNil)
+	c.Check(lifetime, chk.Equals, 2*time.Hour)
+}
+
+func (s *sasRenewalSuite) TestUserDelegationSASRefusesToWiden(c *chk.C) {
+	const account = "https://myaccount.blob.core.windows.net"
+	tests := []struct {
+		resource, sas string
+	}{
+		{account + "/cont/a", "sr=d&sdd=2&sp=rl"},       // directory deeper than the resource
+		{account + "/cont/a/b", "sr=d&sp=rl"},           // no depth
+		{account + "/cont/*/b", "sr=d&sdd=1&sp=rl"},     // directory can't be determined
+		{account + "/cont/a", "sr=bs&sp=r"},             // snapshot
+		{account + "/cont/a", "sr=c&si=policy"},         // stored access policy
+		{account, "ss=b&srt=sco&sp=rl"},                 // whole account
+		{account + "/cont", "ss=b&srt=o&sp=rl"},         // account SAS that can't list containers
+		{account + "/cont", "ss=b&srt=sco&sp=rwdlacup"}, // account-only permissions
+		{account + "/cont/a", "sr=b&sp=ru"},             // update is an account SAS permission
+		{account + "/cont", "sp=rl"},                    // no scope at all
+	}
+
+	for _, t := range tests {
+		_, _, err := userDelegationSASValuesLike(t.resource, t.sas)
+		c.Check(err, chk.NotNil, chk.Commentf(t.resource+"?"+t.sas))
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type specialFileSuite struct{}
+
+var _ = chk.Suite(&specialFileSuite{})
+
+func (s *specialFileSuite) TestSpecialFileClassifierUpload(c *chk.C) {
+	dir := c.MkDir()
+	c.Assert(syscall.Mkfifo(filepath.Join(dir, "fifo"), 0644), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "file"), []byte("hello"), 0644), chk.IsNil)
+
+	classifier := &specialFileClassifier{isUpload: true, localRoot: dir}
+
+	fifo := StoredObject{relativePath: "fifo", entityType: common.EEntityType.File()}
+	classifier.classify(&fifo)
+	c.Assert(fifo.entityType, chk.Equals, common.EEntityType.SpecialFile())
+
+	file := StoredObject{relativePath: "file", entityType: common.EEntityType.File(), size: 5}
+	classifier.classify(&file)
+	c.Assert(file.entityType, chk.Equals, common.EEntityType.File())
+	c.Assert(file.size, chk.Equals, int64(5))
+
+	// a single special file as the source
+	single := StoredObject{relativePath: "", entityType: common.EEntityType.File()}
+	(&specialFileClassifier{isUpload: true, localRoot: filepath.Join(dir, "fifo")}).classify(&single)
+	c.Assert(single.entityType, chk.Equals, common.EEntityType.SpecialFile())
+}
+
+func (s *specialFileSuite) TestSpecialFileClassifierDownload(c *chk.C) {
+	classifier := &specialFileClassifier{isUpload: false}
+
+	device := StoredObject{relativePath: "null", entityType: common.EEntityType.File(), Metadata: common.Metadata{common.POSIXCharDeviceMeta: "true"}}
+	classifier.classify(&device)
+	c.Assert(device.entityType, chk.Equals, common.EEntityType.SpecialFile())
+
+	file := StoredObject{relativePath: "file", entityType: common.EEntityType.File(), Metadata: common.Metadata{common.POSIXModeMeta: "33188"}}
+	classifier.classify(&file)
+	c.Assert(file.entityType, chk.Equals, common.EEntityType.File())
+}
+
+func (s *specialFileSuite) TestSyncDestinationComparatorSpecialFile(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	indexer := newObjectIndexer()
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, false, false)
+
+	// the source is now a FIFO, but the destination is still a plain blob, and newer
+	source := StoredObject{name: "pipe", relativePath: "pipe", entityType: common.EEntityType.SpecialFile(), lastModifiedTime: time.Now().Add(-time.Hour)}
+	c.Assert(indexer.store(source), chk.IsNil)
+	destination := StoredObject{name: "pipe", relativePath: "pipe", lastModifiedTime: time.Now()}
+	c.Assert(destinationComparator.processIfNecessary(destination), chk.IsNil)
+
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+}
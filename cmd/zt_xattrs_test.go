@@ -0,0 +1,103 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type xattrsSuite struct{}
+
+var _ = chk.Suite(&xattrsSuite{})
+
+func (s *xattrsSuite) TestExcludeXattrsSidecarFilter(c *chk.C) {
+	filter := &excludeXattrsSidecarFilter{}
+
+	c.Assert(filter.DoesPass(StoredObject{name: "file.txt", relativePath: "dir/file.txt"}), chk.Equals, true)
+	c.Assert(filter.DoesPass(StoredObject{name: "file.txt" + common.XattrsSidecarSuffix, relativePath: "dir/file.txt" + common.XattrsSidecarSuffix}), chk.Equals, false)
+	c.Assert(filter.AppliesOnlyToFiles(), chk.Equals, false)
+}
+
+func (s *xattrsSuite) TestSyncComparatorsXattrs(c *chk.C) {
+	old := time.Now().Add(-time.Hour)
+	source := StoredObject{name: "file", relativePath: "file", entityType: common.EEntityType.File(), lastModifiedTime: old,
+		Metadata: common.Metadata{common.POSIXXattrsHashMeta: "new"}}
+	destination := StoredObject{name: "file", relativePath: "file", entityType: common.EEntityType.File(), lastModifiedTime: time.Now(),
+		Metadata: common.Metadata{common.POSIXXattrsHashMeta: "old"}}
+
+	// the destination is newer, but its extended attributes differ
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	indexer := newObjectIndexer()
+	c.Assert(indexer.store(source), chk.IsNil)
+	c.Assert(newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, false, true).processIfNecessary(destination), chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	dummyCopyScheduler = dummyProcessor{}
+	indexer = newObjectIndexer()
+	c.Assert(indexer.store(destination), chk.IsNil)
+	c.Assert(newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), false, false, true).processIfNecessary(source), chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	// without the flag, the times decide
+	dummyCopyScheduler = dummyProcessor{}
+	indexer = newObjectIndexer()
+	c.Assert(indexer.store(source), chk.IsNil)
+	c.Assert(newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, false, false).processIfNecessary(destination), chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 0)
+}
+
+func (s *xattrsSuite) TestSyncDeletesSidecarWithBlob(c *chk.C) {
+	var mu sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, chk.Equals, http.MethodDelete)
+		mu.Lock()
+		deleted = append(deleted, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	root, err := url.Parse(server.URL + "/devstoreaccount1/container")
+	c.Assert(err, chk.IsNil)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+	deleter := newRemoteResourceDeleter(root, p, context.Background(), common.ELocation.Blob(), common.EFolderPropertiesOption.NoFolders(), false)
+
+	c.Assert(deleter.delete(StoredObject{name: "plain", relativePath: "dir/plain", entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(deleter.delete(StoredObject{name: "big", relativePath: "dir/big", entityType: common.EEntityType.File(),
+		Metadata: common.Metadata{common.POSIXXattrsSidecarMeta: "true"}}), chk.IsNil)
+
+	c.Assert(deleted, chk.DeepEquals, []string{
+		"/devstoreaccount1/container/dir/plain",
+		"/devstoreaccount1/container/dir/big",
+		"/devstoreaccount1/container/dir/big" + common.XattrsSidecarSuffix,
+	})
+}
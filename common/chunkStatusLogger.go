@@ -254,23 +254,33 @@ type chunkStatusLogger struct {
 	atomicLastRetryCount            int64
 	atomicIsWaitingOnFinalBodyReads int32
 	counts                          []int64
-	outputEnabled                   bool
+	outputEnabled                   bool // true if either the CSV or the trace output is enabled
+	traceEnabled                    bool
 	unsavedEntries                  chan *chunkWaitState
 	flushDone                       chan struct{}
 	cpuMonitor                      CPUMonitor
 }
 
-func NewChunkStatusLogger(jobID JobID, cpuMon CPUMonitor, logFileFolder string, enableOutput bool) ChunkStatusLoggerCloser {
+// NewChunkStatusLogger creates a logger that optionally writes every transition to a CSV log (enableOutput) and/or
+// to a Chrome trace file (enableTrace).
+func NewChunkStatusLogger(jobID JobID, cpuMon CPUMonitor, logFileFolder string, enableOutput bool, enableTrace bool) ChunkStatusLoggerCloser {
 	logger := &chunkStatusLogger{
 		counts:         make([]int64, numWaitReasons()),
-		outputEnabled:  enableOutput,
+		outputEnabled:  enableOutput || enableTrace,
+		traceEnabled:   enableTrace,
 		unsavedEntries: make(chan *chunkWaitState, 1000000),
 		flushDone:      make(chan struct{}),
 		cpuMonitor:     cpuMon,
 	}
-	if enableOutput {
-		chunkLogPath := path.Join(logFileFolder, jobID.String()+"-chunks.log") // its a CSV, but using log extension for consistency with other files in the directory
-		go logger.main(chunkLogPath)
+	if enableOutput || enableTrace {
+		chunkLogPath, tracePath := "", ""
+		if enableOutput {
+			chunkLogPath = path.Join(logFileFolder, jobID.String()+"-chunks.log") // its a CSV, but using log extension for consistency with other files in the directory
+		}
+		if enableTrace {
+			tracePath = path.Join(logFileFolder, jobID.String()+"-chunks-trace.json")
+		}
+		go logger.main(chunkLogPath, tracePath, time.Now())
 	}
 	return logger
 }
@@ -296,7 +306,7 @@ func (csl *chunkStatusLogger) LogChunkStatus(id ChunkID, reason WaitReason) {
 	// always update the in-memory stats, even if output is disabled
 	csl.countStateTransition(id, reason)
 
-	if !csl.outputEnabled || id.IsPseudoChunk() { // pseudo chunks are only for aggregate stats, not detailed logging
+	if !csl.outputEnabled || (id.IsPseudoChunk() && !csl.traceEnabled) { // pseudo chunks are only for aggregate stats and the trace, not the CSV log
 		return
 	}
 
@@ -327,21 +337,51 @@ func (csl *chunkStatusLogger) CloseLogger() {
 	close(csl.unsavedEntries)
 }
 
-func (csl *chunkStatusLogger) main(chunkLogPath string) {
-	f, err := os.Create(chunkLogPath)
-	if err != nil {
-		panic(err.Error())
-	}
-	defer func() { _ = f.Close() }()
+func (csl *chunkStatusLogger) main(chunkLogPath string, tracePath string, start time.Time) {
+	var w *bufio.Writer
+	var trace *chunkTraceWriter
+	var files []*os.File
 
-	w := bufio.NewWriter(f)
-	_, _ = w.WriteString("Name,Offset,State,StateStartTime\n")
+	if chunkLogPath != "" {
+		f, err := os.Create(chunkLogPath)
+		if err != nil {
+			panic(err.Error())
+		}
+		files = append(files, f)
+		w = bufio.NewWriter(f)
+		_, _ = w.WriteString("Name,Offset,State,StateStartTime\n")
+	}
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			panic(err.Error())
+		}
+		files = append(files, f)
+		trace = newChunkTraceWriter(f, start)
+	}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
 
 	doFlush := func() {
-		_ = w.Flush()
-		_ = f.Sync()
+		if w != nil {
+			_ = w.Flush()
+		}
+		if trace != nil {
+			_ = trace.flush()
+		}
+		for _, f := range files {
+			_ = f.Sync()
+		}
 	}
-	defer doFlush()
+	defer func() {
+		if trace != nil {
+			_ = trace.close(time.Now())
+		}
+		doFlush()
+	}()
 
 	alwaysFlushFromNowOn := false
 
@@ -354,7 +394,12 @@ func (csl *chunkStatusLogger) main(chunkLogPath string) {
 			continue // TODO can become break (or be moved to later if we close unsaved entries, once we figure out how we got stuff written to us after CloseLog was called)
 
 		}
-		_, _ = w.WriteString(fmt.Sprintf("%s,%d,%s,%s\n", x.Name, x.OffsetInFile(), x.reason, x.waitStart))
+		if w != nil && !x.IsPseudoChunk() {
+			_, _ = w.WriteString(fmt.Sprintf("%s,%d,%s,%s\n", x.Name, x.OffsetInFile(), x.reason, x.waitStart))
+		}
+		if trace != nil {
+			trace.add(x)
+		}
 		if alwaysFlushFromNowOn {
 			// TODO: remove when we figure out how we got stuff written to us after CloseLog was called. For now, this should handle those cases (if they still exist)
 			doFlush()
@@ -0,0 +1,233 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// chunkTraceWriter turns the stream of chunk state transitions into Chrome Trace Event JSON
+// (the "JSON Array Format"), which can be opened in chrome://tracing or https://ui.perfetto.dev.
+// Each file is shown as a process, whose first thread holds the whole-file states (e.g. XferStart, Epilogue) and whose
+// other threads each hold one chunk. Every state the chunk passes through becomes a span on that thread.
+// It is only ever called from the chunkStatusLogger's main goroutine, so it is not threadsafe.
+type chunkTraceWriter struct {
+	w             *bufio.Writer
+	start         time.Time
+	wroteAny      bool
+	nextPid       int
+	files         map[string]*traceFile
+	lastIdleCheck time.Time
+}
+
+// how long a file must have had no activity (and no chunks in flight) before we consider it finished
+const traceFileIdleTime = 5 * time.Second
+
+// how often we look for finished files
+const traceIdleCheckInterval = time.Second
+
+const traceWholeFileTid = 0
+
+type traceFile struct {
+	pid       int
+	nextTid   int
+	wholeFile *traceChunk
+	chunks    map[int64]*traceChunk // keyed by offset in file
+	first     time.Time
+	last      time.Time
+}
+
+type traceChunk struct {
+	tid    int
+	offset int64
+	length int64
+	reason WaitReason
+	since  time.Time
+}
+
+type traceEvent struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat,omitempty"`
+	Ph   string                 `json:"ph"`
+	Ts   float64                `json:"ts"`
+	Dur  float64                `json:"dur,omitempty"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid"`
+	S    string                 `json:"s,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+func newChunkTraceWriter(w io.Writer, start time.Time) *chunkTraceWriter {
+	t := &chunkTraceWriter{
+		w:             bufio.NewWriter(w),
+		start:         start,
+		nextPid:       1,
+		files:         make(map[string]*traceFile),
+		lastIdleCheck: start,
+	}
+	_, _ = t.w.WriteString("[")
+	return t
+}
+
+// add records one state transition. The previous state of the same chunk (if any) is written out as a completed span.
+func (t *chunkTraceWriter) add(x *chunkWaitState) {
+	f := t.getFile(x.Name, x.waitStart)
+	f.last = x.waitStart
+
+	var c *traceChunk
+	if x.IsPseudoChunk() {
+		if f.wholeFile == nil {
+			f.wholeFile = &traceChunk{tid: traceWholeFileTid, offset: -1}
+		}
+		c = f.wholeFile
+	} else {
+		c = f.chunks[x.offsetInFile]
+		if c == nil {
+			c = &traceChunk{tid: f.nextTid, offset: x.offsetInFile, length: x.length}
+			f.nextTid++
+			f.chunks[x.offsetInFile] = c
+			t.writeMetadata("thread_name", f.pid, c.tid, "chunk at offset "+strconv.FormatInt(c.offset, 10))
+		}
+	}
+
+	if !c.since.IsZero() {
+		t.writeSpan(f, c, x.waitStart, false)
+	}
+
+	switch x.reason {
+	case EWaitReason.ChunkDone(), EWaitReason.Cancelled():
+		if x.reason == EWaitReason.Cancelled() {
+			t.write(traceEvent{Name: x.reason.Name, Cat: "chunk", Ph: "i", S: "t", Ts: t.ts(x.waitStart), Pid: f.pid, Tid: c.tid})
+		}
+		if x.IsPseudoChunk() {
+			f.wholeFile = nil
+		} else {
+			delete(f.chunks, x.offsetInFile)
+		}
+	default:
+		c.reason = x.reason
+		c.since = x.waitStart
+	}
+
+	if x.waitStart.Sub(t.lastIdleCheck) >= traceIdleCheckInterval {
+		t.lastIdleCheck = x.waitStart
+		t.finishIdleFiles(x.waitStart, traceFileIdleTime)
+	}
+}
+
+// flush writes out the spans of all files that have nothing in flight, and pushes everything written so far to the underlying writer
+func (t *chunkTraceWriter) flush() error {
+	t.finishIdleFiles(time.Time{}, 0)
+	return t.w.Flush()
+}
+
+// close writes out everything that remains (marking spans that never completed as unfinished) and terminates the JSON array
+func (t *chunkTraceWriter) close(now time.Time) error {
+	for name, f := range t.files {
+		if f.wholeFile != nil && !f.wholeFile.since.IsZero() {
+			t.writeSpan(f, f.wholeFile, now, true)
+		}
+		for _, c := range f.chunks {
+			if !c.since.IsZero() {
+				t.writeSpan(f, c, now, true)
+			}
+		}
+		t.writeFileSpan(f, name)
+		delete(t.files, name)
+	}
+	_, _ = t.w.WriteString("\n]\n")
+	return t.w.Flush()
+}
+
+func (t *chunkTraceWriter) getFile(name string, now time.Time) *traceFile {
+	f := t.files[name]
+	if f == nil {
+		f = &traceFile{pid: t.nextPid, nextTid: traceWholeFileTid + 1, chunks: make(map[int64]*traceChunk), first: now}
+		t.nextPid++
+		t.files[name] = f
+		t.writeMetadata("process_name", f.pid, traceWholeFileTid, name)
+		t.writeMetadata("thread_name", f.pid, traceWholeFileTid, "file")
+	}
+	return f
+}
+
+// finishIdleFiles writes the overall span for each file that has no chunks in flight and has been idle for at least minIdle,
+// and then forgets the file, so that memory use does not grow with the number of files in the job.
+// If a zero now is given, idle time is not checked.
+func (t *chunkTraceWriter) finishIdleFiles(now time.Time, minIdle time.Duration) {
+	for name, f := range t.files {
+		if f.wholeFile != nil || len(f.chunks) > 0 {
+			continue
+		}
+		if !now.IsZero() && now.Sub(f.last) < minIdle {
+			continue
+		}
+		t.writeFileSpan(f, name)
+		delete(t.files, name)
+	}
+}
+
+func (t *chunkTraceWriter) writeSpan(f *traceFile, c *traceChunk, end time.Time, unfinished bool) {
+	e := traceEvent{Name: c.reason.Name, Cat: "chunk", Ph: "X", Ts: t.ts(c.since), Dur: t.ts(end) - t.ts(c.since), Pid: f.pid, Tid: c.tid}
+	if c.tid == traceWholeFileTid {
+		e.Cat = "file"
+	} else {
+		e.Args = map[string]interface{}{"offset": c.offset, "length": c.length}
+	}
+	if unfinished {
+		if e.Args == nil {
+			e.Args = map[string]interface{}{}
+		}
+		e.Args["unfinished"] = true
+	}
+	t.write(e)
+}
+
+func (t *chunkTraceWriter) writeFileSpan(f *traceFile, name string) {
+	t.write(traceEvent{Name: "File", Cat: "file", Ph: "X", Ts: t.ts(f.first), Dur: t.ts(f.last) - t.ts(f.first), Pid: f.pid, Tid: traceWholeFileTid,
+		Args: map[string]interface{}{"name": name}})
+}
+
+func (t *chunkTraceWriter) writeMetadata(kind string, pid int, tid int, name string) {
+	t.write(traceEvent{Name: kind, Ph: "M", Pid: pid, Tid: tid, Args: map[string]interface{}{"name": name}})
+}
+
+func (t *chunkTraceWriter) write(e traceEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return // can't happen, given the types in traceEvent
+	}
+	if t.wroteAny {
+		_, _ = t.w.WriteString(",")
+	}
+	t.wroteAny = true
+	_, _ = t.w.WriteString("\n")
+	_, _ = t.w.Write(b)
+}
+
+// ts is the trace timestamp, in microseconds since the trace started
+func (t *chunkTraceWriter) ts(when time.Time) float64 {
+	return float64(when.Sub(t.start).Nanoseconds()) / 1000
+}
@@ -27,6 +27,7 @@ import (
 	"hash"
 	"io"
 	"math"
+	"os"
 	"sync/atomic"
 	"time"
 )
@@ -96,6 +97,9 @@ type chunkedFileWriter struct {
 
 	sourceMd5Exists bool
 
+	// set once punching a hole has failed, after which all-zero chunks are written like any other
+	holesUnsupported bool
+
 	err error // This field should be set only by workerRoutine
 }
 
@@ -360,6 +364,11 @@ func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) e
 
 	w.chunkLogger.LogChunkStatus(chunk.id, EWaitReason.DiskIO())
 
+	// leave a hole rather than writing zeros, so that sparse files (e.g. disk images) stay sparse
+	if saved, err := w.trySaveAsHole(chunk, md5Hasher); saved || err != nil {
+		return err
+	}
+
 	// in some cases, e.g. Storage Spaces in Azure VMs, chopping up the writes helps perf. TODO: look into the reasons why it helps
 	for i := 0; i < len(chunk.data); i += maxWriteSize {
 		slice := chunk.data[i:]
@@ -378,6 +387,32 @@ func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) e
 	return nil
 }
 
+// trySaveAsHole saves an all-zero chunk by punching a hole in the file where it goes, instead of writing it.
+// It returns false if the chunk must be written.
+func (w *chunkedFileWriter) trySaveAsHole(chunk fileChunk, md5Hasher hash.Hash) (saved bool, err error) {
+	file, ok := w.file.(*os.File)
+	if !ok || w.holesUnsupported || len(chunk.data) == 0 {
+		return false, nil
+	}
+	for _, b := range chunk.data {
+		if b != 0 {
+			return false, nil
+		}
+	}
+
+	// the file was created at its full size, so the hole only has to be punched, and the write position moved past it
+	if PunchHole(file, chunk.id.OffsetInFile(), int64(len(chunk.data))) != nil {
+		w.holesUnsupported = true
+		return false, nil
+	}
+	if _, err = file.Seek(int64(len(chunk.data)), io.SeekCurrent); err != nil {
+		return false, err
+	}
+
+	md5Hasher.Write(chunk.data)
+	return true, nil
+}
+
 // We use a less strict cache limit
 // if we have relatively few chunks in progress for THIS file. Why? To try to spread
 // the work in progress across a larger number of files, instead of having it
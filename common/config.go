@@ -0,0 +1,169 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The config file lets users keep, in one place, the defaults they would otherwise repeat in wrapper scripts.
+// It holds named profiles; each profile can set environment variables, default flag values per command, and endpoint aliases.
+// Named remotes (see remotes.go) can be defined for all profiles, or per profile.
+// Precedence is: flags on the command line > environment variables > the selected profile > built-in defaults.
+//
+//	profile: prod              # used when neither --profile nor AZCOPY_PROFILE is given
+//	remotes:
+//	  backup:
+//	    url: https://backup.blob.core.windows.net
+//	    credential: env:BACKUP_SAS
+//	profiles:
+//	  prod:
+//	    environment:
+//	      AZCOPY_CONCURRENCY_VALUE: 64
+//	    flags:
+//	      "*":                 # applies to every command that has the flag
+//	        log-level: WARNING
+//	      copy:
+//	        block-size-mb: 16
+//	    aliases:
+//	      prod-data: https://proddata.blob.core.windows.net
+
+// AllCommandsFlagKey is the key under a profile's flags that applies to every command.
+const AllCommandsFlagKey = "*"
+
+// DefaultConfigProfileName is used when neither the config file, nor the user, selects a profile.
+const DefaultConfigProfileName = "default"
+
+type Config struct {
+	// Profile is the profile used when none is selected on the command line or through AZCOPY_PROFILE.
+	Profile  string                   `yaml:"profile"`
+	Profiles map[string]ConfigProfile `yaml:"profiles"`
+	// Remotes are available in every profile.
+	Remotes map[string]ConfigRemote `yaml:"remotes"`
+
+	// Path is where the config was loaded from. It is empty if there was no config file.
+	Path string `yaml:"-"`
+}
+
+type ConfigProfile struct {
+	// Environment holds AZCOPY_* (and other) environment variables, which are only applied if not already set.
+	Environment map[string]string `yaml:"environment"`
+	// Flags maps a command (e.g. "copy", "jobs resume" or "*") to default values for its flags.
+	Flags map[string]map[string]string `yaml:"flags"`
+	// Aliases maps a short name to an endpoint URL, so that "name:path" can be used in place of "URL/path".
+	// An alias is shorthand for a remote with nothing but a URL.
+	Aliases map[string]string `yaml:"aliases"`
+	// Remotes defined here take precedence over those defined for all profiles.
+	Remotes map[string]ConfigRemote `yaml:"remotes"`
+}
+
+// DefaultConfigPath returns the location of the config file, which can be overridden through AZCOPY_CONFIG_FILE.
+func DefaultConfigPath() string {
+	if p := lcm.GetEnvironmentVariable(EEnvironmentVariable.ConfigFile()); p != "" {
+		return p
+	}
+	return filepath.Join(lcm.GetEnvironmentVariable(EEnvironmentVariable.UserDir()), ".azcopy", "config.yaml")
+}
+
+// LoadConfig reads the config file at path. A missing file is not an error, and results in an empty config.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err = yaml.UnmarshalStrict(ByteSliceExtension{ByteSlice: b}.RemoveBOM(), c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	c.Path = path
+
+	return c, nil
+}
+
+// SelectProfile returns the profile to use. An explicitly requested profile must exist, but the default one need not.
+func (c *Config) SelectProfile(requested string) (name string, profile ConfigProfile, err error) {
+	name = requested
+	if name == "" {
+		name = lcm.GetEnvironmentVariable(EEnvironmentVariable.Profile())
+	}
+	if name == "" {
+		name = IffString(c.Profile == "", DefaultConfigProfileName, c.Profile)
+		return name, c.withRemotes(c.Profiles[name]), nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return "", ConfigProfile{}, fmt.Errorf("profile %q is not defined in the config file %s", name, IffString(c.Path == "", DefaultConfigPath(), c.Path))
+	}
+	return name, c.withRemotes(profile), nil
+}
+
+// withRemotes returns the profile with every remote it can use (shared ones, its aliases and its own) in its Remotes.
+func (c *Config) withRemotes(p ConfigProfile) ConfigProfile {
+	remotes := make(map[string]ConfigRemote)
+	for name, remote := range c.Remotes {
+		remotes[name] = remote
+	}
+	for name, endpoint := range p.Aliases {
+		remotes[name] = ConfigRemote{URL: endpoint}
+	}
+	for name, remote := range p.Remotes {
+		remotes[name] = remote
+	}
+
+	p.Remotes = remotes
+	return p
+}
+
+// ApplyEnvironment sets the profile's environment variables that are not already set, and returns the names of those it set.
+func (p ConfigProfile) ApplyEnvironment() []string {
+	applied := make([]string, 0)
+	for name, value := range p.Environment {
+		if _, alreadySet := os.LookupEnv(name); alreadySet {
+			continue
+		}
+		if os.Setenv(name, value) == nil {
+			applied = append(applied, name)
+		}
+	}
+	sort.Strings(applied)
+	return applied
+}
+
+// FlagDefaults returns the default flag values for a command (named as its path without the leading "azcopy"),
+// with the values for that command taking precedence over those for all commands.
+func (p ConfigProfile) FlagDefaults(command string) map[string]string {
+	result := make(map[string]string)
+	for _, key := range []string{AllCommandsFlagKey, command} {
+		for flag, value := range p.Flags[key] {
+			result[flag] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,317 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// A credential helper is a user-supplied executable that AzCopy invokes to obtain short-lived credentials,
+// in the same spirit as git credential helpers or kubectl exec plugins.
+// AzCopy writes a CredentialHelperRequest as JSON to the helper's stdin, and expects a CredentialHelperResponse
+// as JSON on its stdout. A non-zero exit code is treated as a failure, and the helper's stderr is surfaced to the user.
+//
+// Responses are cached in-process, and the helper is invoked again once half of the credential's lifetime has elapsed,
+// so that long-running jobs (including resumed ones) keep working across credential expiry. SAS tokens are swapped into
+// in-flight requests through the SAS renewal mechanism (see RegisterSASRenewer).
+
+// CredentialHelperProtocolVersion is sent to the helper so that the request format can evolve later.
+const CredentialHelperProtocolVersion = 1
+
+var ECredentialHelperKind = CredentialHelperKind("")
+
+// CredentialHelperKind indicates which kind of credential AzCopy is asking the helper for.
+type CredentialHelperKind string
+
+func (CredentialHelperKind) Token() CredentialHelperKind { return CredentialHelperKind("token") }
+func (CredentialHelperKind) SAS() CredentialHelperKind   { return CredentialHelperKind("sas") }
+
+// CredentialHelperInfo describes how to invoke a credential helper.
+type CredentialHelperInfo struct {
+	// Command is the helper executable followed by any arguments, separated by whitespace.
+	Command string `json:"_credential_helper_command"`
+}
+
+// IsEmpty returns true if no credential helper is configured.
+func (h CredentialHelperInfo) IsEmpty() bool {
+	return strings.TrimSpace(h.Command) == ""
+}
+
+// CredentialHelperRequest is written to the helper's stdin.
+type CredentialHelperRequest struct {
+	Version int                  `json:"version"`
+	Kind    CredentialHelperKind `json:"kind"`
+	// Resource is the AAD resource for token requests, or the resource URL (without any SAS) for SAS requests.
+	Resource string `json:"resource"`
+}
+
+// CredentialHelperResponse is read from the helper's stdout. Exactly one of AccessToken or SAS should be set.
+type CredentialHelperResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	SAS         string `json:"sas,omitempty"`
+	// ExpiresOn may be either an RFC3339 timestamp or seconds since the Unix epoch.
+	// It is optional for SAS responses, in which case the SAS's own expiry (se=) is used.
+	ExpiresOn json.RawMessage `json:"expires_on,omitempty"`
+
+	expiry   time.Time
+	obtained time.Time
+}
+
+// Expiry returns the time at which the credential returned by the helper expires.
+func (r *CredentialHelperResponse) Expiry() time.Time {
+	return r.expiry
+}
+
+// needsRefresh follows the same "half of the remaining lifetime" policy as refreshPolicyHalfOfExpiryWithin.
+func (r *CredentialHelperResponse) needsRefresh(now time.Time) bool {
+	if r.expiry.IsZero() {
+		return false // nothing tells us this ever expires
+	}
+
+	return now.After(r.obtained.Add(r.expiry.Sub(r.obtained) / 2))
+}
+
+func (r *CredentialHelperResponse) parseExpiry() error {
+	raw := strings.TrimSpace(string(r.ExpiresOn))
+	if raw == "" || raw == "null" {
+		if r.SAS != "" {
+			r.expiry = SASExpiry(r.SAS)
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(raw, `"`) {
+		var s string
+		if err := json.Unmarshal([]byte(raw), &s); err != nil {
+			return err
+		}
+		raw = s
+	}
+
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		r.expiry = time.Unix(secs, 0).UTC()
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return fmt.Errorf("expires_on %q is neither an RFC3339 timestamp nor a Unix time", raw)
+	}
+	r.expiry = t.UTC()
+	return nil
+}
+
+// Invoke runs the helper once, bypassing the cache.
+func (h CredentialHelperInfo) Invoke(ctx context.Context, req CredentialHelperRequest) (*CredentialHelperResponse, error) {
+	args := strings.Fields(h.Command)
+	if len(args) == 0 {
+		return nil, errors.New("no credential helper is configured")
+	}
+
+	req.Version = CredentialHelperProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %q failed: %w; %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	resp := &CredentialHelperResponse{obtained: time.Now().UTC()}
+	if err := json.Unmarshal(ByteSliceExtension{ByteSlice: stdout.Bytes()}.RemoveBOM(), resp); err != nil {
+		return nil, fmt.Errorf("credential helper %q returned invalid JSON: %w", args[0], err)
+	}
+
+	resp.SAS = strings.TrimPrefix(resp.SAS, "?")
+	switch req.Kind {
+	case ECredentialHelperKind.Token():
+		if resp.AccessToken == "" {
+			return nil, fmt.Errorf("credential helper %q did not return an access_token", args[0])
+		}
+	case ECredentialHelperKind.SAS():
+		if resp.SAS == "" {
+			return nil, fmt.Errorf("credential helper %q did not return a sas", args[0])
+		}
+	}
+
+	if err := resp.parseExpiry(); err != nil {
+		return nil, fmt.Errorf("credential helper %q returned an invalid expiry: %w", args[0], err)
+	} else if req.Kind == ECredentialHelperKind.Token() && resp.expiry.IsZero() {
+		return nil, fmt.Errorf("credential helper %q did not return expires_on for its access_token", args[0])
+	}
+
+	return resp, nil
+}
+
+type credentialHelperCacheKey struct {
+	command string
+	request CredentialHelperRequest
+}
+
+// credentialHelperCall is a helper invocation in progress, which concurrent requests for the same credential wait on
+type credentialHelperCall struct {
+	done chan struct{}
+	resp *CredentialHelperResponse
+	err  error
+}
+
+// credentialHelperCache is shared by the front end and the STE, since both live in the same process.
+// The lock is never held while the helper runs, so that requests for other credentials aren't held up by a slow helper.
+var credentialHelperCache = struct {
+	sync.Mutex
+	responses map[credentialHelperCacheKey]*CredentialHelperResponse
+	inFlight  map[credentialHelperCacheKey]*credentialHelperCall
+}{
+	responses: make(map[credentialHelperCacheKey]*CredentialHelperResponse),
+	inFlight:  make(map[credentialHelperCacheKey]*credentialHelperCall),
+}
+
+// Get returns a cached credential from the helper, invoking the helper only if nothing usable is cached.
+// Concurrent requests for the same credential share one invocation of the helper.
+// Any SAS obtained this way is registered for renewal through the same helper request.
+func (h CredentialHelperInfo) Get(ctx context.Context, req CredentialHelperRequest) (*CredentialHelperResponse, error) {
+	req.Version = CredentialHelperProtocolVersion
+	key := credentialHelperCacheKey{command: h.Command, request: req}
+
+	credentialHelperCache.Lock()
+	if resp, ok := credentialHelperCache.responses[key]; ok && !resp.needsRefresh(time.Now().UTC()) {
+		credentialHelperCache.Unlock()
+		return resp, nil
+	}
+	if call, ok := credentialHelperCache.inFlight[key]; ok {
+		credentialHelperCache.Unlock()
+		select {
+		case <-call.done:
+			return call.resp, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &credentialHelperCall{done: make(chan struct{})}
+	credentialHelperCache.inFlight[key] = call
+	credentialHelperCache.Unlock()
+
+	call.resp, call.err = h.Invoke(ctx, req)
+
+	credentialHelperCache.Lock()
+	delete(credentialHelperCache.inFlight, key)
+	if call.err == nil {
+		credentialHelperCache.responses[key] = call.resp
+	}
+	credentialHelperCache.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	if call.resp.SAS != "" {
+		RegisterSASRenewer(call.resp.SAS, func(ctx context.Context) (string, error) {
+			resp, err := h.Get(ctx, req)
+			if err != nil {
+				return "", err
+			}
+			return resp.SAS, nil
+		})
+	}
+
+	return call.resp, nil
+}
+
+// GetCredentialHelperFromEnvVar returns the credential helper configured through the environment, if any.
+func GetCredentialHelperFromEnvVar() CredentialHelperInfo {
+	return CredentialHelperInfo{Command: lcm.GetEnvironmentVariable(EEnvironmentVariable.CredentialHelper())}
+}
+
+// CredentialHelperLogin obtains a token from a credential helper, persist indicates whether to cache the
+// helper configuration (not just the token) on local disk, so that later invocations and resumed jobs can refresh it.
+func (uotm *UserOAuthTokenManager) CredentialHelperLogin(ctx context.Context, helper CredentialHelperInfo, persist bool) (*OAuthTokenInfo, error) {
+	if helper.IsEmpty() {
+		return nil, errors.New("a credential helper command must be specified")
+	}
+
+	oAuthTokenInfo := &OAuthTokenInfo{
+		CredentialHelper:     true,
+		CredentialHelperInfo: helper,
+	}
+	token, err := oAuthTokenInfo.GetNewTokenFromCredentialHelper(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oAuthTokenInfo.Token = *token
+	uotm.stashedInfo = oAuthTokenInfo
+
+	if persist {
+		err = uotm.credCache.SaveToken(*oAuthTokenInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return oAuthTokenInfo, nil
+}
+
+// GetNewTokenFromCredentialHelper gets a token by invoking the configured credential helper.
+func (credInfo *OAuthTokenInfo) GetNewTokenFromCredentialHelper(ctx context.Context) (*adal.Token, error) {
+	targetResource := Resource
+	if credInfo.Token.Resource != "" && credInfo.Token.Resource != targetResource {
+		targetResource = credInfo.Token.Resource
+	}
+
+	resp, err := credInfo.CredentialHelperInfo.Get(ctx, CredentialHelperRequest{
+		Kind:     ECredentialHelperKind.Token(),
+		Resource: targetResource,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expiresOn := "0"
+	if !resp.Expiry().IsZero() {
+		expiresOn = strconv.FormatInt(resp.Expiry().Unix(), 10)
+	}
+
+	return &adal.Token{
+		AccessToken: resp.AccessToken,
+		ExpiresIn:   "0",
+		ExpiresOn:   json.Number(expiresOn),
+		NotBefore:   "0",
+		Resource:    targetResource,
+		Type:        "Bearer",
+	}, nil
+}
@@ -39,6 +39,7 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.LogLocation(),
 	EEnvironmentVariable.JobPlanLocation(),
 	EEnvironmentVariable.ConcurrencyValue(),
+	EEnvironmentVariable.ConcurrencyTuningStrategy(),
 	EEnvironmentVariable.TransferInitiationPoolSize(),
 	EEnvironmentVariable.EnumerationPoolSize(),
 	EEnvironmentVariable.DisableHierarchicalScanning(),
@@ -48,6 +49,10 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.AWSSecretAccessKey(),
 	EEnvironmentVariable.GoogleAppCredentials(),
 	EEnvironmentVariable.ShowPerfStates(),
+	EEnvironmentVariable.ChunkTrace(),
+	EEnvironmentVariable.OtelTraceExport(),
+	EEnvironmentVariable.TraceParent(),
+	EEnvironmentVariable.TraceState(),
 	EEnvironmentVariable.PacePageBlobs(),
 	EEnvironmentVariable.AutoTuneToCpu(),
 	EEnvironmentVariable.CacheProxyLookup(),
@@ -57,7 +62,15 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.AWSSecretAccessKey(),
 	EEnvironmentVariable.ClientSecret(),
 	EEnvironmentVariable.CertificatePassword(),
+	EEnvironmentVariable.ConfigFile(),
+	EEnvironmentVariable.Profile(),
 	EEnvironmentVariable.AutoLoginType(),
+	EEnvironmentVariable.CredentialHelper(),
+	EEnvironmentVariable.SASRenewal(),
+	EEnvironmentVariable.FederatedTokenFile(),
+	EEnvironmentVariable.AzureClientID(),
+	EEnvironmentVariable.AzureTenantID(),
+	EEnvironmentVariable.AzureAuthorityHost(),
 	EEnvironmentVariable.TenantID(),
 	EEnvironmentVariable.AADEndpoint(),
 	EEnvironmentVariable.ApplicationID(),
@@ -71,6 +84,7 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.DisableSyslog(),
 	EEnvironmentVariable.MimeMapping(),
 	EEnvironmentVariable.DownloadToTempPath(),
+	EEnvironmentVariable.SIDMappingFile(),
 }
 
 var EEnvironmentVariable = EnvironmentVariable{}
@@ -82,10 +96,69 @@ func (EnvironmentVariable) UserDir() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) ConfigFile() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CONFIG_FILE",
+		Description: "Location of the config file holding profiles of default settings. The default is config.yaml in the .azcopy folder of the user's home directory.",
+	}
+}
+
+func (EnvironmentVariable) Profile() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_PROFILE",
+		Description: "Name of the config file profile to use, if --profile is not given. Settings from the profile apply only where neither a flag nor an environment variable is set.",
+	}
+}
+
 func (EnvironmentVariable) AutoLoginType() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_AUTO_LOGIN_TYPE",
-		Description: "Specify the credential type to access Azure Resource without invoking the login command and using the OS secret store, available values SPN, MSI and DEVICE - sequentially for Service Principal, Managed Service Identity and Device workflow.",
+		Description: "Specify the credential type to access Azure Resource without invoking the login command and using the OS secret store, available values SPN, MSI, DEVICE, HELPER and WORKLOAD - sequentially for Service Principal, Managed Service Identity, Device workflow, Credential Helper and Workload Identity Federation.",
+	}
+}
+
+func (EnvironmentVariable) CredentialHelper() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CREDENTIAL_HELPER",
+		Description: "Command (executable followed by arguments) that AzCopy runs to obtain short-lived OAuth tokens or SAS tokens. The request is written to its stdin and the credential read from its stdout, both as JSON. Used by auto login type HELPER, and to obtain SAS tokens for URLs that carry none, when no OAuth token applies and the resource is not public.",
+	}
+}
+
+func (EnvironmentVariable) SASRenewal() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_SAS_RENEWAL",
+		DefaultValue: "none",
+		Description:  "How to replace a SAS token that expires while a job is running. Available values are none (only warn), credentialhelper (ask " + EEnvironmentVariable.CredentialHelper().Name + " for a new SAS) and userdelegation (sign a user delegation SAS with the same permissions using the current Azure AD login, Blob and ADLS Gen2 only).",
+	}
+}
+
+// For workload identity federation. These follow the names used by the Azure workload identity webhook and the Azure SDKs,
+// so that AzCopy picks them up without any extra configuration in a federated pod.
+func (EnvironmentVariable) FederatedTokenFile() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZURE_FEDERATED_TOKEN_FILE",
+		Description: "Path of the file containing the federated token (such as a Kubernetes service account token) used for workload identity federation. The file is read again each time the token is refreshed.",
+	}
+}
+
+func (EnvironmentVariable) AzureClientID() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZURE_CLIENT_ID",
+		Description: "The client ID of the application used for workload identity federation, if " + EEnvironmentVariable.ApplicationID().Name + " is not set.",
+	}
+}
+
+func (EnvironmentVariable) AzureTenantID() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZURE_TENANT_ID",
+		Description: "The tenant ID of the application used for workload identity federation, if " + EEnvironmentVariable.TenantID().Name + " is not set.",
+	}
+}
+
+func (EnvironmentVariable) AzureAuthorityHost() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZURE_AUTHORITY_HOST",
+		Description: "The Azure Active Directory endpoint used for workload identity federation, if " + EEnvironmentVariable.AADEndpoint().Name + " is not set.",
 	}
 }
 
@@ -163,6 +236,15 @@ func (EnvironmentVariable) ConcurrencyValue() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) ConcurrencyTuningStrategy() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name: "AZCOPY_TUNING_STRATEGY",
+		Description: "Chooses how concurrency is tuned, when it is tuned automatically (e.g. in benchmarks). The default, hill-climbing, raises concurrency for as long as that raises throughput. " +
+			"latency[:<milliseconds>] keeps the 95th percentile of request latency near a target (by default, twice the latency seen at the start). " +
+			"sweep[:<step>[:<max>]] is for diagnostics. It tries every multiple of step up to max (by default 16 and 256), and then uses the best of them.",
+	}
+}
+
 // added in so that CPU usage detection can be disabled if advanced users feel it is causing tuning to be too conservative (i.e. not enough concurrency, due to detected CPU usage)
 func (EnvironmentVariable) AutoTuneToCpu() EnvironmentVariable {
 	return EnvironmentVariable{
@@ -272,6 +354,35 @@ func (EnvironmentVariable) ShowPerfStates() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) ChunkTrace() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CHUNK_TRACE",
+		Description: "If set, to anything, the progress of every file and chunk is written, as Chrome trace events, to <jobID>-chunks-trace.json in the log folder. Open it in chrome://tracing or https://ui.perfetto.dev",
+	}
+}
+
+func (EnvironmentVariable) OtelTraceExport() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name: "AZCOPY_OTEL_TRACE_EXPORT",
+		Description: "If set, OpenTelemetry spans for the job, each job part, each transfer and each HTTP request are exported with OTLP. " +
+			"An http:// or https:// URL (e.g. http://localhost:4318) sends them to a collector with OTLP/HTTP. Anything else is the path of a file, to which they are appended in the OTLP JSON format.",
+	}
+}
+
+func (EnvironmentVariable) TraceParent() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "TRACEPARENT",
+		Description: "The W3C trace context (traceparent) of whatever is running AzCopy. When tracing is enabled, the job's span is made its child, so that AzCopy appears in the same distributed trace.",
+	}
+}
+
+func (EnvironmentVariable) TraceState() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "TRACESTATE",
+		Description: "The W3C tracestate that goes with TRACEPARENT, if any.",
+	}
+}
+
 func (EnvironmentVariable) AWSAccessKeyID() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AWS_ACCESS_KEY_ID",
@@ -363,6 +474,14 @@ func (EnvironmentVariable) MimeMapping() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) SIDMappingFile() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:         "AZCOPY_SID_MAPPING_FILE",
+		DefaultValue: "",
+		Description:  "Location of the CSV or JSON file that maps SIDs and AAD object IDs to POSIX uids and gids. Used to translate SMB and ADLS Gen2 permissions to and from POSIX ACLs, to map owners and groups with --preserve-posix-properties, and to resolve domain-relative SIDs on Linux",
+	}
+}
+
 func (EnvironmentVariable) DownloadToTempPath() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:         "AZCOPY_DOWNLOAD_TO_TEMP_PATH",
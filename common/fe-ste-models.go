@@ -487,6 +487,14 @@ func (l Location) String() string {
 	return enum.StringInt(l, reflect.TypeOf(l))
 }
 
+func (l *Location) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(l), s, true, true)
+	if err == nil {
+		*l = val.(Location)
+	}
+	return err
+}
+
 // AllStandardLocations returns all locations that are "normal" for testing purposes. Excludes the likes of Unknown, Benchmark and Pipe
 func (Location) AllStandardLocations() []Location {
 	return []Location{
@@ -1035,6 +1043,8 @@ const (
 	MinParallelChunkCountThreshold = 4 /* minimum number of chunks in parallel for AzCopy to be performant. */
 )
 
+const AdaptiveBlockSizeFlagName = "adaptive-block-size"
+
 // This struct represent a single transfer entry with source and destination details
 // ** DO NOT construct directly. Use cmd.storedObject.ToNewCopyTransfer **
 type CopyTransfer struct {
@@ -1454,8 +1464,9 @@ increasing read_ahead_kb to 8192 for the data disk.`
 const SizePerFileParam = "size-per-file"
 const FileCountParam = "file-count"
 const FileCountDefault = 100
+const SizeDistributionParam = "size-distribution"
 
-// BenchMarkMode enumerates values for Azcopy bench command. Valid values Upload or Download
+// BenchMarkMode enumerates values for Azcopy bench command. Valid values Upload, Download or S2S
 type BenchMarkMode uint8
 
 var EBenchMarkMode = BenchMarkMode(0)
@@ -1464,6 +1475,8 @@ func (BenchMarkMode) Upload() BenchMarkMode { return BenchMarkMode(0) }
 
 func (BenchMarkMode) Download() BenchMarkMode { return BenchMarkMode(1) }
 
+func (BenchMarkMode) S2S() BenchMarkMode { return BenchMarkMode(2) }
+
 func (bm BenchMarkMode) String() string {
 	return enum.StringInt(bm, reflect.TypeOf(bm))
 }
@@ -1514,6 +1527,8 @@ func (EntityType) File()           EntityType { return EntityType(0) }
 func (EntityType) Folder()         EntityType { return EntityType(1) }
 func (EntityType) Symlink()        EntityType { return EntityType(2) }
 func (EntityType) FileProperties() EntityType { return EntityType(3) }
+func (EntityType) Hardlink()       EntityType { return EntityType(4) } // a further name for a file that is already part of the job
+func (EntityType) SpecialFile()    EntityType { return EntityType(5) } // a FIFO, device node or socket, which has no content of its own
 
 func (e EntityType) String() string {
 	return enum.StringInt(e, reflect.TypeOf(e))
@@ -1536,6 +1551,10 @@ func (FolderPropertyOption) AllFoldersExceptRoot() FolderPropertyOption {
 }
 func (FolderPropertyOption) AllFolders() FolderPropertyOption { return FolderPropertyOption(3) }
 
+func (fpo FolderPropertyOption) String() string {
+	return enum.StringInt(fpo, reflect.TypeOf(fpo))
+}
+
 ///////////////////////////////////////////////////////////////////////
 
 var EPreservePermissionsOption = PreservePermissionsOption(0)
@@ -1552,6 +1571,19 @@ func (PreservePermissionsOption) OwnershipAndACLs() PreservePermissionsOption {
 	return PreservePermissionsOption(2)
 }
 
+// POSIXACLsOnly and POSIXOwnershipAndACLs are ACLsOnly and OwnershipAndACLs, for transfers between Azure Files and a Linux
+// file system with no SMB security descriptors, where the permissions are translated to and from POSIX ACLs.
+func (PreservePermissionsOption) POSIXACLsOnly() PreservePermissionsOption {
+	return PreservePermissionsOption(3)
+}
+func (PreservePermissionsOption) POSIXOwnershipAndACLs() PreservePermissionsOption {
+	return PreservePermissionsOption(4)
+}
+
+func (p PreservePermissionsOption) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
 func NewPreservePermissionsOption(preserve, includeOwnership bool, fromTo FromTo) PreservePermissionsOption {
 	if preserve {
 		if fromTo.IsDownload() {
@@ -1572,7 +1604,9 @@ func NewPreservePermissionsOption(preserve, includeOwnership bool, fromTo FromTo
 func (p PreservePermissionsOption) IsTruthy() bool {
 	switch p {
 	case EPreservePermissionsOption.ACLsOnly(),
-		EPreservePermissionsOption.OwnershipAndACLs():
+		EPreservePermissionsOption.OwnershipAndACLs(),
+		EPreservePermissionsOption.POSIXACLsOnly(),
+		EPreservePermissionsOption.POSIXOwnershipAndACLs():
 		return true
 	case EPreservePermissionsOption.None():
 		return false
@@ -1581,6 +1615,26 @@ func (p PreservePermissionsOption) IsTruthy() bool {
 	}
 }
 
+func (p PreservePermissionsOption) IncludesOwnership() bool {
+	return p == EPreservePermissionsOption.OwnershipAndACLs() || p == EPreservePermissionsOption.POSIXOwnershipAndACLs()
+}
+
+func (p PreservePermissionsOption) IsPOSIXMapped() bool {
+	return p == EPreservePermissionsOption.POSIXACLsOnly() || p == EPreservePermissionsOption.POSIXOwnershipAndACLs()
+}
+
+// MapToPOSIX returns the equivalent option that translates the permissions to and from POSIX ACLs.
+func (p PreservePermissionsOption) MapToPOSIX() PreservePermissionsOption {
+	switch p {
+	case EPreservePermissionsOption.ACLsOnly():
+		return EPreservePermissionsOption.POSIXACLsOnly()
+	case EPreservePermissionsOption.OwnershipAndACLs():
+		return EPreservePermissionsOption.POSIXOwnershipAndACLs()
+	default:
+		return p
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 // CpkScopeInfo specifies the name of the encryption scope to use to encrypt the data provided in the request.
@@ -1669,6 +1723,11 @@ func (SetPropertiesFlags) SetTier() SetPropertiesFlags     { return SetPropertie
 func (SetPropertiesFlags) SetMetadata() SetPropertiesFlags { return SetPropertiesFlags(2) }
 func (SetPropertiesFlags) SetBlobTags() SetPropertiesFlags { return SetPropertiesFlags(4) }
 
+// the acl command's changes to ADLS Gen2 ACLs, which are applied recursively. Only one of them is set at a time.
+func (SetPropertiesFlags) SetAccessControl() SetPropertiesFlags    { return SetPropertiesFlags(8) }
+func (SetPropertiesFlags) ModifyAccessControl() SetPropertiesFlags { return SetPropertiesFlags(16) }
+func (SetPropertiesFlags) RemoveAccessControl() SetPropertiesFlags { return SetPropertiesFlags(32) }
+
 // functions to get values (to be used in sde)
 // If Y is inside X then X & Y == Y
 func (op *SetPropertiesFlags) ShouldTransferTier() bool {
@@ -1680,6 +1739,9 @@ func (op *SetPropertiesFlags) ShouldTransferMetaData() bool {
 func (op *SetPropertiesFlags) ShouldTransferBlobTags() bool {
 	return (*op)&ESetPropertiesFlags.SetBlobTags() == ESetPropertiesFlags.SetBlobTags()
 }
+func (op *SetPropertiesFlags) ShouldTransferAccessControl() bool {
+	return (*op)&(ESetPropertiesFlags.SetAccessControl()|ESetPropertiesFlags.ModifyAccessControl()|ESetPropertiesFlags.RemoveAccessControl()) != 0
+}
 
 // //////////////////////////////////////////////////////////////////////////////
 type RehydratePriorityType uint8
@@ -1766,3 +1828,30 @@ func (sht *SymlinkHandlingType) Determine(Follow, Preserve bool) error {
 
 	return nil
 }
+
+////////////////////////////////////////////////////////////////////////////////
+var EHardlinkHandlingType = HardlinkHandlingType(0)
+
+// HardlinkHandlingType controls what happens to files that have more than one name in the source
+type HardlinkHandlingType uint8
+
+func (HardlinkHandlingType) Follow() HardlinkHandlingType   { return HardlinkHandlingType(0) } // every name is transferred as a separate file
+func (HardlinkHandlingType) Preserve() HardlinkHandlingType { return HardlinkHandlingType(1) } // the data is transferred once, the other names as links to it
+
+func (h HardlinkHandlingType) String() string {
+	return enum.StringInt(h, reflect.TypeOf(h))
+}
+
+func (h *HardlinkHandlingType) Parse(s string) error {
+	// allow empty to mean the default
+	if s == "" {
+		*h = EHardlinkHandlingType.Follow()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(h), s, true, true)
+	if err == nil {
+		*h = val.(HardlinkHandlingType)
+	}
+	return err
+}
@@ -0,0 +1,364 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IdentityMapping maps SIDs and AAD object IDs to POSIX uids and gids, and back, for when there's no directory service to do it.
+// It's read from the file named by AZCOPY_SID_MAPPING_FILE, which is either CSV or JSON.
+//
+// The CSV form has one identity per line, "sid,kind,id,name,objectId", where kind is uid or gid, and the name and object ID are optional.
+// The id may be left empty when the name is given, to use the id of the local user or group with that name,
+// so that the same file works on machines that number their users differently.
+// The SID may be left empty when the object ID is given, for identities that only appear in ADLS Gen2 ACLs.
+// A line "sid,domain" gives the SID of the domain, for the domain-relative aliases (DA, DU, etc.) in SDDL.
+// Lines starting with # are comments.
+//
+// The JSON form is {"domainSid": "sid", "identities": [{"sid": "sid", "kind": "uid", "id": 1001, "name": "alice", "objectId": "guid"}]}.
+type IdentityMapping struct {
+	DomainSID string
+
+	uidsBySID map[string]uint32
+	gidsBySID map[string]uint32
+	sidsByUID map[uint32]string
+	sidsByGID map[uint32]string
+
+	uidsByObjectID map[string]uint32
+	gidsByObjectID map[string]uint32
+	objectIDsByUID map[uint32]string
+	objectIDsByGID map[uint32]string
+}
+
+// MappedIdentity is one entry of an identity mapping file.
+type MappedIdentity struct {
+	SID      string  `json:"sid,omitempty"`
+	Kind     string  `json:"kind"` // uid or gid
+	ID       *uint32 `json:"id,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	ObjectID string  `json:"objectId,omitempty"` // the AAD object ID, as used in ADLS Gen2 ACLs
+}
+
+type identityMappingFile struct {
+	DomainSID  string           `json:"domainSid"`
+	Identities []MappedIdentity `json:"identities"`
+}
+
+var identityMappingOnce sync.Once
+var identityMapping *IdentityMapping
+var identityMappingErr error
+
+// GetIdentityMapping returns the mapping in the file named by AZCOPY_SID_MAPPING_FILE, read once per process.
+// It returns nil if the environment variable isn't set.
+func GetIdentityMapping() (*IdentityMapping, error) {
+	identityMappingOnce.Do(func() {
+		path := GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.SIDMappingFile())
+		if path != "" {
+			identityMapping, identityMappingErr = LoadIdentityMapping(path)
+		}
+	})
+
+	return identityMapping, identityMappingErr
+}
+
+// LoadIdentityMapping reads the mapping file at path.
+func LoadIdentityMapping(path string) (*IdentityMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ParseIdentityMapping(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity mapping file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseIdentityMapping reads a mapping in either of the formats described on IdentityMapping.
+func ParseIdentityMapping(r io.Reader) (*IdentityMapping, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var file identityMappingFile
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+	} else if file, err = parseIdentityMappingCSV(data); err != nil {
+		return nil, err
+	}
+
+	m := &IdentityMapping{
+		uidsBySID: map[string]uint32{},
+		gidsBySID: map[string]uint32{},
+		sidsByUID: map[uint32]string{},
+		sidsByGID: map[uint32]string{},
+
+		uidsByObjectID: map[string]uint32{},
+		gidsByObjectID: map[string]uint32{},
+		objectIDsByUID: map[uint32]string{},
+		objectIDsByGID: map[uint32]string{},
+	}
+	if file.DomainSID != "" {
+		m.DomainSID = normalizeMappedSID(file.DomainSID)
+		if !strings.HasPrefix(m.DomainSID, "S-") {
+			return nil, fmt.Errorf("%q is not a domain SID", file.DomainSID)
+		}
+	}
+
+	for k, identity := range file.Identities {
+		if err := m.add(identity); err != nil {
+			return nil, fmt.Errorf("identity %d (%s): %w", k+1, IffString(identity.SID != "", identity.SID, identity.ObjectID), err)
+		}
+	}
+	return m, nil
+}
+
+func parseIdentityMappingCSV(data []byte) (identityMappingFile, error) {
+	file := identityMappingFile{}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return file, nil
+		} else if err != nil {
+			return file, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) == 2 && strings.EqualFold(strings.TrimSpace(record[1]), "domain") {
+			if file.DomainSID != "" {
+				return file, fmt.Errorf("line %d: the domain is given more than once", line)
+			}
+			file.DomainSID = record[0]
+			continue
+		}
+		if len(record) < 3 || len(record) > 5 {
+			return file, fmt.Errorf("line %d: expected sid,kind,id,name,objectId", line)
+		}
+
+		identity := MappedIdentity{SID: record[0], Kind: strings.TrimSpace(record[1])}
+		if id := strings.TrimSpace(record[2]); id != "" {
+			parsed, err := strconv.ParseUint(id, 10, 32)
+			if err != nil {
+				return file, fmt.Errorf("line %d: %q is not a valid id", line, record[2])
+			}
+			parsedID := uint32(parsed)
+			identity.ID = &parsedID
+		}
+		if len(record) >= 4 {
+			identity.Name = strings.TrimSpace(record[3])
+		}
+		if len(record) == 5 {
+			identity.ObjectID = record[4]
+		}
+		file.Identities = append(file.Identities, identity)
+	}
+}
+
+func (m *IdentityMapping) add(identity MappedIdentity) error {
+	sid := normalizeMappedSID(identity.SID)
+	objectID := normalizeMappedObjectID(identity.ObjectID)
+	if sid == "" && objectID == "" {
+		return errors.New("either the SID or the object ID must be given")
+	}
+	if sid != "" && !strings.HasPrefix(sid, "S-") {
+		return fmt.Errorf("%q is not a SID", identity.SID)
+	}
+	if objectID != "" {
+		if _, err := ParseUUID(objectID); err != nil {
+			return fmt.Errorf("%q is not an object ID", identity.ObjectID)
+		}
+	}
+
+	var idsBySID, idsByObjectID map[string]uint32
+	var sidsByID, objectIDsByID map[uint32]string
+	var lookupName func(string) (string, error)
+	switch strings.ToLower(identity.Kind) {
+	case "uid":
+		idsBySID, sidsByID = m.uidsBySID, m.sidsByUID
+		idsByObjectID, objectIDsByID = m.uidsByObjectID, m.objectIDsByUID
+		lookupName = func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}
+	case "gid":
+		idsBySID, sidsByID = m.gidsBySID, m.sidsByGID
+		idsByObjectID, objectIDsByID = m.gidsByObjectID, m.objectIDsByGID
+		lookupName = func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}
+	default:
+		return errors.New("the kind must be uid or gid")
+	}
+
+	var id uint32
+	if identity.ID != nil {
+		id = *identity.ID
+	} else if identity.Name != "" {
+		localID, err := lookupName(identity.Name)
+		if err != nil {
+			return err
+		}
+		parsed, err := strconv.ParseUint(localID, 10, 32)
+		if err != nil {
+			return fmt.Errorf("the local id of %s (%s) isn't numeric", identity.Name, localID)
+		}
+		id = uint32(parsed)
+	} else {
+		return errors.New("either the id or the name must be given")
+	}
+
+	kind := strings.ToLower(identity.Kind)
+	if sid != "" {
+		if err := addMappedID(idsBySID, sidsByID, sid, kind, id); err != nil {
+			return err
+		}
+	}
+	if objectID != "" {
+		if err := addMappedID(idsByObjectID, objectIDsByID, objectID, kind, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addMappedID(idsByName map[string]uint32, namesByID map[uint32]string, name, kind string, id uint32) error {
+	if _, ok := idsByName[name]; ok {
+		return fmt.Errorf("%s is mapped more than once", name)
+	}
+	if _, ok := namesByID[id]; ok {
+		return fmt.Errorf("%s %d is mapped more than once", kind, id)
+	}
+
+	idsByName[name] = id
+	namesByID[id] = name
+	return nil
+}
+
+func normalizeMappedSID(sid string) string {
+	return strings.ToUpper(strings.TrimSpace(sid))
+}
+
+func normalizeMappedObjectID(objectID string) string {
+	return strings.ToLower(strings.TrimSpace(objectID))
+}
+
+// The lookups are safe to call on a nil mapping, which maps nothing.
+
+// UIDForSID returns the uid mapped to the numeric SID.
+func (m *IdentityMapping) UIDForSID(sid string) (uint32, bool) {
+	if m == nil {
+		return 0, false
+	}
+	uid, ok := m.uidsBySID[normalizeMappedSID(sid)]
+	return uid, ok
+}
+
+// GIDForSID returns the gid mapped to the numeric SID.
+func (m *IdentityMapping) GIDForSID(sid string) (uint32, bool) {
+	if m == nil {
+		return 0, false
+	}
+	gid, ok := m.gidsBySID[normalizeMappedSID(sid)]
+	return gid, ok
+}
+
+// SIDForUID returns the numeric SID mapped to the uid.
+func (m *IdentityMapping) SIDForUID(uid uint32) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	sid, ok := m.sidsByUID[uid]
+	return sid, ok
+}
+
+// SIDForGID returns the numeric SID mapped to the gid.
+func (m *IdentityMapping) SIDForGID(gid uint32) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	sid, ok := m.sidsByGID[gid]
+	return sid, ok
+}
+
+// UIDForObjectID returns the uid mapped to the AAD object ID.
+func (m *IdentityMapping) UIDForObjectID(objectID string) (uint32, bool) {
+	if m == nil {
+		return 0, false
+	}
+	uid, ok := m.uidsByObjectID[normalizeMappedObjectID(objectID)]
+	return uid, ok
+}
+
+// GIDForObjectID returns the gid mapped to the AAD object ID.
+func (m *IdentityMapping) GIDForObjectID(objectID string) (uint32, bool) {
+	if m == nil {
+		return 0, false
+	}
+	gid, ok := m.gidsByObjectID[normalizeMappedObjectID(objectID)]
+	return gid, ok
+}
+
+// ObjectIDForUID returns the AAD object ID mapped to the uid.
+func (m *IdentityMapping) ObjectIDForUID(uid uint32) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	objectID, ok := m.objectIDsByUID[uid]
+	return objectID, ok
+}
+
+// ObjectIDForGID returns the AAD object ID mapped to the gid.
+func (m *IdentityMapping) ObjectIDForGID(gid uint32) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	objectID, ok := m.objectIDsByGID[gid]
+	return objectID, ok
+}
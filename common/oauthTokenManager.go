@@ -32,6 +32,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -440,6 +441,137 @@ func (credInfo *OAuthTokenInfo) GetNewTokenFromCert(ctx context.Context) (*adal.
 	}
 }
 
+// workloadIdentityLoginNoUOTM exchanges the federated token in tokenFile for an AAD token, using the client credentials flow
+// with a JWT bearer assertion. See https://learn.microsoft.com/en-us/azure/active-directory/develop/workload-identity-federation
+func workloadIdentityLoginNoUOTM(ctx context.Context, tenantID, activeDirectoryEndpoint, applicationID, tokenFile, resource string) (*OAuthTokenInfo, error) {
+	if tenantID == "" || tenantID == DefaultTenantID {
+		return nil, errors.New("workload identity federation requires the tenant ID of the application")
+	}
+
+	if activeDirectoryEndpoint == "" {
+		activeDirectoryEndpoint = DefaultActiveDirectoryEndpoint
+	}
+
+	if applicationID == "" {
+		return nil, errors.New("workload identity federation requires the client ID of the application")
+	}
+
+	if tokenFile == "" {
+		return nil, errors.New("workload identity federation requires the path of the federated token file")
+	}
+
+	// The token file is rotated by whoever issues it (e.g. the kubelet), so it must be read afresh every time.
+	assertion, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read federated token file: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("client_id", applicationID)
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", strings.TrimSuffix(resource, "/")+"/.default")
+
+	tokenURL := strings.TrimSuffix(activeDirectoryEndpoint, "/") + "/" + tenantID + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := workloadIdentityHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange federated token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to exchange federated token, status code: %v, response: %s", resp.StatusCode, string(b))
+	}
+
+	var result struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(ByteSliceExtension{ByteSlice: b}.RemoveBOM(), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %v", err)
+	}
+
+	expiresIn, err := result.ExpiresIn.Int64()
+	if err != nil || result.AccessToken == "" {
+		return nil, errors.New("invalid token returned for federated token exchange")
+	}
+
+	return &OAuthTokenInfo{
+		Token: adal.Token{
+			AccessToken: result.AccessToken,
+			ExpiresIn:   result.ExpiresIn,
+			ExpiresOn:   json.Number(strconv.FormatInt(time.Now().Add(time.Duration(expiresIn)*time.Second).Unix(), 10)),
+			NotBefore:   "0",
+			Resource:    resource,
+			Type:        result.TokenType,
+		},
+		Tenant:                  tenantID,
+		ActiveDirectoryEndpoint: activeDirectoryEndpoint,
+		ApplicationID:           applicationID,
+		WorkloadIdentity:        true,
+		WorkloadIdentityInfo: WorkloadIdentityInfo{
+			FederatedTokenFile: tokenFile,
+		},
+	}, nil
+}
+
+var workloadIdentityHTTPClient = newAzcopyHTTPClient()
+
+// WorkloadIdentityLogin non-interactively logs in by exchanging a federated token (e.g. from AZURE_FEDERATED_TOKEN_FILE)
+// for an AAD token. persist indicates whether to cache the token on local disk.
+func (uotm *UserOAuthTokenManager) WorkloadIdentityLogin(ctx context.Context, tenantID, activeDirectoryEndpoint, applicationID, tokenFile string, persist bool) (*OAuthTokenInfo, error) {
+	if tokenFile != "" {
+		// persist an absolute path, so that refreshing works regardless of the working directory
+		if abs, err := filepath.Abs(tokenFile); err == nil {
+			tokenFile = abs
+		}
+	}
+
+	oAuthTokenInfo, err := workloadIdentityLoginNoUOTM(ctx, tenantID, activeDirectoryEndpoint, applicationID, tokenFile, Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	uotm.stashedInfo = oAuthTokenInfo
+	if persist {
+		err = uotm.credCache.SaveToken(*oAuthTokenInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return oAuthTokenInfo, nil
+}
+
+// GetNewTokenFromWorkloadIdentity refreshes a token by re-reading the federated token file and exchanging it again.
+// No refresh token is issued for this flow.
+func (credInfo *OAuthTokenInfo) GetNewTokenFromWorkloadIdentity(ctx context.Context) (*adal.Token, error) {
+	targetResource := Resource
+	if credInfo.Token.Resource != "" && credInfo.Token.Resource != targetResource {
+		targetResource = credInfo.Token.Resource
+	}
+
+	tokenInfo, err := workloadIdentityLoginNoUOTM(ctx, credInfo.Tenant, credInfo.ActiveDirectoryEndpoint, credInfo.ApplicationID, credInfo.WorkloadIdentityInfo.FederatedTokenFile, targetResource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenInfo.Token, nil
+}
+
 // UserLogin interactively logins in with specified tenantID and activeDirectoryEndpoint, persist indicates whether to
 // cache the token on local disk.
 func (uotm *UserOAuthTokenManager) UserLogin(tenantID, activeDirectoryEndpoint string, persist bool) (*OAuthTokenInfo, error) {
@@ -628,6 +760,10 @@ type OAuthTokenInfo struct {
 	IdentityInfo            IdentityInfo
 	ServicePrincipalName    bool `json:"_spn"`
 	SPNInfo                 SPNInfo
+	CredentialHelper        bool `json:"_credential_helper"`
+	CredentialHelperInfo    CredentialHelperInfo
+	WorkloadIdentity        bool `json:"_workload_identity"`
+	WorkloadIdentityInfo    WorkloadIdentityInfo
 	// Note: ClientID should be only used for internal integrations through env var with refresh token.
 	// It indicates the Application ID assigned to your app when you registered it with Azure AD.
 	// In this case AzCopy refresh token on behalf of caller.
@@ -652,6 +788,12 @@ type SPNInfo struct {
 	CertPath string `json:"_spn_cert_path"`
 }
 
+// WorkloadIdentityInfo contains info for workload identity federation, where a token issued by an external identity provider
+// (such as a Kubernetes service account token, or a CI system's OIDC token) is exchanged for an AAD token.
+type WorkloadIdentityInfo struct {
+	FederatedTokenFile string `json:"_federated_token_file"`
+}
+
 // Validate validates identity info, at most only one of clientID, objectID or MSI resource ID could be set.
 func (identityInfo *IdentityInfo) Validate() error {
 	v := make(map[string]bool, 3)
@@ -680,6 +822,14 @@ func (credInfo *OAuthTokenInfo) Refresh(ctx context.Context) (*adal.Token, error
 		return credInfo.GetNewTokenFromMSI(ctx)
 	}
 
+	if credInfo.CredentialHelper {
+		return credInfo.GetNewTokenFromCredentialHelper(ctx)
+	}
+
+	if credInfo.WorkloadIdentity {
+		return credInfo.GetNewTokenFromWorkloadIdentity(ctx)
+	}
+
 	if credInfo.ServicePrincipalName {
 		if credInfo.SPNInfo.CertPath != "" {
 			return credInfo.GetNewTokenFromCert(ctx)
@@ -0,0 +1,248 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A remote is a named endpoint, so that (rclone-style) "prod:container/path" can be typed in place of
+// "https://account.blob.core.windows.net/container/path?sas". Remotes are defined in the config file (see config.go).
+// The commands keep "name:path" as it was typed. It is resolved when its location is inferred (which honours an explicit type)
+// and when it becomes a ResourceString, which fetches the SAS and remembers the remote, so that logs and output can show the
+// remote's name rather than the secret-bearing URL.
+
+// ConfigRemote is how a remote is defined in the config file.
+type ConfigRemote struct {
+	URL string `yaml:"url"`
+	// Type is the location type, e.g. Blob or BlobFS. It is only needed when it can't be inferred from the URL (e.g. for emulators).
+	Type string `yaml:"type"`
+	// Credential refers to where a SAS for the remote comes from, so that the SAS itself need not be in the config file:
+	// "env:NAME" reads it from an environment variable, "file:PATH" from a file, and "helper" obtains it from the credential helper.
+	// When empty, the remote is accessed with whatever credential would be used for its URL (e.g. an Azure AD login).
+	Credential string `yaml:"credential,omitempty"`
+}
+
+const (
+	remoteCredentialEnvPrefix  = "env:"
+	remoteCredentialFilePrefix = "file:"
+	remoteCredentialHelper     = "helper"
+)
+
+// Location returns the remote's explicit location type, or Unknown if it should be inferred from its URL.
+func (r ConfigRemote) Location() (Location, error) {
+	if r.Type == "" {
+		return ELocation.Unknown(), nil
+	}
+
+	var loc Location
+	if err := loc.Parse(r.Type); err != nil {
+		return ELocation.Unknown(), fmt.Errorf("invalid type %q for remote: %w", r.Type, err)
+	}
+	return loc, nil
+}
+
+// SAS returns the SAS that the remote's credential reference refers to, without a leading '?'.
+func (r ConfigRemote) SAS(ctx context.Context) (string, error) {
+	var sas string
+	switch ref := r.Credential; {
+	case ref == "":
+		return "", nil
+	case strings.HasPrefix(ref, remoteCredentialEnvPrefix):
+		name := strings.TrimPrefix(ref, remoteCredentialEnvPrefix)
+		sas = os.Getenv(name)
+		if sas == "" {
+			return "", fmt.Errorf("environment variable %s, which holds the SAS for the remote, is not set", name)
+		}
+	case strings.HasPrefix(ref, remoteCredentialFilePrefix):
+		b, err := os.ReadFile(strings.TrimPrefix(ref, remoteCredentialFilePrefix))
+		if err != nil {
+			return "", fmt.Errorf("failed to read the SAS for the remote: %w", err)
+		}
+		sas = strings.TrimSpace(string(b))
+	case ref == remoteCredentialHelper:
+		helper := GetCredentialHelperFromEnvVar()
+		if helper.IsEmpty() {
+			return "", fmt.Errorf("the remote's credential comes from the credential helper, but %s is not set", EEnvironmentVariable.CredentialHelper().Name)
+		}
+		resp, err := helper.Get(ctx, CredentialHelperRequest{Kind: ECredentialHelperKind.SAS(), Resource: strings.TrimRight(r.URL, "/")})
+		if err != nil {
+			return "", err
+		}
+		sas = resp.SAS
+	default:
+		return "", fmt.Errorf("invalid credential reference %q; expected env:NAME, file:PATH or helper", ref)
+	}
+
+	return strings.TrimPrefix(sas, "?"), nil
+}
+
+// Remote is a remote that has been resolved for use by the current command.
+type Remote struct {
+	Name string
+	// URL is the remote's endpoint, without any SAS or trailing '/'.
+	URL      string
+	Location Location
+
+	credential string
+}
+
+// SAS returns the SAS for the remote, without a leading '?', or "" if it has no credential reference.
+func (r Remote) SAS(ctx context.Context) (string, error) {
+	sas, err := ConfigRemote{URL: r.URL, Credential: r.credential}.SAS(ctx)
+	if err != nil {
+		return "", fmt.Errorf("remote %q: %w", r.Name, err)
+	}
+	return sas, nil
+}
+
+var configuredRemotes = struct {
+	sync.RWMutex
+	byName map[string]ConfigRemote
+}{byName: make(map[string]ConfigRemote)}
+
+// SetConfiguredRemotes sets the remotes that "name:path" references can refer to, i.e. those of the selected profile.
+func SetConfiguredRemotes(remotes map[string]ConfigRemote) {
+	configuredRemotes.Lock()
+	defer configuredRemotes.Unlock()
+	configuredRemotes.byName = make(map[string]ConfigRemote, len(remotes))
+	for name, r := range remotes {
+		configuredRemotes.byName[name] = r
+	}
+}
+
+// ResolveRemoteReference resolves raw, if it is "name:path" where name is a configured remote, into the URL it refers to
+// (without any SAS), and registers the remote so that the URL can be displayed as raw again.
+// ok is false, and raw should be used as-is, if raw does not refer to a remote.
+func ResolveRemoteReference(raw string) (resolved string, remote Remote, ok bool, err error) {
+	name, path, isReference := SplitRemoteReference(raw)
+	if !isReference {
+		return raw, Remote{}, false, nil
+	}
+	configuredRemotes.RLock()
+	config, found := configuredRemotes.byName[name]
+	configuredRemotes.RUnlock()
+	if !found {
+		return raw, Remote{}, false, nil // e.g. a local file name that happens to contain a colon
+	}
+
+	loc, err := config.Location()
+	if err != nil {
+		return raw, Remote{}, false, fmt.Errorf("remote %q: %w", name, err)
+	}
+	remote = Remote{Name: name, URL: strings.TrimRight(config.URL, "/"), Location: loc, credential: config.Credential}
+	RegisterRemote(remote)
+
+	resolved = remote.URL
+	if path != "" {
+		resolved += "/" + path
+	}
+	return resolved, remote, true, nil
+}
+
+// remote names are at least two characters, so that Windows drive letters (C:) are never mistaken for them
+var remoteReferenceRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_.-]+):(.*)$`)
+
+// SplitRemoteReference splits "name:path" into its parts. It does not check that the remote exists.
+func SplitRemoteReference(raw string) (name, path string, ok bool) {
+	m := remoteReferenceRegex.FindStringSubmatch(raw)
+	if m == nil || strings.HasPrefix(m[2], "//") { // a URL, not a remote
+		return "", "", false
+	}
+	return m[1], strings.TrimLeft(m[2], "/"), true
+}
+
+var resolvedRemotes = struct {
+	sync.RWMutex
+	byName map[string]Remote
+}{byName: make(map[string]Remote)}
+
+// RegisterRemote records that r is in use, so that URLs under it can be recognized later.
+func RegisterRemote(r Remote) {
+	if r.URL == "" {
+		return
+	}
+	resolvedRemotes.Lock()
+	defer resolvedRemotes.Unlock()
+	resolvedRemotes.byName[r.Name] = r
+}
+
+// RemoteForURL returns the registered remote that raw (a URL, possibly with a query) is under, and the path below it.
+// If several match, the one with the longest URL wins.
+func RemoteForURL(raw string) (remote Remote, path string, ok bool) {
+	resolvedRemotes.RLock()
+	defer resolvedRemotes.RUnlock()
+
+	names := make([]string, 0, len(resolvedRemotes.byName))
+	for name := range resolvedRemotes.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names) // so ties are broken the same way every time
+
+	for _, name := range names {
+		r := resolvedRemotes.byName[name]
+		if !strings.HasPrefix(strings.ToLower(raw), strings.ToLower(r.URL)) {
+			continue
+		}
+
+		rest := raw[len(r.URL):]
+		if rest != "" && rest[0] != '/' && rest[0] != '?' {
+			continue // e.g. https://acct.blob.core.windows.net/c2 is not under https://acct.blob.core.windows.net/c
+		}
+		if ok && len(r.URL) <= len(remote.URL) {
+			continue
+		}
+
+		if i := strings.Index(rest, "?"); i >= 0 {
+			rest = rest[:i]
+		}
+		remote, path, ok = r, strings.TrimPrefix(rest, "/"), true
+	}
+	return
+}
+
+// DisplayStringForURL returns how raw should be shown to the user: as "name:path" if it is under a remote,
+// otherwise as the URL with any signature redacted.
+func DisplayStringForURL(raw string) string {
+	if remote, path, ok := RemoteForURL(raw); ok {
+		return remote.Name + ":" + path
+	}
+	return URLStringExtension(raw).RedactSecretQueryParamForLogging()
+}
+
+// DisplayValue returns how the resource should be shown in logs and output, without any SAS.
+// A resource that was given as (or is under) a remote is shown as "name:path".
+func (r ResourceString) DisplayValue() string {
+	if r.Remote != "" {
+		resolvedRemotes.RLock()
+		remote, ok := resolvedRemotes.byName[r.Remote]
+		resolvedRemotes.RUnlock()
+		if ok && strings.HasPrefix(strings.ToLower(r.Value), strings.ToLower(remote.URL)) {
+			return remote.Name + ":" + strings.TrimPrefix(r.Value[len(remote.URL):], "/")
+		}
+	}
+	return DisplayStringForURL(r.Value)
+}
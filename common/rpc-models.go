@@ -48,6 +48,8 @@ type ResourceString struct {
 	Value      string
 	SAS        string // SAS should NOT be persisted in the plan files (both for security reasons, and because, at the time of any resume, it may be stale anyway. Resume requests fresh SAS on command line)
 	ExtraQuery string
+	// Remote is the name of the remote (see remotes.go) that the resource was given as, if any, so that it can be displayed that way.
+	Remote string
 }
 
 func (r ResourceString) Clone() ResourceString {
@@ -145,6 +147,7 @@ type CopyJobPartOrderRequest struct {
 	PreserveSMBPermissions         PreservePermissionsOption
 	PreserveSMBInfo                bool
 	PreservePOSIXProperties        bool
+	PreserveXattrs                 bool
 	S2SGetPropertiesInBackend      bool
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
@@ -159,6 +162,10 @@ type CopyJobPartOrderRequest struct {
 	// This may not always be the case (for instance, if we opt to use multiple OAuth tokens). At that point, this will likely be it's own CredentialInfo.
 	S2SSourceCredentialType CredentialType // Only Anonymous and OAuth will really be used in response to this, but S3 and GCP will come along too...
 	FileAttributes FileTransferAttributes
+
+	// IsBenchmark is set for the downloads and S2S copies that the bench command measures, which, unlike its uploads,
+	// can't be recognized from their FromTo. Like the credentials, it's not saved in the plan, since benchmarks can't be resumed.
+	IsBenchmark bool
 }
 
 // CredentialInfo contains essential credential info which need be transited between modules,
@@ -222,10 +229,12 @@ type BlobTransferAttributes struct {
 	PutMd5                   bool                  // when uploading, should we create and PUT Content-MD5 hashes
 	MD5ValidationOption      HashValidationOption  // when downloading, how strictly should we validate MD5 hashes?
 	BlockSizeInBytes         int64                 // when uploading/downloading/copying, specify the size of each chunk
+	AdaptiveBlockSize        bool                  // when BlockSizeInBytes is zero, choose the size for each file as it starts, from what has been observed so far
 	DeleteSnapshotsOption    DeleteSnapshotsOption // when deleting, specify what to do with the snapshots
 	BlobTagsString           string                // when user explicitly provides blob tags
 	PermanentDeleteOption    PermanentDeleteOption // Permanently deletes soft-deleted snapshots when indicated by user
 	RehydratePriority        RehydratePriorityType // rehydrate priority of blob
+	AccessControlList        string                // the ACL entries that the acl command sets, modifies or removes
 }
 
 // This struct represents the optional attribute for file request header
@@ -306,6 +315,32 @@ type ListJobSummaryResponse struct {
 
 	PerformanceAdvice []PerformanceAdvice
 	IsCleanupJob      bool
+
+	// Latency distributions of the requests made by the job, one per type of operation.
+	// Like the network stats above, these are only available in the process running the job, and they are only filled in once the job is done.
+	RequestLatencies []RequestLatencySummary `json:",omitempty"`
+}
+
+// RequestLatencySummary describes the end-to-end latencies of one type of request (e.g. PutBlock), from sending the request
+// to receiving the response headers. Each try of a retried request counts separately.
+type RequestLatencySummary struct {
+	Operation       string
+	Count           int64 `json:",string"`
+	P50Milliseconds int64 `json:",string"`
+	P95Milliseconds int64 `json:",string"`
+	P99Milliseconds int64 `json:",string"`
+	MaxMilliseconds int64 `json:",string"`
+	SlowestRequests []SlowRequest
+}
+
+// SlowRequest identifies one of the slowest requests of its type, so that it can be looked up in the logs of the service
+type SlowRequest struct {
+	Milliseconds     int64 `json:",string"`
+	StartTime        time.Time
+	StatusCode       int    `json:",string"` // zero if there was no response
+	ClientRequestID  string // x-ms-client-request-id
+	ServiceRequestID string // x-ms-request-id, empty if there was no response
+	URL              string // with any SAS redacted
 }
 
 // wraps the standard ListJobSummaryResponse with sync-specific stats
@@ -0,0 +1,173 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JeffreyRichter/enum/enum"
+)
+
+// SAS tokens are captured in transfer URLs when a job is planned, and are not persisted in the job plan.
+// A job that outlives its SAS therefore fails part-way through. To avoid that, the front end registers a SASRenewer for
+// each SAS it hands to the STE, and the STE's pipelines ask for the current SAS (via RenewSAS) before every try.
+
+var ESASRenewalMode = SASRenewalMode(0)
+
+// SASRenewalMode controls how AzCopy obtains a replacement for a SAS that is about to expire.
+type SASRenewalMode uint8
+
+// None only warns about SAS tokens that will expire soon.
+func (SASRenewalMode) None() SASRenewalMode { return SASRenewalMode(0) }
+
+// CredentialHelper asks the credential helper (AZCOPY_CREDENTIAL_HELPER) for a replacement SAS.
+func (SASRenewalMode) CredentialHelper() SASRenewalMode { return SASRenewalMode(1) }
+
+// UserDelegation signs a user delegation SAS with the same scope and permissions, using the current OAuth session. Blob only.
+func (SASRenewalMode) UserDelegation() SASRenewalMode { return SASRenewalMode(2) }
+
+func (m SASRenewalMode) String() string {
+	return enum.StringInt(m, reflect.TypeOf(m))
+}
+
+func (m *SASRenewalMode) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(m), s, true, true)
+	if err == nil {
+		*m = val.(SASRenewalMode)
+	}
+	return err
+}
+
+// SASRenewalThreshold is how long before its expiry a SAS gets renewed. It matches the threshold used for OAuth tokens.
+var SASRenewalThreshold = DefaultTokenExpiryWithinThreshold
+
+// SASRenewer returns a replacement SAS, without a leading '?'.
+type SASRenewer func(ctx context.Context) (string, error)
+
+type sasRenewal struct {
+	lock    sync.Mutex
+	renew   SASRenewer
+	current string
+	expiry  time.Time
+	failed  bool // so that a failing renewer is only reported once
+}
+
+var sasRenewals = struct {
+	sync.RWMutex
+	bySignature map[string]*sasRenewal
+}{bySignature: make(map[string]*sasRenewal)}
+
+// SASExpiry returns the signed expiry (se=) of a SAS, or the zero time if it has none.
+func SASExpiry(sas string) time.Time {
+	values, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+	if err != nil {
+		return time.Time{}
+	}
+
+	se := values.Get("se")
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z", "2006-01-02"} {
+		if t, err := time.Parse(layout, se); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// sasSignature returns the signature (sig=) of a SAS, which uniquely identifies it.
+func sasSignature(sas string) string {
+	values, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+	if err != nil {
+		return ""
+	}
+	return values.Get("sig")
+}
+
+// RegisterSASRenewer arranges for sas to be swapped for the output of renew when it nears expiry.
+// Registering the same SAS twice keeps the first renewer.
+func RegisterSASRenewer(sas string, renew SASRenewer) {
+	sig := sasSignature(sas)
+	if sig == "" {
+		return
+	}
+
+	sasRenewals.Lock()
+	defer sasRenewals.Unlock()
+	if _, ok := sasRenewals.bySignature[sig]; !ok {
+		sasRenewals.bySignature[sig] = &sasRenewal{renew: renew, current: strings.TrimPrefix(sas, "?"), expiry: SASExpiry(sas)}
+	}
+}
+
+// RenewSAS returns the SAS that should be used in place of sas. ok is false if no renewer is registered for sas,
+// in which case it must be used as-is. A renewal failure is returned once; after that the latest SAS is returned silently,
+// leaving the service to have the final say.
+func RenewSAS(ctx context.Context, sas string) (renewed string, ok bool, err error) {
+	sig := sasSignature(sas)
+	if sig == "" {
+		return "", false, nil
+	}
+
+	sasRenewals.RLock()
+	r, ok := sasRenewals.bySignature[sig]
+	sasRenewals.RUnlock()
+	if !ok {
+		return "", false, nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.expiry.IsZero() || time.Until(r.expiry) > SASRenewalThreshold {
+		return r.current, true, nil
+	}
+
+	next, err := r.renew(ctx)
+	if err != nil {
+		if r.failed {
+			return r.current, true, nil
+		}
+		r.failed = true
+		return r.current, true, fmt.Errorf("failed to renew a SAS that expires at %v: %w", r.expiry.Format(time.RFC3339), err)
+	}
+
+	next = strings.TrimPrefix(next, "?")
+	if next != r.current {
+		r.current = next
+		r.expiry = SASExpiry(next)
+		r.failed = false
+
+		// A URL may already carry the renewed SAS (e.g. one built after renewal), so it must resolve to the same renewal.
+		if newSig := sasSignature(next); newSig != "" {
+			sasRenewals.Lock()
+			if _, exists := sasRenewals.bySignature[newSig]; !exists {
+				sasRenewals.bySignature[newSig] = r
+			}
+			sasRenewals.Unlock()
+		}
+	}
+
+	return r.current, true, nil
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io"
+	"os"
+)
+
+// FileDataRange is a part of a sparse file that holds data, from Start up to (but not including) End
+type FileDataRange struct {
+	Start int64
+	End   int64
+}
+
+// sparseFileReader reads a local file whose holes are known, without touching the disk for the parts that are holes.
+// The bytes in a hole read as zeros, as they would from the file itself.
+type sparseFileReader struct {
+	*os.File
+	size       int64
+	dataRanges []FileDataRange // in order
+}
+
+// NewSparseFileReader returns a reader for the file that skips its holes, or the file itself if it has none (or if
+// the OS or file system can't tell us where they are).
+func NewSparseFileReader(file *os.File) CloseableReaderAt {
+	fi, err := file.Stat()
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return file
+	}
+
+	dataRanges, ok := getFileDataRanges(file, fi.Size())
+	if !ok || (len(dataRanges) == 1 && dataRanges[0].Start == 0 && dataRanges[0].End >= fi.Size()) {
+		return file // not sparse
+	}
+
+	return &sparseFileReader{File: file, size: fi.Size(), dataRanges: dataRanges}
+}
+
+func (r *sparseFileReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+		err = io.EOF
+	}
+	n = int(end - off)
+
+	for i := 0; i < n; i++ {
+		p[i] = 0
+	}
+
+	for _, d := range r.dataRanges {
+		if d.End <= off {
+			continue
+		}
+		if d.Start >= end {
+			break
+		}
+
+		readStart, readEnd := d.Start, d.End
+		if readStart < off {
+			readStart = off
+		}
+		if readEnd > end {
+			readEnd = end
+		}
+		if _, readErr := r.File.ReadAt(p[readStart-off:readEnd-off], readStart); readErr != nil {
+			return 0, readErr
+		}
+	}
+
+	return n, err
+}
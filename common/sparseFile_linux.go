@@ -0,0 +1,66 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// getFileDataRanges uses SEEK_DATA and SEEK_HOLE to find where the file's data is
+func getFileDataRanges(file *os.File, size int64) ([]FileDataRange, bool) {
+	fd := int(file.Fd())
+	dataRanges := make([]FileDataRange, 0)
+
+	for offset := int64(0); offset < size; {
+		start, err := unix.Seek(fd, offset, unix.SEEK_DATA)
+		if err == unix.ENXIO {
+			break // no more data, the rest is a hole
+		} else if err != nil {
+			return nil, false // e.g. EINVAL, where the file system doesn't support it
+		}
+
+		end, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+		if err != nil {
+			return nil, false
+		}
+		if end > size {
+			end = size
+		}
+
+		dataRanges = append(dataRanges, FileDataRange{Start: start, End: end})
+		offset = end
+	}
+
+	// put the file offset back, for anyone reading it sequentially
+	if _, err := unix.Seek(fd, 0, unix.SEEK_SET); err != nil {
+		return nil, false
+	}
+
+	return dataRanges, true
+}
+
+// PunchHole deallocates the given range of the file, so that it reads as zeros without taking up space on disk.
+// The size of the file is unchanged.
+func PunchHole(file *os.File, offset int64, length int64) error {
+	return unix.Fallocate(int(file.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, offset, length)
+}
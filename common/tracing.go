@@ -0,0 +1,200 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// When tracing is enabled (with AZCOPY_OTEL_TRACE_EXPORT) there is an OpenTelemetry span for each job, job part, transfer and HTTP request,
+// exported with OTLP to a collector or a file. When it is not, the tracer is OpenTelemetry's no-op one, so creating spans costs next to nothing.
+
+const tracerName = "github.com/Azure/azure-storage-azcopy"
+
+// how long we wait for spans to be exported, when flushing them at the end of a job
+const tracingFlushTimeout = 10 * time.Second
+
+var tracerProvider *sdktrace.TracerProvider // nil if tracing is not enabled
+
+// InitTracing enables tracing, if exportTo is not empty. An http:// or https:// URL is taken to be an OTLP/HTTP collector
+// (with /v1/traces as the default path), and anything else to be a file that the spans are appended to, in the OTLP JSON format.
+func InitTracing(exportTo string) error {
+	if exportTo == "" {
+		return nil
+	}
+
+	var client otlptrace.Client
+	if u, err := url.Parse(exportTo); err == nil && (strings.EqualFold(u.Scheme, "http") || strings.EqualFold(u.Scheme, "https")) {
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+		if u.Path != "" && u.Path != "/" {
+			options = append(options, otlptracehttp.WithURLPath(u.Path))
+		}
+		if strings.EqualFold(u.Scheme, "http") {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		client = otlptracehttp.NewClient(options...)
+	} else {
+		client = &otlpFileClient{path: exportTo}
+	}
+
+	exporter, err := otlptrace.New(context.Background(), client)
+	if err != nil {
+		return fmt.Errorf("cannot export traces to %s: %w", exportTo, err)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String("azcopy"),
+		semconv.ServiceVersionKey.String(AzcopyVersion))
+	tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// failures to export shouldn't clutter the console, which is where OpenTelemetry reports them by default
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		if AzcopyCurrentJobLogger != nil {
+			AzcopyCurrentJobLogger.Log(pipeline.LogWarning, "Failed to export trace spans: "+err.Error())
+		}
+	}))
+	return nil
+}
+
+// Tracer returns the tracer that all of AzCopy's spans come from
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName, trace.WithInstrumentationVersion(AzcopyVersion))
+}
+
+// FlushTracing exports all the spans that have ended, so that they are not lost if the process exits soon after.
+// It's called when a job is done, rather than at exit, so that a job's spans are exported before the job is reported as done.
+func FlushTracing() error {
+	if tracerProvider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	return tracerProvider.ForceFlush(ctx)
+}
+
+// WithTraceParent returns a context whose span is the one identified by a W3C traceparent (and optionally tracestate) value.
+// That lets the spans of a job appear as children of the span, in some other process, that ran AzCopy.
+// The context is returned unchanged if traceParent is empty or invalid.
+func WithTraceParent(ctx context.Context, traceParent, traceState string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	if traceState != "" {
+		carrier["tracestate"] = traceState
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// otlpFileClient writes spans to a file instead of sending them to a collector. Each batch is written as one line,
+// holding the JSON form of an OTLP export request, which is the format that the collector's otlpjsonfile receiver reads.
+type otlpFileClient struct {
+	path string
+	lock sync.Mutex
+	file *os.File
+}
+
+func (c *otlpFileClient) Start(ctx context.Context) error {
+	f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	c.file = f
+	return nil
+}
+
+func (c *otlpFileClient) Stop(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.file.Close()
+}
+
+func (c *otlpFileClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	line, err := marshalOTLPJSON(&collectortracepb.ExportTraceServiceRequest{ResourceSpans: protoSpans})
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, err = c.file.Write(append(line, '\n'))
+	return err
+}
+
+// marshalOTLPJSON differs from the standard JSON mapping of protobuf in the two ways that OTLP requires:
+// enums are written as numbers, and trace and span IDs are written in hex rather than base64
+func marshalOTLPJSON(request *collectortracepb.ExportTraceServiceRequest) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var doc interface{}
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	hexEncodeIDs(doc)
+	return json.Marshal(doc)
+}
+
+func hexEncodeIDs(v interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if s, ok := e.(string); ok && (k == "traceId" || k == "spanId" || k == "parentSpanId") {
+				if raw, err := base64.StdEncoding.DecodeString(s); err == nil {
+					x[k] = hex.EncodeToString(raw)
+				}
+			} else {
+				hexEncodeIDs(e)
+			}
+		}
+	case []interface{}:
+		for _, e := range x {
+			hexEncodeIDs(e)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package common
 
 import (
 	"github.com/Azure/azure-storage-blob-go/azblob"
-	"os"
 	"strconv"
 	"time"
 )
@@ -21,8 +20,11 @@ const ( // POSIX property metadata
 	POSIXATimeMeta         = "posix_atime"
 	POSIXFolderMeta        = "hdi_isfolder" // todo: read & use these
 	POSIXSymlinkMeta       = "is_symlink"
+	POSIXHardlinkMeta      = "posix_hardlink" // the (escaped) path of the data blob, relative to the link's folder. Not a stat property, so not in AllLinuxProperties
 	POSIXOwnerMeta         = "posix_owner"
 	POSIXGroupMeta         = "posix_group"
+	POSIXOwnerSIDMeta      = "posix_owner_sid" // only with an identity mapping file
+	POSIXGroupSIDMeta      = "posix_group_sid"
 	POSIXModeMeta          = "permissions"
 	POSIXModTimeMeta       = "modtime"
 	LINUXAttributeMeta     = "linux_attribute"
@@ -45,6 +47,8 @@ var AllLinuxProperties = []string{
 	POSIXSymlinkMeta,
 	POSIXOwnerMeta,
 	POSIXGroupMeta,
+	POSIXOwnerSIDMeta,
+	POSIXGroupSIDMeta,
 	POSIXModeMeta,
 	LINUXStatxMaskMeta,
 	LINUXAttributeMaskMeta,
@@ -225,6 +229,10 @@ func ReadStatFromMetadata(metadata azblob.Metadata, contentLength int64) (UnixSt
 		s.groupGID = uint32(g)
 	}
 
+	if err := mapOwnershipFromMetadata(metadata, &s); err != nil {
+		return s, err
+	}
+
 	if mode, ok := metadata[POSIXModeMeta]; ok {
 		m, err := strconv.ParseUint(mode, 10, 32)
 		if err != nil {
@@ -317,6 +325,7 @@ const ( // Values cloned from x/sys/unix to avoid dependency
 	STATX_TYPE            = 0x1
 	STATX_UID             = 0x8
 
+	S_IFMT   = 0xf000 // the bits of the mode that give the file type
 	S_IFSOCK = 0xc000
 	S_IFBLK  = 0x6000
 	S_IFCHR  = 0x2000
@@ -348,23 +357,6 @@ func AddStatToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
 		return
 	}
 
-	applyMode := func(mode os.FileMode) {
-		modes := map[uint32]string {
-			S_IFCHR: POSIXCharDeviceMeta,
-			S_IFBLK: POSIXBlockDeviceMeta,
-			S_IFSOCK: POSIXSocketMeta,
-			S_IFIFO: POSIXFIFOMeta,
-			S_IFDIR: POSIXFolderMeta,
-			S_IFLNK: POSIXSymlinkMeta,
-		}
-
-		for modeToTest, metaToApply := range modes {
-			if mode & os.FileMode(modeToTest) == os.FileMode(modeToTest) {
-				tryAddMetadata(metadata, metaToApply, "true")
-			}
-		}
-	}
-
 	if s.Extended() { // try to poll the other properties
 		mask := s.StatxMask()
 
@@ -388,9 +380,11 @@ func AddStatToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
 			tryAddMetadata(metadata, POSIXGroupMeta, strconv.FormatUint(uint64(s.Group()), 10))
 		}
 
+		addMappedOwnershipToMetadata(metadata, s.Owner(), StatXReturned(mask, STATX_UID), s.Group(), StatXReturned(mask, STATX_GID))
+
 		if StatXReturned(mask, STATX_MODE) {
 			tryAddMetadata(metadata, POSIXModeMeta, strconv.FormatUint(uint64(s.FileMode()), 10))
-			applyMode(os.FileMode(s.FileMode()))
+			addFileTypeToMetadata(s.FileMode(), metadata)
 		}
 
 		if StatXReturned(mask, STATX_INO) {
@@ -400,7 +394,7 @@ func AddStatToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
 		// This is not optional.
 		tryAddMetadata(metadata, POSIXDevMeta, strconv.FormatUint(s.Device(), 10))
 
-		if StatXReturned(mask, STATX_MODE) && ((s.FileMode()&S_IFCHR) == S_IFCHR || (s.FileMode()&S_IFBLK) == S_IFBLK) {
+		if StatXReturned(mask, STATX_MODE) && IsDeviceMode(s.FileMode()) {
 			tryAddMetadata(metadata, POSIXRDevMeta, strconv.FormatUint(s.RDevice(), 10))
 		}
 
@@ -421,12 +415,13 @@ func AddStatToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
 		tryAddMetadata(metadata, POSIXNlinkMeta, strconv.FormatUint(s.NLink(), 10))
 		tryAddMetadata(metadata, POSIXOwnerMeta, strconv.FormatUint(uint64(s.Owner()), 10))
 		tryAddMetadata(metadata, POSIXGroupMeta, strconv.FormatUint(uint64(s.Group()), 10))
+		addMappedOwnershipToMetadata(metadata, s.Owner(), true, s.Group(), true)
 		tryAddMetadata(metadata, POSIXModeMeta, strconv.FormatUint(uint64(s.FileMode()), 10))
-		applyMode(os.FileMode(s.FileMode()))
+		addFileTypeToMetadata(s.FileMode(), metadata)
 		tryAddMetadata(metadata, POSIXINodeMeta, strconv.FormatUint(s.INode(), 10))
 		tryAddMetadata(metadata, POSIXDevMeta, strconv.FormatUint(s.Device(), 10))
 
-		if (s.FileMode()&S_IFCHR) == S_IFCHR || (s.FileMode()&S_IFBLK) == S_IFBLK { // this is not relevant unless the file is a block or character device.
+		if IsDeviceMode(s.FileMode()) { // this is not relevant unless the file is a block or character device.
 			tryAddMetadata(metadata, POSIXRDevMeta, strconv.FormatUint(s.RDevice(), 10))
 		}
 
@@ -436,6 +431,78 @@ func AddStatToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
 	}
 }
 
+// AddSpecialFileToBlobMetadata records just what's needed to recreate a FIFO, device node or socket:
+// its type and mode, and for a device, the device it stands for.
+func AddSpecialFileToBlobMetadata(s UnixStatAdapter, metadata azblob.Metadata) {
+	tryAddMetadata(metadata, POSIXModeMeta, strconv.FormatUint(uint64(s.FileMode()), 10))
+	addFileTypeToMetadata(s.FileMode(), metadata)
+
+	if IsDeviceMode(s.FileMode()) {
+		tryAddMetadata(metadata, POSIXRDevMeta, strconv.FormatUint(s.RDevice(), 10))
+	}
+}
+
+// IsDeviceMode tells whether a mode is that of a block or character device
+func IsDeviceMode(mode uint32) bool {
+	return mode&S_IFMT == S_IFCHR || mode&S_IFMT == S_IFBLK
+}
+
+// IsSpecialFileMode tells whether a mode is that of a FIFO, device or socket, which have no content of their own
+func IsSpecialFileMode(mode uint32) bool {
+	return IsDeviceMode(mode) || mode&S_IFMT == S_IFIFO || mode&S_IFMT == S_IFSOCK
+}
+
+func addFileTypeToMetadata(mode uint32, metadata azblob.Metadata) {
+	// the file types aren't separate bits (e.g. a symlink's has the bits of a character device's), so compare the whole type
+	types := map[uint32]string{
+		S_IFCHR:  POSIXCharDeviceMeta,
+		S_IFBLK:  POSIXBlockDeviceMeta,
+		S_IFSOCK: POSIXSocketMeta,
+		S_IFIFO:  POSIXFIFOMeta,
+		S_IFDIR:  POSIXFolderMeta,
+		S_IFLNK:  POSIXSymlinkMeta,
+	}
+
+	if metaToApply, ok := types[mode&S_IFMT]; ok {
+		tryAddMetadata(metadata, metaToApply, "true")
+	}
+}
+
+// addMappedOwnershipToMetadata records the SIDs that the identity mapping gives the owner and group,
+// so that a machine that numbers its users differently can map them back to its own uid and gid.
+// Errors in the mapping file are reported when the command starts, so they're ignored here.
+func addMappedOwnershipToMetadata(metadata azblob.Metadata, uid uint32, hasUID bool, gid uint32, hasGID bool) {
+	mapping, _ := GetIdentityMapping()
+
+	if sid, ok := mapping.SIDForUID(uid); ok && hasUID {
+		tryAddMetadata(metadata, POSIXOwnerSIDMeta, sid)
+	}
+	if sid, ok := mapping.SIDForGID(gid); ok && hasGID {
+		tryAddMetadata(metadata, POSIXGroupSIDMeta, sid)
+	}
+}
+
+// mapOwnershipFromMetadata replaces the owner and group read from the metadata with the local uid and gid
+// of their SIDs, where the identity mapping has them.
+func mapOwnershipFromMetadata(metadata azblob.Metadata, s *UnixStatContainer) error {
+	mapping, err := GetIdentityMapping()
+	if err != nil {
+		return err
+	}
+
+	if sid, ok := metadata[POSIXOwnerSIDMeta]; ok {
+		if uid, ok := mapping.UIDForSID(sid); ok {
+			s.ownerUID = uid
+		}
+	}
+	if sid, ok := metadata[POSIXGroupSIDMeta]; ok {
+		if gid, ok := mapping.GIDForSID(sid); ok {
+			s.groupGID = gid
+		}
+	}
+	return nil
+}
+
 func StatXReturned(mask uint32, want uint32) bool {
 	return (mask & want) == want
 }
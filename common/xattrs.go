@@ -0,0 +1,113 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	POSIXXattrsMeta        = "posix_xattrs"         // the extended attributes, when they fit in the metadata
+	POSIXXattrsSidecarMeta = "posix_xattrs_sidecar" // set when the extended attributes are in a sidecar blob instead
+	POSIXXattrsHashMeta    = "posix_xattrs_md5"     // a hash of the extended attributes, so that sync can tell when they change
+
+	// XattrsSidecarSuffix is added to the name of a blob to get the name of the sidecar blob holding its extended attributes
+	XattrsSidecarSuffix = ".azcopy-xattrs"
+	// XattrsMetadataMaxBytes is the most that the extended attributes may take in the metadata of a blob.
+	// Blob metadata is limited to 8 KiB in all, so this leaves room for the POSIX properties and the user's own metadata.
+	XattrsMetadataMaxBytes = 4096
+)
+
+// the namespaces of the extended attributes that are preserved. system.* holds ACLs, which are for the file system
+// to translate, and trusted.* can only be read by root.
+var preservedXattrNamespaces = []string{"user.", "security."}
+
+// Xattrs holds the extended attributes of a file, by name
+type Xattrs map[string][]byte
+
+// IsPreservedXattr tells whether an extended attribute is one that --preserve-xattrs transfers
+func IsPreservedXattr(name string) bool {
+	for _, ns := range preservedXattrNamespaces {
+		if strings.HasPrefix(name, ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal gives the form of the extended attributes that is stored in a sidecar blob. JSON, as map keys are sorted,
+// always gives the same form for the same attributes.
+func (x Xattrs) Marshal() ([]byte, error) {
+	return json.Marshal(x)
+}
+
+// Encode gives the form of the extended attributes that is stored in metadata, which must be ASCII.
+// It's the JSON of Marshal, whose values are already base64, with anything that isn't ASCII in the names escaped.
+func (x Xattrs) Encode() (string, error) {
+	buf, err := x.Marshal()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, r := range string(buf) {
+		if r < utf8.RuneSelf {
+			sb.WriteRune(r)
+		} else if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			fmt.Fprintf(&sb, `\u%04x\u%04x`, r1, r2)
+		} else {
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		}
+	}
+	return sb.String(), nil
+}
+
+// Hash gives a hash of the extended attributes, or "" if there are none
+func (x Xattrs) Hash() (string, error) {
+	if len(x) == 0 {
+		return "", nil
+	}
+
+	buf, err := x.Marshal()
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(buf)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// UnmarshalXattrs reads extended attributes in the form returned by Marshal
+func UnmarshalXattrs(buf []byte) (Xattrs, error) {
+	var x Xattrs
+	err := json.Unmarshal(buf, &x)
+	return x, err
+}
+
+// DecodeXattrs reads extended attributes in the form returned by Encode
+func DecodeXattrs(s string) (Xattrs, error) {
+	return UnmarshalXattrs([]byte(s))
+}
@@ -0,0 +1,120 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetXattrs reads the extended attributes of the file that --preserve-xattrs transfers
+func GetXattrs(path string) (Xattrs, error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	x := Xattrs{}
+	for _, name := range names {
+		if !IsPreservedXattr(name) {
+			continue
+		}
+
+		value, err := getXattr(path, name)
+		if err == unix.ENODATA {
+			continue // removed since it was listed
+		} else if err != nil {
+			return nil, err
+		}
+		x[name] = value
+	}
+
+	return x, nil
+}
+
+// SetXattrs writes the extended attributes to the file
+func SetXattrs(path string, x Xattrs) error {
+	for name, value := range x {
+		if err := unix.Setxattr(path, name, value, 0); err != nil {
+			return &xattrError{name: name, err: err}
+		}
+	}
+	return nil
+}
+
+type xattrError struct {
+	name string
+	err  error
+}
+
+func (e *xattrError) Error() string {
+	return "extended attribute " + e.name + ": " + e.err.Error()
+}
+
+func (e *xattrError) Unwrap() error {
+	return e.err
+}
+
+func listXattrs(path string) ([]string, error) {
+	for {
+		size, err := unix.Listxattr(path, nil)
+		if err == unix.ENOTSUP {
+			return nil, nil // the file system doesn't have extended attributes, so the file has none
+		} else if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		size, err = unix.Listxattr(path, buf)
+		if err == unix.ERANGE {
+			continue // more were added since the size was read
+		} else if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, name := range strings.Split(string(buf[:size]), "\x00") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		size, err = unix.Getxattr(path, name, buf)
+		if err == unix.ERANGE {
+			continue // it grew since the size was read
+		} else if err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	}
+}
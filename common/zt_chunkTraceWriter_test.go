@@ -0,0 +1,139 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type chunkTraceWriterSuite struct{}
+
+var _ = chk.Suite(&chunkTraceWriterSuite{})
+
+func parseTrace(c *chk.C, b []byte) []traceEvent {
+	var events []traceEvent
+	c.Assert(json.Unmarshal(b, &events), chk.IsNil)
+	return events
+}
+
+func findSpans(events []traceEvent, name string) []traceEvent {
+	var result []traceEvent
+	for _, e := range events {
+		if e.Ph == "X" && e.Name == name {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (s *chunkTraceWriterSuite) TestChunkTraceSpans(c *chk.C) {
+	start := time.Now()
+	buf := &bytes.Buffer{}
+	t := newChunkTraceWriter(buf, start)
+
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	whole := NewPseudoChunkIDForWholeFile("file1")
+	chunk0 := NewChunkID("file1", 0, 100)
+	chunk1 := NewChunkID("file1", 100, 50)
+
+	t.add(&chunkWaitState{ChunkID: whole, reason: EWaitReason.XferStart(), waitStart: at(0)})
+	t.add(&chunkWaitState{ChunkID: whole, reason: EWaitReason.ChunkDone(), waitStart: at(1)})
+	t.add(&chunkWaitState{ChunkID: chunk0, reason: EWaitReason.RAMToSchedule(), waitStart: at(1)})
+	t.add(&chunkWaitState{ChunkID: chunk1, reason: EWaitReason.RAMToSchedule(), waitStart: at(2)})
+	t.add(&chunkWaitState{ChunkID: chunk0, reason: EWaitReason.Body(), waitStart: at(3)})
+	t.add(&chunkWaitState{ChunkID: chunk0, reason: EWaitReason.ChunkDone(), waitStart: at(10)})
+	t.add(&chunkWaitState{ChunkID: chunk1, reason: EWaitReason.Cancelled(), waitStart: at(12)})
+	c.Assert(t.flush(), chk.IsNil)
+	c.Assert(t.close(at(20)), chk.IsNil)
+
+	events := parseTrace(c, buf.Bytes())
+
+	xferStart := findSpans(events, "XferStart")
+	c.Assert(xferStart, chk.HasLen, 1)
+	c.Assert(xferStart[0].Tid, chk.Equals, traceWholeFileTid)
+	c.Assert(xferStart[0].Dur, chk.Equals, 1000.0)
+
+	body := findSpans(events, "Body")
+	c.Assert(body, chk.HasLen, 1)
+	c.Assert(body[0].Ts, chk.Equals, 3000.0)
+	c.Assert(body[0].Dur, chk.Equals, 7000.0)
+	c.Assert(body[0].Args["length"], chk.Equals, 100.0) // JSON numbers come back as float64
+
+	ram := findSpans(events, "RAM")
+	c.Assert(ram, chk.HasLen, 2)
+	c.Assert(ram[0].Tid, chk.Not(chk.Equals), ram[1].Tid) // each chunk is its own thread
+
+	file := findSpans(events, "File")
+	c.Assert(file, chk.HasLen, 1)
+	c.Assert(file[0].Ts, chk.Equals, 0.0)
+	c.Assert(file[0].Dur, chk.Equals, 12000.0)
+
+	cancelled := 0
+	processNames := 0
+	for _, e := range events {
+		c.Assert(e.Pid, chk.Equals, file[0].Pid)
+		if e.Ph == "i" && e.Name == "Cancelled" {
+			cancelled++
+		}
+		if e.Ph == "M" && e.Name == "process_name" {
+			processNames++
+			c.Assert(e.Args["name"], chk.Equals, "file1")
+		}
+	}
+	c.Assert(cancelled, chk.Equals, 1)
+	c.Assert(processNames, chk.Equals, 1)
+}
+
+func (s *chunkTraceWriterSuite) TestChunkTraceUnfinishedAndIdleFiles(c *chk.C) {
+	start := time.Now()
+	buf := &bytes.Buffer{}
+	t := newChunkTraceWriter(buf, start)
+
+	// file1 finishes early, and is written out once a later event shows it has been idle long enough
+	done := NewChunkID("file1", 0, 10)
+	t.add(&chunkWaitState{ChunkID: done, reason: EWaitReason.Body(), waitStart: start})
+	t.add(&chunkWaitState{ChunkID: done, reason: EWaitReason.ChunkDone(), waitStart: start.Add(time.Second)})
+	open := NewChunkID("file2", 0, 10)
+	t.add(&chunkWaitState{ChunkID: open, reason: EWaitReason.Body(), waitStart: start.Add(10 * time.Second)})
+	c.Assert(t.files, chk.HasLen, 1)
+	_, stillTracked := t.files["file2"]
+	c.Assert(stillTracked, chk.Equals, true)
+
+	// file2 never finishes, so its span is closed off and marked as such
+	c.Assert(t.close(start.Add(15*time.Second)), chk.IsNil)
+	events := parseTrace(c, buf.Bytes())
+	body := findSpans(events, "Body")
+	c.Assert(body, chk.HasLen, 2)
+	c.Assert(body[1].Args["unfinished"], chk.Equals, true)
+	c.Assert(body[1].Dur, chk.Equals, float64(5*time.Second/time.Microsecond))
+	c.Assert(findSpans(events, "File"), chk.HasLen, 2)
+}
+
+func (s *chunkTraceWriterSuite) TestChunkTraceEmpty(c *chk.C) {
+	buf := &bytes.Buffer{}
+	t := newChunkTraceWriter(buf, time.Now())
+	c.Assert(t.close(time.Now()), chk.IsNil)
+	c.Assert(parseTrace(c, buf.Bytes()), chk.HasLen, 0)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type credentialHelperTestSuite struct{}
+
+var _ = chk.Suite(&credentialHelperTestSuite{})
+
+// writeHelper writes a shell script that counts its invocations in a file next to it, and prints body.
+func (s *credentialHelperTestSuite) writeHelper(c *chk.C, body string) (helper CredentialHelperInfo, countFile string) {
+	if runtime.GOOS == "windows" {
+		c.Skip("credential helper tests use a shell script")
+	}
+
+	dir := c.MkDir()
+	countFile = filepath.Join(dir, "count")
+	script := filepath.Join(dir, "helper.sh")
+	content := fmt.Sprintf("#!/bin/sh\ncat > %s.last\necho x >> %s\ncat <<'EOF'\n%s\nEOF\n", countFile, countFile, body)
+	c.Assert(os.WriteFile(script, []byte(content), 0700), chk.IsNil)
+
+	return CredentialHelperInfo{Command: "/bin/sh " + script}, countFile
+}
+
+func (s *credentialHelperTestSuite) invocations(c *chk.C, countFile string) int {
+	b, err := os.ReadFile(countFile)
+	c.Assert(err, chk.IsNil)
+	return strings.Count(string(b), "x")
+}
+
+func (s *credentialHelperTestSuite) TestTokenFromHelperIsCached(c *chk.C) {
+	expiry := time.Now().Add(time.Hour).Unix()
+	helper, countFile := s.writeHelper(c, fmt.Sprintf(`{"access_token": "tok", "expires_on": %d}`, expiry))
+
+	tokenInfo := OAuthTokenInfo{CredentialHelper: true, CredentialHelperInfo: helper}
+	token, err := tokenInfo.Refresh(context.Background())
+	c.Assert(err, chk.IsNil)
+	c.Assert(token.AccessToken, chk.Equals, "tok")
+	c.Assert(token.Expires().Unix(), chk.Equals, expiry)
+
+	// a second refresh within the first half of the token's lifetime is served from the cache
+	_, err = tokenInfo.Refresh(context.Background())
+	c.Assert(err, chk.IsNil)
+	c.Assert(s.invocations(c, countFile), chk.Equals, 1)
+
+	// the request tells the helper what is wanted
+	req, err := os.ReadFile(countFile + ".last")
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(req), chk.Equals, `{"version":1,"kind":"token","resource":"https://storage.azure.com"}`)
+}
+
+func (s *credentialHelperTestSuite) TestExpiredSASIsRenewed(c *chk.C) {
+	// an already-expired SAS is past half its lifetime, so every lookup re-invokes the helper
+	helper, countFile := s.writeHelper(c, `{"sas": "?sv=2020-10-02&se=2000-01-01T00:00:00Z&sig=abc"}`)
+
+	resp, err := helper.Get(context.Background(), CredentialHelperRequest{Kind: ECredentialHelperKind.SAS(), Resource: "https://acct.blob.core.windows.net/c"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(resp.SAS, chk.Equals, "sv=2020-10-02&se=2000-01-01T00:00:00Z&sig=abc")
+	c.Assert(resp.Expiry().Year(), chk.Equals, 2000)
+
+	renewed, ok, err := RenewSAS(context.Background(), resp.SAS)
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(renewed, chk.Equals, resp.SAS)
+	c.Assert(s.invocations(c, countFile), chk.Equals, 2)
+
+	// SAS tokens that did not come from a helper are left alone
+	_, ok, err = RenewSAS(context.Background(), "sv=2020-10-02&sig=user-supplied")
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, false)
+}
+
+func (s *credentialHelperTestSuite) TestHelperFailure(c *chk.C) {
+	helper, _ := s.writeHelper(c, `{"sas": "sig=abc"}`)
+
+	// a token was requested, but the helper only knows about SAS
+	_, err := helper.Invoke(context.Background(), CredentialHelperRequest{Kind: ECredentialHelperKind.Token(), Resource: Resource})
+	c.Assert(err, chk.NotNil)
+
+	_, err = CredentialHelperInfo{Command: "/bin/sh -c false"}.Invoke(context.Background(), CredentialHelperRequest{Kind: ECredentialHelperKind.SAS()})
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *credentialHelperTestSuite) TestSlowHelperDoesNotBlockOthers(c *chk.C) {
+	slow, slowCount := s.writeHelper(c, `{"sas": "sv=2020-10-02&sig=slow"}`)
+	fast, _ := s.writeHelper(c, `{"sas": "sv=2020-10-02&sig=fast"}`)
+
+	// the slow helper sleeps after it has counted its invocation
+	script := strings.Fields(slow.Command)[1]
+	content, err := os.ReadFile(script)
+	c.Assert(err, chk.IsNil)
+	c.Assert(os.WriteFile(script, []byte(strings.Replace(string(content), "cat <<", "sleep 2\ncat <<", 1)), 0700), chk.IsNil)
+
+	req := CredentialHelperRequest{Kind: ECredentialHelperKind.SAS(), Resource: "https://acct.blob.core.windows.net/c"}
+	results := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := slow.Get(context.Background(), req)
+			c.Check(err, chk.IsNil)
+			results <- resp.SAS
+		}()
+	}
+	for {
+		if _, err := os.Stat(slowCount); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// while the slow helper runs, other credentials can still be obtained
+	start := time.Now()
+	resp, err := fast.Get(context.Background(), req)
+	c.Assert(err, chk.IsNil)
+	c.Assert(resp.SAS, chk.Equals, "sv=2020-10-02&sig=fast")
+	c.Assert(time.Since(start) < time.Second, chk.Equals, true)
+
+	// and the concurrent requests for the slow one share a single invocation
+	c.Assert(<-results, chk.Equals, "sv=2020-10-02&sig=slow")
+	c.Assert(<-results, chk.Equals, "sv=2020-10-02&sig=slow")
+	c.Assert(s.invocations(c, slowCount), chk.Equals, 1)
+}
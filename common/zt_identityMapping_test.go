@@ -0,0 +1,168 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type identityMappingSuite struct{}
+
+var _ = chk.Suite(&identityMappingSuite{})
+
+// Contoso SIDs, with fake RIDs.
+const testIdentityMappingCSV = `# sid,kind,id,name
+S-1-5-21-1004336348-1177238915-682003330,domain
+S-1-5-21-1004336348-1177238915-682003330-99991, uid, 1001, alice
+S-1-5-21-1004336348-1177238915-682003330-99992, uid, 1002
+S-1-5-21-1004336348-1177238915-682003330-99995, gid, 100, staff
+`
+
+const testIdentityMappingJSON = `{
+	"domainSid": "S-1-5-21-1004336348-1177238915-682003330",
+	"identities": [
+		{"sid": "S-1-5-21-1004336348-1177238915-682003330-99991", "kind": "uid", "id": 1001, "name": "alice"},
+		{"sid": "S-1-5-21-1004336348-1177238915-682003330-99992", "kind": "uid", "id": 1002},
+		{"sid": "S-1-5-21-1004336348-1177238915-682003330-99995", "kind": "gid", "id": 100, "name": "staff"}
+	]
+}`
+
+func (s *identityMappingSuite) TestParseIdentityMapping(c *chk.C) {
+	for _, format := range []string{testIdentityMappingCSV, testIdentityMappingJSON} {
+		m, err := ParseIdentityMapping(strings.NewReader(format))
+		c.Assert(err, chk.IsNil)
+
+		c.Assert(m.DomainSID, chk.Equals, "S-1-5-21-1004336348-1177238915-682003330")
+		uid, ok := m.UIDForSID("s-1-5-21-1004336348-1177238915-682003330-99992")
+		c.Assert(ok, chk.Equals, true)
+		c.Assert(uid, chk.Equals, uint32(1002))
+		sid, ok := m.SIDForGID(100)
+		c.Assert(ok, chk.Equals, true)
+		c.Assert(sid, chk.Equals, "S-1-5-21-1004336348-1177238915-682003330-99995")
+		_, ok = m.GIDForSID("S-1-5-21-1004336348-1177238915-682003330-99991")
+		c.Assert(ok, chk.Equals, false)
+	}
+
+	// a nil mapping maps nothing
+	var m *IdentityMapping
+	_, ok := m.SIDForUID(1001)
+	c.Assert(ok, chk.Equals, false)
+
+	for _, invalid := range []string{
+		"S-1-5-21-1,uid,1\nS-1-5-21-1,uid,2",   // SID mapped twice
+		"S-1-5-21-1,uid,1\nS-1-5-21-2,uid,1",   // uid mapped twice
+		"S-1-5-21-1,user,1",                    // unknown kind
+		"S-1-5-21-1,uid,-1",                    // invalid id
+		"S-1-5-21-1,uid,",                      // neither id nor name
+		"alice,uid,1",                          // not a SID
+		"S-1-5-21-1,uid",                       // missing a column
+		"S-1-5-21-1,domain\nS-1-5-21-2,domain", // two domains
+		`{"identities": [{"sid": "S-1-5-21-1", "kind": "uid"}]}`,
+	} {
+		_, err := ParseIdentityMapping(strings.NewReader(invalid))
+		c.Assert(err, chk.NotNil, chk.Commentf(invalid))
+	}
+}
+
+func (s *identityMappingSuite) TestIdentityMappingObjectIDs(c *chk.C) {
+	for _, format := range []string{
+		"S-1-5-21-1-1001,uid,1001,,6F5B9E1C-0B47-4B0E-9F0F-1A3B2C4D5E01\n,gid,100,,6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e05",
+		`{"identities": [{"sid": "S-1-5-21-1-1001", "kind": "uid", "id": 1001, "objectId": "6F5B9E1C-0B47-4B0E-9F0F-1A3B2C4D5E01"},
+			{"kind": "gid", "id": 100, "objectId": "6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e05"}]}`,
+	} {
+		m, err := ParseIdentityMapping(strings.NewReader(format))
+		c.Assert(err, chk.IsNil)
+
+		uid, ok := m.UIDForObjectID("6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e01")
+		c.Assert(ok, chk.Equals, true)
+		c.Assert(uid, chk.Equals, uint32(1001))
+		objectID, ok := m.ObjectIDForGID(100)
+		c.Assert(ok, chk.Equals, true)
+		c.Assert(objectID, chk.Equals, "6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e05")
+		_, ok = m.SIDForGID(100) // the group has no SID
+		c.Assert(ok, chk.Equals, false)
+	}
+
+	for _, invalid := range []string{
+		",uid,1",        // neither SID nor object ID
+		",uid,1,,alice", // not an object ID
+		",uid,1,,6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e01\n,uid,2,,6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e01", // object ID mapped twice
+	} {
+		_, err := ParseIdentityMapping(strings.NewReader(invalid))
+		c.Assert(err, chk.NotNil, chk.Commentf(invalid))
+	}
+}
+
+func (s *identityMappingSuite) TestIdentityMappingLocalNames(c *chk.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("Windows has no POSIX ids")
+	}
+
+	// with no id, the name is looked up locally
+	m, err := ParseIdentityMapping(strings.NewReader("S-1-5-21-1-500,uid,,root\nS-1-5-21-1-512,gid,,root"))
+	c.Assert(err, chk.IsNil)
+	uid, ok := m.UIDForSID("S-1-5-21-1-500")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(uid, chk.Equals, uint32(0))
+
+	_, err = ParseIdentityMapping(strings.NewReader("S-1-5-21-1-500,uid,,no-such-user-azcopy"))
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *identityMappingSuite) TestPOSIXOwnershipMapping(c *chk.C) {
+	m, err := ParseIdentityMapping(strings.NewReader(testIdentityMappingCSV))
+	c.Assert(err, chk.IsNil)
+	identityMappingOnce = sync.Once{}
+	identityMappingOnce.Do(func() { identityMapping = m })
+	defer func() {
+		identityMappingOnce = sync.Once{}
+		identityMapping = nil
+	}()
+
+	// the uploading machine records the SIDs alongside its own ids...
+	metadata := azblob.Metadata{}
+	AddStatToBlobMetadata(UnixStatContainer{ownerUID: 1001, groupGID: 100}, metadata)
+	c.Assert(metadata[POSIXOwnerMeta], chk.Equals, "1001")
+	c.Assert(metadata[POSIXOwnerSIDMeta], chk.Equals, "S-1-5-21-1004336348-1177238915-682003330-99991")
+	c.Assert(metadata[POSIXGroupSIDMeta], chk.Equals, "S-1-5-21-1004336348-1177238915-682003330-99995")
+
+	// ...which a downloading machine with different ids maps to its own
+	metadata[POSIXOwnerMeta] = "2001"
+	metadata[POSIXOwnerSIDMeta] = "S-1-5-21-1004336348-1177238915-682003330-99992"
+	stat, err := ReadStatFromMetadata(metadata, 0)
+	c.Assert(err, chk.IsNil)
+	c.Assert(stat.Owner(), chk.Equals, uint32(1002))
+	c.Assert(stat.Group(), chk.Equals, uint32(100))
+
+	// ids with no SID are left as they are
+	metadata = azblob.Metadata{}
+	AddStatToBlobMetadata(UnixStatContainer{ownerUID: 4242, groupGID: 4242}, metadata)
+	_, ok := metadata[POSIXOwnerSIDMeta]
+	c.Assert(ok, chk.Equals, false)
+	stat, err = ReadStatFromMetadata(metadata, 0)
+	c.Assert(err, chk.IsNil)
+	c.Assert(stat.Owner(), chk.Equals, uint32(4242))
+}
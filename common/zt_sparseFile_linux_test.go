@@ -0,0 +1,99 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+)
+
+type sparseFileSuite struct{}
+
+var _ = chk.Suite(&sparseFileSuite{})
+
+const sparseTestMB = 1024 * 1024
+
+// makeSparseFile makes a 4 MB file with data only in its second MB, or skips if the file system can't make holes
+func makeSparseFile(c *chk.C) (string, []byte) {
+	path := filepath.Join(c.MkDir(), "sparse")
+	f, err := os.Create(path)
+	c.Assert(err, chk.IsNil)
+	defer f.Close()
+
+	data := bytes.Repeat([]byte{'a'}, sparseTestMB)
+	c.Assert(f.Truncate(4*sparseTestMB), chk.IsNil)
+	_, err = f.WriteAt(data, sparseTestMB)
+	c.Assert(err, chk.IsNil)
+
+	ranges, ok := getFileDataRanges(f, 4*sparseTestMB)
+	if !ok || len(ranges) == 0 || ranges[0].Start != sparseTestMB {
+		c.Skip("the file system doesn't support holes")
+	}
+
+	expected := make([]byte, 4*sparseTestMB)
+	copy(expected[sparseTestMB:], data)
+	return path, expected
+}
+
+func (s *sparseFileSuite) TestSparseFileReader(c *chk.C) {
+	path, expected := makeSparseFile(c)
+
+	f, err := os.Open(path)
+	c.Assert(err, chk.IsNil)
+	reader := NewSparseFileReader(f)
+	defer reader.Close()
+
+	_, isSparse := reader.(*sparseFileReader)
+	c.Assert(isSparse, chk.Equals, true)
+
+	// whole file
+	buf := make([]byte, len(expected))
+	n, err := reader.ReadAt(buf, 0)
+	c.Assert(err, chk.IsNil)
+	c.Assert(n, chk.Equals, len(expected))
+	c.Assert(bytes.Equal(buf, expected), chk.Equals, true)
+
+	// across the start of the data, and past the end of the file
+	buf = make([]byte, sparseTestMB)
+	n, err = reader.ReadAt(buf, sparseTestMB/2)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(buf[:n], expected[sparseTestMB/2:sparseTestMB/2+n]), chk.Equals, true)
+
+	n, err = reader.ReadAt(buf, 3*sparseTestMB+10)
+	c.Assert(err, chk.Equals, io.EOF)
+	c.Assert(n, chk.Equals, sparseTestMB-10)
+}
+
+func (s *sparseFileSuite) TestNonSparseFileIsReadDirectly(c *chk.C) {
+	path := filepath.Join(c.MkDir(), "dense")
+	c.Assert(os.WriteFile(path, []byte("not sparse"), 0644), chk.IsNil)
+
+	f, err := os.Open(path)
+	c.Assert(err, chk.IsNil)
+	reader := NewSparseFileReader(f)
+	defer reader.Close()
+
+	c.Assert(reader, chk.Equals, f)
+}
+
+func (s *sparseFileSuite) TestPunchHole(c *chk.C) {
+	path, _ := makeSparseFile(c)
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	c.Assert(err, chk.IsNil)
+	defer f.Close()
+
+	if err := PunchHole(f, sparseTestMB, sparseTestMB); err != nil {
+		c.Skip("the file system doesn't support punching holes")
+	}
+
+	ranges, ok := getFileDataRanges(f, 4*sparseTestMB)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(ranges, chk.HasLen, 0)
+
+	fi, err := f.Stat()
+	c.Assert(err, chk.IsNil)
+	c.Assert(fi.Size(), chk.Equals, int64(4*sparseTestMB))
+}
@@ -0,0 +1,113 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	chk "gopkg.in/check.v1"
+)
+
+type tracingSuite struct{}
+
+var _ = chk.Suite(&tracingSuite{})
+
+// what we need of the OTLP JSON format
+type otlpJSONLine struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []struct {
+				Key   string
+				Value struct {
+					StringValue string
+				}
+			}
+		}
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID      string `json:"traceId"`
+				SpanID       string `json:"spanId"`
+				ParentSpanID string `json:"parentSpanId"`
+				Name         string
+				Kind         int
+			}
+		}
+	}
+}
+
+func (s *tracingSuite) TestTracesAreExportedToFile(c *chk.C) {
+	path := filepath.Join(c.MkDir(), "traces.jsonl")
+	c.Assert(InitTracing(path), chk.IsNil)
+	defer func() {
+		_ = tracerProvider.Shutdown(context.Background())
+		tracerProvider = nil
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	}()
+
+	ctx := WithTraceParent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "")
+	ctx, job := Tracer().Start(ctx, "Job")
+	_, request := Tracer().Start(ctx, "PutBlock", trace.WithSpanKind(trace.SpanKindClient))
+	request.End()
+	job.End()
+	c.Assert(FlushTracing(), chk.IsNil)
+
+	b, err := os.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	c.Assert(lines, chk.HasLen, 1)
+	var exported otlpJSONLine
+	c.Assert(json.Unmarshal([]byte(lines[0]), &exported), chk.IsNil)
+
+	c.Assert(exported.ResourceSpans, chk.HasLen, 1)
+	serviceName := ""
+	for _, a := range exported.ResourceSpans[0].Resource.Attributes {
+		if a.Key == "service.name" {
+			serviceName = a.Value.StringValue
+		}
+	}
+	c.Assert(serviceName, chk.Equals, "azcopy")
+
+	spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+	c.Assert(spans, chk.HasLen, 2)
+	c.Assert(spans[0].Name, chk.Equals, "PutBlock")
+	c.Assert(spans[0].Kind, chk.Equals, int(trace.SpanKindClient))
+	c.Assert(spans[1].Name, chk.Equals, "Job")
+
+	// IDs are in hex, and the job is a child of the span identified by the traceparent
+	c.Assert(spans[1].TraceID, chk.Equals, "0af7651916cd43dd8448eb211c80319c")
+	c.Assert(spans[1].ParentSpanID, chk.Equals, "b7ad6b7169203331")
+	c.Assert(spans[0].TraceID, chk.Equals, spans[1].TraceID)
+	c.Assert(spans[0].ParentSpanID, chk.Equals, spans[1].SpanID)
+	c.Assert(spans[1].SpanID, chk.HasLen, 16)
+}
+
+func (s *tracingSuite) TestInvalidTraceParentIsIgnored(c *chk.C) {
+	for _, traceParent := range []string{"", "nonsense", "00-00000000000000000000000000000000-b7ad6b7169203331-01"} {
+		ctx := WithTraceParent(context.Background(), traceParent, "")
+		c.Assert(trace.SpanContextFromContext(ctx).IsValid(), chk.Equals, false, chk.Commentf(traceParent))
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type unixStatAdapterSuite struct{}
+
+var _ = chk.Suite(&unixStatAdapterSuite{})
+
+func (s *unixStatAdapterSuite) TestFileTypeMetadata(c *chk.C) {
+	// the bits of a symlink's type include those of a character device's, and a socket's those of a folder's
+	symlink := azblob.Metadata{}
+	AddStatToBlobMetadata(UnixStatContainer{mode: S_IFLNK | 0777}, symlink)
+	c.Assert(symlink[POSIXSymlinkMeta], chk.Equals, "true")
+	c.Assert(symlink[POSIXCharDeviceMeta], chk.Equals, "")
+
+	socket := azblob.Metadata{}
+	AddStatToBlobMetadata(UnixStatContainer{mode: S_IFSOCK | 0755}, socket)
+	c.Assert(socket[POSIXSocketMeta], chk.Equals, "true")
+	c.Assert(socket[POSIXFolderMeta], chk.Equals, "")
+	c.Assert(socket[POSIXRDevMeta], chk.Equals, "")
+}
+
+func (s *unixStatAdapterSuite) TestSpecialFileMetadataRoundTrip(c *chk.C) {
+	metadata := azblob.Metadata{}
+	AddSpecialFileToBlobMetadata(UnixStatContainer{mode: S_IFBLK | 0660, repDevID: 2049, ownerUID: 1000}, metadata)
+	c.Assert(metadata[POSIXBlockDeviceMeta], chk.Equals, "true")
+	c.Assert(metadata[POSIXCharDeviceMeta], chk.Equals, "")
+	c.Assert(metadata[POSIXOwnerMeta], chk.Equals, "") // only what's needed to recreate it
+
+	stat, err := ReadStatFromMetadata(metadata, 0)
+	c.Assert(err, chk.IsNil)
+	c.Assert(IsSpecialFileMode(stat.FileMode()), chk.Equals, true)
+	c.Assert(IsDeviceMode(stat.FileMode()), chk.Equals, true)
+	c.Assert(stat.FileMode(), chk.Equals, uint32(S_IFBLK|0660))
+	c.Assert(stat.RDevice(), chk.Equals, uint64(2049))
+
+	c.Assert(IsSpecialFileMode(0x8000|0644), chk.Equals, false) // a regular file
+	c.Assert(IsSpecialFileMode(S_IFLNK|0777), chk.Equals, false)
+}
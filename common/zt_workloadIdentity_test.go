@@ -0,0 +1,101 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type workloadIdentityTestSuite struct{}
+
+var _ = chk.Suite(&workloadIdentityTestSuite{})
+
+// mockTokenEndpoint answers the token exchange with an access token derived from the assertion it was given,
+// so tests can tell which version of the federated token file was used.
+func (s *workloadIdentityTestSuite) mockTokenEndpoint(c *chk.C) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, chk.Equals, "/my-tenant/oauth2/v2.0/token")
+		c.Check(r.ParseForm(), chk.IsNil)
+		c.Check(r.PostForm.Get("client_id"), chk.Equals, "my-client")
+		c.Check(r.PostForm.Get("grant_type"), chk.Equals, "client_credentials")
+		c.Check(r.PostForm.Get("client_assertion_type"), chk.Equals, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		c.Check(r.PostForm.Get("scope"), chk.Equals, "https://storage.azure.com/.default")
+
+		if r.PostForm.Get("client_assertion") == "expired-assertion" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "token-for-%s"}`, r.PostForm.Get("client_assertion"))
+	}))
+}
+
+func (s *workloadIdentityTestSuite) TestWorkloadIdentityLoginAndRefresh(c *chk.C) {
+	server := s.mockTokenEndpoint(c)
+	defer server.Close()
+
+	tokenFile := filepath.Join(c.MkDir(), "token")
+	c.Assert(os.WriteFile(tokenFile, []byte("first-assertion\n"), 0600), chk.IsNil)
+
+	uotm := &UserOAuthTokenManager{}
+	tokenInfo, err := uotm.WorkloadIdentityLogin(context.Background(), "my-tenant", server.URL+"/", "my-client", tokenFile, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(tokenInfo.AccessToken, chk.Equals, "token-for-first-assertion")
+	c.Assert(tokenInfo.WorkloadIdentity, chk.Equals, true)
+	c.Assert(tokenInfo.Expires().After(time.Now().Add(50*time.Minute)), chk.Equals, true)
+
+	// the issuer rotates the file; refreshing must pick up the new assertion, and survive a round trip through the cache format
+	c.Assert(os.WriteFile(tokenFile, []byte("second-assertion"), 0600), chk.IsNil)
+	b, err := tokenInfo.toJSON()
+	c.Assert(err, chk.IsNil)
+	reloaded, err := jsonToTokenInfo(b)
+	c.Assert(err, chk.IsNil)
+
+	token, err := reloaded.Refresh(context.Background())
+	c.Assert(err, chk.IsNil)
+	c.Assert(token.AccessToken, chk.Equals, "token-for-second-assertion")
+
+	c.Assert(os.WriteFile(tokenFile, []byte("expired-assertion"), 0600), chk.IsNil)
+	_, err = reloaded.Refresh(context.Background())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *workloadIdentityTestSuite) TestWorkloadIdentityLoginValidation(c *chk.C) {
+	uotm := &UserOAuthTokenManager{}
+
+	_, err := uotm.WorkloadIdentityLogin(context.Background(), "", "", "my-client", "token", false)
+	c.Assert(err, chk.NotNil)
+
+	_, err = uotm.WorkloadIdentityLogin(context.Background(), "my-tenant", "", "", "token", false)
+	c.Assert(err, chk.NotNil)
+
+	_, err = uotm.WorkloadIdentityLogin(context.Background(), "my-tenant", "", "my-client", "", false)
+	c.Assert(err, chk.NotNil)
+}
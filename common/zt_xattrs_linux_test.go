@@ -0,0 +1,45 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+	chk "gopkg.in/check.v1"
+)
+
+func (s *xattrsSuite) TestGetSetXattrs(c *chk.C) {
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(os.WriteFile(path, []byte("hello"), 0644), chk.IsNil)
+
+	err := SetXattrs(path, Xattrs{"user.azcopy_test": []byte("value")})
+	if errors.Is(err, unix.ENOTSUP) {
+		c.Skip("the temp file system doesn't support user extended attributes")
+	}
+	c.Assert(err, chk.IsNil)
+
+	x, err := GetXattrs(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(x["user.azcopy_test"]), chk.Equals, "value")
+}
@@ -0,0 +1,93 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	chk "gopkg.in/check.v1"
+)
+
+type xattrsSuite struct{}
+
+var _ = chk.Suite(&xattrsSuite{})
+
+func (s *xattrsSuite) TestXattrsEncodeRoundTrip(c *chk.C) {
+	x := Xattrs{"user.mime_type": []byte("text/plain"), "security.selinux": []byte{0x00, 0xff, 'a'}}
+
+	encoded, err := x.Encode()
+	c.Assert(err, chk.IsNil)
+	decoded, err := DecodeXattrs(encoded)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decoded, chk.DeepEquals, x)
+}
+
+func (s *xattrsSuite) TestXattrsEncodedOnce(c *chk.C) {
+	x := Xattrs{"user.comment": bytes.Repeat([]byte("a"), 3000)}
+
+	// the values are base64 in the JSON already, so encoding for metadata adds nothing
+	marshalled, err := x.Marshal()
+	c.Assert(err, chk.IsNil)
+	encoded, err := x.Encode()
+	c.Assert(err, chk.IsNil)
+	c.Assert(encoded, chk.Equals, string(marshalled))
+	c.Assert(len(encoded) <= XattrsMetadataMaxBytes, chk.Equals, true)
+
+	// names that aren't ASCII are escaped, since metadata must be ASCII
+	x = Xattrs{"user.café": []byte("1"), "user.\U0001F600": []byte("2")}
+	encoded, err = x.Encode()
+	c.Assert(err, chk.IsNil)
+	for _, r := range encoded {
+		c.Assert(r < utf8.RuneSelf, chk.Equals, true, chk.Commentf(encoded))
+	}
+	decoded, err := DecodeXattrs(encoded)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decoded, chk.DeepEquals, x)
+}
+
+func (s *xattrsSuite) TestXattrsHash(c *chk.C) {
+	empty, err := Xattrs{}.Hash()
+	c.Assert(err, chk.IsNil)
+	c.Assert(empty, chk.Equals, "")
+
+	// the same attributes hash the same, whatever order they were added in
+	a := Xattrs{}
+	a["user.a"] = []byte("1")
+	a["user.b"] = []byte("2")
+	b := Xattrs{}
+	b["user.b"] = []byte("2")
+	b["user.a"] = []byte("1")
+	hashA, _ := a.Hash()
+	hashB, _ := b.Hash()
+	c.Assert(hashA, chk.Equals, hashB)
+
+	b["user.b"] = []byte("3")
+	hashB, _ = b.Hash()
+	c.Assert(hashA, chk.Not(chk.Equals), hashB)
+}
+
+func (s *xattrsSuite) TestIsPreservedXattr(c *chk.C) {
+	c.Assert(IsPreservedXattr("user.comment"), chk.Equals, true)
+	c.Assert(IsPreservedXattr("security.capability"), chk.Equals, true)
+	c.Assert(IsPreservedXattr("system.posix_acl_access"), chk.Equals, false)
+	c.Assert(IsPreservedXattr("trusted.overlay.opaque"), chk.Equals, false)
+}
@@ -100,8 +100,18 @@ var JobsAdmin interface {
 
 	TryGetPerformanceAdvice(bytesInJob uint64, filesInJob uint32, fromTo common.FromTo, dir common.TransferDirection, p *ste.PipelineNetworkStats) []common.PerformanceAdvice
 
+	// GetTuningResults returns the final state of the concurrency tuner, and the throughput since it finished tuning
+	GetTuningResults() (finalReason string, finalConcurrency int, megabitsPerSecAfterTuning float64)
+
 	SetConcurrencySettingsToAuto()
 
+	// SetConcurrencyTuningStrategy changes how concurrency is tuned, overriding the environment variable. It must be called before any job starts
+	SetConcurrencyTuningStrategy(strategySpec string) error
+
+	// ResetConcurrencyTuning starts tuning afresh, so that the tuning results of the next job don't include the jobs before it.
+	// It must be called between jobs
+	ResetConcurrencyTuning()
+
 	// JobMgrCleanUp do the JobMgr cleanup.
 	JobMgrCleanUp(jobId common.JobID)
 	ListJobs(givenStatus common.JobStatus) common.ListJobsResponse
@@ -143,6 +153,7 @@ func initJobsAdmin(appCtx context.Context, concurrency ste.ConcurrencySettings,
 		appCtx:                  appCtx,
 		commandLineMbpsCap:      targetRateInMegaBitsPerSec,
 		provideBenchmarkResults: providePerfAdvice,
+		tuningStrategy:          common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ConcurrencyTuningStrategy()),
 	}
 	// create new context with the defaultService api version set as value to serviceAPIVersionOverride in the app context.
 	ja.appCtx = context.WithValue(ja.appCtx, ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
@@ -153,7 +164,11 @@ func initJobsAdmin(appCtx context.Context, concurrency ste.ConcurrencySettings,
 	// the first piece of work actually arrives. Why do it then?
 	// So that we don't start tuning with no traffic to process, since doing so skews
 	// the tuning results and, in the worst case, leads to "completion" of tuning before any traffic has been sent.
-	ja.concurrencyTuner = ja.createConcurrencyTuner()
+	var err error
+	ja.concurrencyTuner, err = ja.createConcurrencyTuner()
+	if err != nil {
+		common.GetLifecycleMgr().Error(fmt.Sprintf("Cannot use environment variable %s, due to error %s", common.EEnvironmentVariable.ConcurrencyTuningStrategy().Name, err))
+	}
 
 	JobsAdmin = ja
 
@@ -199,22 +214,25 @@ func getMaxRamForChunks() int64 {
 	return maxRamBytesToUse
 }
 
-func (ja *jobsAdmin) createConcurrencyTuner() ste.ConcurrencyTuner {
+func (ja *jobsAdmin) createConcurrencyTuner() (ste.ConcurrencyTuner, error) {
 	if ja.concurrency.AutoTuneMainPool() {
-		t := ste.NewAutoConcurrencyTuner(ja.concurrency.InitialMainPoolSize, ja.concurrency.MaxMainPoolSize.Value, ja.provideBenchmarkResults)
+		t, err := ste.NewConcurrencyTuner(ja.tuningStrategy, ja.concurrency.InitialMainPoolSize, ja.concurrency.MaxMainPoolSize.Value, ja.provideBenchmarkResults)
+		if err != nil {
+			return nil, err
+		}
 		if !t.RequestCallbackWhenStable(func() { ja.recordTuningCompleted(true) }) {
 			panic("could not register tuning completion callback")
 		}
-		return t
+		return t, nil
 	} else {
 		ja.recordTuningCompleted(false)
-		return &ste.NullConcurrencyTuner{FixedValue: ja.concurrency.InitialMainPoolSize}
+		return &ste.NullConcurrencyTuner{FixedValue: ja.concurrency.InitialMainPoolSize}, nil
 	}
 }
 
 func (ja *jobsAdmin) recordTuningCompleted(showOutput bool) {
 	// remember how many bytes were transferred during tuning, so we can exclude them from our post-tuning throughput calculations
-	atomic.StoreInt64(&ja.atomicBytesTransferredWhileTuning, ja.BytesOverWire())
+	atomic.StoreInt64(&ja.atomicBytesTransferredWhileTuning, ja.BytesOverWire()-atomic.LoadInt64(&ja.atomicBytesTransferredBeforeTuning))
 	atomic.StoreInt64(&ja.atomicTuningEndSeconds, time.Now().Unix())
 
 	if showOutput {
@@ -239,12 +257,13 @@ func (ja *jobsAdmin) recordTuningCompleted(showOutput bool) {
 // There will be only 1 instance of the jobsAdmin type.
 // The coordinator uses this to manage all the running jobs and their job parts.
 type jobsAdmin struct {
-	atomicBytesTransferredWhileTuning int64
-	atomicTuningEndSeconds            int64
-	atomicCurrentMainPoolSize         int32 // align 64 bit integers for 32 bit arch
-	concurrency                       ste.ConcurrencySettings
-	logger                            common.ILoggerCloser
-	jobIDToJobMgr                     jobIDToJobMgr // Thread-safe map from each JobID to its JobInfo
+	atomicBytesTransferredBeforeTuning int64 // by the jobs that ran before ResetConcurrencyTuning
+	atomicBytesTransferredWhileTuning  int64
+	atomicTuningEndSeconds             int64
+	atomicCurrentMainPoolSize          int32 // align 64 bit integers for 32 bit arch
+	concurrency                        ste.ConcurrencySettings
+	logger                             common.ILoggerCloser
+	jobIDToJobMgr                      jobIDToJobMgr // Thread-safe map from each JobID to its JobInfo
 	// Other global state can be stored in more fields here...
 	logDir                  string // Where log files are stored
 	planDir                 string // Initialize to directory where Job Part Plans are stored
@@ -254,6 +273,7 @@ type jobsAdmin struct {
 	cacheLimiter            common.CacheLimiter
 	fileCountLimiter        common.CacheLimiter
 	concurrencyTuner        ste.ConcurrencyTuner
+	tuningStrategy          string
 	commandLineMbpsCap      float64
 	provideBenchmarkResults bool
 	cpuMonitor              common.CPUMonitor
@@ -469,7 +489,33 @@ func (ja *jobsAdmin) SetConcurrencySettingsToAuto() {
 
 	// recreate the concurrency tuner.
 	// Tuner isn't called until the first job part is scheduled for transfer, so it is safe to update it before that.
-	ja.concurrencyTuner = ja.createConcurrencyTuner()
+	// The strategy was already checked when the first tuner was created, so there can't be an error here.
+	ja.concurrencyTuner, _ = ja.createConcurrencyTuner()
+}
+
+func (ja *jobsAdmin) SetConcurrencyTuningStrategy(strategySpec string) error {
+	previous := ja.tuningStrategy
+	ja.tuningStrategy = strategySpec
+
+	// as above, it's safe to replace the tuner because no job has started yet
+	t, err := ja.createConcurrencyTuner()
+	if err != nil {
+		ja.tuningStrategy = previous
+		return err
+	}
+	ja.concurrencyTuner = t
+	return nil
+}
+
+func (ja *jobsAdmin) ResetConcurrencyTuning() {
+	// the bytes of the jobs so far, and the time their tuning ended, mustn't count towards the next job's results
+	atomic.StoreInt64(&ja.atomicBytesTransferredBeforeTuning, ja.BytesOverWire())
+	atomic.StoreInt64(&ja.atomicBytesTransferredWhileTuning, 0)
+	atomic.StoreInt64(&ja.atomicTuningEndSeconds, 0)
+
+	// each job manager gets the tuner when it's created, so the next job will use the new one.
+	// The strategy was already checked when the current tuner was created, so there can't be an error here.
+	ja.concurrencyTuner, _ = ja.createConcurrencyTuner()
 }
 
 // TODO: I think something is wrong here: I think delete and cleanup should be merged together.
@@ -555,16 +601,8 @@ func (ja *jobsAdmin) TryGetPerformanceAdvice(bytesInJob uint64, filesInJob uint3
 		return make([]common.PerformanceAdvice, 0)
 	}
 
-	megabitsPerSec := float64(0)
 	finalReason, finalConcurrency := ja.concurrencyTuner.GetFinalState()
-
-	secondsAfterTuning := float64(0)
-	tuningEndSeconds := atomic.LoadInt64(&ja.atomicTuningEndSeconds)
-	if tuningEndSeconds > 0 {
-		bytesTransferredAfterTuning := ja.BytesOverWire() - atomic.LoadInt64(&ja.atomicBytesTransferredWhileTuning)
-		secondsAfterTuning = time.Since(time.Unix(tuningEndSeconds, 0)).Seconds()
-		megabitsPerSec = (8 * float64(bytesTransferredAfterTuning) / secondsAfterTuning) / (1000 * 1000)
-	}
+	megabitsPerSec, secondsAfterTuning := ja.throughputAfterTuning()
 
 	// if we we didn't run enough after the end of tuning, due to too little time or too close the slow patch as throughput winds down approaching 100%,
 	// then pretend that we didn't get any tuning result at all
@@ -579,10 +617,28 @@ func (ja *jobsAdmin) TryGetPerformanceAdvice(bytesInJob uint64, filesInJob uint3
 	}
 
 	isToAzureFiles := fromTo.To() == common.ELocation.File()
-	a := ste.NewPerformanceAdvisor(p, ja.commandLineMbpsCap, int64(megabitsPerSec), finalReason, finalConcurrency, dir, averageBytesPerFile, isToAzureFiles)
+	strategy, decisions := ja.concurrencyTuner.GetTuningDecisions()
+	a := ste.NewPerformanceAdvisor(p, ja.commandLineMbpsCap, int64(megabitsPerSec), finalReason, finalConcurrency, strategy, decisions, dir, averageBytesPerFile, isToAzureFiles)
 	return a.GetAdvice()
 }
 
+func (ja *jobsAdmin) GetTuningResults() (finalReason string, finalConcurrency int, megabitsPerSecAfterTuning float64) {
+	finalReason, finalConcurrency = ja.concurrencyTuner.GetFinalState()
+	megabitsPerSecAfterTuning, _ = ja.throughputAfterTuning()
+	return
+}
+
+// throughputAfterTuning excludes the bytes transferred while tuning, since the throughput varies so much then
+func (ja *jobsAdmin) throughputAfterTuning() (megabitsPerSec float64, secondsAfterTuning float64) {
+	tuningEndSeconds := atomic.LoadInt64(&ja.atomicTuningEndSeconds)
+	if tuningEndSeconds > 0 {
+		bytesTransferredAfterTuning := ja.BytesOverWire() - atomic.LoadInt64(&ja.atomicBytesTransferredBeforeTuning) - atomic.LoadInt64(&ja.atomicBytesTransferredWhileTuning)
+		secondsAfterTuning = time.Since(time.Unix(tuningEndSeconds, 0)).Seconds()
+		megabitsPerSec = (8 * float64(bytesTransferredAfterTuning) / secondsAfterTuning) / (1000 * 1000)
+	}
+	return
+}
+
 func (ja *jobsAdmin) messageHandler(inputChan <-chan *common.LCMMsg) {
 	toBitsPerSec := func(megaBitsPerSec int64) int64 {
 		return megaBitsPerSec * 1000 * 1000 / 8
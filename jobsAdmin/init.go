@@ -79,6 +79,11 @@ func MainSTE(concurrency ste.ConcurrencySettings, targetRateInMegaBitsPerSec flo
 		ste.EnvironmentMimeMap = config.MIMETypeMapping
 	}
 
+	// if we've been asked to export traces
+	if err := common.InitTracing(common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.OtelTraceExport())); err != nil {
+		return err
+	}
+
 	deserialize := func(request *http.Request, v interface{}) {
 		// TODO: Check the HTTP verb here?
 		// reading the entire request body and closing the request body
@@ -180,6 +185,7 @@ func ExecuteNewCopyJobPartOrder(order common.CopyJobPartOrderRequest) common.Cop
 		ste.InMemoryTransitJobState{
 			CredentialInfo:          order.CredentialInfo,
 			S2SSourceCredentialType: order.S2SSourceCredentialType,
+			IsBenchmark:             order.IsBenchmark,
 		})
 	// Supply no plan MMF because we don't have one, and AddJobPart will create one on its own.
 	jm.AddJobPart(order.PartNum, jppfn, nil, order.SourceRoot.SAS, order.DestinationRoot.SAS, true, nil) // Add this part to the Job and schedule its transfers
@@ -282,6 +288,16 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 	if len(req.DestinationSAS) > 0 && req.DestinationSAS[0] == '?' {
 		req.DestinationSAS = req.DestinationSAS[1:]
 	}
+	// Bring plan files written by an older AzCopy up to date, so that jobs survive an upgrade
+	if migrated, err := ste.MigrateJobPlanFiles(common.AzcopyJobPlanFolder, req.JobID); err != nil {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              err.Error(),
+		}
+	} else if migrated > 0 {
+		JobsAdmin.LogToJobLog(fmt.Sprintf("Migrated %d plan file(s) of job %s to plan version %d", migrated, req.JobID, ste.DataSchemaVersion), pipeline.LogInfo)
+	}
+
 	// Always search the plan files in Azcopy folder,
 	// and resurrect the Job with provided credentials, to ensure SAS and etc get updated.
 	if !JobsAdmin.ResurrectJob(req.JobID, req.SourceSAS, req.DestinationSAS) {
@@ -343,7 +359,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 			if len(req.SourceSAS) == 0 {
 				plan := jpm.Plan()
 				if plan.FromTo.From() == common.ELocation.Blob() {
-					src := string(plan.SourceRoot[:plan.SourceRootLength])
+					src := plan.SourceRoot()
 					if common.IsSourcePublicBlob(src, steCtx) {
 						break
 					}
@@ -358,7 +374,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 
 				plan := jpm.Plan()
 				if plan.FromTo.From() == common.ELocation.Blob() && len(req.DestinationSAS) != 0 {
-					src := string(plan.SourceRoot[:plan.SourceRootLength])
+					src := plan.SourceRoot()
 					if common.IsSourcePublicBlob(src, steCtx) {
 						break
 					}
@@ -530,6 +546,10 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		}
 	}
 
+	if js.JobStatus.IsJobDone() && pipeStats != nil {
+		js.RequestLatencies = pipeStats.RequestLatencies()
+	}
+
 	return js
 }
 
@@ -655,6 +675,9 @@ func resurrectJobSummary(jm ste.IJobMgr) common.ListJobSummaryResponse {
 	if part0PlanStatus == common.EJobStatus.Cancelled() {
 		js.JobStatus = part0PlanStatus
 		js.PerformanceAdvice = JobsAdmin.TryGetPerformanceAdvice(js.TotalBytesExpected, js.TotalTransfers-js.TransfersSkipped, part0.Plan().FromTo, dir, p)
+		if pipeStats != nil {
+			js.RequestLatencies = pipeStats.RequestLatencies()
+		}
 		return js
 	}
 	// Job is completed if Job order is complete AND ALL transfers are completed/failed
@@ -665,6 +688,9 @@ func resurrectJobSummary(jm ste.IJobMgr) common.ListJobSummaryResponse {
 
 	if js.JobStatus.IsJobDone() {
 		js.PerformanceAdvice = JobsAdmin.TryGetPerformanceAdvice(js.TotalBytesExpected, js.TotalTransfers-js.TransfersSkipped, part0.Plan().FromTo, dir, p)
+		if pipeStats != nil {
+			js.RequestLatencies = pipeStats.RequestLatencies()
+		}
 	}
 
 	return js
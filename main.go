@@ -41,6 +41,11 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano()) // make sure our random numbers actually are random (but remember, use crypto/rand for anything where strong/reliable randomness is required
 
+	// the profile may set environment variables, including those read just below
+	if err := cmd.LoadConfigProfile(os.Args[1:]); err != nil {
+		log.Fatalf("Problem loading the config file. %v", err)
+	}
+
 	azcopyLogPathFolder := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.LogLocation())     // user specified location for log files
 	azcopyJobPlanFolder := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.JobPlanLocation()) // user specified location for plan files
 
@@ -0,0 +1,222 @@
+//go:build linux
+// +build linux
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sddl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+/*
+ * ADLS Gen2 ACLs are POSIX ACLs written out as text, e.g. "user::rwx,user:<object ID>:r-x,group::r-x,mask::r-x,other::---",
+ * with a directory's default ACL following as "default:user::rwx,...". The only real difference is that
+ * named users and groups, and the owner and group of the path, are AAD object IDs rather than uids and gids.
+ */
+
+const adlsDefaultScope = "default:"
+
+// ADLSAccessControl is the owner, group and ACL of an ADLS Gen2 path.
+type ADLSAccessControl struct {
+	Owner string // an AAD object ID, or empty to leave it as it is
+	Group string
+	ACL   string
+}
+
+// TranslatePosixToADLS converts a file's owner, group and POSIX ACLs to ADLS Gen2 access control.
+// The default ACL is only given for directories, and may be nil.
+// Uids and gids that have no object ID in the mapping are reported in the second return value;
+// an unmapped owner or group is left out, so that the destination keeps its own.
+func TranslatePosixToADLS(uid, gid uint32, access, defaultACL PosixACL, mapping *common.IdentityMapping) (ADLSAccessControl, []UnrepresentableACE) {
+	var unrepresentable []UnrepresentableACE
+	report := func(entry string, reason string) {
+		unrepresentable = append(unrepresentable, UnrepresentableACE{ACE: entry, Reason: reason})
+	}
+
+	ac := ADLSAccessControl{}
+	var ok bool
+	if ac.Owner, ok = mapping.ObjectIDForUID(uid); !ok {
+		report(fmt.Sprintf("owner %d", uid), "the uid has no object ID in the identity mapping")
+	}
+	if ac.Group, ok = mapping.ObjectIDForGID(gid); !ok {
+		report(fmt.Sprintf("group %d", gid), "the gid has no object ID in the identity mapping")
+	}
+
+	var entries []string
+	for _, scope := range []struct {
+		prefix string
+		acl    PosixACL
+	}{{"", access}, {adlsDefaultScope, defaultACL}} {
+		for _, e := range scope.acl {
+			var entry string
+
+			switch e.Tag {
+			case ACL_USER_OBJ:
+				entry = "user:"
+			case ACL_USER:
+				objectID, ok := mapping.ObjectIDForUID(e.ID)
+				if !ok {
+					report(scope.prefix+e.String(), "the entry's uid has no object ID in the identity mapping")
+					continue
+				}
+				entry = "user:" + objectID
+			case ACL_GROUP_OBJ:
+				entry = "group:"
+			case ACL_GROUP:
+				objectID, ok := mapping.ObjectIDForGID(e.ID)
+				if !ok {
+					report(scope.prefix+e.String(), "the entry's gid has no object ID in the identity mapping")
+					continue
+				}
+				entry = "group:" + objectID
+			case ACL_MASK:
+				entry = "mask:"
+			case ACL_OTHER:
+				entry = "other:"
+			default:
+				report(scope.prefix+e.String(), "unknown POSIX ACL entry tag")
+				continue
+			}
+
+			entries = append(entries, scope.prefix+entry+":"+posixPermString(e.Perm))
+		}
+	}
+
+	ac.ACL = strings.Join(entries, ",")
+	return ac, unrepresentable
+}
+
+// TranslateADLSToPosix converts ADLS Gen2 access control to a POSIX owner, group, access ACL and default ACL,
+// the reverse of TranslatePosixToADLS. Object IDs that have no uid or gid in the mapping are reported in Unrepresentable.
+// It only fails if the ACL is malformed.
+func TranslateADLSToPosix(ac ADLSAccessControl, mapping *common.IdentityMapping) (PosixTranslation, error) {
+	t := PosixTranslation{}
+	report := func(entry string, reason string) {
+		t.Unrepresentable = append(t.Unrepresentable, UnrepresentableACE{ACE: entry, Reason: reason})
+	}
+
+	if ac.Owner != "" {
+		if t.UID, t.HasUID = mapping.UIDForObjectID(ac.Owner); !t.HasUID {
+			report("owner "+ac.Owner, "the owner has no uid in the identity mapping")
+		}
+	}
+	if ac.Group != "" {
+		if t.GID, t.HasGID = mapping.GIDForObjectID(ac.Group); !t.HasGID {
+			report("group "+ac.Group, "the group has no gid in the identity mapping")
+		}
+	}
+
+	if strings.TrimSpace(ac.ACL) == "" {
+		return t, nil
+	}
+
+	for _, entry := range strings.Split(ac.ACL, ",") {
+		entry = strings.TrimSpace(entry)
+		isDefault := strings.HasPrefix(entry, adlsDefaultScope)
+
+		parts := strings.Split(strings.TrimPrefix(entry, adlsDefaultScope), ":")
+		if len(parts) != 3 {
+			return t, fmt.Errorf("invalid ADLS Gen2 ACL entry %q", entry)
+		}
+		perm, err := parseADLSPerm(parts[2])
+		if err != nil {
+			return t, fmt.Errorf("invalid ADLS Gen2 ACL entry %q: %w", entry, err)
+		}
+
+		e := PosixACLEntry{Perm: perm, ID: ACL_UNDEFINED_ID}
+		qualifier := parts[1]
+		switch parts[0] {
+		case "user":
+			e.Tag = ACL_USER_OBJ
+			if qualifier != "" {
+				uid, ok := mapping.UIDForObjectID(qualifier)
+				if !ok {
+					report(entry, "the entry's object ID has no uid in the identity mapping")
+					continue
+				}
+				e.Tag, e.ID = ACL_USER, uid
+			}
+		case "group":
+			e.Tag = ACL_GROUP_OBJ
+			if qualifier != "" {
+				gid, ok := mapping.GIDForObjectID(qualifier)
+				if !ok {
+					report(entry, "the entry's object ID has no gid in the identity mapping")
+					continue
+				}
+				e.Tag, e.ID = ACL_GROUP, gid
+			}
+		case "mask":
+			e.Tag = ACL_MASK
+		case "other":
+			e.Tag = ACL_OTHER
+		default:
+			return t, fmt.Errorf("invalid ADLS Gen2 ACL entry %q: unknown type %s", entry, parts[0])
+		}
+
+		if isDefault {
+			t.DefaultACL = append(t.DefaultACL, e)
+		} else {
+			t.ACL = append(t.ACL, e)
+		}
+	}
+
+	sortPosixACL(t.ACL)
+	sortPosixACL(t.DefaultACL)
+	return t, nil
+}
+
+// parseADLSPerm parses the "rwx" form of an ADLS Gen2 ACL entry's permissions.
+func parseADLSPerm(s string) (uint16, error) {
+	if len(s) != 3 {
+		return 0, fmt.Errorf("permissions %q are not of the form rwx", s)
+	}
+
+	var perm uint16
+	for k, bit := range []struct {
+		c    byte
+		perm uint16
+	}{{'r', ACL_READ}, {'w', ACL_WRITE}, {'x', ACL_EXECUTE}} {
+		switch s[k] {
+		case bit.c:
+			perm |= bit.perm
+		case '-':
+		default:
+			return 0, fmt.Errorf("permissions %q are not of the form rwx", s)
+		}
+	}
+	return perm, nil
+}
+
+// sortPosixACL puts the entries in the order the kernel requires, which the tags' values already follow.
+func sortPosixACL(acl PosixACL) {
+	sort.SliceStable(acl, func(i, j int) bool {
+		if acl[i].Tag != acl[j].Tag {
+			return acl[i].Tag < acl[j].Tag
+		}
+		return acl[i].ID < acl[j].ID
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sddl
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type adlsACLSuite struct{}
+
+var _ = chk.Suite(&adlsACLSuite{})
+
+const adlsMapping = `# sid,kind,id,name,objectId
+S-1-5-21-1004336348-1177238915-682003330-99991, uid, 1001, , 6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e01
+, uid, 1002, , 6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e02
+, gid, 100, , 6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e05
+`
+
+func (s *adlsACLSuite) TestTranslatePosixToADLS(c *chk.C) {
+	mapping, err := common.ParseIdentityMapping(strings.NewReader(adlsMapping))
+	c.Assert(err, chk.IsNil)
+
+	access := PosixACL{
+		{Tag: ACL_USER_OBJ, Perm: ACL_READ | ACL_WRITE | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_USER, Perm: ACL_READ, ID: 1002},
+		{Tag: ACL_USER, Perm: ACL_READ, ID: 4242}, // unmapped
+		{Tag: ACL_GROUP_OBJ, Perm: ACL_READ | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_MASK, Perm: ACL_READ | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_OTHER, Perm: 0, ID: ACL_UNDEFINED_ID},
+	}
+	defaultACL := PosixACL{
+		{Tag: ACL_USER_OBJ, Perm: ACL_READ | ACL_WRITE | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_GROUP_OBJ, Perm: ACL_READ, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_GROUP, Perm: ACL_READ | ACL_WRITE, ID: 100},
+		{Tag: ACL_MASK, Perm: ACL_READ | ACL_WRITE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_OTHER, Perm: 0, ID: ACL_UNDEFINED_ID},
+	}
+
+	ac, unrepresentable := TranslatePosixToADLS(1001, 200, access, defaultACL, mapping)
+	c.Assert(ac.Owner, chk.Equals, "6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e01")
+	c.Assert(ac.Group, chk.Equals, "") // unmapped, so the destination keeps its own
+	c.Assert(ac.ACL, chk.Equals, "user::rwx,user:6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e02:r--,group::r-x,mask::r-x,other::---,"+
+		"default:user::rwx,default:group::r--,default:group:6f5b9e1c-0b47-4b0e-9f0f-1a3b2c4d5e05:rw-,default:mask::rw-,default:other::---")
+	c.Assert(unrepresentable, chk.HasLen, 2)
+	c.Assert(unrepresentable[0].ACE, chk.Equals, "group 200")
+	c.Assert(unrepresentable[1].ACE, chk.Equals, "u:4242:r--")
+
+	// and translating back should give the original, less what couldn't be translated
+	t, err := TranslateADLSToPosix(ac, mapping)
+	c.Assert(err, chk.IsNil)
+	c.Assert(t.Unrepresentable, chk.HasLen, 0)
+	c.Assert(t.HasUID, chk.Equals, true)
+	c.Assert(t.UID, chk.Equals, uint32(1001))
+	c.Assert(t.HasGID, chk.Equals, false)
+	c.Assert(t.ACL.String(), chk.Equals, "u::rwx,u:1002:r--,g::r-x,m::r-x,o::---")
+	c.Assert(t.DefaultACL.String(), chk.Equals, "u::rwx,g::r--,g:100:rw-,m::rw-,o::---")
+}
+
+func (s *adlsACLSuite) TestTranslateADLSToPosix(c *chk.C) {
+	mapping, err := common.ParseIdentityMapping(strings.NewReader(adlsMapping))
+	c.Assert(err, chk.IsNil)
+
+	// ADLS Gen2 may list the entries in any order, and object IDs in either case
+	t, err := TranslateADLSToPosix(ADLSAccessControl{
+		Owner: "$superuser",
+		ACL:   "other::r--,group::r-x,user:6F5B9E1C-0B47-4B0E-9F0F-1A3B2C4D5E02:rw-,user:00000000-0000-0000-0000-000000000009:rwx,mask::rwx,user::rwx",
+	}, mapping)
+	c.Assert(err, chk.IsNil)
+	c.Assert(t.HasUID, chk.Equals, false)
+	c.Assert(t.ACL.String(), chk.Equals, "u::rwx,u:1002:rw-,g::r-x,m::rwx,o::r--")
+	c.Assert(t.DefaultACL, chk.IsNil)
+	c.Assert(t.Unrepresentable, chk.HasLen, 2)
+	c.Assert(t.Unrepresentable[0].ACE, chk.Equals, "owner $superuser")
+	c.Assert(t.Unrepresentable[1].ACE, chk.Equals, "user:00000000-0000-0000-0000-000000000009:rwx")
+
+	for _, invalid := range []string{
+		"user::rwx,group", // missing fields
+		"user::rwq",       // invalid permissions
+		"everyone::rwx",   // unknown type
+		"user::rw",        // short permissions
+	} {
+		_, err := TranslateADLSToPosix(ADLSAccessControl{ACL: invalid}, mapping)
+		c.Assert(err, chk.NotNil, chk.Commentf(invalid))
+	}
+}
@@ -0,0 +1,533 @@
+//go:build linux
+// +build linux
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sddl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+/*
+ * POSIX ACLs, as stored by Linux in the system.posix_acl_access and system.posix_acl_default xattrs.
+ * See include/uapi/linux/posix_acl_xattr.h.
+ */
+const (
+	XATTR_POSIX_ACL_ACCESS  = "system.posix_acl_access"
+	XATTR_POSIX_ACL_DEFAULT = "system.posix_acl_default"
+
+	POSIX_ACL_XATTR_VERSION = 0x0002
+	ACL_UNDEFINED_ID        = 0xFFFFFFFF
+
+	// Entry tags.
+	ACL_USER_OBJ  = 0x01
+	ACL_USER      = 0x02
+	ACL_GROUP_OBJ = 0x04
+	ACL_GROUP     = 0x08
+	ACL_MASK      = 0x10
+	ACL_OTHER     = 0x20
+
+	// Entry permissions.
+	ACL_READ    = 0x04
+	ACL_WRITE   = 0x02
+	ACL_EXECUTE = 0x01
+)
+
+const posixACLEntrySize = 8 // tag (2 bytes), perm (2 bytes), id (4 bytes)
+
+type PosixACLEntry struct {
+	Tag  uint16
+	Perm uint16
+	ID   uint32 // ACL_UNDEFINED_ID, except for ACL_USER and ACL_GROUP entries
+}
+
+// PosixACL holds its entries in the order the kernel requires: owner, named users by uid, owning group,
+// named groups by gid, mask, other.
+type PosixACL []PosixACLEntry
+
+// PosixACLFromMode returns the minimal ACL equivalent to the permission bits of a file mode.
+func PosixACLFromMode(mode uint32) PosixACL {
+	return PosixACL{
+		{Tag: ACL_USER_OBJ, Perm: uint16(mode>>6) & 7, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_GROUP_OBJ, Perm: uint16(mode>>3) & 7, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_OTHER, Perm: uint16(mode) & 7, ID: ACL_UNDEFINED_ID},
+	}
+}
+
+// ParsePosixACL decodes the value of a system.posix_acl_access xattr.
+func ParsePosixACL(data []byte) (PosixACL, error) {
+	if len(data) < 4 || (len(data)-4)%posixACLEntrySize != 0 {
+		return nil, fmt.Errorf("POSIX ACL xattr has invalid length %d", len(data))
+	}
+	if version := binary.LittleEndian.Uint32(data[0:4]); version != POSIX_ACL_XATTR_VERSION {
+		return nil, fmt.Errorf("POSIX ACL xattr has unsupported version %d", version)
+	}
+
+	acl := make(PosixACL, 0, (len(data)-4)/posixACLEntrySize)
+	for offset := 4; offset < len(data); offset += posixACLEntrySize {
+		acl = append(acl, PosixACLEntry{
+			Tag:  binary.LittleEndian.Uint16(data[offset:]),
+			Perm: binary.LittleEndian.Uint16(data[offset+2:]),
+			ID:   binary.LittleEndian.Uint32(data[offset+4:]),
+		})
+	}
+	return acl, nil
+}
+
+// Marshal encodes the ACL as the value of a system.posix_acl_access xattr.
+func (acl PosixACL) Marshal() []byte {
+	data := make([]byte, 4+len(acl)*posixACLEntrySize)
+	binary.LittleEndian.PutUint32(data[0:4], POSIX_ACL_XATTR_VERSION)
+
+	for k, e := range acl {
+		offset := 4 + k*posixACLEntrySize
+		binary.LittleEndian.PutUint16(data[offset:], e.Tag)
+		binary.LittleEndian.PutUint16(data[offset+2:], e.Perm)
+		binary.LittleEndian.PutUint32(data[offset+4:], e.ID)
+	}
+	return data
+}
+
+// String returns the ACL in the short text form accepted by setfacl, e.g. "u::rwx,g::r-x,o::---".
+func (acl PosixACL) String() string {
+	entries := make([]string, len(acl))
+	for k, e := range acl {
+		entries[k] = e.String()
+	}
+	return strings.Join(entries, ",")
+}
+
+func (e PosixACLEntry) String() string {
+	perm := posixPermString(e.Perm)
+
+	switch e.Tag {
+	case ACL_USER_OBJ:
+		return "u::" + perm
+	case ACL_USER:
+		return fmt.Sprintf("u:%d:%s", e.ID, perm)
+	case ACL_GROUP_OBJ:
+		return "g::" + perm
+	case ACL_GROUP:
+		return fmt.Sprintf("g:%d:%s", e.ID, perm)
+	case ACL_MASK:
+		return "m::" + perm
+	case ACL_OTHER:
+		return "o::" + perm
+	default:
+		return fmt.Sprintf("0x%x:%d:%s", e.Tag, e.ID, perm)
+	}
+}
+
+// posixPermString returns the permissions in the "rwx" form shared by setfacl and ADLS Gen2.
+func posixPermString(perm uint16) string {
+	s := []byte("---")
+	if perm&ACL_READ != 0 {
+		s[0] = 'r'
+	}
+	if perm&ACL_WRITE != 0 {
+		s[1] = 'w'
+	}
+	if perm&ACL_EXECUTE != 0 {
+		s[2] = 'x'
+	}
+	return string(s)
+}
+
+// UnrepresentableACE is an ACE (or POSIX ACL entry) that has no equivalent on the other side of a translation,
+// and so was left out of it.
+type UnrepresentableACE struct {
+	ACE    string
+	Reason string
+}
+
+func (u UnrepresentableACE) String() string {
+	return u.ACE + ": " + u.Reason
+}
+
+// PosixTranslation is a security descriptor translated to POSIX terms.
+type PosixTranslation struct {
+	UID    uint32
+	HasUID bool // false when the owner has no uid in the mapping
+	GID    uint32
+	HasGID bool // false when the group has no gid in the mapping
+	ACL    PosixACL
+
+	// DefaultACL is the default ACL of a directory, which only ADLS Gen2 has. It's nil if there's none.
+	DefaultACL PosixACL
+
+	Unrepresentable []UnrepresentableACE
+}
+
+// Specific file rights that grant each POSIX permission.
+// The same bits mean list directory, add file and traverse for directories.
+const (
+	posixReadRights    = FILE_READ_DATA
+	posixWriteRights   = FILE_WRITE_DATA
+	posixExecuteRights = FILE_EXECUTE
+)
+
+var (
+	everyoneSID    = mustCanonicalizeSid("WD")
+	ownerRightsSID = mustCanonicalizeSid("OW")
+)
+
+func mustCanonicalizeSid(sid string) string {
+	canonical, err := CanonicalizeSid(sid)
+	if err != nil {
+		panic(err)
+	}
+	return canonical
+}
+
+func aceFlagsContain(aceFlags, flag string) bool {
+	aceFlags = strings.ToUpper(aceFlags)
+	for i := 0; i+1 < len(aceFlags); i += 2 {
+		if aceFlags[i:i+2] == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func accessMaskToPosixPerm(mask uint32) uint16 {
+	if mask&GENERIC_ALL != 0 {
+		return ACL_READ | ACL_WRITE | ACL_EXECUTE
+	}
+
+	var perm uint16
+	if mask&GENERIC_READ != 0 || mask&posixReadRights != 0 {
+		perm |= ACL_READ
+	}
+	if mask&GENERIC_WRITE != 0 || mask&posixWriteRights != 0 {
+		perm |= ACL_WRITE
+	}
+	if mask&GENERIC_EXECUTE != 0 || mask&posixExecuteRights != 0 {
+		perm |= ACL_EXECUTE
+	}
+	return perm
+}
+
+func posixPermToAccessMask(perm uint16) uint32 {
+	var mask uint32
+	if perm&ACL_READ != 0 {
+		mask |= FILE_GENERIC_READ
+	}
+	if perm&ACL_WRITE != 0 {
+		mask |= FILE_GENERIC_WRITE
+	}
+	if perm&ACL_EXECUTE != 0 {
+		mask |= FILE_GENERIC_EXECUTE
+	}
+	return mask
+}
+
+// Deny ACEs only take away the specific rights behind each POSIX permission, since the generic ones
+// share rights such as SYNCHRONIZE and READ_CONTROL, which would take every other permission with them.
+const (
+	posixReadDenyRights    = FILE_READ_DATA
+	posixWriteDenyRights   = FILE_WRITE_DATA | FILE_APPEND_DATA
+	posixExecuteDenyRights = FILE_EXECUTE
+)
+
+func posixPermToDenyMask(perm uint16) uint32 {
+	var mask uint32
+	if perm&ACL_READ != 0 {
+		mask |= posixReadDenyRights
+	}
+	if perm&ACL_WRITE != 0 {
+		mask |= posixWriteDenyRights
+	}
+	if perm&ACL_EXECUTE != 0 {
+		mask |= posixExecuteDenyRights
+	}
+	return mask
+}
+
+// accessMaskToDeniedPosixPerm returns the POSIX permissions that a deny ACE with the mask takes away.
+func accessMaskToDeniedPosixPerm(mask uint32) uint16 {
+	perm := accessMaskToPosixPerm(mask)
+	if mask&FILE_APPEND_DATA != 0 {
+		perm |= ACL_WRITE
+	}
+	return perm
+}
+
+// TranslateSDDLToPosix converts the owner, group and DACL of the security descriptor to a POSIX access ACL.
+// ACEs for the owner and group become the owning user and group entries, Everyone becomes "other",
+// and any other SID becomes a named user or group entry if the mapping has a uid or gid for it.
+// Deny ACEs for the owner or a mapped user take those rights out of the user's entry, as TranslatePosixToSDDL writes them.
+// Other deny ACEs, inherit-only ACEs, object and conditional ACEs, and unmapped SIDs have no POSIX equivalent,
+// and are reported in Unrepresentable.
+func TranslateSDDLToPosix(s SDDLString, mapping *common.IdentityMapping) PosixTranslation {
+	t := PosixTranslation{}
+	report := func(ace string, reason string) {
+		t.Unrepresentable = append(t.Unrepresentable, UnrepresentableACE{ACE: ace, Reason: reason})
+	}
+
+	ownerSID, _ := CanonicalizeSid(strings.TrimSpace(s.OwnerSID))
+	groupSID, _ := CanonicalizeSid(strings.TrimSpace(s.GroupSID))
+	if s.OwnerSID != "" {
+		if t.UID, t.HasUID = mapping.UIDForSID(ownerSID); !t.HasUID {
+			report("O:"+s.OwnerSID, "the owner has no uid in the identity mapping")
+		}
+	}
+	if s.GroupSID != "" {
+		if t.GID, t.HasGID = mapping.GIDForSID(groupSID); !t.HasGID {
+			report("G:"+s.GroupSID, "the group has no gid in the identity mapping")
+		}
+	}
+
+	var userObj, groupObj, other, userObjDenied uint16
+	users := map[uint32]uint16{}
+	usersDenied := map[uint32]uint16{}
+	groups := map[uint32]uint16{}
+
+	for _, ace := range s.DACL.ACLEntries {
+		aceString := "(" + strings.Join(ace.Sections, ";") + ")"
+		if len(ace.Sections) < 6 {
+			report(aceString, "the ACE is malformed")
+			continue
+		}
+
+		deny := false
+		switch strings.ToUpper(ace.Sections[0]) {
+		case "A":
+		case "D":
+			deny = true
+		default:
+			report(aceString, "only access allowed ACEs can be represented")
+			continue
+		}
+		if aceFlagsContain(ace.Sections[1], "IO") {
+			report(aceString, "inherit-only ACEs don't apply to the file itself")
+			continue
+		}
+
+		mask, err := aceRightsToAccessMask(strings.TrimSpace(ace.Sections[2]))
+		if err != nil {
+			report(aceString, err.Error())
+			continue
+		}
+		perm := accessMaskToPosixPerm(mask)
+
+		sid, err := CanonicalizeSid(strings.TrimSpace(ace.Sections[5]))
+		if err != nil {
+			report(aceString, err.Error())
+			continue
+		}
+
+		if deny {
+			// a user's own entry is all that POSIX grants them, so denying a user is the same as leaving rights out of it;
+			// groups and Everyone can't be denied, since their members may have entries of their own
+			if uid, ok := mapping.UIDForSID(sid); ok && sid != ownerSID {
+				usersDenied[uid] |= accessMaskToDeniedPosixPerm(mask)
+			} else if sid == ownerSID || sid == ownerRightsSID {
+				userObjDenied |= accessMaskToDeniedPosixPerm(mask)
+			} else {
+				report(aceString, "POSIX ACLs can only deny access to a user")
+			}
+			continue
+		}
+
+		if uid, ok := mapping.UIDForSID(sid); ok && sid != ownerSID {
+			users[uid] |= perm
+		} else if gid, ok := mapping.GIDForSID(sid); ok && sid != groupSID {
+			groups[gid] |= perm
+		} else {
+			switch sid {
+			case ownerSID, ownerRightsSID:
+				userObj |= perm
+			case groupSID:
+				groupObj |= perm
+			case everyoneSID:
+				other |= perm
+			default:
+				report(aceString, "the SID has no uid or gid in the identity mapping")
+			}
+		}
+	}
+
+	// Everyone includes the owner, the group and the named users and groups, but POSIX "other" doesn't.
+	userObj = (userObj | other) &^ userObjDenied
+	groupObj |= other
+	for uid := range usersDenied {
+		if _, ok := users[uid]; !ok {
+			users[uid] = 0 // a denied user still needs an entry, or "other" would give them what they were denied
+		}
+	}
+
+	t.ACL = append(t.ACL, PosixACLEntry{Tag: ACL_USER_OBJ, Perm: userObj, ID: ACL_UNDEFINED_ID})
+	t.ACL = appendNamedPosixEntries(t.ACL, ACL_USER, users, other, usersDenied)
+	t.ACL = append(t.ACL, PosixACLEntry{Tag: ACL_GROUP_OBJ, Perm: groupObj, ID: ACL_UNDEFINED_ID})
+	t.ACL = appendNamedPosixEntries(t.ACL, ACL_GROUP, groups, other, nil)
+
+	if len(users) > 0 || len(groups) > 0 {
+		// The mask caps the named entries and the owning group, so it must allow everything they're granted.
+		mask := groupObj
+		for _, e := range t.ACL {
+			if e.Tag == ACL_USER || e.Tag == ACL_GROUP {
+				mask |= e.Perm
+			}
+		}
+		t.ACL = append(t.ACL, PosixACLEntry{Tag: ACL_MASK, Perm: mask, ID: ACL_UNDEFINED_ID})
+	}
+
+	t.ACL = append(t.ACL, PosixACLEntry{Tag: ACL_OTHER, Perm: other, ID: ACL_UNDEFINED_ID})
+	return t
+}
+
+func appendNamedPosixEntries(acl PosixACL, tag uint16, perms map[uint32]uint16, other uint16, denied map[uint32]uint16) PosixACL {
+	ids := make([]uint32, 0, len(perms))
+	for id := range perms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		acl = append(acl, PosixACLEntry{Tag: tag, Perm: (perms[id] | other) &^ denied[id], ID: id})
+	}
+	return acl
+}
+
+// TranslatePosixToSDDL converts a file's owner, group and POSIX access ACL to a protected security descriptor,
+// the reverse of TranslateSDDLToPosix. Named entries, and the owning group, are limited by the ACL's mask.
+// "other" becomes an ACE for Everyone, which the owner and the named users and groups are members of too, so they're
+// denied whatever Everyone has that their own entry doesn't. A group can only be denied rights that no user entry
+// or other group entry grants, since its members may be granted them that way; the rest are reported as unrepresentable.
+// Uids and gids that have no SID in the mapping are reported in the second return value.
+func TranslatePosixToSDDL(uid, gid uint32, acl PosixACL, mapping *common.IdentityMapping) (SDDLString, []UnrepresentableACE) {
+	var unrepresentable []UnrepresentableACE
+	report := func(entry string, reason string) {
+		unrepresentable = append(unrepresentable, UnrepresentableACE{ACE: entry, Reason: reason})
+	}
+
+	s := SDDLString{DACL: ACLList{Flags: "P"}}
+	ownerSID, hasOwner := mapping.SIDForUID(uid)
+	if hasOwner {
+		s.OwnerSID = ownerSID
+	} else {
+		report(fmt.Sprintf("owner %d", uid), "the uid has no SID in the identity mapping")
+	}
+	groupSID, hasGroup := mapping.SIDForGID(gid)
+	if hasGroup {
+		s.GroupSID = groupSID
+	} else {
+		report(fmt.Sprintf("group %d", gid), "the gid has no SID in the identity mapping")
+	}
+
+	var mask uint16 = ACL_READ | ACL_WRITE | ACL_EXECUTE
+	for _, e := range acl {
+		if e.Tag == ACL_MASK {
+			mask = e.Perm
+		}
+	}
+
+	type translatedEntry struct {
+		entry PosixACLEntry
+		sid   string
+		perm  uint16 // what the entry effectively grants
+	}
+	var entries []translatedEntry
+	var other, userClass uint16
+	for _, e := range acl {
+		var sid string
+		var ok bool
+		perm := e.Perm
+
+		switch e.Tag {
+		case ACL_USER_OBJ:
+			sid, ok = ownerSID, hasOwner
+		case ACL_USER:
+			sid, ok = mapping.SIDForUID(e.ID)
+			perm &= mask
+		case ACL_GROUP_OBJ:
+			sid, ok = groupSID, hasGroup
+			perm &= mask
+		case ACL_GROUP:
+			sid, ok = mapping.SIDForGID(e.ID)
+			perm &= mask
+		case ACL_OTHER:
+			sid, ok = "WD", true
+			other = perm
+		case ACL_MASK:
+			continue
+		default:
+			report(e.String(), "unknown POSIX ACL entry tag")
+			continue
+		}
+
+		if e.Tag == ACL_USER_OBJ || e.Tag == ACL_USER {
+			userClass |= perm
+		}
+		if !ok {
+			report(e.String(), "the entry's uid or gid has no SID in the identity mapping")
+			continue
+		}
+		entries = append(entries, translatedEntry{entry: e, sid: sid, perm: perm})
+	}
+
+	// deny ACEs go first, so that they take precedence over what Everyone is allowed
+	for i, t := range entries {
+		denied := other &^ t.perm
+		switch t.entry.Tag {
+		case ACL_OTHER:
+			continue
+		case ACL_GROUP_OBJ, ACL_GROUP:
+			grantedElsewhere := userClass
+			for j, u := range entries {
+				if j != i && (u.entry.Tag == ACL_GROUP_OBJ || u.entry.Tag == ACL_GROUP) {
+					grantedElsewhere |= u.perm
+				}
+			}
+			if undeniable := denied & grantedElsewhere; undeniable != 0 {
+				report(t.entry.String(), fmt.Sprintf("the group's members get %s from Everyone, since it can't be denied to the group "+
+					"without also denying it to the members that other entries grant it to", posixPermString(undeniable)))
+				denied &^= undeniable
+			}
+		}
+		if denied == 0 {
+			continue
+		}
+
+		s.DACL.ACLEntries = append(s.DACL.ACLEntries, ACLEntry{
+			Sections: []string{"D", "", fmt.Sprintf("0x%x", posixPermToDenyMask(denied)), "", "", t.sid},
+		})
+	}
+
+	for _, t := range entries {
+		if t.perm == 0 {
+			// an allow ACE with no rights is the same as no ACE at all
+			continue
+		}
+
+		s.DACL.ACLEntries = append(s.DACL.ACLEntries, ACLEntry{
+			Sections: []string{"A", "", aceRightsToString(posixPermToAccessMask(t.perm)), "", "", t.sid},
+		})
+	}
+
+	return s, unrepresentable
+}
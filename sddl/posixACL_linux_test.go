@@ -0,0 +1,152 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sddl
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type posixACLSuite struct{}
+
+var _ = chk.Suite(&posixACLSuite{})
+
+// Contoso SIDs again, with fake RIDs.
+const contosoMapping = `# sid,kind,id
+S-1-5-21-1004336348-1177238915-682003330-99991, uid, 1001
+S-1-5-21-1004336348-1177238915-682003330-99992, uid, 1002
+S-1-5-21-1004336348-1177238915-682003330-99995, gid, 100
+S-1-5-21-1004336348-1177238915-682003330-99996, gid, 200
+`
+
+func (s *posixACLSuite) mapping(c *chk.C) *common.IdentityMapping {
+	m, err := common.ParseIdentityMapping(strings.NewReader(contosoMapping))
+	c.Assert(err, chk.IsNil)
+	return m
+}
+
+func (s *posixACLSuite) TestDomainRelativeSIDs(c *chk.C) {
+	mapping, err := common.ParseIdentityMapping(strings.NewReader("S-1-5-21-1004336348-1177238915-682003330,domain"))
+	c.Assert(err, chk.IsNil)
+	getIdentityMapping = func() (*common.IdentityMapping, error) { return mapping, nil }
+	defer func() { getIdentityMapping = common.GetIdentityMapping }()
+
+	sid, err := OSTranslateSID("DU")
+	c.Assert(err, chk.IsNil)
+	c.Assert(sid, chk.Equals, "S-1-5-21-1004336348-1177238915-682003330-513")
+
+	// without the domain, they can't be translated
+	getIdentityMapping = func() (*common.IdentityMapping, error) { return nil, nil }
+	_, err = OSTranslateSID("DU")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *posixACLSuite) TestTranslateSDDLToPosix(c *chk.C) {
+	parsed, err := ParseSDDL(`O:S-1-5-21-1004336348-1177238915-682003330-99991G:S-1-5-21-1004336348-1177238915-682003330-99995D:P` +
+		`(A;;FA;;;S-1-5-21-1004336348-1177238915-682003330-99991)` + // owner: rwx
+		`(A;;0x1200a9;;;S-1-5-21-1004336348-1177238915-682003330-99995)` + // group: r-x
+		`(A;;FR;;;S-1-5-21-1004336348-1177238915-682003330-99992)` + // named user: r--
+		`(A;;0x1301bf;;;S-1-5-21-1004336348-1177238915-682003330-99996)` + // named group: rwx
+		`(A;;FX;;;WD)` + // everyone: --x
+		`(D;;FX;;;S-1-5-21-1004336348-1177238915-682003330-99992)` + // deny a user what everyone has
+		`(D;;FW;;;S-1-5-21-1004336348-1177238915-682003330-99996)` + // deny a group
+		`(A;OICIIO;FA;;;CO)` + // inherit only
+		`(A;;FA;;;S-1-5-21-1004336348-1177238915-682003330-99999)`) // unmapped
+	c.Assert(err, chk.IsNil)
+
+	t := TranslateSDDLToPosix(parsed, s.mapping(c))
+	c.Assert(t.HasUID, chk.Equals, true)
+	c.Assert(t.UID, chk.Equals, uint32(1001))
+	c.Assert(t.HasGID, chk.Equals, true)
+	c.Assert(t.GID, chk.Equals, uint32(100))
+	c.Assert(t.ACL.String(), chk.Equals, "u::rwx,u:1002:r--,g::r-x,g:200:rwx,m::rwx,o::--x")
+
+	c.Assert(t.Unrepresentable, chk.HasLen, 3)
+	c.Assert(t.Unrepresentable[0].ACE, chk.Equals, "(D;;FW;;;S-1-5-21-1004336348-1177238915-682003330-99996)")
+	c.Assert(t.Unrepresentable[1].ACE, chk.Equals, "(A;OICIIO;FA;;;CO)")
+	c.Assert(t.Unrepresentable[2].ACE, chk.Equals, "(A;;FA;;;S-1-5-21-1004336348-1177238915-682003330-99999)")
+}
+
+func (s *posixACLSuite) TestTranslatePosixToSDDL(c *chk.C) {
+	acl := PosixACL{
+		{Tag: ACL_USER_OBJ, Perm: ACL_READ | ACL_WRITE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_USER, Perm: ACL_READ | ACL_WRITE, ID: 1002},
+		{Tag: ACL_USER, Perm: ACL_READ, ID: 4242}, // unmapped
+		{Tag: ACL_GROUP_OBJ, Perm: ACL_READ | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_MASK, Perm: ACL_READ, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_OTHER, Perm: 0, ID: ACL_UNDEFINED_ID},
+	}
+
+	// the ACL should survive its trip through the xattr format
+	parsedACL, err := ParsePosixACL(acl.Marshal())
+	c.Assert(err, chk.IsNil)
+	c.Assert(parsedACL, chk.DeepEquals, acl)
+
+	translated, unrepresentable := TranslatePosixToSDDL(1001, 100, parsedACL, s.mapping(c))
+	c.Assert(translated.String(), chk.Equals, "O:S-1-5-21-1004336348-1177238915-682003330-99991G:S-1-5-21-1004336348-1177238915-682003330-99995D:P"+
+		"(A;;0x12019f;;;S-1-5-21-1004336348-1177238915-682003330-99991)"+ // rw-
+		"(A;;0x120089;;;S-1-5-21-1004336348-1177238915-682003330-99992)"+ // limited to r-- by the mask
+		"(A;;0x120089;;;S-1-5-21-1004336348-1177238915-682003330-99995)") // also limited by the mask
+	c.Assert(unrepresentable, chk.HasLen, 1)
+	c.Assert(unrepresentable[0].ACE, chk.Equals, "u:4242:r--")
+
+	// and translating back should give the effective permissions of the original
+	parsed, err := ParseSDDL(translated.String())
+	c.Assert(err, chk.IsNil)
+	back := TranslateSDDLToPosix(parsed, s.mapping(c))
+	c.Assert(back.Unrepresentable, chk.HasLen, 0)
+	c.Assert(back.ACL.String(), chk.Equals, "u::rw-,u:1002:r--,g::r--,m::r--,o::---")
+}
+
+func (s *posixACLSuite) TestTranslatePosixToSDDLDeniesWhatOtherGrants(c *chk.C) {
+	// other has more than the owner and the group, as in a mode of 0047
+	acl := PosixACL{
+		{Tag: ACL_USER_OBJ, Perm: 0, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_USER, Perm: ACL_READ, ID: 1002},
+		{Tag: ACL_GROUP_OBJ, Perm: ACL_READ, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_GROUP, Perm: ACL_READ | ACL_WRITE, ID: 200},
+		{Tag: ACL_MASK, Perm: ACL_READ | ACL_WRITE, ID: ACL_UNDEFINED_ID},
+		{Tag: ACL_OTHER, Perm: ACL_READ | ACL_WRITE | ACL_EXECUTE, ID: ACL_UNDEFINED_ID},
+	}
+
+	translated, unrepresentable := TranslatePosixToSDDL(1001, 100, acl, s.mapping(c))
+	c.Assert(translated.String(), chk.Equals, "O:S-1-5-21-1004336348-1177238915-682003330-99991G:S-1-5-21-1004336348-1177238915-682003330-99995D:P"+
+		"(D;;0x27;;;S-1-5-21-1004336348-1177238915-682003330-99991)"+ // the owner has nothing
+		"(D;;0x26;;;S-1-5-21-1004336348-1177238915-682003330-99992)"+ // -wx
+		"(D;;0x20;;;S-1-5-21-1004336348-1177238915-682003330-99995)"+ // --x, but not -w-, which group 200 grants its members
+		"(D;;0x20;;;S-1-5-21-1004336348-1177238915-682003330-99996)"+ // --x
+		"(A;;0x120089;;;S-1-5-21-1004336348-1177238915-682003330-99992)"+
+		"(A;;0x120089;;;S-1-5-21-1004336348-1177238915-682003330-99995)"+
+		"(A;;0x12019f;;;S-1-5-21-1004336348-1177238915-682003330-99996)"+
+		"(A;;0x1201bf;;;WD)")
+	c.Assert(unrepresentable, chk.HasLen, 1)
+	c.Assert(unrepresentable[0].ACE, chk.Equals, "g::r--")
+
+	// and translating back should give the original permissions of the users; POSIX can't deny groups what other has
+	parsed, err := ParseSDDL(translated.String())
+	c.Assert(err, chk.IsNil)
+	back := TranslateSDDLToPosix(parsed, s.mapping(c))
+	c.Assert(back.ACL.String(), chk.Equals, "u::---,u:1002:r--,g::rwx,g:200:rwx,m::rwx,o::rwx")
+	c.Assert(back.Unrepresentable, chk.HasLen, 2)
+}
@@ -566,6 +566,9 @@ var wellKnownSidShortcuts = map[string]AnySID{
 	"SS": {SID_REVISION, 1, SECURITY_AUTHENTICATION_AUTHORITY, []uint32{SECURITY_AUTHENTICATION_SERVICE_ASSERTED_RID}},
 }
 
+// Overridden in tests.
+var getIdentityMapping = common.GetIdentityMapping
+
 // TODO: Validate completeness/correctness.
 var domainRidShortcuts = map[string]uint32{
 	"RO": DOMAIN_GROUP_RID_ENTERPRISE_READONLY_DOMAIN_CONTROLLERS,
@@ -819,10 +822,17 @@ func stringToSid(sidString string) ([]byte, error) {
 			}
 		} else if rid, ok := domainRidShortcuts[sidString]; ok {
 			// Domain RID like "DU"?
-			// TODO: Add domain RID support. We need to prefix the domain SID.
-			fmt.Printf("Got well known RID %d\n", rid)
+			// There's no domain to ask for its SID, so it has to come from the identity mapping file.
+			mapping, err := getIdentityMapping()
+			if err != nil {
+				return nil, err
+			}
+			if mapping == nil || mapping.DomainSID == "" {
+				return nil, fmt.Errorf("Domain RID (%s) needs the domain SID, which can be given in the identity mapping file named by %s",
+					sidString, common.EEnvironmentVariable.SIDMappingFile().Name)
+			}
 
-			panic("Domain RIDs not yet implemented!")
+			return stringToSid(fmt.Sprintf("%s-%d", mapping.DomainSID, rid))
 		} else {
 			return nil, fmt.Errorf("Invalid SID: %s", sidStringOriginal)
 		}
@@ -1267,6 +1277,39 @@ func SecurityDescriptorToString(sd []byte) (string, error) {
 	return sddlString, nil
 }
 
+// Convert the rights section of an ACE string, either shorthand names or an integral mask, to an access mask.
+func aceRightsToAccessMask(aceRights string) (uint32, error) {
+	var accessMask uint32 = 0
+
+	// Hex right string will start with 0x or 0X.
+	if len(aceRights) > 2 && (aceRights[0:2] == "0x" || aceRights[0:2] == "0X") {
+		accessMask, err := strconv.ParseUint(aceRights[2:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to parse integral aceRights %s: %v", aceRights, err)
+		}
+		return uint32(accessMask), nil
+	}
+
+	for i := 0; i < len(aceRights); {
+		// Must have even number of characters.
+		if i+1 == len(aceRights) {
+			return 0, fmt.Errorf("Invalid aceRights: %s", aceRights)
+		}
+
+		right := aceRights[i : i+2]
+
+		if mask, ok := aceStringToRightsMap[right]; ok {
+			accessMask |= mask
+		} else {
+			return 0, fmt.Errorf("Unknown aceRight(%s): %s", right, aceRights)
+		}
+
+		i += 2
+	}
+
+	return accessMask, nil
+}
+
 // SecurityDescriptorFromString converts a SDDL formatted string into a binary Security Descriptor in
 // SECURITY_DESCRIPTOR_RELATIVE format.
 func SecurityDescriptorFromString(sddlString string) ([]byte, error) {
@@ -1365,38 +1408,6 @@ func SecurityDescriptorFromString(sddlString string) ([]byte, error) {
 		return flags, nil
 	}
 
-	aceRightsToAccessMask := func(aceRights string) (uint32, error) {
-		var accessMask uint32 = 0
-
-		// Hex right string will start with 0x or 0X.
-		if len(aceRights) > 2 && (aceRights[0:2] == "0x" || aceRights[0:2] == "0X") {
-			accessMask, err := strconv.ParseUint(aceRights[2:], 16, 32)
-			if err != nil {
-				return 0, fmt.Errorf("Failed to parse integral aceRights %s: %v", aceRights, err)
-			}
-			return uint32(accessMask), nil
-		}
-
-		for i := 0; i < len(aceRights); {
-			// Must have even number of characters.
-			if i+1 == len(aceRights) {
-				return 0, fmt.Errorf("Invalid aceRights: %s", aceRights)
-			}
-
-			right := aceRights[i : i+2]
-
-			if mask, ok := aceStringToRightsMap[right]; ok {
-				accessMask |= mask
-			} else {
-				return 0, fmt.Errorf("Unknown aceRight(%s): %s", right, aceRights)
-			}
-
-			i += 2
-		}
-
-		return accessMask, nil
-	}
-
 	aclEntryToSlice := func(aclEntry ACLEntry) ([]byte, error) {
 		// ace_type;ace_flags;rights;object_guid;inherit_object_guid;account_sid;(resource_attribute)
 		if len(aclEntry.Sections) != 6 {
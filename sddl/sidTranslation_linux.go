@@ -23,6 +23,7 @@
 package sddl
 
 // Note that all usages of OSTranslateSID gracefully handle the error, rather than throwing the error.
+// Domain-relative SIDs (e.g. DU) can only be translated when the identity mapping file gives the domain SID.
 func OSTranslateSID(SID string) (string, error) {
 	return CanonicalizeSid(SID)
 }
@@ -13,7 +13,7 @@ import (
 // dataSchemaVersion defines the data schema version of JobPart order files supported by
 // current version of azcopy
 // To be Incremented every time when we release azcopy with changed dataSchema
-const DataSchemaVersion common.Version = 18
+const DataSchemaVersion common.Version = 22
 
 const (
 	CustomHeaderMaxBytes = 256
@@ -41,14 +41,10 @@ type JobPartPlanHeader struct {
 	StartTime              int64             // The start time of this part
 	JobID                  common.JobID      // Job Part's JobID
 	PartNum                common.PartNumber // Job Part's part number (0+)
-	SourceRootLength       uint16            // The length of the source root path
-	SourceRoot             [1000]byte        // The root directory of the source
-	SourceExtraQueryLength uint16
-	SourceExtraQuery       [1000]byte // Extra query params applicable to the source
-	DestinationRootLength  uint16     // The length of the destination root path
-	DestinationRoot        [1000]byte // The root directory of the destination
-	DestExtraQueryLength   uint16
-	DestExtraQuery         [1000]byte                  // Extra query params applicable to the dest
+	SourceRootLength       uint32            // The length of the root directory of the source, which follows the command string
+	SourceExtraQueryLength uint32            // The length of the extra query params applicable to the source, which follow the source root
+	DestinationRootLength  uint32            // The length of the root directory of the destination, which follows the source's extra query
+	DestExtraQueryLength   uint32            // The length of the extra query params applicable to the dest, which follow the destination root
 	IsFinalPart            bool                        // True if this is the Job's last part; else false
 	ForceWrite             common.OverwriteOption      // True if the existing blobs needs to be overwritten.
 	ForceIfReadOnly        bool                        // Supplements ForceWrite with an additional setting for Azure Files. If true, the read-only attribute will be cleared before we overwrite
@@ -94,6 +90,15 @@ type JobPartPlanHeader struct {
 	PermanentDeleteOption common.PermanentDeleteOption
 
 	RehydratePriority common.RehydratePriorityType
+
+	// PreserveXattrs represents whether the extended attributes of local files are sent or restored
+	PreserveXattrs bool
+
+	// AccessControlListLength is the length of the ACL entries of the acl command, which follow the roots
+	AccessControlListLength uint32
+
+	// AdaptiveBlockSize says whether the block size of each file is chosen as it starts, rather than fixed for the whole job
+	AdaptiveBlockSize bool
 }
 
 // Status returns the job status stored in JobPartPlanHeader in thread-safe manner
@@ -121,13 +126,17 @@ func (jpph *JobPartPlanHeader) Transfer(transferIndex uint32) *JobPartPlanTransf
 		panic(errors.New("requesting a transfer index greater than what is available"))
 	}
 
-	// (Job Part Plan's file address) + (header size) + (padding to 8 bytes) --> beginning of transfers in file
+	// (Job Part Plan's file address) + (beginning of transfers in file)
 	// Add (transfer size) * (transfer index)
-	transfersOffset := unsafe.Sizeof(*jpph) + uintptr(jpph.CommandStringLength)
-	transfersOffset = (transfersOffset + 7) & ^uintptr(7)
+	transfersOffset := uintptr(jpph.transfersOffset())
 	return (*JobPartPlanTransfer)(unsafe.Pointer((uintptr(unsafe.Pointer(jpph)) + transfersOffset) + (unsafe.Sizeof(JobPartPlanTransfer{}) * uintptr(transferIndex))))
 }
 
+// transfersOffset returns the offset of the first transfer: (header size) + (command string, roots and ACL) + (padding to 8 bytes)
+func (jpph *JobPartPlanHeader) transfersOffset() int64 {
+	return (jpph.accessControlListOffset() + int64(jpph.AccessControlListLength) + 7) & ^7
+}
+
 // CommandString returns the command string given by user when job was created
 func (jpph *JobPartPlanHeader) CommandString() string {
 	commandSlice := []byte{}
@@ -138,6 +147,55 @@ func (jpph *JobPartPlanHeader) CommandString() string {
 	return string(commandSlice)
 }
 
+// rootsOffset returns the offset of the source and destination roots, which follow the command string
+func (jpph *JobPartPlanHeader) rootsOffset() int64 {
+	return int64(unsafe.Sizeof(*jpph)) + int64(jpph.CommandStringLength)
+}
+
+func (jpph *JobPartPlanHeader) rootsLength() int64 {
+	return int64(jpph.SourceRootLength) + int64(jpph.SourceExtraQueryLength) + int64(jpph.DestinationRootLength) + int64(jpph.DestExtraQueryLength)
+}
+
+// SourceRoot returns the root directory of the source
+func (jpph *JobPartPlanHeader) SourceRoot() string {
+	return jpph.getRootString(jpph.rootsOffset(), jpph.SourceRootLength)
+}
+
+// SourceExtraQuery returns the extra query params applicable to the source
+func (jpph *JobPartPlanHeader) SourceExtraQuery() string {
+	return jpph.getRootString(jpph.rootsOffset()+int64(jpph.SourceRootLength), jpph.SourceExtraQueryLength)
+}
+
+// DestinationRoot returns the root directory of the destination
+func (jpph *JobPartPlanHeader) DestinationRoot() string {
+	return jpph.getRootString(jpph.rootsOffset()+int64(jpph.SourceRootLength)+int64(jpph.SourceExtraQueryLength), jpph.DestinationRootLength)
+}
+
+// DestExtraQuery returns the extra query params applicable to the dest
+func (jpph *JobPartPlanHeader) DestExtraQuery() string {
+	return jpph.getRootString(jpph.rootsOffset()+int64(jpph.SourceRootLength)+int64(jpph.SourceExtraQueryLength)+int64(jpph.DestinationRootLength), jpph.DestExtraQueryLength)
+}
+
+// accessControlListOffset returns the offset of the acl command's ACL entries, which follow the roots
+func (jpph *JobPartPlanHeader) accessControlListOffset() int64 {
+	return jpph.rootsOffset() + jpph.rootsLength()
+}
+
+// AccessControlList returns the ACL entries that the acl command sets, modifies or removes
+func (jpph *JobPartPlanHeader) AccessControlList() string {
+	return jpph.getRootString(jpph.accessControlListOffset(), jpph.AccessControlListLength)
+}
+
+func (jpph *JobPartPlanHeader) getRootString(offset int64, length uint32) string {
+	tempSlice := []byte{}
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&tempSlice))
+	sh.Data = uintptr(unsafe.Pointer(jpph)) + uintptr(offset) // Address of Job Part Plan + this string's offset
+	sh.Len = int(length)
+	sh.Cap = sh.Len
+
+	return string(tempSlice)
+}
+
 func (jpph *JobPartPlanHeader) TransferSrcDstRelatives(transferIndex uint32) (relSource, relDest string) {
 	jppt := jpph.Transfer(transferIndex)
 
@@ -181,10 +239,10 @@ func (jpph *JobPartPlanHeader) GetRelativeSrcDstStrings(transferIndex uint32) (s
 // TransferSrcDstDetail returns the source and destination string for a transfer at given transferIndex in JobPartOrder
 // Also indication of entity type since that's often necessary to avoid ambiguity about what the source and dest are
 func (jpph *JobPartPlanHeader) TransferSrcDstStrings(transferIndex uint32) (source, destination string, isFolder bool) {
-	srcRoot := string(jpph.SourceRoot[:jpph.SourceRootLength])
-	srcExtraQuery := string(jpph.SourceExtraQuery[:jpph.SourceExtraQueryLength])
-	dstRoot := string(jpph.DestinationRoot[:jpph.DestinationRootLength])
-	dstExtraQuery := string(jpph.DestExtraQuery[:jpph.DestExtraQueryLength])
+	srcRoot := jpph.SourceRoot()
+	srcExtraQuery := jpph.SourceExtraQuery()
+	dstRoot := jpph.DestinationRoot()
+	dstExtraQuery := jpph.DestExtraQuery()
 
 	jppt := jpph.Transfer(transferIndex)
 	isFolder = jppt.EntityType == common.EEntityType.Folder()
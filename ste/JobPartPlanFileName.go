@@ -81,18 +81,6 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	}
 
 	// Validate that the passed-in strings can fit in their respective fields
-	if len(order.SourceRoot.Value) > len(JobPartPlanHeader{}.SourceRoot) {
-		panic(fmt.Errorf("source root string is too large: %q", order.SourceRoot))
-	}
-	if len(order.SourceRoot.ExtraQuery) > len(JobPartPlanHeader{}.SourceExtraQuery) {
-		panic(fmt.Errorf("source extra query strings too large: %q", order.SourceRoot.ExtraQuery))
-	}
-	if len(order.DestinationRoot.Value) > len(JobPartPlanHeader{}.DestinationRoot) {
-		panic(fmt.Errorf("destination root string is too large: %q", order.DestinationRoot))
-	}
-	if len(order.DestinationRoot.ExtraQuery) > len(JobPartPlanHeader{}.DestExtraQuery) {
-		panic(fmt.Errorf("destination extra query strings too large: %q", order.DestinationRoot.ExtraQuery))
-	}
 	if len(order.BlobAttributes.ContentType) > len(JobPartPlanDstBlob{}.ContentType) {
 		panic(fmt.Errorf("content type string is too large: %q", order.BlobAttributes.ContentType))
 	}
@@ -170,10 +158,10 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		StartTime:              time.Now().UnixNano(),
 		JobID:                  order.JobID,
 		PartNum:                order.PartNum,
-		SourceRootLength:       uint16(len(order.SourceRoot.Value)),
-		SourceExtraQueryLength: uint16(len(order.SourceRoot.ExtraQuery)),
-		DestinationRootLength:  uint16(len(order.DestinationRoot.Value)),
-		DestExtraQueryLength:   uint16(len(order.DestinationRoot.ExtraQuery)),
+		SourceRootLength:       uint32(len(order.SourceRoot.Value)),
+		SourceExtraQueryLength: uint32(len(order.SourceRoot.ExtraQuery)),
+		DestinationRootLength:  uint32(len(order.DestinationRoot.Value)),
+		DestExtraQueryLength:   uint32(len(order.DestinationRoot.ExtraQuery)),
 		IsFinalPart:            order.IsFinalPart,
 		ForceWrite:             order.ForceWrite,
 		ForceIfReadOnly:        order.ForceIfReadOnly,
@@ -221,17 +209,15 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		DeleteSnapshotsOption:          order.BlobAttributes.DeleteSnapshotsOption,
 		PermanentDeleteOption:          order.BlobAttributes.PermanentDeleteOption,
 		RehydratePriority:              order.BlobAttributes.RehydratePriority,
+		PreserveXattrs:                 order.PreserveXattrs,
+		AccessControlListLength:        uint32(len(order.BlobAttributes.AccessControlList)),
+		AdaptiveBlockSize:              order.BlobAttributes.AdaptiveBlockSize,
 		DstFileData: JobPartPlanDstFile{
 			TrailingDot: order.FileAttributes.TrailingDot,
 		},
 	}
 
 	// Copy any strings into their respective fields
-	// do NOT copy Source/DestinationRoot.SAS, since we do NOT persist SASs
-	copy(jpph.SourceRoot[:], order.SourceRoot.Value)
-	copy(jpph.SourceExtraQuery[:], order.SourceRoot.ExtraQuery)
-	copy(jpph.DestinationRoot[:], order.DestinationRoot.Value)
-	copy(jpph.DestExtraQuery[:], order.DestinationRoot.ExtraQuery)
 	copy(jpph.DstBlobData.ContentType[:], order.BlobAttributes.ContentType)
 	copy(jpph.DstBlobData.ContentEncoding[:], order.BlobAttributes.ContentEncoding)
 	copy(jpph.DstBlobData.ContentLanguage[:], order.BlobAttributes.ContentLanguage)
@@ -250,6 +236,23 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	}
 	eof += int64(bytesWritten)
 
+	// write the roots after the command string, in the order that JobPartPlanHeader expects them
+	// do NOT write Source/DestinationRoot.SAS, since we do NOT persist SASs
+	for _, root := range []string{order.SourceRoot.Value, order.SourceRoot.ExtraQuery, order.DestinationRoot.Value, order.DestinationRoot.ExtraQuery} {
+		bytesWritten, err = file.WriteString(root)
+		if err != nil {
+			panic(err)
+		}
+		eof += int64(bytesWritten)
+	}
+
+	// then the acl command's ACL entries
+	bytesWritten, err = file.WriteString(order.BlobAttributes.AccessControlList)
+	if err != nil {
+		panic(err)
+	}
+	eof += int64(bytesWritten)
+
 	// ensure 8 byte alignment so that Atomic fields of JobPartPlanTransfer can actually be accessed atomically
 	paddingLen := ((eof + 7) & ^7) - eof
 	if paddingLen != 0 {
@@ -0,0 +1,290 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// The types in this file are decoded, self-describing copies of what is in a job part plan file,
+// for tools that inspect plan files without loading the job into the STE (e.g. "jobs inspect").
+
+// JobPartPlanInfo describes a JobPartPlanHeader.
+type JobPartPlanInfo struct {
+	Version                        common.Version
+	JobID                          common.JobID
+	PartNum                        common.PartNumber
+	StartTime                      time.Time
+	IsFinalPart                    bool
+	CommandString                  string
+	SourceRoot                     string
+	SourceExtraQuery               string `json:",omitempty"`
+	DestinationRoot                string
+	DestExtraQuery                 string `json:",omitempty"`
+	FromTo                         string
+	FolderPropertyOption           string
+	ForceWrite                     string
+	ForceIfReadOnly                bool
+	AutoDecompress                 bool
+	NumTransfers                   uint32
+	LogLevel                       string
+	PreservePermissions            string
+	PreserveSMBInfo                bool
+	PreservePOSIXProperties        bool
+	S2SGetPropertiesInBackend      bool
+	S2SSourceChangeValidation      bool
+	DestLengthValidation           bool
+	S2SInvalidMetadataHandleOption string
+	BlobFSRecursiveDelete          bool
+	DeleteSnapshotsOption          string
+	PermanentDeleteOption          string
+	RehydratePriority              string
+	PreserveXattrs                 bool
+	AdaptiveBlockSize              bool
+	AccessControlList              string `json:",omitempty"`
+	JobStatus                      string
+	PartStatus                     string
+	DstBlobData                    JobPartPlanDstBlobInfo
+	DstLocalData                   JobPartPlanDstLocalInfo
+	DstFileData                    JobPartPlanDstFileInfo
+}
+
+// JobPartPlanDstBlobInfo describes a JobPartPlanDstBlob.
+type JobPartPlanDstBlobInfo struct {
+	BlobType           string
+	NoGuessMimeType    bool
+	ContentType        string `json:",omitempty"`
+	ContentEncoding    string `json:",omitempty"`
+	ContentLanguage    string `json:",omitempty"`
+	ContentDisposition string `json:",omitempty"`
+	CacheControl       string `json:",omitempty"`
+	BlockBlobTier      string
+	PageBlobTier       string
+	PutMd5             bool
+	Metadata           string `json:",omitempty"`
+	BlobTags           string `json:",omitempty"`
+	CpkInfo            bool
+	IsSourceEncrypted  bool
+	CpkScopeInfo       string `json:",omitempty"`
+	BlockSize          int64
+	SetPropertiesFlags string
+}
+
+// JobPartPlanDstLocalInfo describes a JobPartPlanDstLocal.
+type JobPartPlanDstLocalInfo struct {
+	PreserveLastModifiedTime bool
+	MD5VerificationOption    string
+}
+
+// JobPartPlanDstFileInfo describes a JobPartPlanDstFile.
+type JobPartPlanDstFileInfo struct {
+	TrailingDot string
+}
+
+// JobPartPlanTransferInfo describes a JobPartPlanTransfer, including the strings stored after it.
+type JobPartPlanTransferInfo struct {
+	PartNum        common.PartNumber
+	TransferIndex  uint32
+	Source         string // relative to the part's SourceRoot
+	Destination    string // relative to the part's DestinationRoot
+	EntityType     string
+	SourceSize     int64
+	ModifiedTime   time.Time
+	CompletionTime time.Time `json:",omitempty"`
+	Status         common.TransferStatus
+	ErrorCode      int32
+
+	// source properties, only present for S2S transfers
+	ContentType        string          `json:",omitempty"`
+	ContentEncoding    string          `json:",omitempty"`
+	ContentLanguage    string          `json:",omitempty"`
+	ContentDisposition string          `json:",omitempty"`
+	CacheControl       string          `json:",omitempty"`
+	ContentMD5         []byte          `json:",omitempty"`
+	Metadata           common.Metadata `json:",omitempty"`
+	BlobType           string          `json:",omitempty"`
+	BlobTier           string          `json:",omitempty"`
+	BlobVersionID      string          `json:",omitempty"`
+	BlobSnapshotID     string          `json:",omitempty"`
+	BlobTags           common.BlobTags `json:",omitempty"`
+}
+
+// Info decodes the header of the job part plan.
+func (jpph *JobPartPlanHeader) Info() JobPartPlanInfo {
+	b := &jpph.DstBlobData
+	return JobPartPlanInfo{
+		Version:                        jpph.Version,
+		JobID:                          jpph.JobID,
+		PartNum:                        jpph.PartNum,
+		StartTime:                      time.Unix(0, jpph.StartTime).UTC(),
+		IsFinalPart:                    jpph.IsFinalPart,
+		CommandString:                  jpph.CommandString(),
+		SourceRoot:                     jpph.SourceRoot(),
+		SourceExtraQuery:               jpph.SourceExtraQuery(),
+		DestinationRoot:                jpph.DestinationRoot(),
+		DestExtraQuery:                 jpph.DestExtraQuery(),
+		FromTo:                         jpph.FromTo.String(),
+		FolderPropertyOption:           jpph.Fpo.String(),
+		ForceWrite:                     jpph.ForceWrite.String(),
+		ForceIfReadOnly:                jpph.ForceIfReadOnly,
+		AutoDecompress:                 jpph.AutoDecompress,
+		NumTransfers:                   jpph.NumTransfers,
+		LogLevel:                       jpph.LogLevel.String(),
+		PreservePermissions:            jpph.PreservePermissions.String(),
+		PreserveSMBInfo:                jpph.PreserveSMBInfo,
+		PreservePOSIXProperties:        jpph.PreservePOSIXProperties,
+		S2SGetPropertiesInBackend:      jpph.S2SGetPropertiesInBackend,
+		S2SSourceChangeValidation:      jpph.S2SSourceChangeValidation,
+		DestLengthValidation:           jpph.DestLengthValidation,
+		S2SInvalidMetadataHandleOption: jpph.S2SInvalidMetadataHandleOption.String(),
+		BlobFSRecursiveDelete:          jpph.BlobFSRecursiveDelete,
+		DeleteSnapshotsOption:          jpph.DeleteSnapshotsOption.String(),
+		PermanentDeleteOption:          jpph.PermanentDeleteOption.String(),
+		RehydratePriority:              jpph.RehydratePriority.String(),
+		PreserveXattrs:                 jpph.PreserveXattrs,
+		AdaptiveBlockSize:              jpph.AdaptiveBlockSize,
+		AccessControlList:              jpph.AccessControlList(),
+		JobStatus:                      jpph.JobStatus().String(),
+		PartStatus:                     jpph.JobPartStatus().String(),
+		DstBlobData: JobPartPlanDstBlobInfo{
+			BlobType:           b.BlobType.String(),
+			NoGuessMimeType:    b.NoGuessMimeType,
+			ContentType:        string(b.ContentType[:b.ContentTypeLength]),
+			ContentEncoding:    string(b.ContentEncoding[:b.ContentEncodingLength]),
+			ContentLanguage:    string(b.ContentLanguage[:b.ContentLanguageLength]),
+			ContentDisposition: string(b.ContentDisposition[:b.ContentDispositionLength]),
+			CacheControl:       string(b.CacheControl[:b.CacheControlLength]),
+			BlockBlobTier:      b.BlockBlobTier.String(),
+			PageBlobTier:       b.PageBlobTier.String(),
+			PutMd5:             b.PutMd5,
+			Metadata:           string(b.Metadata[:b.MetadataLength]),
+			BlobTags:           string(b.BlobTags[:b.BlobTagsLength]),
+			CpkInfo:            b.CpkInfo,
+			IsSourceEncrypted:  b.IsSourceEncrypted,
+			CpkScopeInfo:       string(b.CpkScopeInfo[:b.CpkScopeInfoLength]),
+			BlockSize:          b.BlockSize,
+			SetPropertiesFlags: fmt.Sprint(b.SetPropertiesFlags),
+		},
+		DstLocalData: JobPartPlanDstLocalInfo{
+			PreserveLastModifiedTime: jpph.DstLocalData.PreserveLastModifiedTime,
+			MD5VerificationOption:    jpph.DstLocalData.MD5VerificationOption.String(),
+		},
+		DstFileData: JobPartPlanDstFileInfo{
+			TrailingDot: jpph.DstFileData.TrailingDot.String(),
+		},
+	}
+}
+
+// TransferInfo decodes the transfer at transferIndex.
+func (jpph *JobPartPlanHeader) TransferInfo(transferIndex uint32) JobPartPlanTransferInfo {
+	t := jpph.Transfer(transferIndex)
+	src, dst := jpph.TransferSrcDstRelatives(transferIndex)
+	headers, metadata, blobType, blobTier, _, _, _, _, _, versionID, snapshotID, tags := jpph.TransferSrcPropertiesAndMetadata(transferIndex)
+
+	info := JobPartPlanTransferInfo{
+		PartNum:            jpph.PartNum,
+		TransferIndex:      transferIndex,
+		Source:             src,
+		Destination:        dst,
+		EntityType:         t.EntityType.String(),
+		SourceSize:         t.SourceSize,
+		ModifiedTime:       time.Unix(0, t.ModifiedTime).UTC(),
+		Status:             t.TransferStatus(),
+		ErrorCode:          t.ErrorCode(),
+		ContentType:        headers.ContentType,
+		ContentEncoding:    headers.ContentEncoding,
+		ContentLanguage:    headers.ContentLanguage,
+		ContentDisposition: headers.ContentDisposition,
+		CacheControl:       headers.CacheControl,
+		ContentMD5:         headers.ContentMD5,
+		Metadata:           metadata,
+		BlobType:           string(blobType),
+		BlobTier:           string(blobTier),
+		BlobVersionID:      versionID,
+		BlobSnapshotID:     snapshotID,
+		BlobTags:           tags,
+	}
+	if t.CompletionTime != 0 {
+		info.CompletionTime = time.Unix(0, int64(t.CompletionTime)).UTC()
+	}
+	return info
+}
+
+// checkBounds makes sure that the transfer table, and the strings of every transfer, are within a file of fileSize bytes,
+// so that a truncated or partly written plan file is reported rather than read past its end.
+func (jpph *JobPartPlanHeader) checkBounds(fileSize int64) error {
+	transfersEnd := jpph.transfersOffset() + int64(jpph.NumTransfers)*int64(unsafe.Sizeof(JobPartPlanTransfer{}))
+	if transfersEnd > fileSize {
+		return fmt.Errorf("its %d transfers need %d bytes, but it only has %d", jpph.NumTransfers, transfersEnd, fileSize)
+	}
+
+	for i := uint32(0); i < jpph.NumTransfers; i++ {
+		t := jpph.Transfer(i)
+		stringsEnd := t.SrcOffset
+		for _, length := range []int16{t.SrcLength, t.DstLength,
+			t.SrcContentTypeLength, t.SrcContentEncodingLength, t.SrcContentLanguageLength, t.SrcContentDispositionLength,
+			t.SrcCacheControlLength, t.SrcContentMD5Length, t.SrcMetadataLength, t.SrcBlobTypeLength, t.SrcBlobTierLength,
+			t.SrcBlobVersionIDLength, t.SrcBlobSnapshotIDLength, t.SrcBlobTagsLength} {
+			if length < 0 {
+				return fmt.Errorf("transfer %d has a string of negative length", i)
+			}
+			stringsEnd += int64(length)
+		}
+		if t.SrcOffset < transfersEnd || stringsEnd > fileSize {
+			return fmt.Errorf("the strings of transfer %d, from byte %d to %d, are not between the transfer table and the end of the file", i, t.SrcOffset, stringsEnd)
+		}
+	}
+	return nil
+}
+
+// MapReadOnly memory-maps the plan file for reading only, so that it can be inspected while another AzCopy process
+// is working on the job. Unlike Map, it reports failures rather than panicking, including when the file is too short
+// for the transfers and strings that its header and transfer table describe.
+func (jpfn JobPartPlanFileName) MapReadOnly() (*JobPartPlanMMF, error) {
+	file, err := os.Open(jpfn.GetJobPartPlanPath())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.Size() < int64(unsafe.Sizeof(JobPartPlanHeader{})) {
+		return nil, fmt.Errorf("plan file %s is too small to be valid", jpfn)
+	}
+
+	mmf, err := common.NewMMF(file, false, 0, fileInfo.Size())
+	if err != nil {
+		return nil, err
+	}
+	planMMF := (*JobPartPlanMMF)(mmf)
+	if err = planMMF.Plan().checkBounds(fileInfo.Size()); err != nil {
+		planMMF.Unmap()
+		return nil, fmt.Errorf("plan file %s is truncated or corrupt: %w", jpfn, err)
+	}
+	return planMMF, nil
+}
@@ -0,0 +1,284 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// Plan files are memory-mapped structs, so their layout is tied to the version of AzCopy that wrote them (see DataSchemaVersion).
+// To let jobs survive an upgrade of AzCopy, each change to the layout comes with a migration, which rewrites a plan file
+// of the previous version into the next one. MigrateJobPlanFiles chains these together to bring old plans up to date.
+//
+// Migrated plans are written to new files, named for the current version; the originals are left alone, so that the
+// older AzCopy can still resume the job if need be. Both are removed by "jobs rm" and "jobs clean".
+
+// planMigration rewrites the contents of a plan file of one version into those of the next version.
+// It must preserve everything that resuming depends on, such as transfer statuses and error codes.
+type planMigration func(old []byte) ([]byte, error)
+
+// planMigrations is keyed by the version that each migration upgrades from.
+var planMigrations = map[common.Version]planMigration{}
+
+// planVersionSize is the size of JobPartPlanHeader.Version, which every version of the layout starts with.
+const planVersionSize = 4
+
+// planFileVersion returns the data schema version in the name of a plan file.
+func planFileVersion(name string) (common.Version, bool) {
+	i := strings.LastIndex(name, ".steV")
+	if i < 0 {
+		return 0, false
+	}
+
+	var v common.Version
+	if _, err := fmt.Sscanf(name[i:], ".steV%d", &v); err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// MigrateJobPlanFiles upgrades the plan files of the given job, in planDir, that were written by older versions of AzCopy.
+// Parts that already have a plan file of the current version are left alone. It returns the number of parts migrated.
+func MigrateJobPlanFiles(planDir string, jobID common.JobID) (migrated int, err error) {
+	matches, err := filepath.Glob(filepath.Join(planDir, jobID.String()+"--*.steV*"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(matches)
+
+	for _, path := range matches {
+		name := filepath.Base(path)
+		version, ok := planFileVersion(name)
+		if !ok || version == DataSchemaVersion {
+			continue
+		} else if version > DataSchemaVersion {
+			return migrated, fmt.Errorf("plan file %s was written by a newer version of AzCopy, and cannot be used by this one", name)
+		}
+
+		target := filepath.Join(planDir, strings.TrimSuffix(name, fmt.Sprintf(".steV%d", version))+fmt.Sprintf(".steV%d", DataSchemaVersion))
+		if _, err := os.Stat(target); err == nil {
+			continue // already migrated
+		}
+
+		if err := migratePlanFile(path, target, version); err != nil {
+			return migrated, fmt.Errorf("failed to migrate plan file %s: %w", name, err)
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
+func migratePlanFile(source, target string, version common.Version) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	if data, err = migratePlan(data, version); err != nil {
+		return err
+	}
+
+	// write to a temporary name first, so that a partially written plan is never picked up
+	tmp := target + ".migrating"
+	if err := os.WriteFile(tmp, data, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// migratePlan applies migrations to the contents of a plan file until it reaches DataSchemaVersion.
+func migratePlan(data []byte, version common.Version) ([]byte, error) {
+	for version < DataSchemaVersion {
+		if len(data) < planVersionSize {
+			return nil, fmt.Errorf("plan is too small to be valid")
+		}
+		if actual := common.Version(binary.LittleEndian.Uint32(data)); actual != version {
+			return nil, fmt.Errorf("plan file's header says it is version %d, but its name says %d", actual, version)
+		}
+
+		migrate, ok := planMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("there is no migration from plan version %d; the job must be resumed with the version of AzCopy that created it", version)
+		}
+
+		var err error
+		if data, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("migrating from plan version %d: %w", version, err)
+		}
+		version++
+	}
+
+	if actual := common.Version(binary.LittleEndian.Uint32(data)); actual != DataSchemaVersion {
+		return nil, fmt.Errorf("migrated plan has version %d rather than %d", actual, DataSchemaVersion)
+	}
+	return data, nil
+}
+
+// Layout of version 18, whose header held the source and destination roots in fixed arrays of 1000 bytes,
+// each preceded by a uint16 length.
+const (
+	planV18HeaderSize          = 10672
+	planV18RootsOffset         = 36
+	planV18RootsEnd            = 4044 // where the fields after the roots begin
+	planV18CommandStringOffset = 4060 // of CommandStringLength
+	planV18NumTransfersOffset  = 4064
+	planV18TransferSize        = 72 // the transfers begin with SrcOffset
+	planV18RootSize            = 1000
+)
+
+func init() {
+	planMigrations[18] = migratePlanV18
+}
+
+// migratePlanV18 moves the roots out of the header, to follow the command string.
+func migratePlanV18(old []byte) ([]byte, error) {
+	if len(old) < planV18HeaderSize {
+		return nil, fmt.Errorf("plan is too small to be valid")
+	}
+
+	var roots [][]byte
+	offset := planV18RootsOffset
+	for i := 0; i < 4; i++ {
+		length := int(binary.LittleEndian.Uint16(old[offset:]))
+		if length > planV18RootSize {
+			return nil, fmt.Errorf("root length %d is too large", length)
+		}
+		roots = append(roots, old[offset+2:offset+2+length])
+		offset += 2 + planV18RootSize
+	}
+
+	commandStringLength := int64(binary.LittleEndian.Uint32(old[planV18CommandStringOffset:]))
+	numTransfers := int64(binary.LittleEndian.Uint32(old[planV18NumTransfersOffset:]))
+	oldTransfersOffset := alignTo8(planV18HeaderSize + commandStringLength)
+	if int64(len(old)) < oldTransfersOffset+numTransfers*planV18TransferSize {
+		return nil, fmt.Errorf("plan is too small to hold its %d transfers", numTransfers)
+	}
+
+	// the header, with uint32 lengths in place of the roots. The fields after them keep their alignment,
+	// since the header shrinks by a multiple of 8 bytes.
+	data := make([]byte, 0, len(old))
+	data = append(data, old[:planV18RootsOffset]...)
+	for _, root := range roots {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(root)))
+	}
+	data = append(data, old[planV18RootsEnd:planV18HeaderSize]...)
+	binary.LittleEndian.PutUint32(data, 19)
+
+	// then the command string and the roots, padded to 8 bytes
+	data = append(data, old[planV18HeaderSize:planV18HeaderSize+commandStringLength]...)
+	for _, root := range roots {
+		data = append(data, root...)
+	}
+	newTransfersOffset := alignTo8(int64(len(data)))
+	data = append(data, make([]byte, newTransfersOffset-int64(len(data)))...)
+
+	// then the transfers and their strings, whose offsets from the start of the file have moved
+	data = append(data, old[oldTransfersOffset:]...)
+	shift := newTransfersOffset - oldTransfersOffset
+	for t := int64(0); t < numTransfers; t++ {
+		srcOffset := data[newTransfersOffset+t*planV18TransferSize:]
+		binary.LittleEndian.PutUint64(srcOffset, uint64(int64(binary.LittleEndian.Uint64(srcOffset))+shift))
+	}
+
+	return data, nil
+}
+
+func alignTo8(offset int64) int64 {
+	return (offset + 7) & ^7
+}
+
+// Layout of version 19, whose header had no ACL entries for the acl command.
+const (
+	planV19HeaderSize          = 6680
+	planV19RootsOffset         = 36 // of the four uint32 root lengths
+	planV19CommandStringOffset = 68 // of CommandStringLength
+	planV19NumTransfersOffset  = 72
+	planV19TransferSize        = 72
+)
+
+func init() {
+	planMigrations[19] = migratePlanV19
+}
+
+// migratePlanV19 adds AccessControlListLength to the end of the header. Old plans have no ACL entries.
+func migratePlanV19(old []byte) ([]byte, error) {
+	if len(old) < planV19HeaderSize {
+		return nil, fmt.Errorf("plan is too small to be valid")
+	}
+
+	stringsLength := int64(binary.LittleEndian.Uint32(old[planV19CommandStringOffset:]))
+	for i := 0; i < 4; i++ {
+		stringsLength += int64(binary.LittleEndian.Uint32(old[planV19RootsOffset+4*i:]))
+	}
+	numTransfers := int64(binary.LittleEndian.Uint32(old[planV19NumTransfersOffset:]))
+	oldTransfersOffset := alignTo8(planV19HeaderSize + stringsLength)
+	if int64(len(old)) < oldTransfersOffset+numTransfers*planV19TransferSize {
+		return nil, fmt.Errorf("plan is too small to hold its %d transfers", numTransfers)
+	}
+
+	// the header, with a zero length (and padding) at its end
+	data := make([]byte, 0, len(old)+8)
+	data = append(data, old[:planV19HeaderSize]...)
+	data = append(data, make([]byte, 8)...)
+	binary.LittleEndian.PutUint32(data, 20)
+
+	// then the command string and the roots, which are unchanged, and the transfers, which move along with them
+	data = append(data, old[planV19HeaderSize:oldTransfersOffset]...)
+	newTransfersOffset := oldTransfersOffset + 8
+	data = append(data, old[oldTransfersOffset:]...)
+	for t := int64(0); t < numTransfers; t++ {
+		srcOffset := data[newTransfersOffset+t*planV19TransferSize:]
+		binary.LittleEndian.PutUint64(srcOffset, uint64(int64(binary.LittleEndian.Uint64(srcOffset))+8))
+	}
+
+	return data, nil
+}
+
+func init() {
+	planMigrations[20] = migratePlanV20
+}
+
+// migratePlanV20 only changes the version. PreserveXattrs took the place of padding in the header, which was zero,
+// so old plans don't preserve extended attributes.
+func migratePlanV20(old []byte) ([]byte, error) {
+	data := append([]byte(nil), old...)
+	binary.LittleEndian.PutUint32(data, 21)
+	return data, nil
+}
+
+func init() {
+	planMigrations[21] = migratePlanV21
+}
+
+// migratePlanV21 only changes the version. AdaptiveBlockSize took the place of padding at the end of the header, which was zero,
+// so old plans keep the block sizes they were created with.
+func migratePlanV21(old []byte) ([]byte, error) {
+	data := append([]byte(nil), old...)
+	binary.LittleEndian.PutUint32(data, 22)
+	return data, nil
+}
@@ -0,0 +1,82 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// PlanHeader returns the header of the plan file held in data, after checking that it is a plan of the current version.
+// The header points into data, so it is only valid for as long as data is.
+func PlanHeader(data []byte) (*JobPartPlanHeader, error) {
+	if len(data) < int(unsafe.Sizeof(JobPartPlanHeader{})) {
+		return nil, fmt.Errorf("plan is too small to be valid")
+	}
+
+	// []byte allocations of this size are 8 byte aligned, as the atomic fields of the plan require
+	plan := (*JobPartPlanHeader)(unsafe.Pointer(&data[0]))
+	if plan.Version != DataSchemaVersion {
+		return nil, fmt.Errorf("plan has version %d rather than %d", plan.Version, DataSchemaVersion)
+	}
+	if int64(len(data)) < plan.transfersOffset()+int64(plan.NumTransfers)*int64(unsafe.Sizeof(JobPartPlanTransfer{})) {
+		return nil, fmt.Errorf("plan is too small to hold its %d transfers", plan.NumTransfers)
+	}
+	return plan, nil
+}
+
+// RerootJobPartPlan returns a copy of the plan file held in data, with its source and/or destination root replaced.
+// An empty root is left as it was. The transfers, which are relative to the roots, are kept as they are.
+func RerootJobPartPlan(data []byte, sourceRoot, destinationRoot string) ([]byte, error) {
+	plan, err := PlanHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []string{plan.SourceRoot(), plan.SourceExtraQuery(), plan.DestinationRoot(), plan.DestExtraQuery()}
+	if sourceRoot != "" {
+		roots[0] = sourceRoot
+	}
+	if destinationRoot != "" {
+		roots[2] = destinationRoot
+	}
+
+	// the roots follow the command string, and precede the ACL entries, the transfers and their strings
+	oldTransfersOffset := plan.transfersOffset()
+	newData := make([]byte, 0, len(data)+len(sourceRoot)+len(destinationRoot)+8)
+	newData = append(newData, data[:plan.rootsOffset()]...)
+	for _, root := range roots {
+		newData = append(newData, root...)
+	}
+	newData = append(newData, plan.AccessControlList()...)
+	newData = append(newData, make([]byte, ((len(newData)+7) & ^7)-len(newData))...)
+	newData = append(newData, data[oldTransfersOffset:]...)
+
+	newPlan := (*JobPartPlanHeader)(unsafe.Pointer(&newData[0]))
+	newPlan.SourceRootLength = uint32(len(roots[0]))
+	newPlan.DestinationRootLength = uint32(len(roots[2]))
+
+	// the transfers' strings are addressed from the start of the file, so they moved along with the transfers
+	shift := newPlan.transfersOffset() - oldTransfersOffset
+	for t := uint32(0); t < newPlan.NumTransfers; t++ {
+		newPlan.Transfer(t).SrcOffset += shift
+	}
+	return newData, nil
+}
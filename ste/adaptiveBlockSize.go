@@ -0,0 +1,167 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// With adaptive block sizes, the block size of each file is chosen when its transfer starts,
+// from the size of the file, the service's limits, and what has been seen of the requests made so far to the same host.
+// Files that start later in the job therefore benefit from what was learned from earlier ones.
+
+const (
+	// adaptive block sizes never go above this, unless that's the only way to keep within the service's limit on the number of blocks.
+	adaptiveMaxBlockSize = 100 * 1024 * 1024
+
+	// nor do they take so much memory that fewer than this many chunks can be in memory at once
+	adaptiveMinChunksInMemory = 16
+
+	// with throughput observations, we aim for each request to take about this long, so that the fixed per-request
+	// costs (round trips, request processing) are small relative to the time spent moving data
+	adaptiveTargetRequestDuration = 2 * time.Second
+
+	// files bigger than the default block size are split into at least this many chunks, so that they can still be transferred in parallel
+	adaptiveMinChunksPerFile = 8
+
+	// downloads use bigger ranges when time-to-first-byte is above this, as long as the service isn't busy.
+	// The range size grows in proportion to the latency beyond it.
+	adaptiveHighLatencyMs             = 200
+	adaptiveMaxServerBusyPercentage   = 1.0
+	adaptiveRequestBodyThresholdBytes = 1024 * 1024 // only requests with bodies at least this big tell us about throughput
+)
+
+// blockSizeObservations are what has been seen of requests to a host
+type blockSizeObservations struct {
+	bytesPerSecondPerRequest float64 // the throughput of a single request, for requests with large bodies. Zero if unknown
+	averageLatencyMs         int64   // of all requests, from sending to receiving the response headers. Zero if unknown
+	serverBusyPercentage     float32
+}
+
+// chooseAdaptiveBlockSize picks the block size for one file, between the default and the limits imposed by memory and the service.
+func chooseAdaptiveBlockSize(fileSize int64, isDownload bool, memLimit int64, ob blockSizeObservations) int64 {
+	const mib = 1024 * 1024
+	size := int64(common.DefaultBlockBlobBlockSize)
+
+	if isDownload {
+		// Downloads can't measure throughput per request in the pipeline, since the body is read after the response is returned.
+		// But high latency with no sign of throttling means time is being lost on round trips, which bigger ranges amortize
+		if ob.averageLatencyMs > adaptiveHighLatencyMs && ob.serverBusyPercentage < adaptiveMaxServerBusyPercentage {
+			size = size * ob.averageLatencyMs / adaptiveHighLatencyMs
+		}
+	} else if ob.bytesPerSecondPerRequest > 0 {
+		if forThroughput := int64(ob.bytesPerSecondPerRequest * adaptiveTargetRequestDuration.Seconds()); forThroughput > size {
+			size = forThroughput
+		}
+	}
+
+	// keep enough chunks for the file to be worked on in parallel
+	if perFileMax := fileSize / adaptiveMinChunksPerFile; size > perFileMax {
+		size = perFileMax
+	}
+	if size < common.DefaultBlockBlobBlockSize {
+		size = common.DefaultBlockBlobBlockSize
+	}
+
+	// and don't use too much memory
+	if size > adaptiveMaxBlockSize {
+		size = adaptiveMaxBlockSize
+	}
+	if memMax := memLimit / adaptiveMinChunksInMemory; memLimit > 0 && size > memMax {
+		size = memMax
+	}
+
+	// the service's limits come last, since they are not negotiable
+	if size*common.MaxNumberOfBlocksPerBlob < fileSize {
+		size = (fileSize + common.MaxNumberOfBlocksPerBlob - 1) / common.MaxNumberOfBlocksPerBlob
+	}
+
+	// round up to whole MiB, which also keeps page blob chunks aligned to pages. Rounding up can't break
+	// the block count limit, and the maximum block size is itself a whole number of MiB
+	size = (size + mib - 1) / mib * mib
+	if size > common.MaxBlockBlobBlockSize {
+		size = common.MaxBlockBlobBlockSize
+	}
+	return size
+}
+
+// hostRequestStats accumulates the requests made to one host
+type hostRequestStats struct {
+	atomicRequestCount      int64
+	atomicTotalMilliseconds int64
+	atomicBodyBytes         int64 // of the requests with large bodies
+	atomicBodyMilliseconds  int64 // of the requests with large bodies
+}
+
+// requestStatsByHost holds the stats that the adaptive block size choice needs, for each host
+type requestStatsByHost struct {
+	hosts sync.Map // host name to *hostRequestStats
+}
+
+func (r *requestStatsByHost) record(host string, bodyBytes int64, duration time.Duration) {
+	v, ok := r.hosts.Load(host)
+	if !ok {
+		v, _ = r.hosts.LoadOrStore(host, &hostRequestStats{})
+	}
+	s := v.(*hostRequestStats)
+	ms := duration.Milliseconds()
+	atomic.AddInt64(&s.atomicRequestCount, 1)
+	atomic.AddInt64(&s.atomicTotalMilliseconds, ms)
+	if bodyBytes >= adaptiveRequestBodyThresholdBytes {
+		atomic.AddInt64(&s.atomicBodyBytes, bodyBytes)
+		atomic.AddInt64(&s.atomicBodyMilliseconds, ms)
+	}
+}
+
+func (r *requestStatsByHost) observations(host string) blockSizeObservations {
+	ob := blockSizeObservations{}
+	v, ok := r.hosts.Load(host)
+	if !ok {
+		return ob
+	}
+	s := v.(*hostRequestStats)
+	if count := atomic.LoadInt64(&s.atomicRequestCount); count > 0 {
+		ob.averageLatencyMs = atomic.LoadInt64(&s.atomicTotalMilliseconds) / count
+	}
+	if ms := atomic.LoadInt64(&s.atomicBodyMilliseconds); ms > 0 {
+		ob.bytesPerSecondPerRequest = float64(atomic.LoadInt64(&s.atomicBodyBytes)) / (float64(ms) / 1000)
+	}
+	return ob
+}
+
+// adaptiveBlockSizeHost is the host whose requests govern the block size: the remote end for uploads and downloads,
+// and the destination for S2S, since that's where the blocks are put.
+func adaptiveBlockSizeHost(fromTo common.FromTo, src, dst string) string {
+	remote := dst
+	if fromTo.IsDownload() {
+		remote = src
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
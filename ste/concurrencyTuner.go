@@ -21,6 +21,8 @@
 package ste
 
 import (
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 )
@@ -36,8 +38,23 @@ type ConcurrencyTuner interface {
 	// GetFinalState returns the final state of the tuner
 	GetFinalState() (finalReason string, finalRecommendedConcurrency int)
 
+	// GetTuningDecisions returns a description of the tuning strategy, and each concurrency value it has tried so far, with what was observed there
+	GetTuningDecisions() (strategy string, decisions []ConcurrencyDecision)
+
 	// recordRetry informs the concurrencyTuner that a retry has happened
 	recordRetry()
+
+	// recordLatency informs the concurrencyTuner of the end-to-end duration of one request
+	recordLatency(milliseconds int64)
+}
+
+// ConcurrencyDecision is one concurrency value recommended by the tuner, and what was observed once it was in use
+type ConcurrencyDecision struct {
+	Concurrency  int
+	Reason       string
+	Mbps         int
+	P95LatencyMs int
+	Retries      int64
 }
 
 type NullConcurrencyTuner struct {
@@ -56,12 +73,23 @@ func (n *NullConcurrencyTuner) GetFinalState() (finalReason string, finalRecomme
 	return ConcurrencyReasonTunerDisabled, n.FixedValue
 }
 
+func (n *NullConcurrencyTuner) GetTuningDecisions() (strategy string, decisions []ConcurrencyDecision) {
+	return "", nil
+}
+
 func (n *NullConcurrencyTuner) recordRetry() {
 	// noop
 }
 
+func (n *NullConcurrencyTuner) recordLatency(milliseconds int64) {
+	// noop
+}
+
+// autoConcurrencyTuner runs a concurrencyTuningStrategy, feeding it the observations that are passed in
+// to GetRecommendedConcurrency, and passing out the recommendations that it makes
 type autoConcurrencyTuner struct {
 	atomicRetryCount int64
+	atomicFinished   int32
 	observations     chan struct {
 		mbps      int
 		isHighCpu bool
@@ -73,13 +101,32 @@ type autoConcurrencyTuner struct {
 	initialConcurrency  int
 	maxConcurrency      int
 	callbacksWhenStable chan func()
+	strategy            concurrencyTuningStrategy
+	strategyDescription string
+	decisions           []ConcurrencyDecision
+	pendingDecision     *ConcurrencyDecision // the recommendation that is awaiting its observation
 	finalReason         string
 	finalConcurrency    int
 	lockFinal           sync.Mutex
-	isBenchmarking      bool
+	latencies           []int64 // milliseconds, of the requests since the last observation
+	lockLatencies       sync.Mutex
 }
 
+// NewAutoConcurrencyTuner creates a tuner that uses the default, hill-climbing, strategy
 func NewAutoConcurrencyTuner(initial, max int, isBenchmarking bool) ConcurrencyTuner {
+	return newStrategyConcurrencyTuner(initial, max, &hillClimbingStrategy{isBenchmarking: isBenchmarking})
+}
+
+// NewConcurrencyTuner creates a tuner that uses the strategy named in strategySpec. See ParseConcurrencyTuningStrategy for its format.
+func NewConcurrencyTuner(strategySpec string, initial, max int, isBenchmarking bool) (ConcurrencyTuner, error) {
+	strategy, err := parseConcurrencyTuningStrategy(strategySpec, isBenchmarking)
+	if err != nil {
+		return nil, err
+	}
+	return newStrategyConcurrencyTuner(initial, max, strategy), nil
+}
+
+func newStrategyConcurrencyTuner(initial, max int, strategy concurrencyTuningStrategy) *autoConcurrencyTuner {
 	t := &autoConcurrencyTuner{
 		observations: make(chan struct {
 			mbps      int
@@ -92,8 +139,9 @@ func NewAutoConcurrencyTuner(initial, max int, isBenchmarking bool) ConcurrencyT
 		initialConcurrency:  initial,
 		maxConcurrency:      max,
 		callbacksWhenStable: make(chan func(), 1000),
+		strategy:            strategy,
+		strategyDescription: strategy.description(),
 		lockFinal:           sync.Mutex{},
-		isBenchmarking:      isBenchmarking,
 	}
 	go t.worker()
 	return t
@@ -124,6 +172,36 @@ func (t *autoConcurrencyTuner) recordRetry() {
 	atomic.AddInt64(&t.atomicRetryCount, 1)
 }
 
+// the most latencies we keep between observations. Beyond this, new ones overwrite old ones
+const maxTunerLatencySamples = 10000
+
+func (t *autoConcurrencyTuner) recordLatency(milliseconds int64) {
+	if atomic.LoadInt32(&t.atomicFinished) == 1 {
+		return // no-one needs them anymore
+	}
+	t.lockLatencies.Lock()
+	defer t.lockLatencies.Unlock()
+	if len(t.latencies) < maxTunerLatencySamples {
+		t.latencies = append(t.latencies, milliseconds)
+	} else {
+		t.latencies[rand.Intn(maxTunerLatencySamples)] = milliseconds
+	}
+}
+
+// takeP95Latency returns the 95th percentile of the latencies recorded since it was last called (or zero if there were none)
+func (t *autoConcurrencyTuner) takeP95Latency() int {
+	t.lockLatencies.Lock()
+	latencies := t.latencies
+	t.latencies = nil
+	t.lockLatencies.Unlock()
+
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return int(latencies[(len(latencies)*95-1)/100])
+}
+
 const (
 	ConcurrencyReasonNone          = ""
 	ConcurrencyReasonTunerDisabled = "tuner disabled" // used as the final (non-finished) state for null tuner
@@ -134,133 +212,56 @@ const (
 	concurrencyReasonHighCpu       = "at optimum, but may be limited by CPU"
 	concurrencyReasonAtOptimum     = "at optimum"
 	concurrencyReasonFinished      = "tuning already finished (or never started)"
+	concurrencyReasonLatencyHigh   = "backing off, latency above target"
+	concurrencyReasonAtLatency     = "at latency target"
+	concurrencyReasonSweeping      = "sweeping"
 )
 
 func (t *autoConcurrencyTuner) worker() {
-	const standardMultiplier = 2
-	const boostedMultiplier = standardMultiplier * 2
-	const topOfBoostZone = 256 // boosted multiplier applies up to this many connections
-	const slowdownFactor = 5
-	const minMulitplier = 1.19 // really this is 1.2, but use a little less to make the floating point comparisons robust
-	const fudgeFactor = 0.2
-
-	multiplier := float32(boostedMultiplier)
-	concurrency := float32(t.initialConcurrency)
-	atMax := false
-	highCpu := false
-	everSawHighCpu := false
-	sawHighMultiGbps := false
-	probeHigherRegardless := false
-	dontBackoffRegardless := false
-	multiplierReductionCount := 0
-	lastReason := ConcurrencyReasonNone
-
 	// get initial baseline throughput
-	lastSpeed, _ := t.getCurrentSpeed()
-
-	for { // todo, add the conditions here
-		rateChangeReason := concurrencyReasonSeeking
+	baseline := t.getObservation()
+	t.recordDecision(ConcurrencyDecision{Concurrency: t.initialConcurrency, Reason: concurrencyReasonInitial}, baseline)
 
-		if concurrency >= topOfBoostZone && multiplier > standardMultiplier {
-			multiplier = standardMultiplier // don't use boosted multiplier for ever
-		}
-
-		// enforce a ceiling
-		atMax = concurrency*multiplier > float32(t.maxConcurrency)
-		if atMax {
-			multiplier = float32(t.maxConcurrency) / concurrency
-			rateChangeReason = concurrencyReasonHitMax
-		}
+	concurrency, lastReason := t.strategy.run(t.initialConcurrency, t.maxConcurrency, baseline, t.probe)
 
-		// compute increase
-		concurrency = concurrency * multiplier
-		desiredSpeedIncrease := lastSpeed * (multiplier - 1) * fudgeFactor // we'd like it to speed up linearly, but we'll accept a _lot_ less, according to fudge factor in the interests of finding best possible speed
-		desiredNewSpeed := lastSpeed + desiredSpeedIncrease
-
-		// action the increase and measure its effect
-		lastReason = t.setConcurrency(concurrency, rateChangeReason)
-		lastSpeed, highCpu = t.getCurrentSpeed()
-		if lastSpeed > 11000 {
-			sawHighMultiGbps = true
-		}
-		if highCpu {
-			everSawHighCpu = true // this doesn't stop us probing higher concurrency, since sometimes that works even when CPU looks high, but it does change the way we report the result
-		}
-
-		if t.isBenchmarking {
-			// Be a little more aggressive if we are tuning for benchmarking purposes (as opposed to day to day use)
-
-			// If we are seeing retries (within "normal" concurrency range) then for benchmarking purposes we don't want to back off.
-			// (Since if we back off the retries might stop and then they won't be reported on as a limiting factor.)
-			sawRetry := atomic.SwapInt64(&t.atomicRetryCount, 0) > 0
-			dontBackoffRegardless = sawRetry && concurrency <= 256
-
-			// Workaround for variable throughput when targeting 20 Gbps account limit (concurrency around 64 didn't seem to give stable throughput in some tests)
-			// TODO: review this, and look for root cause/better solution
-			probeHigherRegardless = sawHighMultiGbps && concurrency >= 32 && concurrency < 128 && multiplier >= standardMultiplier
-		}
+	t.storeFinalState(lastReason, float32(concurrency))
+	atomic.StoreInt32(&t.atomicFinished, 1)
+	t.signalStability()
 
-		// decide what to do based on the measurement
-		if lastSpeed > desiredNewSpeed || probeHigherRegardless {
-			// Our concurrency change gave the hoped-for speed increase, so loop around and see if another increase will also work,
-			// unless already at max
-			if atMax {
-				break
-			}
-		} else if dontBackoffRegardless {
-			// nothing more we can do
-			break
-		} else {
-			// the new speed didn't work, so we conclude it was too aggressive and back off to where we were before
-			concurrency = concurrency / multiplier
-
-			// reduce multiplier to probe more slowly on the next iteration
-			if multiplier > standardMultiplier {
-				multiplier = standardMultiplier // just back off from our "boosted" multiplier
-			} else {
-				multiplier = 1 + (multiplier-1)/slowdownFactor // back off to a much smaller multiplier
-			}
-
-			// bump multiplier up until its at least enough to influence the connection count by 1
-			// (but, to make sure our algorithm terminates, limit how much we do this)
-			multiplierReductionCount++
-			if multiplierReductionCount <= 2 {
-				for int(multiplier*concurrency) == int(concurrency) {
-					multiplier += 0.05
-				}
-			}
-
-			if multiplier < minMulitplier {
-				break // no point in tuning anymore
-			} else {
-				lastReason = t.setConcurrency(concurrency, concurrencyReasonBackoff) //nolint:staticcheck
-				lastSpeed, _ = t.getCurrentSpeed()                                   // must re-measure immediately after backing off
-			}
-		}
+	// now just provide an "inactive" value for ever
+	for {
+		_ = t.setConcurrency(float32(concurrency), concurrencyReasonFinished)
+		_ = t.getObservation() // read from the channel
+		t.signalStability()    // in case anyone new has "subscribed"
 	}
+}
 
-	if atMax {
-		// provide no special "we found the best value" result, because actually we possibly didn't find it, we just hit the max,
-		// and we've already notified caller of that reason, when we tied using the max
-	} else {
-		// provide the final value once with a reason why its our final value
-		if everSawHighCpu {
-			lastReason = t.setConcurrency(concurrency, concurrencyReasonHighCpu)
-		} else {
-			lastReason = t.setConcurrency(concurrency, concurrencyReasonAtOptimum)
-		}
-		_, _ = t.getCurrentSpeed() // read from the channel
+// probe recommends the given concurrency, and returns what is observed when it is in use
+func (t *autoConcurrencyTuner) probe(concurrency int, reason string) tuningObservation {
+	_ = t.setConcurrency(float32(concurrency), reason)
+	ob := t.getObservation()
+	t.recordDecision(ConcurrencyDecision{Concurrency: concurrency, Reason: reason}, ob)
+	return ob
+}
+
+func (t *autoConcurrencyTuner) getObservation() tuningObservation {
+	mbps, isHighCpu := t.getCurrentSpeed()
+	return tuningObservation{
+		mbps:         mbps,
+		isHighCpu:    isHighCpu,
+		retries:      atomic.SwapInt64(&t.atomicRetryCount, 0),
+		p95LatencyMs: t.takeP95Latency(),
 	}
+}
 
-	t.storeFinalState(lastReason, concurrency)
-	t.signalStability()
+func (t *autoConcurrencyTuner) recordDecision(d ConcurrencyDecision, ob tuningObservation) {
+	t.lockFinal.Lock()
+	defer t.lockFinal.Unlock()
 
-	// now just provide an "inactive" value for ever
-	for {
-		_ = t.setConcurrency(concurrency, concurrencyReasonFinished)
-		_, _ = t.getCurrentSpeed() // read from the channel
-		t.signalStability()        // in case anyone new has "subscribed"
-	}
+	d.Mbps = int(ob.mbps)
+	d.P95LatencyMs = ob.p95LatencyMs
+	d.Retries = ob.retries
+	t.decisions = append(t.decisions, d)
 }
 
 func (t *autoConcurrencyTuner) setConcurrency(mbps float32, reason string) string {
@@ -295,6 +296,7 @@ func (t *autoConcurrencyTuner) storeFinalState(reason string, concurrency float3
 
 	t.finalReason = reason
 	t.finalConcurrency = int(concurrency)
+	t.strategyDescription = t.strategy.description() // may now include values that the strategy worked out as it ran
 }
 
 func (t *autoConcurrencyTuner) GetFinalState() (reason string, concurrency int) {
@@ -304,6 +306,13 @@ func (t *autoConcurrencyTuner) GetFinalState() (reason string, concurrency int)
 	return t.finalReason, t.finalConcurrency
 }
 
+func (t *autoConcurrencyTuner) GetTuningDecisions() (strategy string, decisions []ConcurrencyDecision) {
+	t.lockFinal.Lock()
+	defer t.lockFinal.Unlock()
+
+	return t.strategyDescription, append([]ConcurrencyDecision{}, t.decisions...)
+}
+
 func (t *autoConcurrencyTuner) RequestCallbackWhenStable(callback func()) (callbackAccepted bool) {
 	select {
 	case t.callbacksWhenStable <- callback:
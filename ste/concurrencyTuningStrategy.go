@@ -0,0 +1,360 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tuningObservation is what was seen while a particular concurrency value was in use
+type tuningObservation struct {
+	mbps         float32
+	isHighCpu    bool
+	retries      int64 // number of 503s seen since the previous observation
+	p95LatencyMs int   // 95th percentile of the end-to-end request durations since the previous observation. Zero if there were no requests
+}
+
+// concurrencyTuningStrategy decides how to tune concurrency, based on what it observes
+type concurrencyTuningStrategy interface {
+	// run does the tuning, starting from what was observed at the initial concurrency.
+	// Each call to probe recommends a new value, and returns what was observed once that value was in use.
+	// When run returns, its result is the value it has settled on, and the reason it gave when it last called probe.
+	run(initial, max int, baseline tuningObservation, probe func(concurrency int, reason string) tuningObservation) (finalConcurrency int, finalReason string)
+
+	// description names the strategy, and its settings, for display
+	description() string
+}
+
+const (
+	HillClimbingStrategyName = "hill-climbing"
+	LatencyStrategyName      = "latency"
+	SweepStrategyName        = "sweep"
+)
+
+// parseConcurrencyTuningStrategy reads a strategy spec, which is one of
+//
+//	hill-climbing (or empty)
+//	latency[:<target p95 latency in milliseconds>]
+//	sweep[:<step>[:<max>]]
+func parseConcurrencyTuningStrategy(spec string, isBenchmarking bool) (concurrencyTuningStrategy, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(spec)), ":")
+	args := make([]int, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid setting '%s' in concurrency tuning strategy '%s'. Settings must be positive whole numbers", p, spec)
+		}
+		args = append(args, n)
+	}
+	tooMany := func(max int) error {
+		if len(args) > max {
+			return fmt.Errorf("too many settings in concurrency tuning strategy '%s'", spec)
+		}
+		return nil
+	}
+
+	switch parts[0] {
+	case "", HillClimbingStrategyName:
+		if err := tooMany(0); err != nil {
+			return nil, err
+		}
+		return &hillClimbingStrategy{isBenchmarking: isBenchmarking}, nil
+	case LatencyStrategyName:
+		if err := tooMany(1); err != nil {
+			return nil, err
+		}
+		s := &latencyTargetStrategy{}
+		if len(args) > 0 {
+			s.targetMs = args[0]
+		}
+		return s, nil
+	case SweepStrategyName:
+		if err := tooMany(2); err != nil {
+			return nil, err
+		}
+		s := &sweepStrategy{step: defaultSweepStep, end: defaultSweepEnd}
+		if len(args) > 0 {
+			s.step = args[0]
+		}
+		if len(args) > 1 {
+			s.end = args[1]
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown concurrency tuning strategy '%s'. Valid strategies are %s, %s[:<targetMilliseconds>] and %s[:<step>[:<max>]]",
+			spec, HillClimbingStrategyName, LatencyStrategyName, SweepStrategyName)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// hillClimbingStrategy is the default. It keeps raising concurrency while that gives worthwhile increases in throughput,
+// backing off and raising it more slowly when it doesn't, until the increases it's trying are too small to bother with.
+type hillClimbingStrategy struct {
+	isBenchmarking bool
+}
+
+func (s *hillClimbingStrategy) description() string {
+	return HillClimbingStrategyName
+}
+
+func (s *hillClimbingStrategy) run(initial, max int, baseline tuningObservation, probe func(concurrency int, reason string) tuningObservation) (int, string) {
+	const standardMultiplier = 2
+	const boostedMultiplier = standardMultiplier * 2
+	const topOfBoostZone = 256 // boosted multiplier applies up to this many connections
+	const slowdownFactor = 5
+	const minMulitplier = 1.19 // really this is 1.2, but use a little less to make the floating point comparisons robust
+	const fudgeFactor = 0.2
+
+	multiplier := float32(boostedMultiplier)
+	concurrency := float32(initial)
+	atMax := false
+	everSawHighCpu := false
+	sawHighMultiGbps := false
+	probeHigherRegardless := false
+	dontBackoffRegardless := false
+	multiplierReductionCount := 0
+	lastReason := ConcurrencyReasonNone
+
+	// start from the initial baseline throughput
+	lastSpeed := baseline.mbps
+
+	for { // todo, add the conditions here
+		rateChangeReason := concurrencyReasonSeeking
+
+		if concurrency >= topOfBoostZone && multiplier > standardMultiplier {
+			multiplier = standardMultiplier // don't use boosted multiplier for ever
+		}
+
+		// enforce a ceiling
+		atMax = concurrency*multiplier > float32(max)
+		if atMax {
+			multiplier = float32(max) / concurrency
+			rateChangeReason = concurrencyReasonHitMax
+		}
+
+		// compute increase
+		concurrency = concurrency * multiplier
+		desiredSpeedIncrease := lastSpeed * (multiplier - 1) * fudgeFactor // we'd like it to speed up linearly, but we'll accept a _lot_ less, according to fudge factor in the interests of finding best possible speed
+		desiredNewSpeed := lastSpeed + desiredSpeedIncrease
+
+		// action the increase and measure its effect
+		lastReason = rateChangeReason
+		ob := probe(int(concurrency), rateChangeReason)
+		lastSpeed = ob.mbps
+		if lastSpeed > 11000 {
+			sawHighMultiGbps = true
+		}
+		if ob.isHighCpu {
+			everSawHighCpu = true // this doesn't stop us probing higher concurrency, since sometimes that works even when CPU looks high, but it does change the way we report the result
+		}
+
+		if s.isBenchmarking {
+			// Be a little more aggressive if we are tuning for benchmarking purposes (as opposed to day to day use)
+
+			// If we are seeing retries (within "normal" concurrency range) then for benchmarking purposes we don't want to back off.
+			// (Since if we back off the retries might stop and then they won't be reported on as a limiting factor.)
+			sawRetry := ob.retries > 0
+			dontBackoffRegardless = sawRetry && concurrency <= 256
+
+			// Workaround for variable throughput when targeting 20 Gbps account limit (concurrency around 64 didn't seem to give stable throughput in some tests)
+			// TODO: review this, and look for root cause/better solution
+			probeHigherRegardless = sawHighMultiGbps && concurrency >= 32 && concurrency < 128 && multiplier >= standardMultiplier
+		}
+
+		// decide what to do based on the measurement
+		if lastSpeed > desiredNewSpeed || probeHigherRegardless {
+			// Our concurrency change gave the hoped-for speed increase, so loop around and see if another increase will also work,
+			// unless already at max
+			if atMax {
+				break
+			}
+		} else if dontBackoffRegardless {
+			// nothing more we can do
+			break
+		} else {
+			// the new speed didn't work, so we conclude it was too aggressive and back off to where we were before
+			concurrency = concurrency / multiplier
+
+			// reduce multiplier to probe more slowly on the next iteration
+			if multiplier > standardMultiplier {
+				multiplier = standardMultiplier // just back off from our "boosted" multiplier
+			} else {
+				multiplier = 1 + (multiplier-1)/slowdownFactor // back off to a much smaller multiplier
+			}
+
+			// bump multiplier up until its at least enough to influence the connection count by 1
+			// (but, to make sure our algorithm terminates, limit how much we do this)
+			multiplierReductionCount++
+			if multiplierReductionCount <= 2 {
+				for int(multiplier*concurrency) == int(concurrency) {
+					multiplier += 0.05
+				}
+			}
+
+			if multiplier < minMulitplier {
+				break // no point in tuning anymore
+			} else {
+				lastReason = concurrencyReasonBackoff                              //nolint:staticcheck
+				lastSpeed = probe(int(concurrency), concurrencyReasonBackoff).mbps // must re-measure immediately after backing off
+			}
+		}
+	}
+
+	if atMax {
+		// provide no special "we found the best value" result, because actually we possibly didn't find it, we just hit the max,
+		// and we've already notified caller of that reason, when we tied using the max
+	} else {
+		// provide the final value once with a reason why its our final value
+		if everSawHighCpu {
+			lastReason = concurrencyReasonHighCpu
+		} else {
+			lastReason = concurrencyReasonAtOptimum
+		}
+		_ = probe(int(concurrency), lastReason)
+	}
+
+	return int(concurrency), lastReason
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// latencyTargetStrategy uses additive-increase/multiplicative-decrease (as in TCP congestion control) to keep the
+// 95th percentile of request latency close to a target. Each interval in which latency stays under the target (and the service
+// reports no throttling) adds a fixed number of connections, and each interval over it cuts the number by a fixed proportion.
+// It settles once it has had to cut back a few times, since by then it has found where latency starts to climb.
+// Useful when AzCopy shares a link, or a storage account, with latency-sensitive traffic.
+type latencyTargetStrategy struct {
+	targetMs int // if zero, the target is worked out from the latency at the initial concurrency
+}
+
+const (
+	latencyStrategyIncrease          = 16
+	latencyStrategyDecreaseFactor    = 0.7
+	latencyStrategyDecreasesToSettle = 3
+
+	// without a user-defined target, we aim for this multiple of the latency at the initial, low, concurrency,
+	// but no lower than the floor (since the baseline might be very low, or might not have been measured at all)
+	latencyStrategyBaselineMultiple = 2
+	latencyStrategyTargetFloorMs    = 100
+)
+
+func (s *latencyTargetStrategy) description() string {
+	if s.targetMs == 0 {
+		return LatencyStrategyName
+	}
+	return fmt.Sprintf("%s (p95 target %d ms)", LatencyStrategyName, s.targetMs)
+}
+
+func (s *latencyTargetStrategy) run(initial, max int, baseline tuningObservation, probe func(concurrency int, reason string) tuningObservation) (int, string) {
+	if s.targetMs == 0 {
+		s.targetMs = baseline.p95LatencyMs * latencyStrategyBaselineMultiple
+		if s.targetMs < latencyStrategyTargetFloorMs {
+			s.targetMs = latencyStrategyTargetFloorMs
+		}
+	}
+
+	concurrency := initial
+	decreases := 0
+	ob := baseline
+	for {
+		var reason string
+		if ob.p95LatencyMs > s.targetMs || ob.retries > 0 {
+			decreases++
+			concurrency = int(float32(concurrency) * latencyStrategyDecreaseFactor)
+			if concurrency < 1 {
+				concurrency = 1
+			}
+			if decreases >= latencyStrategyDecreasesToSettle {
+				break
+			}
+			reason = concurrencyReasonLatencyHigh
+		} else {
+			if concurrency >= max {
+				// we already said we'd hit the max, when we moved here
+				return concurrency, concurrencyReasonHitMax
+			}
+			concurrency += latencyStrategyIncrease
+			reason = concurrencyReasonSeeking
+			if concurrency >= max {
+				concurrency = max
+				reason = concurrencyReasonHitMax
+			}
+		}
+		ob = probe(concurrency, reason)
+	}
+
+	_ = probe(concurrency, concurrencyReasonAtLatency)
+	return concurrency, concurrencyReasonAtLatency
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// sweepStrategy is for diagnostics. It tries every multiple of a fixed step, up to a limit, so that the whole
+// curve of throughput (and latency) against concurrency can be seen in its decisions. Then it settles on whichever value
+// gave the best throughput.
+type sweepStrategy struct {
+	step int
+	end  int
+}
+
+const (
+	defaultSweepStep = 16
+	defaultSweepEnd  = 256
+)
+
+func (s *sweepStrategy) description() string {
+	return fmt.Sprintf("%s (step %d, up to %d)", SweepStrategyName, s.step, s.end)
+}
+
+func (s *sweepStrategy) run(initial, max int, baseline tuningObservation, probe func(concurrency int, reason string) tuningObservation) (int, string) {
+	end := s.end
+	if end > max {
+		end = max
+	}
+
+	best, bestMbps := initial, baseline.mbps
+	everSawHighCpu := baseline.isHighCpu
+	concurrency := initial
+	for {
+		next := (concurrency/s.step + 1) * s.step // go to the next multiple of step, so the values are round numbers regardless of the initial value
+		if next > end {
+			break
+		}
+		concurrency = next
+		ob := probe(concurrency, concurrencyReasonSweeping)
+		if ob.mbps > bestMbps {
+			best, bestMbps = concurrency, ob.mbps
+		}
+		everSawHighCpu = everSawHighCpu || ob.isHighCpu
+	}
+
+	reason := concurrencyReasonAtOptimum
+	if best == concurrency && concurrency != initial {
+		reason = concurrencyReasonHitMax // throughput was still going up at the end of the sweep, so the best value may be higher still
+	} else if everSawHighCpu {
+		reason = concurrencyReasonHighCpu
+	}
+	_ = probe(best, reason)
+	return best, reason
+}
@@ -116,6 +116,11 @@ func (a *azureFilesDownloader) PutSDDL(sip ISMBPropertyBearingSourceInfoProvider
 		return errorNoSddlFound
 	}
 
+	if txInfo.PreserveSMBPermissions.IsPOSIXMapped() {
+		// The destination has nowhere to keep an SDDL, so translate it.
+		return a.putPosixACL(sddlString, txInfo)
+	}
+
 	// We don't need to worry about making the SDDL string portable as this is expected for persistence into Azure Files in the first place.
 	sd, err := sddl.SecurityDescriptorFromString(sddlString)
 	if err != nil {
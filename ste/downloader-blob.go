@@ -80,6 +80,13 @@ func (bd *blobDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipelin
 
 func (bd *blobDownloader) Epilogue() {
 	if bd.jptm != nil {
+		// before the POSIX properties, which may take away the permission to write them
+		if bd.jptm.IsLive() && bd.jptm.Info().PreserveXattrs {
+			if err := restoreXattrs(bd.jptm); err != nil {
+				bd.jptm.FailActiveDownload("set extended attributes", err)
+			}
+		}
+
 		if bd.jptm.IsLive() && bd.jptm.Info().PreservePOSIXProperties {
 			bsip, err := newBlobSourceInfoProvider(bd.jptm)
 			if err != nil {
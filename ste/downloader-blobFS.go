@@ -65,6 +65,12 @@ func (bd *blobFSDownloader) Epilogue() {
 				}
 			}
 		}
+
+		if bd.jptm.IsLive() && bd.jptm.Info().PreserveSMBPermissions.IsTruthy() {
+			if err := bd.applyADLSAccessControl(bd.jptm); err != nil {
+				bd.jptm.FailActiveDownload("set ACLs", err)
+			}
+		}
 	}
 }
 
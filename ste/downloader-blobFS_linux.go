@@ -5,6 +5,7 @@ package ste
 import (
 	"fmt"
 	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/sddl"
 	"golang.org/x/sys/unix"
 	"io"
 	"os"
@@ -188,5 +189,27 @@ func (bd *blobFSDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error
 		}
 	}
 
+	if jptm.Info().PreserveSMBPermissions.IsTruthy() {
+		if err := bd.applyADLSAccessControl(jptm); err != nil {
+			return fmt.Errorf("set ACLs: %w", err)
+		}
+	}
+
 	return nil
+}
+
+// applyADLSAccessControl preserves the source's ADLS Gen2 ACL, owner and group as the destination's POSIX ACLs and ownership.
+// It runs after the UNIX properties, so that the ACL's mode bits win.
+func (bd *blobFSDownloader) applyADLSAccessControl(jptm IJobPartTransferMgr) error {
+	sip, err := newBlobSourceInfoProvider(jptm)
+	if err != nil {
+		return err
+	}
+
+	ac, err := sip.(*blobSourceInfoProvider).AccessControl()
+	if err != nil {
+		return err
+	}
+
+	return putADLSAccessControl(jptm, sddl.ADLSAccessControl{Owner: ac.Owner, Group: ac.Group, ACL: ac.ACL}, jptm.Info())
 }
\ No newline at end of file
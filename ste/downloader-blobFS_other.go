@@ -4,4 +4,8 @@ package ste
 
 func (bd *blobFSDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
 	return nil
-}
\ No newline at end of file
+}
+
+func (bd *blobFSDownloader) applyADLSAccessControl(jptm IJobPartTransferMgr) error {
+	return nil // POSIX ACLs are only preserved on Linux
+}
@@ -90,6 +90,47 @@ func (bd *blobDownloader) CreateFile(jptm IJobPartTransferMgr, destination strin
 	return
 }
 
+// CreateSpecialFile makes the FIFO, device node or socket described by the metadata of the blob, then applies the
+// rest of the POSIX properties if they're being preserved.
+func (bd *blobDownloader) CreateSpecialFile(jptm IJobPartTransferMgr) error {
+	sip, err := newBlobSourceInfoProvider(jptm)
+	if err != nil {
+		return err
+	}
+
+	stat, err := sip.(IUNIXPropertyBearingSourceInfoProvider).GetUNIXProperties() // Blob may have unix properties.
+	if err != nil {
+		return err
+	}
+
+	mode := stat.FileMode()
+	if !common.IsSpecialFileMode(mode) {
+		return fmt.Errorf("the blob does not record the mode of a special file (mode %o)", mode)
+	}
+
+	var rdev uint64
+	if common.IsDeviceMode(mode) {
+		rdev = stat.RDevice()
+	}
+
+	destination := jptm.Info().Destination
+	err = unix.Mknod(destination, mode, int(rdev))
+	if err == unix.EPERM && common.IsDeviceMode(mode) {
+		return fmt.Errorf("creating device nodes requires privileges (e.g. running as root, or CAP_MKNOD): %w", err)
+	} else if err != nil {
+		return err
+	}
+
+	if jptm.Info().PreservePOSIXProperties {
+		bd.txInfo = jptm.Info()
+		if stage, err := bd.ApplyUnixProperties(stat); err != nil {
+			return fmt.Errorf("when applying POSIX properties (%s): %w", stage, err)
+		}
+	}
+
+	return nil
+}
+
 func (bd *blobDownloader) ApplyUnixProperties(adapter common.UnixStatAdapter) (stage string, err error) {
 	// At this point, mode has already been applied. Let's work out what we need to apply, and apply the rest.
 	destination := bd.txInfo.Destination
@@ -2,6 +2,12 @@
 
 package ste
 
+import "errors"
+
 func (bd *blobDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
 	return nil
 }
+
+func (bd *blobDownloader) CreateSpecialFile(jptm IJobPartTransferMgr) error {
+	return errors.New("special files can only be created on Linux")
+}
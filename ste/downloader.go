@@ -68,6 +68,12 @@ type symlinkDownloader interface {
 	CreateSymlink(jptm IJobPartTransferMgr) error
 }
 
+// specialFileDownloader is a downloader that can also recreate FIFOs, device nodes and sockets.
+type specialFileDownloader interface {
+	downloader
+	CreateSpecialFile(jptm IJobPartTransferMgr) error
+}
+
 // smbPropertyAwareDownloader is a windows-triggered interface.
 // Code outside of windows-specific files shouldn't implement this ever.
 type smbPropertyAwareDownloader interface {
@@ -26,6 +26,7 @@ import (
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"net/http"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -34,6 +35,8 @@ import (
 	"github.com/Azure/azure-pipeline-go/pipeline"
 
 	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ IJobMgr = &jobMgr{}
@@ -49,6 +52,8 @@ type InMemoryTransitJobState struct {
 	CredentialInfo common.CredentialInfo
 	// S2SSourceCredentialType can override the CredentialInfo.CredentialType when being used for the source (e.g. Source Info Provider and when using GetS2SSourceBlobTokenCredential)
 	S2SSourceCredentialType common.CredentialType
+	// IsBenchmark marks jobs of the bench command that don't have the Benchmark location at either end
+	IsBenchmark bool
 }
 
 type IJobMgr interface {
@@ -133,6 +138,7 @@ func NewJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx conte
 
 	// atomicAllTransfersScheduled is set to 1 since this api is also called when new job part is ordered.
 	enableChunkLogOutput := level.ToPipelineLogLevel() == pipeline.LogDebug
+	enableChunkTrace := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ChunkTrace()) != ""
 
 	/* Create book-keeping channels */
 	jobPartProgressCh := make(chan jobPartProgressInfo)
@@ -152,7 +158,7 @@ func NewJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx conte
 	jm := jobMgr{jobID: jobID, jobPartMgrs: newJobPartToJobPartMgr(), include: map[string]int{}, exclude: map[string]int{},
 		httpClient:           NewAzcopyHTTPClient(concurrency.MaxIdleConnections),
 		logger:               jobLogger,
-		chunkStatusLogger:    common.NewChunkStatusLogger(jobID, cpuMon, logFileFolder, enableChunkLogOutput),
+		chunkStatusLogger:    common.NewChunkStatusLogger(jobID, cpuMon, logFileFolder, enableChunkLogOutput, enableChunkTrace),
 		concurrency:          concurrency,
 		overwritePrompter:    newOverwritePrompter(),
 		pipelineNetworkStats: newPipelineNetworkStats(tuner), // let the stats coordinate with the concurrency tuner
@@ -221,7 +227,13 @@ func (jm *jobMgr) Reset(appCtx context.Context, commandString string) IJobMgr {
 		jm.logger.Log(pipeline.LogError, fmt.Sprintf("Job-Command %s", commandString))
 	}
 	jm.logConcurrencyParameters()
-	jm.ctx, jm.cancel = context.WithCancel(appCtx)
+	// when tracing, the job's span is the child of that of whatever started AzCopy, if we were told what that is
+	lcm := common.GetLifecycleMgr()
+	spanCtx := common.WithTraceParent(appCtx,
+		lcm.GetEnvironmentVariable(common.EEnvironmentVariable.TraceParent()),
+		lcm.GetEnvironmentVariable(common.EEnvironmentVariable.TraceState()))
+	spanCtx, jm.span = common.Tracer().Start(spanCtx, "Job", trace.WithAttributes(traceAttrJobID.String(jm.jobID.String())))
+	jm.ctx, jm.cancel = context.WithCancel(spanCtx)
 	atomic.StoreUint64(&jm.atomicNumberOfBytesCovered, 0)
 	atomic.StoreUint64(&jm.atomicTotalBytesToXfer, 0)
 	jm.partsDone = 0
@@ -297,6 +309,7 @@ type jobMgr struct {
 	jobID                common.JobID // The Job's unique ID
 	ctx                  context.Context
 	cancel               context.CancelFunc
+	span                 trace.Span // the job's span, when tracing. Its context is the parent of jm.ctx
 	pipelineNetworkStats *PipelineNetworkStats
 
 	// Share the same HTTP Client across all job parts, so that the we maximize re-use of
@@ -572,10 +585,18 @@ func (jm *jobMgr) ResumeTransfers(appCtx context.Context) {
 	// Since while creating the JobMgr, atomicAllTransfersScheduled is set to true
 	// reset it to false while resuming it
 	// jm.ResetAllTransfersScheduled()
-	jm.jobPartMgrs.Iterate(false, func(p common.PartNumber, jpm IJobPartMgr) {
-		jm.QueueJobParts(jpm)
-		// jpm.ScheduleTransfers(jm.ctx, includeTransfer, excludeTransfer)
+	// queue the parts in order, as they were when the job was first run. Hard links wait on the parts before theirs.
+	parts := make([]common.PartNumber, 0)
+	jpms := make(map[common.PartNumber]IJobPartMgr)
+	jm.jobPartMgrs.Iterate(true, func(p common.PartNumber, jpm IJobPartMgr) {
+		parts = append(parts, p)
+		jpms[p] = jpm
 	})
+	sort.Slice(parts, func(i, j int) bool { return parts[i] < parts[j] })
+	for _, p := range parts {
+		jm.QueueJobParts(jpms[p])
+		// jpm.ScheduleTransfers(jm.ctx, includeTransfer, excludeTransfer)
+	}
 }
 
 // AllTransfersScheduled returns whether Job has completely resumed or not
@@ -662,16 +683,24 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 					jm.Log(pipeline.LogInfo, fmt.Sprintf("%s %s successfully completed, cancelled or paused", partDescription, jm.jobID.String()))
 				}
 
-				switch part0Plan.JobStatus() {
+				finalStatus := part0Plan.JobStatus()
+				switch finalStatus {
 				case common.EJobStatus.Cancelling():
-					part0Plan.SetJobStatus(common.EJobStatus.Cancelled())
-					if shouldLog {
-						jm.Log(pipeline.LogInfo, fmt.Sprintf("%s %v successfully cancelled", partDescription, jm.jobID))
-					}
+					finalStatus = common.EJobStatus.Cancelled()
 				case common.EJobStatus.InProgress():
-					part0Plan.SetJobStatus((common.EJobStatus).EnhanceJobStatusInfo(jobProgressInfo.transfersSkipped > 0,
+					finalStatus = (common.EJobStatus).EnhanceJobStatusInfo(jobProgressInfo.transfersSkipped > 0,
 						jobProgressInfo.transfersFailed > 0,
-						jobProgressInfo.transfersCompleted > 0))
+						jobProgressInfo.transfersCompleted > 0)
+				}
+
+				// the spans must be exported before the status is set, since the front end may exit as soon as it sees that the job is done
+				jm.endJobSpan(part0Plan.FromTo, finalStatus, jobProgressInfo)
+
+				if finalStatus != part0Plan.JobStatus() {
+					part0Plan.SetJobStatus(finalStatus)
+					if finalStatus == common.EJobStatus.Cancelled() && shouldLog {
+						jm.Log(pipeline.LogInfo, fmt.Sprintf("%s %v successfully cancelled", partDescription, jm.jobID))
+					}
 				}
 
 				// reset counters
@@ -689,6 +718,24 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 	}
 }
 
+// endJobSpan ends the job's span, and exports it along with the spans of the job's parts, transfers and requests
+func (jm *jobMgr) endJobSpan(fromTo common.FromTo, status common.JobStatus, progress jobPartProgressInfo) {
+	jm.span.SetAttributes(
+		traceAttrFromTo.String(fromTo.String()),
+		traceAttrStatus.String(status.String()),
+		traceAttrTransfersCompleted.Int(progress.transfersCompleted),
+		traceAttrTransfersFailed.Int(progress.transfersFailed),
+		traceAttrTransfersSkipped.Int(progress.transfersSkipped))
+	if status == common.EJobStatus.Failed() || status == common.EJobStatus.CompletedWithErrors() || status == common.EJobStatus.CompletedWithErrorsAndSkipped() {
+		jm.span.SetStatus(codes.Error, status.String())
+	}
+	jm.span.End()
+
+	if err := common.FlushTracing(); err != nil {
+		jm.Log(pipeline.LogWarning, "Failed to export trace spans: "+err.Error())
+	}
+}
+
 func (jm *jobMgr) getInMemoryTransitJobState() InMemoryTransitJobState {
 	return jm.inMemoryTransitJobState
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/Azure/azure-storage-azcopy/v10/common"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/azure-storage-file-go/azfile"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -51,6 +52,7 @@ type IJobPartMgr interface {
 	FileCountLimiter() common.CacheLimiter
 	ExclusiveDestinationMap() *common.ExclusiveStringMap
 	ChunkStatusLogger() common.ChunkStatusLogger
+	PipelineNetworkStats() *PipelineNetworkStats
 	common.ILogger
 	SourceProviderPipeline() pipeline.Pipeline
 	SecondarySourceProviderPipeline() pipeline.Pipeline
@@ -182,8 +184,10 @@ func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryO
 	f := []pipeline.Factory{
 		azblob.NewTelemetryPolicyFactory(o.Telemetry),
 		azblob.NewUniqueRequestIDPolicyFactory(),
+		newXferTracingPolicyFactory(),       // span per request, covering all its tries
 		NewBlobXferRetryPolicyFactory(r),    // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newSASRenewalPolicyFactory(),        // swap in renewed SAS tokens before each try
 		c,
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
 		// NewPacerPolicyFactory(p),
@@ -204,6 +208,7 @@ func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryO
 			SyslogDisabled:               common.IsForceLoggingDisabled(),
 		}),
 		newXferStatsPolicyFactory(statsAcc),
+		newXferTracingTryPolicyFactory(),
 	}
 	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
 }
@@ -218,8 +223,10 @@ func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryO
 	f := []pipeline.Factory{
 		azbfs.NewTelemetryPolicyFactory(o.Telemetry),
 		azbfs.NewUniqueRequestIDPolicyFactory(),
+		newXferTracingPolicyFactory(),       // span per request, covering all its tries
 		NewBFSXferRetryPolicyFactory(r),     // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newSASRenewalPolicyFactory(),        // swap in renewed SAS tokens before each try
 	}
 
 	f = append(f, c)
@@ -230,7 +237,8 @@ func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryO
 			LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold,
 			SyslogDisabled:               common.IsForceLoggingDisabled(),
 		}),
-		newXferStatsPolicyFactory(statsAcc))
+		newXferStatsPolicyFactory(statsAcc),
+		newXferTracingTryPolicyFactory())
 
 	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
 }
@@ -244,8 +252,10 @@ func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r azfile.Ret
 	f := []pipeline.Factory{
 		azfile.NewTelemetryPolicyFactory(o.Telemetry),
 		azfile.NewUniqueRequestIDPolicyFactory(),
+		newXferTracingPolicyFactory(),       // span per request, covering all its tries
 		azfile.NewRetryPolicyFactory(r),     // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newSASRenewalPolicyFactory(),        // swap in renewed SAS tokens before each try
 		NewVersionPolicyFactory(),
 		NewTrailingDotPolicyFactory(trailingDot),
 		c,
@@ -255,6 +265,7 @@ func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r azfile.Ret
 			SyslogDisabled:               common.IsForceLoggingDisabled(),
 		}),
 		newXferStatsPolicyFactory(statsAcc),
+		newXferTracingTryPolicyFactory(),
 	}
 	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
 }
@@ -332,6 +343,8 @@ type jobPartMgr struct {
 	// used defensively to protect double init
 	atomicPipelinesInitedIndicator uint32
 
+	span trace.Span // this part's span, when tracing. Ended when the part's transfers are all done
+
 	// numberOfTransfersDone_doNotUse represents the number of transfer of JobPartOrder
 	// which are either completed or failed
 	// numberOfTransfersDone_doNotUse determines the final cancellation of JobPartOrder
@@ -438,6 +451,11 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context, sourceBlobToken
 
 	jpm.createPipelines(jobCtx, sourceBlobToken) // pipeline is created per job part manager
 
+	jobCtx, jpm.span = common.Tracer().Start(jobCtx, "JobPart", trace.WithAttributes(
+		traceAttrPartNumber.Int(int(plan.PartNum)),
+		traceAttrFromTo.String(plan.FromTo.String()),
+		traceAttrTransferCount.Int(int(plan.NumTransfers))))
+
 	// *** Schedule this job part's transfers ***
 	for t := uint32(0); t < plan.NumTransfers; t++ {
 		jppt := plan.Transfer(t)
@@ -470,6 +488,7 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context, sourceBlobToken
 
 		// Each transfer gets its own context (so any chunk can cancel the whole transfer) based off the job's context
 		transferCtx, transferCancel := context.WithCancel(jobCtx)
+		transferCtx = jpm.startTransferSpan(transferCtx, t)
 		// Initialize a job part transfer manager
 		jptm := &jobPartTransferMgr{
 			jobPartMgr:          jpm,
@@ -554,7 +573,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 		userAgent = common.S3ImportUserAgent
 	} else if fromTo.From() == common.ELocation.GCP() {
 		userAgent = common.GCPImportUserAgent
-	} else if fromTo.From() == common.ELocation.Benchmark() || fromTo.To() == common.ELocation.Benchmark() {
+	} else if fromTo.From() == common.ELocation.Benchmark() || fromTo.To() == common.ELocation.Benchmark() ||
+		jpm.jobMgr.getInMemoryTransitJobState().IsBenchmark {
 		userAgent = common.BenchmarkUserAgent
 	} else {
 		userAgent = common.GetLifecycleMgr().AddUserAgentPrefix(common.UserAgent)
@@ -669,7 +689,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 			jpm.jobMgr.PipelineNetworkStats())
 
 		// If we need to write specifically to the gen2 endpoint, we should have this available.
-		if fromTo.To() == common.ELocation.BlobFS() || jpm.Plan().PreservePermissions.IsTruthy() || jpm.Plan().PreservePOSIXProperties {
+		if fromTo.To() == common.ELocation.BlobFS() || jpm.Plan().PreservePermissions.IsTruthy() || jpm.Plan().PreservePOSIXProperties ||
+			jpm.Plan().DstBlobData.SetPropertiesFlags.ShouldTransferAccessControl() {
 			credential := common.CreateBlobFSCredential(ctx, credInfo, credOption)
 			jpm.secondaryPipeline = NewBlobFSPipeline(
 				credential,
@@ -764,6 +785,10 @@ func (jpm *jobPartMgr) CacheLimiter() common.CacheLimiter {
 	return jpm.cacheLimiter
 }
 
+func (jpm *jobPartMgr) PipelineNetworkStats() *PipelineNetworkStats {
+	return jpm.jobMgr.PipelineNetworkStats()
+}
+
 func (jpm *jobPartMgr) FileCountLimiter() common.CacheLimiter {
 	return jpm.fileCountLimiter
 }
@@ -935,6 +960,11 @@ func (jpm *jobPartMgr) ReportTransferDone(status common.TransferStatus) (transfe
 		}
 		jpm.Plan().SetJobPartStatus(common.EJobStatus.EnhanceJobStatusInfo(jppi.transfersSkipped > 0,
 			jppi.transfersFailed > 0, jppi.transfersCompleted > 0))
+		jpm.span.SetAttributes(
+			traceAttrTransfersCompleted.Int(jppi.transfersCompleted),
+			traceAttrTransfersFailed.Int(jppi.transfersFailed),
+			traceAttrTransfersSkipped.Int(jppi.transfersSkipped))
+		jpm.span.End()
 		jpm.jobMgr.ReportJobPartDone(jppi)
 		
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, Part#=%d, TransfersDone=%d of %d",
@@ -112,6 +112,7 @@ type TransferInfo struct {
 	PreserveSMBPermissions  common.PreservePermissionsOption
 	PreserveSMBInfo         bool
 	PreservePOSIXProperties bool
+	PreserveXattrs          bool
 	BlobFSRecursiveDelete   bool
 
 	// Transfer info for S2S copy
@@ -159,6 +160,10 @@ func (i TransferInfo) entityTypeLogIndicator() string {
 		return "(folder properties) "
 	} else if i.IsFilePropertiesTransfer() {
 		return "(file properties) "
+	} else if i.EntityType == common.EEntityType.Hardlink() {
+		return "(hard link) "
+	} else if i.EntityType == common.EEntityType.SpecialFile() {
+		return "(special file) "
 	} else {
 		return ""
 	}
@@ -345,6 +350,13 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 
 	sourceSize := plan.Transfer(jptm.transferIndex).SourceSize
 	var blockSize = dstBlobData.BlockSize
+	isAdaptiveBlockSize := blockSize == 0 && plan.AdaptiveBlockSize
+	if isAdaptiveBlockSize {
+		// the user asked us to choose, based on what we've seen so far
+		host := adaptiveBlockSizeHost(plan.FromTo, src, dst)
+		blockSize = chooseAdaptiveBlockSize(sourceSize, plan.FromTo.IsDownload(), jptm.CacheLimiter().Limit(),
+			jptm.jobPartMgr.PipelineNetworkStats().blockSizeObservations(host))
+	}
 	// If the blockSize is 0, then User didn't provide any blockSize
 	// We need to set the blockSize in such way that number of blocks per blob
 	// does not exceeds 50000 (max number of block per blob)
@@ -388,6 +400,7 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		PreserveSMBPermissions:         plan.PreservePermissions,
 		PreserveSMBInfo:                plan.PreserveSMBInfo,
 		PreservePOSIXProperties:        plan.PreservePOSIXProperties,
+		PreserveXattrs:                 plan.PreserveXattrs,
 		S2SGetPropertiesInBackend:      s2sGetPropertiesInBackend,
 		S2SSourceChangeValidation:      s2sSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: s2sInvalidMetadataHandleOption,
@@ -403,6 +416,10 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		RehydratePriority: plan.RehydratePriority.ToRehydratePriorityType(),
 	}
 
+	if isAdaptiveBlockSize && jptm.ShouldLog(pipeline.LogDebug) {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, fmt.Sprintf("Adaptive block size of %d bytes chosen.", blockSize))
+	}
+
 	return *jptm.transferInfo
 }
 
@@ -873,9 +890,9 @@ const (
 func (jptm *jobPartTransferMgr) LogAtLevelForCurrentTransfer(level pipeline.LogLevel, msg string) {
 	// order of log elements here is mirrored, with some more added, in logTransferError
 	info := jptm.Info()
-	fullMsg := common.URLStringExtension(info.Source).RedactSecretQueryParamForLogging() + " " + info.entityTypeLogIndicator() +
+	fullMsg := common.DisplayStringForURL(info.Source) + " " + info.entityTypeLogIndicator() +
 		msg +
-		" Dst: " + common.URLStringExtension(info.Destination).RedactSecretQueryParamForLogging()
+		" Dst: " + common.DisplayStringForURL(info.Destination)
 
 	jptm.Log(level, fullMsg)
 }
@@ -883,8 +900,8 @@ func (jptm *jobPartTransferMgr) LogAtLevelForCurrentTransfer(level pipeline.LogL
 func (jptm *jobPartTransferMgr) logTransferError(errorCode transferErrorCode, source, destination, errorMsg string, status int) {
 	// order of log elements here is mirrored, in subset, in LogForCurrentTransfer
 	info := jptm.Info() // TODO we are getting a lot of Info calls and its (presumably) not well-optimized.  Profile that?
-	msg := fmt.Sprintf("%v: %v", errorCode, info.entityTypeLogIndicator()) + common.URLStringExtension(source).RedactSecretQueryParamForLogging() +
-		fmt.Sprintf(" : %03d : %s\n   Dst: ", status, errorMsg) + common.URLStringExtension(destination).RedactSecretQueryParamForLogging()
+	msg := fmt.Sprintf("%v: %v", errorCode, info.entityTypeLogIndicator()) + common.DisplayStringForURL(source) +
+		fmt.Sprintf(" : %03d : %s\n   Dst: ", status, errorMsg) + common.DisplayStringForURL(destination)
 	jptm.Log(pipeline.LogError, msg)
 }
 
@@ -917,22 +934,22 @@ func (jptm *jobPartTransferMgr) LogError(resource, context string, err error) {
 	_, status, msg := ErrorEx{err}.ErrorCodeAndString()
 	MSRequestID := ErrorEx{err}.MSRequestID()
 	jptm.Log(pipeline.LogError,
-		fmt.Sprintf("%s: %d: %s-%s. X-Ms-Request-Id:%s\n", common.URLStringExtension(resource).RedactSecretQueryParamForLogging(), status, context, msg, MSRequestID))
+		fmt.Sprintf("%s: %d: %s-%s. X-Ms-Request-Id:%s\n", common.DisplayStringForURL(resource), status, context, msg, MSRequestID))
 }
 
 func (jptm *jobPartTransferMgr) LogTransferStart(source, destination, description string) {
 	jptm.Log(pipeline.LogInfo,
 		fmt.Sprintf("Starting transfer: Source %q Destination %q. %s",
-			common.URLStringExtension(source).RedactSecretQueryParamForLogging(),
-			common.URLStringExtension(destination).RedactSecretQueryParamForLogging(),
+			common.DisplayStringForURL(source),
+			common.DisplayStringForURL(destination),
 			description))
 }
 
 func (jptm *jobPartTransferMgr) LogTransferInfo(level pipeline.LogLevel, source, destination, msg string) {
 	jptm.Log(level,
 		fmt.Sprintf("Transfer: Source %q Destination %q. %s",
-			common.URLStringExtension(source).RedactSecretQueryParamForLogging(),
-			common.URLStringExtension(destination).RedactSecretQueryParamForLogging(),
+			common.DisplayStringForURL(source),
+			common.DisplayStringForURL(destination),
 			msg))
 }
 
@@ -964,6 +981,8 @@ func (jptm *jobPartTransferMgr) ReportTransferDone() uint32 {
 		ErrorCode:          jptm.ErrorCode(),
 	})
 
+	endTransferSpan(jptm.ctx, jptm.jobPartPlanTransfer.TransferStatus(), jptm.ErrorCode())
+
 	return jptm.jobPartMgr.ReportTransferDone(jptm.jobPartPlanTransfer.TransferStatus())
 }
 
@@ -989,7 +1008,7 @@ func (jptm *jobPartTransferMgr) FolderDeletionManager() common.FolderDeletionMan
 
 func (jptm *jobPartTransferMgr) GetDestinationRoot() string {
 	p := jptm.jobPartMgr.Plan()
-	return string(p.DestinationRoot[:p.DestinationRootLength])
+	return p.DestinationRoot()
 }
 
 func (jptm *jobPartTransferMgr) ShouldInferContentType() bool {
@@ -26,6 +26,7 @@ import (
 	"github.com/Azure/azure-storage-azcopy/v10/common"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 )
 
@@ -71,6 +72,16 @@ func (AdviceType) ConcurrencyHighCpu() AdviceType {
 		"Network bandwidth may not be accurately measured because concurrency tuning encountered high CPU usage"}
 }
 
+func (AdviceType) ConcurrencyLatencyTarget() AdviceType {
+	return AdviceType{"ConcurrencyLimitedByLatencyTarget",
+		"Network bandwidth not measured because concurrency tuning was limited by a latency target"}
+}
+
+func (AdviceType) ConcurrencyTuningDecisions() AdviceType {
+	return AdviceType{"ConcurrencyTuningDecisions",
+		"Concurrency was tuned with a non-default strategy"}
+}
+
 func (AdviceType) NetworkIsBottleneck() AdviceType {
 	return AdviceType{"NetworkIsBottleneck",
 		"Network bandwidth appears to be the key factor governing performance."}
@@ -115,6 +126,8 @@ type PerformanceAdvisor struct {
 	capMbps                        float64 // 0 if no cap
 	finalConcurrencyTunerReason    string
 	finalConcurrency               int
+	tuningStrategy                 string
+	tuningDecisions                []ConcurrencyDecision
 	azureVmCores                   int // 0 if not azure VM
 	azureVmSizeName                string
 	direction                      common.TransferDirection
@@ -126,12 +139,14 @@ type PerformanceAdvisor struct {
 	isToAzureFiles bool
 }
 
-func NewPerformanceAdvisor(stats *PipelineNetworkStats, commandLineMbpsCap float64, mbps int64, finalReason string, finalConcurrency int, dir common.TransferDirection, avgBytesPerFile int64, isToAzureFiles bool) *PerformanceAdvisor {
+func NewPerformanceAdvisor(stats *PipelineNetworkStats, commandLineMbpsCap float64, mbps int64, finalReason string, finalConcurrency int, tuningStrategy string, tuningDecisions []ConcurrencyDecision, dir common.TransferDirection, avgBytesPerFile int64, isToAzureFiles bool) *PerformanceAdvisor {
 	p := &PerformanceAdvisor{
 		capMbps:                     commandLineMbpsCap,
 		mbps:                        mbps,
 		finalConcurrencyTunerReason: finalReason,
 		finalConcurrency:            finalConcurrency,
+		tuningStrategy:              tuningStrategy,
+		tuningDecisions:             tuningDecisions,
 		direction:                   dir,
 		avgBytesPerFile:             avgBytesPerFile,
 		isToAzureFiles:              isToAzureFiles,
@@ -320,6 +335,12 @@ func (p *PerformanceAdvisor) GetAdvice() []common.PerformanceAdvice {
 			addAdvice(EAdviceType.ConcurrencyHitUpperLimit(),
 				"Auto-tuning of concurrency hit its upper limit before finding maximum throughput.  Therefore the maximum "+
 					"possible throughput was not found")
+		case concurrencyReasonAtLatency:
+			addAdvice(EAdviceType.ConcurrencyLatencyTarget(),
+				"Concurrency was tuned with the %s strategy, which settled on %d concurrent connections to keep request latency near its target. "+
+					"Throughput of %d Mega bits/sec was obtained. Because concurrency was not raised as far as possible, the available network "+
+					"bandwidth was not measured. To measure it, benchmark with the default strategy, %s.",
+				p.tuningStrategy, p.finalConcurrency, p.mbps, HillClimbingStrategyName)
 		default:
 			addAdvice(EAdviceType.ConcurrencyNotEnoughTime(),
 				"The job completed before AzCopy could find the maximum possible throughput.  Try benchmarking with more files "+
@@ -329,6 +350,16 @@ func (p *PerformanceAdvisor) GetAdvice() []common.PerformanceAdvice {
 		}
 	}
 
+	// Tuning decisions, when they weren't made the usual way, since then they are part of what the user is investigating
+	if p.tuningStrategy != "" && p.tuningStrategy != HillClimbingStrategyName && len(p.tuningDecisions) > 0 {
+		steps := make([]string, len(p.tuningDecisions))
+		for i, d := range p.tuningDecisions {
+			steps[i] = fmt.Sprintf("%d connections (%s): %d Mbps, p95 latency %d ms, %d retries", d.Concurrency, d.Reason, d.Mbps, d.P95LatencyMs, d.Retries)
+		}
+		addAdvice(EAdviceType.ConcurrencyTuningDecisions(),
+			"Concurrency was tuned with the %s strategy. It tried, in order: %s.", p.tuningStrategy, strings.Join(steps, "; "))
+	}
+
 	// TODO: consider how to factor in CPU load - will it be reflected in concurrency tuner results, or separate?
 
 	// TODO: should we also output aka.ms links to the relevant doc pages?  Hard to maintain?
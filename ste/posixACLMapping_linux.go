@@ -0,0 +1,218 @@
+//go:build linux
+// +build linux
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/sddl"
+
+	"github.com/pkg/xattr"
+	"golang.org/x/sys/unix"
+)
+
+// This file translates SMB permissions to and from POSIX ACLs, for the POSIX-mapped PreservePermissionsOptions,
+// and ADLS Gen2 ACLs to and from POSIX ACLs, for Local<->BlobFS transfers that preserve permissions.
+
+// loadSIDMapping returns the mapping named by AZCOPY_SID_MAPPING_FILE, which the translation can't do without.
+func loadSIDMapping() (*common.IdentityMapping, error) {
+	mapping, err := common.GetIdentityMapping()
+	if err == nil && mapping == nil {
+		err = fmt.Errorf("%s must be set to translate permissions to and from POSIX ACLs", common.EEnvironmentVariable.SIDMappingFile().Name)
+	}
+	return mapping, err
+}
+
+// logUnrepresentable reports the parts of the permissions that were left out of the translation.
+// They're warnings rather than failures, since the rest of the permissions still apply.
+func logUnrepresentable(jptm IJobPartTransferMgr, unrepresentable []sddl.UnrepresentableACE) {
+	for _, u := range unrepresentable {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Permission not representable, so it was not transferred: "+u.String())
+	}
+}
+
+// getPosixACL reads the POSIX ACL in the named xattr. ok is false if the file doesn't have one,
+// or its filesystem doesn't support them.
+func getPosixACL(path string, name string) (acl sddl.PosixACL, ok bool, err error) {
+	data, err := xattr.Get(path, name)
+	if errors.Is(err, xattr.ENOATTR) || errors.Is(err, unix.ENOTSUP) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("xattr.Get(%s, %s) failed: %w", path, name, err)
+	}
+
+	acl, err = sddl.ParsePosixACL(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid POSIX ACL on %s: %w", path, err)
+	}
+	return acl, true, nil
+}
+
+// setPosixACL writes the POSIX ACL to the named xattr.
+func setPosixACL(path string, name string, acl sddl.PosixACL) error {
+	err := xattr.Set(path, name, acl.Marshal())
+	if err != nil {
+		return fmt.Errorf("xattr.Set(%s, %s, %s) failed: %w", path, name, acl, err)
+	}
+	return nil
+}
+
+// chownMapped sets the owner and group of the destination to the ones the translation mapped, if any.
+func chownMapped(destination string, translation sddl.PosixTranslation) error {
+	if !translation.HasUID && !translation.HasGID {
+		return nil
+	}
+
+	uid, gid := -1, -1 // leave unmapped ones as they are
+	if translation.HasUID {
+		uid = int(translation.UID)
+	}
+	if translation.HasGID {
+		gid = int(translation.GID)
+	}
+
+	if err := os.Lchown(destination, uid, gid); err != nil {
+		return fmt.Errorf("ownership could not be restored. It may help to add --%s=false to the AzCopy command line (so that ACLS will be preserved but ownership will not), "+
+			"or to run as root. err=%v", common.PreserveOwnerFlagName, err)
+	}
+	return nil
+}
+
+// putPosixACL translates the SDDL from Azure Files to a POSIX ACL on the destination, and to its owner if ownership is preserved.
+func (a *azureFilesDownloader) putPosixACL(sddlString string, txInfo TransferInfo) error {
+	mapping, err := loadSIDMapping()
+	if err != nil {
+		return err
+	}
+
+	parsedSDDL, err := sddl.ParseSDDL(sddlString)
+	if err != nil {
+		return fmt.Errorf("Failed to parse SDDL (%s) for file %s: %w", sddlString, txInfo.Destination, err)
+	}
+
+	translation := sddl.TranslateSDDLToPosix(parsedSDDL, mapping)
+	logUnrepresentable(a.jptm, translation.Unrepresentable)
+
+	if txInfo.PreserveSMBPermissions.IncludesOwnership() {
+		if err := chownMapped(txInfo.Destination, translation); err != nil {
+			return err
+		}
+	}
+
+	return setPosixACL(txInfo.Destination, sddl.XATTR_POSIX_ACL_ACCESS, translation.ACL)
+}
+
+// getSDDLFromPosix translates the source's owner, group and POSIX ACL to SDDL for Azure Files.
+func (f localFileSourceInfoProvider) getSDDLFromPosix() (string, error) {
+	mapping, err := loadSIDMapping()
+	if err != nil {
+		return "", err
+	}
+
+	stat, err := f.GetUNIXProperties()
+	if err != nil {
+		return "", err
+	}
+
+	acl, ok, err := getPosixACL(f.transferInfo.Source, sddl.XATTR_POSIX_ACL_ACCESS)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		// the file's permission bits are all there is
+		acl = sddl.PosixACLFromMode(stat.FileMode())
+	}
+
+	translated, unrepresentable := sddl.TranslatePosixToSDDL(stat.Owner(), stat.Group(), acl, mapping)
+	logUnrepresentable(f.jptm, unrepresentable)
+
+	return translated.String(), nil
+}
+
+// getADLSAccessControl translates the source's owner, group and POSIX ACLs to ADLS Gen2 access control.
+// Directories carry their default ACL too.
+func (f localFileSourceInfoProvider) getADLSAccessControl() (sddl.ADLSAccessControl, error) {
+	mapping, err := loadSIDMapping()
+	if err != nil {
+		return sddl.ADLSAccessControl{}, err
+	}
+
+	stat, err := f.GetUNIXProperties()
+	if err != nil {
+		return sddl.ADLSAccessControl{}, err
+	}
+
+	access, ok, err := getPosixACL(f.transferInfo.Source, sddl.XATTR_POSIX_ACL_ACCESS)
+	if err != nil {
+		return sddl.ADLSAccessControl{}, err
+	} else if !ok {
+		access = sddl.PosixACLFromMode(stat.FileMode())
+	}
+
+	var defaultACL sddl.PosixACL
+	if f.transferInfo.IsFolderPropertiesTransfer() {
+		if defaultACL, _, err = getPosixACL(f.transferInfo.Source, sddl.XATTR_POSIX_ACL_DEFAULT); err != nil {
+			return sddl.ADLSAccessControl{}, err
+		}
+	}
+
+	ac, unrepresentable := sddl.TranslatePosixToADLS(stat.Owner(), stat.Group(), access, defaultACL, mapping)
+	logUnrepresentable(f.jptm, unrepresentable)
+	return ac, nil
+}
+
+// putADLSAccessControl translates ADLS Gen2 access control to POSIX ACLs on the destination, and to its owner if ownership is preserved.
+func putADLSAccessControl(jptm IJobPartTransferMgr, ac sddl.ADLSAccessControl, txInfo TransferInfo) error {
+	mapping, err := loadSIDMapping()
+	if err != nil {
+		return err
+	}
+
+	translation, err := sddl.TranslateADLSToPosix(ac, mapping)
+	if err != nil {
+		return err
+	}
+	logUnrepresentable(jptm, translation.Unrepresentable)
+
+	if txInfo.PreserveSMBPermissions.IncludesOwnership() {
+		if err := chownMapped(txInfo.Destination, translation); err != nil {
+			return err
+		}
+	}
+
+	if len(translation.ACL) != 0 {
+		if err := setPosixACL(txInfo.Destination, sddl.XATTR_POSIX_ACL_ACCESS, translation.ACL); err != nil {
+			return err
+		}
+	}
+	if len(translation.DefaultACL) != 0 && txInfo.IsFolderPropertiesTransfer() {
+		if err := setPosixACL(txInfo.Destination, sddl.XATTR_POSIX_ACL_DEFAULT, translation.DefaultACL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"math/bits"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// Request latencies are recorded in HDR-style histograms: values below latencySubBucketCount milliseconds are recorded exactly,
+// and bigger ones in buckets whose width doubles with each doubling of the value, so that every value is recorded to within about 3%.
+// That gives accurate tail percentiles at a fixed cost per request, no matter how many requests there are.
+const (
+	latencySubBucketBits  = 5
+	latencySubBucketCount = 1 << latencySubBucketBits
+	latencyMaxExponent    = 26 // so the biggest value that is recorded without clamping is about 24 days
+	latencyBucketCount    = (latencyMaxExponent + 2) * latencySubBucketCount
+
+	// how many of the slowest requests of each type we keep, so that they can be looked up in the service's logs
+	slowestRequestsToKeep = 5
+)
+
+// latencyBucketIndex returns the bucket in which the given number of milliseconds is counted
+func latencyBucketIndex(ms int64) int {
+	if ms < 0 {
+		ms = 0
+	}
+	exponent := bits.Len64(uint64(ms)) - (latencySubBucketBits + 1)
+	if exponent < 0 {
+		exponent = 0
+	}
+	if exponent > latencyMaxExponent {
+		return latencyBucketCount - 1
+	}
+	return exponent*latencySubBucketCount + int(ms>>exponent)
+}
+
+// latencyBucketHighestValue returns the biggest number of milliseconds that is counted in the given bucket
+func latencyBucketHighestValue(index int) int64 {
+	exponent := index/latencySubBucketCount - 1
+	if exponent < 0 {
+		exponent = 0
+	}
+	subBucket := int64(index - exponent*latencySubBucketCount)
+	return (subBucket+1)<<exponent - 1
+}
+
+type latencyHistogram struct {
+	atomicCounts [latencyBucketCount]int64
+	atomicCount  int64
+	atomicMax    int64
+}
+
+func (h *latencyHistogram) record(ms int64) {
+	atomic.AddInt64(&h.atomicCounts[latencyBucketIndex(ms)], 1)
+	atomic.AddInt64(&h.atomicCount, 1)
+	for {
+		max := atomic.LoadInt64(&h.atomicMax)
+		if ms <= max || atomic.CompareAndSwapInt64(&h.atomicMax, max, ms) {
+			break
+		}
+	}
+}
+
+// percentile returns the value below which the given percentage of the recorded values fall.
+// Like HDR histograms, it reports the highest value of the bucket concerned, but never more than the maximum that was recorded
+func (h *latencyHistogram) percentile(percent float64) int64 {
+	count := atomic.LoadInt64(&h.atomicCount)
+	if count == 0 {
+		return 0
+	}
+	target := int64(float64(count)*percent/100 + 0.5)
+	if target < 1 {
+		target = 1
+	}
+	max := atomic.LoadInt64(&h.atomicMax)
+	var cumulative int64
+	for i := range h.atomicCounts {
+		cumulative += atomic.LoadInt64(&h.atomicCounts[i])
+		if cumulative >= target {
+			if v := latencyBucketHighestValue(i); v < max {
+				return v
+			}
+			break
+		}
+	}
+	return max
+}
+
+// slowestRequests keeps the slowest few requests recorded, slowest first
+type slowestRequests struct {
+	atomicThreshold int64 // requests no slower than this don't make it into a full list, so needn't take the lock
+	lock            sync.Mutex
+	requests        []common.SlowRequest
+}
+
+func (s *slowestRequests) consider(ms int64, makeSample func() common.SlowRequest) {
+	if ms <= atomic.LoadInt64(&s.atomicThreshold) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := sort.Search(len(s.requests), func(i int) bool { return s.requests[i].Milliseconds < ms })
+	if i >= slowestRequestsToKeep {
+		return // another goroutine got in first with slower ones
+	}
+	s.requests = append(s.requests, common.SlowRequest{})
+	copy(s.requests[i+1:], s.requests[i:])
+	s.requests[i] = makeSample()
+	if len(s.requests) > slowestRequestsToKeep {
+		s.requests = s.requests[:slowestRequestsToKeep]
+	}
+	if len(s.requests) == slowestRequestsToKeep {
+		atomic.StoreInt64(&s.atomicThreshold, s.requests[len(s.requests)-1].Milliseconds)
+	}
+}
+
+func (s *slowestRequests) get() []common.SlowRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]common.SlowRequest{}, s.requests...)
+}
+
+type operationLatencies struct {
+	histogram latencyHistogram
+	slowest   slowestRequests
+}
+
+// requestLatencyStats holds the latencies of the requests made by a job, by type of operation
+type requestLatencyStats struct {
+	operations sync.Map // operation name to *operationLatencies
+}
+
+func (r *requestLatencyStats) record(request *http.Request, response *http.Response, start time.Time, duration time.Duration) {
+	op := requestOperationName(request)
+	v, ok := r.operations.Load(op)
+	if !ok {
+		v, _ = r.operations.LoadOrStore(op, &operationLatencies{})
+	}
+	l := v.(*operationLatencies)
+
+	ms := duration.Milliseconds()
+	l.histogram.record(ms)
+	l.slowest.consider(ms, func() common.SlowRequest {
+		sample := common.SlowRequest{
+			Milliseconds:    ms,
+			StartTime:       start,
+			ClientRequestID: request.Header.Get("x-ms-client-request-id"),
+			URL:             common.URLExtension{URL: *request.URL}.RedactSecretQueryParamForLogging(),
+		}
+		if response != nil {
+			sample.StatusCode = response.StatusCode
+			sample.ServiceRequestID = response.Header.Get("x-ms-request-id")
+		}
+		return sample
+	})
+}
+
+// summaries returns the latency distribution of each type of operation, ordered by operation name
+func (r *requestLatencyStats) summaries() []common.RequestLatencySummary {
+	var result []common.RequestLatencySummary
+	r.operations.Range(func(key, value interface{}) bool {
+		l := value.(*operationLatencies)
+		result = append(result, common.RequestLatencySummary{
+			Operation:       key.(string),
+			Count:           atomic.LoadInt64(&l.histogram.atomicCount),
+			P50Milliseconds: l.histogram.percentile(50),
+			P95Milliseconds: l.histogram.percentile(95),
+			P99Milliseconds: l.histogram.percentile(99),
+			MaxMilliseconds: atomic.LoadInt64(&l.histogram.atomicMax),
+			SlowestRequests: l.slowest.get(),
+		})
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Operation < result[j].Operation })
+	return result
+}
+
+// requestOperationName names the REST operation that a request is for, following the names in the service documentation,
+// so that the latencies of (say) PutBlock and PutBlockList are not lumped together.
+// Ranged reads are named separately from whole ones, since they are what downloads mostly consist of.
+func requestOperationName(r *http.Request) string {
+	q := r.URL.Query()
+	comp := q.Get("comp")
+	h := r.Header
+	fromURL := ""
+	if h.Get("x-ms-copy-source") != "" {
+		fromURL = "FromURL"
+	}
+	rangeSuffix := ""
+	if h.Get("x-ms-range") != "" || h.Get("Range") != "" {
+		rangeSuffix = " range"
+	}
+	host := strings.ToLower(r.URL.Host)
+
+	switch {
+	case strings.Contains(host, ".dfs."):
+		switch r.Method {
+		case http.MethodPut:
+			if h.Get("x-ms-rename-source") != "" {
+				return "Rename"
+			}
+			switch q.Get("resource") {
+			case "file":
+				return "CreateFile"
+			case "directory":
+				return "CreateDirectory"
+			case "filesystem":
+				return "CreateFilesystem"
+			}
+		case http.MethodPatch:
+			switch action := q.Get("action"); action {
+			case "append":
+				return "Append"
+			case "flush":
+				return "Flush"
+			case "":
+			default:
+				return strings.ToUpper(action[:1]) + action[1:]
+			}
+		case http.MethodGet:
+			if q.Get("resource") != "" {
+				return "List"
+			}
+			return "Read" + rangeSuffix
+		}
+	case strings.Contains(host, ".file."):
+		switch r.Method {
+		case http.MethodPut:
+			switch {
+			case comp == "range":
+				return "PutRange" + fromURL
+			case comp == "" && q.Get("restype") == "directory":
+				return "CreateDirectory"
+			case comp == "" && q.Get("restype") == "share":
+				return "CreateShare"
+			case comp == "" && fromURL != "":
+				return "StartCopy"
+			case comp == "":
+				return "CreateFile"
+			}
+		case http.MethodGet:
+			switch comp {
+			case "":
+				if q.Get("restype") == "" {
+					return "GetFile" + rangeSuffix
+				}
+			case "list":
+				return "ListFilesAndDirectories"
+			case "rangelist":
+				return "ListRanges"
+			}
+		}
+	default:
+		switch r.Method {
+		case http.MethodPut:
+			switch comp {
+			case "block":
+				return "PutBlock" + fromURL
+			case "blocklist":
+				return "PutBlockList"
+			case "page":
+				return "PutPage" + fromURL
+			case "appendblock":
+				return "AppendBlock" + fromURL
+			case "":
+				switch {
+				case q.Get("restype") == "container":
+					return "CreateContainer"
+				case fromURL != "" && h.Get("x-ms-requires-sync") != "":
+					return "CopyFromURL"
+				case fromURL != "" && h.Get("x-ms-blob-type") != "":
+					return "PutBlobFromURL"
+				case fromURL != "":
+					return "StartCopyFromURL"
+				default:
+					return "PutBlob"
+				}
+			}
+		case http.MethodGet:
+			switch comp {
+			case "":
+				if q.Get("restype") == "" {
+					return "GetBlob" + rangeSuffix
+				}
+			case "list":
+				return "ListBlobs"
+			case "blocklist":
+				return "GetBlockList"
+			case "pagelist":
+				return "GetPageRanges"
+			}
+		}
+	}
+
+	// what's left is named the same way for all services
+	switch {
+	case r.Method == http.MethodHead:
+		return "GetProperties"
+	case r.Method == http.MethodDelete:
+		return "Delete"
+	case comp != "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+		return "Set" + strings.ToUpper(comp[:1]) + comp[1:]
+	case comp != "":
+		return "Get" + strings.ToUpper(comp[:1]) + comp[1:]
+	default:
+		return r.Method
+	}
+}
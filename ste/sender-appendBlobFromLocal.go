@@ -31,6 +31,7 @@ type appendBlobUploader struct {
 
 	md5Channel chan []byte
 	sip        ISourceInfoProvider
+	pipeline   pipeline.Pipeline
 }
 
 func (u *appendBlobUploader) Prologue(ps common.PrologueState) (destinationModified bool) {
@@ -48,6 +49,15 @@ func (u *appendBlobUploader) Prologue(ps common.PrologueState) (destinationModif
 		}
 	}
 
+	if u.jptm.Info().PreserveXattrs {
+		// Clone the metadata before we write to it, we shouldn't be writing to the same metadata as every other blob.
+		u.metadataToApply = common.Metadata(u.metadataToApply).Clone().ToAzBlobMetadata()
+
+		if err := addXattrsToBlobMetadata(u.jptm, u.sip, u.jptm.Info().Destination, u.pipeline, u.metadataToApply); err != nil {
+			u.jptm.FailActiveSend("GetXattrs", err)
+		}
+	}
+
 	return u.appendBlobSenderBase.Prologue(ps)
 }
 
@@ -57,7 +67,7 @@ func newAppendBlobUploader(jptm IJobPartTransferMgr, destination string, p pipel
 		return nil, err
 	}
 
-	return &appendBlobUploader{appendBlobSenderBase: *senderBase, md5Channel: newMd5Channel(), sip: sip}, nil
+	return &appendBlobUploader{appendBlobSenderBase: *senderBase, md5Channel: newMd5Channel(), sip: sip, pipeline: p}, nil
 }
 
 func (u *appendBlobUploader) Md5Channel() chan<- []byte {
@@ -95,6 +105,10 @@ func (u *appendBlobUploader) Epilogue() {
 	}
 
 	u.appendBlobSenderBase.Epilogue()
+
+	if jptm.Info().PreserveXattrs {
+		removeStaleXattrsSidecar(jptm, jptm.Info().Destination, u.pipeline, u.metadataToApply)
+	}
 }
 
 func (u *appendBlobUploader) GetDestinationLength() (int64, error) {
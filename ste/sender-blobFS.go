@@ -254,7 +254,16 @@ func (u *blobFSSenderBase) SetPOSIXProperties() error {
 }
 
 func (u *blobFSSenderBase) SetFolderProperties() error {
-	return u.SetPOSIXProperties()
+	if err := u.SetPOSIXProperties(); err != nil {
+		return err
+	}
+
+	if u.jptm.Info().PreserveSMBPermissions.IsTruthy() {
+		if err := u.setADLSAccessControl(); err != nil {
+			return fmt.Errorf("failed to set ACLs: %w", err)
+		}
+	}
+	return nil
 }
 
 func (u *blobFSSenderBase) DirUrlToString() string {
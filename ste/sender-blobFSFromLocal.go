@@ -100,5 +100,11 @@ func (u *blobFSUploader) Epilogue() {
 				jptm.FailActiveUpload("Setting POSIX Properties", err)
 			}
 		}
+		if jptm.Info().PreserveSMBPermissions.IsTruthy() {
+			err := u.setADLSAccessControl()
+			if err != nil {
+				jptm.FailActiveUpload("Setting ACLs", err)
+			}
+		}
 	}
 }
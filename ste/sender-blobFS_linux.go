@@ -0,0 +1,29 @@
+// +build linux
+
+package ste
+
+import "github.com/Azure/azure-storage-azcopy/v10/azbfs"
+
+// setADLSAccessControl preserves the source's POSIX ACLs, owner and group as the destination's ADLS Gen2 ACL.
+func (u *blobFSSenderBase) setADLSAccessControl() error {
+	sip, ok := u.sip.(*localFileSourceInfoProvider)
+	if !ok {
+		return nil // only local files have POSIX ACLs
+	}
+
+	ac, err := sip.getADLSAccessControl()
+	if err != nil {
+		return err
+	}
+	accessControl := azbfs.BlobFSAccessControl{Owner: ac.Owner, Group: ac.Group, ACL: ac.ACL}
+
+	if u.jptm.Info().IsFolderPropertiesTransfer() {
+		if u.dirURL().IsFileSystemRoot() {
+			return nil // the root of the filesystem isn't ours to change
+		}
+		_, err = u.dirURL().SetAccessControl(u.jptm.Context(), accessControl)
+	} else {
+		_, err = u.fileURL().SetAccessControl(u.jptm.Context(), accessControl)
+	}
+	return err
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package ste
+
+func (u *blobFSSenderBase) setADLSAccessControl() error {
+	return nil // POSIX ACLs are only preserved on Linux
+}
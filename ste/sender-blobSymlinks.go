@@ -72,6 +72,27 @@ func (s *blobSymlinkSender) SendSymlink(linkData string) error {
 	return err
 }
 
+// SendHardlink writes an empty blob that refers to the blob holding the data, target being its path relative to
+// this blob's folder. The POSIX properties, if preserved, are those on the data blob, since they belong to the inode.
+func (s *blobSymlinkSender) SendHardlink(target string) error {
+	s.metadataToApply[common.POSIXHardlinkMeta] = target
+
+	_, err := s.destBlockBlobURL.Upload(s.jptm.Context(), strings.NewReader(""), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}, s.destBlobTier, s.blobTagsToApply, s.cpkToApply, azblob.ImmutabilityPolicyOptions{})
+	return err
+}
+
+// SendSpecialFile writes an empty blob whose metadata says what kind of special file it stands for, with its mode
+// and, for a device, the device number, which is all that's needed to recreate it.
+func (s *blobSymlinkSender) SendSpecialFile() error {
+	err := s.getSpecialFileProperties()
+	if err != nil {
+		return fmt.Errorf("when getting special file properties: %w", err)
+	}
+
+	_, err = s.destBlockBlobURL.Upload(s.jptm.Context(), strings.NewReader(""), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}, s.destBlobTier, s.blobTagsToApply, s.cpkToApply, azblob.ImmutabilityPolicyOptions{})
+	return err
+}
+
 // ===== Implement sender so that it can be returned in newBlobUploader. =====
 /*
 	It's OK to just panic all of these out, as they will never get called in a symlink transfer.
@@ -29,3 +29,29 @@ func (s *blobSymlinkSender) getExtraProperties() error {
 
 	return nil
 }
+
+func (s *blobSymlinkSender) getSpecialFileProperties() error {
+	unixSIP, ok := s.sip.(IUNIXPropertyBearingSourceInfoProvider)
+	if !ok {
+		return errors.New("the source does not have POSIX properties")
+	}
+
+	statAdapter, err := unixSIP.GetUNIXProperties()
+	if err != nil {
+		return err
+	}
+
+	if !common.IsSpecialFileMode(statAdapter.FileMode()) { // sanity check the file hasn't been replaced since it was enumerated
+		return fmt.Errorf("sanity check: the source is no longer a special file (mode %o)", statAdapter.FileMode())
+	}
+
+	// Clone the metadata before we write to it, we shouldn't be writing to the same metadata as every other blob.
+	s.metadataToApply = common.Metadata(s.metadataToApply).Clone().ToAzBlobMetadata()
+	if s.jptm.Info().PreservePOSIXProperties {
+		common.AddStatToBlobMetadata(statAdapter, s.metadataToApply)
+	} else {
+		common.AddSpecialFileToBlobMetadata(statAdapter, s.metadataToApply)
+	}
+
+	return nil
+}
@@ -2,6 +2,12 @@
 
 package ste
 
+import "errors"
+
 func (s *blobSymlinkSender) getExtraProperties() error {
 	return nil
 }
+
+func (s *blobSymlinkSender) getSpecialFileProperties() error {
+	return errors.New("special files can only be preserved on Linux")
+}
@@ -35,6 +35,7 @@ type blockBlobUploader struct {
 	blockBlobSenderBase
 
 	md5Channel chan []byte
+	pipeline   pipeline.Pipeline
 }
 
 func newBlockBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
@@ -43,7 +44,7 @@ func newBlockBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeli
 		return nil, err
 	}
 
-	return &blockBlobUploader{blockBlobSenderBase: *senderBase, md5Channel: newMd5Channel()}, nil
+	return &blockBlobUploader{blockBlobSenderBase: *senderBase, md5Channel: newMd5Channel(), pipeline: p}, nil
 }
 
 func (s *blockBlobUploader) Prologue(ps common.PrologueState) (destinationModified bool) {
@@ -62,6 +63,15 @@ func (s *blockBlobUploader) Prologue(ps common.PrologueState) (destinationModifi
 		}
 	}
 
+	if s.jptm.Info().PreserveXattrs {
+		// Clone the metadata before we write to it, we shouldn't be writing to the same metadata as every other blob.
+		s.metadataToApply = common.Metadata(s.metadataToApply).Clone().ToAzBlobMetadata()
+
+		if err := addXattrsToBlobMetadata(s.jptm, s.sip, s.jptm.Info().Destination, s.pipeline, s.metadataToApply); err != nil {
+			s.jptm.FailActiveSend("GetXattrs", err)
+		}
+	}
+
 	return s.blockBlobSenderBase.Prologue(ps)
 }
 
@@ -189,6 +199,10 @@ func (u *blockBlobUploader) Epilogue() {
 	}
 
 	u.blockBlobSenderBase.Epilogue()
+
+	if jptm.Info().PreserveXattrs {
+		removeStaleXattrsSidecar(jptm, jptm.Info().Destination, u.pipeline, u.metadataToApply)
+	}
 }
 
 func (u *blockBlobUploader) GetDestinationLength() (int64, error) {
@@ -33,6 +33,7 @@ type pageBlobUploader struct {
 
 	md5Channel chan []byte
 	sip        ISourceInfoProvider
+	pipeline   pipeline.Pipeline
 }
 
 func newPageBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
@@ -41,7 +42,7 @@ func newPageBlobUploader(jptm IJobPartTransferMgr, destination string, p pipelin
 		return nil, err
 	}
 
-	return &pageBlobUploader{pageBlobSenderBase: *senderBase, md5Channel: newMd5Channel(), sip: sip}, nil
+	return &pageBlobUploader{pageBlobSenderBase: *senderBase, md5Channel: newMd5Channel(), sip: sip, pipeline: p}, nil
 }
 
 func (u *pageBlobUploader) Prologue(ps common.PrologueState) (destinationModified bool) {
@@ -59,6 +60,15 @@ func (u *pageBlobUploader) Prologue(ps common.PrologueState) (destinationModifie
 		}
 	}
 
+	if u.jptm.Info().PreserveXattrs {
+		// Clone the metadata before we write to it, we shouldn't be writing to the same metadata as every other blob.
+		u.metadataToApply = common.Metadata(u.metadataToApply).Clone().ToAzBlobMetadata()
+
+		if err := addXattrsToBlobMetadata(u.jptm, u.sip, u.jptm.Info().Destination, u.pipeline, u.metadataToApply); err != nil {
+			u.jptm.FailActiveSend("GetXattrs", err)
+		}
+	}
+
 	return u.pageBlobSenderBase.Prologue(ps)
 }
 
@@ -134,6 +144,10 @@ func (u *pageBlobUploader) Epilogue() {
 	}
 
 	u.pageBlobSenderBase.Epilogue()
+
+	if jptm.Info().PreserveXattrs {
+		removeStaleXattrsSidecar(jptm, jptm.Info().Destination, u.pipeline, u.metadataToApply)
+	}
 }
 
 func (u *pageBlobUploader) GetDestinationLength() (int64, error) {
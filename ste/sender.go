@@ -107,6 +107,20 @@ type symlinkSender interface {
 	SendSymlink(linkData string) error
 }
 
+/////////////////////////////////////////////////////////////////////////////////////////////////
+// hardlinkSender is a sender that can record that a file is a further name of a file sent in the same job
+/////////////////////////////////////////////////////////////////////////////////////////////////
+type hardlinkSender interface {
+	SendHardlink(target string) error
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////
+// specialFileSender is a sender that can record a FIFO, device node or socket, so that it can be recreated
+/////////////////////////////////////////////////////////////////////////////////////////////////
+type specialFileSender interface {
+	SendSpecialFile() error
+}
+
 type senderFactory func(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error)
 
 /////////////////////////////////////////////////////////////////////////////////////////////////
@@ -224,7 +238,7 @@ func newBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pi
 
 	if jptm.Info().IsFolderPropertiesTransfer() {
 		return newBlobFolderSender(jptm, destination, p, pacer, sip)
-	} else if jptm.Info().EntityType == common.EEntityType.Symlink() {
+	} else if jptm.Info().EntityType == common.EEntityType.Symlink() || jptm.Info().EntityType == common.EEntityType.Hardlink() || jptm.Info().EntityType == common.EEntityType.SpecialFile() {
 		return newBlobSymlinkSender(jptm, destination, p, pacer, sip)
 	}
 
@@ -84,10 +84,18 @@ func (f localFileSourceInfoProvider) OpenSourceFile() (common.CloseableReaderAt,
 		return emptyCloseableReaderAt{}, nil
 	}
 
+	var file *os.File
 	if custom, ok := interface{}(f).(ICustomLocalOpener); ok {
-		return custom.Open(path)
+		file, err = custom.Open(path)
+	} else {
+		file, err = os.Open(path)
 	}
-	return os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the holes of sparse files (e.g. disk images) are not read from disk. Page blobs skip them anyway, since they are all zeros
+	return common.NewSparseFileReader(file), nil
 }
 
 func (f localFileSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
@@ -101,3 +109,7 @@ func (f localFileSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time,
 func (f localFileSourceInfoProvider) EntityType() common.EntityType {
 	return f.transferInfo.EntityType
 }
+
+func (f localFileSourceInfoProvider) GetXattrs() (common.Xattrs, error) {
+	return common.GetXattrs(f.transferInfo.Source)
+}
@@ -177,6 +177,10 @@ func (s StatTAdapter) CTime() time.Time {
 //       Windows where we need to pass FILE_FLAG_BACKUP_SEMANTICS flag for opening file.
 
 func (f localFileSourceInfoProvider) GetSDDL() (string, error) {
+	if f.transferInfo.PreserveSMBPermissions.IsPOSIXMapped() {
+		return f.getSDDLFromPosix()
+	}
+
 	// We only need Owner, Group, and DACLs for azure files, CIFS_XATTR_CIFS_NTSD gets us that.
 	const securityInfoFlags sddl.SECURITY_INFORMATION = sddl.DACL_SECURITY_INFORMATION | sddl.OWNER_SECURITY_INFORMATION | sddl.GROUP_SECURITY_INFORMATION
 
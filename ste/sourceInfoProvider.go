@@ -98,6 +98,12 @@ type IUNIXPropertyBearingSourceInfoProvider interface {
 	HasUNIXProperties() bool
 }
 
+type IXattrBearingSourceInfoProvider interface {
+	ISourceInfoProvider
+
+	GetXattrs() (common.Xattrs, error)
+}
+
 type ISymlinkBearingSourceInfoProvider interface {
 	ISourceInfoProvider
 
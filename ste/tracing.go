@@ -0,0 +1,101 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The spans of a job nest like this: Job > JobPart > Transfer > one span per HTTP request (named for its operation, e.g. PutBlock).
+// Requests made outside of transfers (e.g. when creating containers) are children of the job part or job.
+// See common.InitTracing for how tracing is turned on.
+
+const (
+	traceAttrJobID              = attribute.Key("azcopy.job_id")
+	traceAttrFromTo             = attribute.Key("azcopy.from_to")
+	traceAttrPartNumber         = attribute.Key("azcopy.part_number")
+	traceAttrTransferCount      = attribute.Key("azcopy.transfer_count")
+	traceAttrTransfersCompleted = attribute.Key("azcopy.transfers_completed")
+	traceAttrTransfersFailed    = attribute.Key("azcopy.transfers_failed")
+	traceAttrTransfersSkipped   = attribute.Key("azcopy.transfers_skipped")
+	traceAttrSource             = attribute.Key("azcopy.source")
+	traceAttrDestination        = attribute.Key("azcopy.destination")
+	traceAttrIsFolder           = attribute.Key("azcopy.is_folder")
+	traceAttrSize               = attribute.Key("azcopy.size")
+	traceAttrStatus             = attribute.Key("azcopy.status")
+	traceAttrErrorCode          = attribute.Key("azcopy.error_code")
+	traceAttrRetryCount         = attribute.Key("azcopy.retry_count")
+	traceAttrClientRequestID    = attribute.Key("azcopy.client_request_id")
+	traceAttrServiceRequestID   = attribute.Key("azcopy.service_request_id")
+	traceAttrTry                = attribute.Key("azcopy.try")
+	traceAttrError              = attribute.Key("azcopy.error")
+)
+
+var transferRetryCountContextKey = contextKey{"transferRetryCount"}
+
+// startTransferSpan starts the span of one transfer. The returned context carries the span, so that the transfer's requests are
+// its children, and also a count of the transfer's retries, which those requests add to
+func (jpm *jobPartMgr) startTransferSpan(ctx context.Context, transferIndex uint32) context.Context {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx // tracing is off
+	}
+	plan := jpm.Plan()
+	src, dst, isFolder := plan.TransferSrcDstStrings(transferIndex)
+	ctx, _ = common.Tracer().Start(ctx, "Transfer", trace.WithAttributes(
+		traceAttrFromTo.String(plan.FromTo.String()),
+		traceAttrSource.String(common.URLStringExtension(src).RedactSecretQueryParamForLogging()),
+		traceAttrDestination.String(common.URLStringExtension(dst).RedactSecretQueryParamForLogging()),
+		traceAttrIsFolder.Bool(isFolder),
+		traceAttrSize.Int64(plan.Transfer(transferIndex).SourceSize)))
+	return context.WithValue(ctx, transferRetryCountContextKey, new(int64))
+}
+
+// endTransferSpan ends the span started by startTransferSpan, if there is one
+func endTransferSpan(ctx context.Context, status common.TransferStatus, errorCode int32) {
+	retries, ok := ctx.Value(transferRetryCountContextKey).(*int64)
+	if !ok {
+		return // not traced
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		traceAttrStatus.String(status.String()),
+		traceAttrRetryCount.Int64(atomic.LoadInt64(retries)))
+	if status == common.ETransferStatus.Failed() || status == common.ETransferStatus.BlobTierFailure() {
+		if errorCode != 0 {
+			span.SetAttributes(traceAttrErrorCode.Int64(int64(errorCode)))
+		}
+		span.SetStatus(codes.Error, status.String())
+	}
+	span.End()
+}
+
+// addTransferRetries adds to the retry count of the transfer (if any) whose context this is
+func addTransferRetries(ctx context.Context, retries int64) {
+	if counter, ok := ctx.Value(transferRetryCountContextKey).(*int64); ok {
+		atomic.AddInt64(counter, retries)
+	}
+}
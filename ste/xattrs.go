@@ -0,0 +1,167 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// xattrsSidecarURL returns the URL of the blob that holds the extended attributes of the given blob, when they
+// don't fit in its metadata
+func xattrsSidecarURL(blobURL string) (azblob.BlobURLParts, error) {
+	u, err := url.Parse(blobURL)
+	if err != nil {
+		return azblob.BlobURLParts{}, err
+	}
+	parts := azblob.NewBlobURLParts(*u)
+	parts.BlobName += common.XattrsSidecarSuffix
+	return parts, nil
+}
+
+// addXattrsToBlobMetadata records the extended attributes of the local file in the metadata of its blob, or, when
+// they're too big for that, in a sidecar blob next to it. Either way, their hash goes in the metadata, for sync.
+func addXattrsToBlobMetadata(jptm IJobPartTransferMgr, sip ISourceInfoProvider, destination string, p pipeline.Pipeline, metadata azblob.Metadata) error {
+	// sync may have put a hash from its listing in the metadata, but it's the one for what's sent now that counts
+	delete(metadata, common.POSIXXattrsHashMeta)
+
+	xattrSIP, ok := sip.(IXattrBearingSourceInfoProvider)
+	if !ok {
+		return nil
+	}
+
+	xattrs, err := xattrSIP.GetXattrs()
+	if err != nil || len(xattrs) == 0 {
+		return err
+	}
+
+	hash, err := xattrs.Hash()
+	if err != nil {
+		return err
+	}
+	metadata[common.POSIXXattrsHashMeta] = hash
+
+	encoded, err := xattrs.Encode()
+	if err != nil {
+		return err
+	}
+	if len(encoded) <= common.XattrsMetadataMaxBytes {
+		metadata[common.POSIXXattrsMeta] = encoded
+		return nil
+	}
+
+	buf, err := xattrs.Marshal()
+	if err != nil {
+		return err
+	}
+	sidecar, err := xattrsSidecarURL(destination)
+	if err != nil {
+		return err
+	}
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, fmt.Sprintf("Extended attributes take %d bytes, so are sent in %s", len(buf), sidecar.BlobName))
+
+	_, err = azblob.NewBlockBlobURL(sidecar.URL(), p).Upload(jptm.Context(), bytes.NewReader(buf),
+		azblob.BlobHTTPHeaders{ContentType: "application/json"}, azblob.Metadata{}, azblob.BlobAccessConditions{},
+		azblob.AccessTierNone, nil, common.ToClientProvidedKeyOptions(jptm.CpkInfo(), jptm.CpkScopeInfo()), azblob.ImmutabilityPolicyOptions{})
+	if err != nil {
+		return fmt.Errorf("uploading sidecar blob: %w", err)
+	}
+	metadata[common.POSIXXattrsSidecarMeta] = "true"
+	return nil
+}
+
+// deleteXattrsSidecar deletes the sidecar blob of the given blob, if there is one
+func deleteXattrsSidecar(ctx context.Context, blobURL string, p pipeline.Pipeline) error {
+	sidecar, err := xattrsSidecarURL(blobURL)
+	if err != nil {
+		return err
+	}
+	_, err = azblob.NewBlobURL(sidecar.URL(), p).Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	if stgErr, ok := err.(azblob.StorageError); ok && stgErr.Response().StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// removeStaleXattrsSidecar deletes the sidecar blob left by an earlier upload of the blob, once the blob has been
+// uploaded with its extended attributes in its metadata (or without any). We don't know whether the blob had one,
+// so this costs a request per blob, which is only made with --preserve-xattrs. A failure leaves an unused blob behind,
+// so it is only logged.
+func removeStaleXattrsSidecar(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, metadata azblob.Metadata) {
+	if !jptm.IsLive() {
+		return
+	}
+	if _, inSidecar := metadata[common.POSIXXattrsSidecarMeta]; inSidecar {
+		return
+	}
+	if err := deleteXattrsSidecar(jptm.Context(), destination, p); err != nil {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Could not delete the sidecar blob of extended attributes that are now in the metadata: "+err.Error())
+	}
+}
+
+// restoreXattrs writes the extended attributes recorded by an upload to the downloaded file
+func restoreXattrs(jptm IJobPartTransferMgr) error {
+	info := jptm.Info()
+
+	var xattrs common.Xattrs
+	var err error
+	if encoded, ok := info.SrcMetadata[common.POSIXXattrsMeta]; ok {
+		xattrs, err = common.DecodeXattrs(encoded)
+	} else if _, ok := info.SrcMetadata[common.POSIXXattrsSidecarMeta]; ok {
+		xattrs, err = downloadXattrsSidecar(jptm)
+	} else {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return common.SetXattrs(info.Destination, xattrs)
+}
+
+func downloadXattrsSidecar(jptm IJobPartTransferMgr) (common.Xattrs, error) {
+	sidecar, err := xattrsSidecarURL(jptm.Info().Source)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := azblob.NewBlobURL(sidecar.URL(), jptm.SourceProviderPipeline()).Download(jptm.Context(), 0, azblob.CountToEnd,
+		azblob.BlobAccessConditions{}, false, common.ToClientProvidedKeyOptions(jptm.CpkInfo(), jptm.CpkScopeInfo()))
+	if err != nil {
+		return nil, fmt.Errorf("downloading sidecar blob: %w", err)
+	}
+	body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody})
+	defer body.Close()
+
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading sidecar blob: %w", err)
+	}
+	return common.UnmarshalXattrs(buf)
+}
@@ -192,6 +192,10 @@ func anyToRemote(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, sen
 		}
 	case common.EEntityType.Symlink():
 		anyToRemote_symlink(jptm, info, p, pacer, senderFactory, sipf)
+	case common.EEntityType.Hardlink():
+		anyToRemote_hardlink(jptm, info, p, pacer, senderFactory, sipf)
+	case common.EEntityType.SpecialFile():
+		anyToRemote_specialFile(jptm, info, p, pacer, senderFactory, sipf)
 	}
 }
 
@@ -0,0 +1,58 @@
+package ste
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// anyToRemote_hardlink sends a file that is a further name of a file sent in the same job. Only a reference to that
+// file is sent; the front end put its path, relative to this file's folder, in the transfer's metadata.
+func anyToRemote_hardlink(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pipeline, pacer pacer, senderFactory senderFactory, sipf sourceInfoProviderFactory) {
+	// Check if cancelled
+	if jptm.WasCanceled() {
+		/* This is earliest we detect that jptm has been cancelled before we reach destination */
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	target, ok := info.SrcMetadata[common.POSIXHardlinkMeta]
+	if !ok || target == "" {
+		jptm.LogSendError(info.Source, info.Destination, "the hard link was scheduled without the file it links to", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// Create SIP
+	srcInfoProvider, err := sipf(jptm)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	baseSender, err := senderFactory(jptm, info.Destination, p, pacer, srcInfoProvider)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	s, ok := baseSender.(hardlinkSender)
+	if !ok {
+		jptm.LogSendError(info.Source, info.Destination, "sender implementation does not support hard links", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	err = s.SendHardlink(target)
+	if err != nil {
+		jptm.FailActiveSend("creating destination hard link representative", err)
+	}
+
+	commonSenderCompletion(jptm, baseSender, info)
+}
@@ -0,0 +1,50 @@
+package ste
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// anyToRemote_specialFile sends a FIFO, device node or socket. These have no content, so only what kind of file it
+// is, and which device it stands for, is sent.
+func anyToRemote_specialFile(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pipeline, pacer pacer, senderFactory senderFactory, sipf sourceInfoProviderFactory) {
+	// Check if cancelled
+	if jptm.WasCanceled() {
+		/* This is earliest we detect that jptm has been cancelled before we reach destination */
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// Create SIP
+	srcInfoProvider, err := sipf(jptm)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	baseSender, err := senderFactory(jptm, info.Destination, p, pacer, srcInfoProvider)
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	s, ok := baseSender.(specialFileSender)
+	if !ok {
+		jptm.LogSendError(info.Source, info.Destination, "sender implementation does not support special files", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	err = s.SendSpecialFile()
+	if err != nil {
+		jptm.FailActiveSend("creating destination special file representative", err)
+	}
+
+	commonSenderCompletion(jptm, baseSender, info)
+}
@@ -92,6 +92,13 @@ func doDeleteBlob(jptm IJobPartTransferMgr, p pipeline.Pipeline) {
 		// in all other cases, make the transfer as failed
 		transferDone(common.ETransferStatus.Failed(), err)
 	} else {
+		// the sidecar holding extended attributes too big for the metadata goes with its blob
+		if _, hasSidecar := info.SrcMetadata[common.POSIXXattrsSidecarMeta]; hasSidecar {
+			if err := deleteXattrsSidecar(jptm.Context(), info.Source, p); err != nil {
+				transferDone(common.ETransferStatus.Failed(), fmt.Errorf("deleting sidecar blob: %w", err))
+				return
+			}
+		}
 		transferDone(common.ETransferStatus.Success(), nil)
 	}
 }
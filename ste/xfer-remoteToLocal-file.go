@@ -43,6 +43,10 @@ func remoteToLocal(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, d
 		remoteToLocal_folder(jptm, p, pacer, df)
 	} else if info.EntityType == common.EEntityType.Symlink() {
 		remoteToLocal_symlink(jptm, p, pacer, df)
+	} else if info.EntityType == common.EEntityType.Hardlink() {
+		remoteToLocal_hardlink(jptm, p, pacer, df)
+	} else if info.EntityType == common.EEntityType.SpecialFile() {
+		remoteToLocal_specialFile(jptm, p, pacer, df)
 	} else {
 		remoteToLocal_file(jptm, p, pacer, df)
 	}
@@ -491,7 +495,7 @@ redoCompletion:
 			}
 
 			plan := jptm.(*jobPartTransferMgr).jobPartMgr.Plan()
-			dataRoot := string(plan.DestinationRoot[:plan.DestinationRootLength])
+			dataRoot := plan.DestinationRoot()
 			_, idx := jptm.TransferIndex()
 			_, relDest := plan.TransferSrcDstRelatives(idx)
 			adapter, err := common.NewHashDataAdapter(common.LocalHashDir, dataRoot, common.LocalHashStorageMode)
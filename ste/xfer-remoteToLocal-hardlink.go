@@ -0,0 +1,128 @@
+package ste
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// how often a hard link checks whether the files in the earlier job parts are done
+const hardlinkWaitInterval = 200 * time.Millisecond
+
+// remoteToLocal_hardlink recreates a hard link to a file downloaded in the same job. The front end puts every
+// hard link in the last job parts, so waiting for the earlier parts is enough to know the file it links to is in place.
+// That's why such downloads can't be distributed: each worker runs its parts as jobs of their own.
+func remoteToLocal_hardlink(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, df downloaderFactory) {
+	info := jptm.Info()
+
+	// Perform initial checks
+	// If the transfer was cancelled, then report transfer as done
+	if jptm.WasCanceled() {
+		/* This is the earliest we detect that jptm was cancelled, before we go to destination */
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	target, err := hardlinkTarget(jptm, info)
+	if err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	if !waitForEarlierJobParts(jptm) {
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	targetProps, err := os.Lstat(target)
+	if err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, fmt.Sprintf("the file it links to, %s, is not at the destination: %s", target, err), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// a sync (or a resumed job) may find the link already there
+	if dstProps, err := os.Lstat(info.Destination); err == nil && os.SameFile(dstProps, targetProps) {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Already linked to "+target)
+		commonDownloaderCompletion(jptm, info, common.EEntityType.Hardlink())
+		return
+	}
+
+	if !removeLocalDestinationForLink(jptm, info) {
+		return
+	}
+
+	err = common.CreateParentDirectoryIfNotExist(info.Destination, jptm.GetFolderCreationTracker())
+	if err == nil {
+		err = os.Link(target, info.Destination)
+	}
+	if err != nil {
+		jptm.FailActiveSend("creating destination hard link", err)
+	}
+
+	commonDownloaderCompletion(jptm, info, common.EEntityType.Hardlink())
+}
+
+// hardlinkTarget works out where the file that the hard link refers to was downloaded. References that lead out of
+// the destination are refused, since links to arbitrary local files could then be made by whoever wrote the blobs.
+func hardlinkTarget(jptm IJobPartTransferMgr, info TransferInfo) (string, error) {
+	ref, ok := info.SrcMetadata[common.POSIXHardlinkMeta]
+	if !ok || ref == "" {
+		return "", errors.New("the hard link was scheduled without the file it links to")
+	}
+	ref, err := url.PathUnescape(ref)
+	if err != nil {
+		return "", fmt.Errorf("the reference to the file it links to is invalid: %w", err)
+	}
+
+	target := filepath.Join(filepath.Dir(info.Destination), filepath.FromSlash(ref))
+
+	root := jptm.(*jobPartTransferMgr).jobPartMgr.Plan().DestinationRoot()
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("the file it links to, %s, is outside of the destination", target)
+	}
+
+	return target, nil
+}
+
+// waitForEarlierJobParts blocks until all the transfers in the job parts before this transfer's part are done.
+// It returns false if the job is cancelled while waiting.
+func waitForEarlierJobParts(jptm IJobPartTransferMgr) bool {
+	jpm := jptm.(*jobPartTransferMgr).jobPartMgr.(*jobPartMgr)
+	partNum := jpm.Plan().PartNum
+
+	for {
+		done := true
+		jpm.jobMgr.IterateJobParts(true, func(k common.PartNumber, v IJobPartMgr) {
+			if k >= partNum {
+				return
+			}
+			if other, ok := v.(*jobPartMgr); ok && atomic.LoadUint32(&other.atomicTransfersDone) != other.Plan().NumTransfers {
+				done = false
+			}
+		})
+		if done {
+			return true
+		}
+
+		select {
+		case <-jptm.Context().Done():
+			return false
+		case <-time.After(hardlinkWaitInterval):
+		}
+	}
+}
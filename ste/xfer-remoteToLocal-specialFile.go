@@ -0,0 +1,41 @@
+package ste
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// remoteToLocal_specialFile recreates a FIFO, device node or socket from a blob written for it by an upload
+func remoteToLocal_specialFile(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, df downloaderFactory) {
+	info := jptm.Info()
+
+	// Perform initial checks
+	// If the transfer was cancelled, then report transfer as done
+	if jptm.WasCanceled() {
+		/* This is the earliest we detect that jptm was cancelled, before we go to destination */
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+	if !removeLocalDestinationForLink(jptm, info) {
+		return
+	}
+
+	dl, ok := df().(specialFileDownloader)
+	if !ok {
+		jptm.LogDownloadError(info.Source, info.Destination, "downloader implementation does not support special files", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	err := common.CreateParentDirectoryIfNotExist(info.Destination, jptm.GetFolderCreationTracker())
+	if err == nil {
+		err = dl.CreateSpecialFile(jptm)
+	}
+	if err != nil {
+		jptm.FailActiveSend("creating destination special file", err)
+	}
+
+	commonDownloaderCompletion(jptm, info, common.EEntityType.SpecialFile())
+}
@@ -17,6 +17,29 @@ func remoteToLocal_symlink(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer
 		jptm.ReportTransferDone()
 		return
 	}
+	if !removeLocalDestinationForLink(jptm, info) {
+		return
+	}
+
+	dl, ok := df().(symlinkDownloader)
+	if !ok {
+		jptm.LogDownloadError(info.Source, info.Destination, "downloader implementation does not support symlinks", 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	err := dl.CreateSymlink(jptm)
+	if err != nil {
+		jptm.FailActiveSend("creating destination symlink", err)
+	}
+
+	commonDownloaderCompletion(jptm, info, common.EEntityType.Symlink())
+}
+
+// removeLocalDestinationForLink clears the way for a link to be created at the destination, applying the overwrite
+// option to whatever is there already. It returns false if the transfer is done with, having been skipped or failed.
+func removeLocalDestinationForLink(jptm IJobPartTransferMgr, info TransferInfo) bool {
 	// if the force Write flags is set to false or prompt
 	// then check the file exists at the remote location
 	// if it does, react accordingly
@@ -41,13 +64,13 @@ func remoteToLocal_symlink(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer
 				jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "File already exists, so will be skipped")
 				jptm.SetStatus(common.ETransferStatus.SkippedEntityAlreadyExists())
 				jptm.ReportTransferDone()
-				return
+				return false
 			} else {
 				err = os.Remove(info.Destination)
 				if err != nil && !os.IsNotExist(err) { // should not get back a non-existent error, but if we do, it's not a bad thing.
 					jptm.FailActiveSend("deleting old file", err)
 					jptm.ReportTransferDone()
-					return
+					return false
 				}
 			}
 		}
@@ -56,22 +79,9 @@ func remoteToLocal_symlink(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer
 		if err != nil && !os.IsNotExist(err) { // it's OK to fail because it doesn't exist.
 			jptm.FailActiveSend("deleting old file", err)
 			jptm.ReportTransferDone()
-			return
+			return false
 		}
 	}
 
-	dl, ok := df().(symlinkDownloader)
-	if !ok {
-		jptm.LogDownloadError(info.Source, info.Destination, "downloader implementation does not support symlinks", 0)
-		jptm.SetStatus(common.ETransferStatus.Failed())
-		jptm.ReportTransferDone()
-		return
-	}
-
-	err := dl.CreateSymlink(jptm)
-	if err != nil {
-		jptm.FailActiveSend("creating destination symlink", err)
-	}
-
-	commonDownloaderCompletion(jptm, info, common.EEntityType.Symlink())
+	return true
 }
@@ -0,0 +1,216 @@
+package ste
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// AccessControlContinuationSuffix names the files, in the plan folder, that hold the continuation token of an
+// unfinished recursive ACL change, so that a resumed job carries on from there rather than starting over
+const AccessControlContinuationSuffix = ".aclContinuation"
+
+// the number of paths the service changes per request
+const accessControlRecursiveBatchSize = int32(2000)
+
+// AccessControlContinuationFile returns the file that holds the continuation token of the given transfer
+func AccessControlContinuationFile(jobID common.JobID, partNum common.PartNumber, transferIndex uint32) string {
+	return filepath.Join(common.AzcopyJobPlanFolder, fmt.Sprintf("%s--%05d-%d%s", jobID.String(), partNum, transferIndex, AccessControlContinuationSuffix))
+}
+
+func setAccessControlBlobFS(jptm IJobPartTransferMgr) {
+	info := jptm.Info()
+	plan := jptm.(*jobPartTransferMgr).jobPartMgr.Plan()
+	p := jptm.(*jobPartTransferMgr).jobPartMgr.(*jobPartMgr).secondaryPipeline // the dfs endpoint; set-properties jobs otherwise use blob
+	_, transferIndex := jptm.TransferIndex()
+	continuationFile := AccessControlContinuationFile(info.JobID, plan.PartNum, transferIndex)
+
+	transferDone := func(status common.TransferStatus, err error) {
+		if status == common.ETransferStatus.Failed() {
+			jptm.LogError(info.Source, "SET-ACL ERROR ", err)
+		} else {
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf("SET-ACL SUCCESSFUL: %s", strings.Split(info.Source, "?")[0]))
+			_ = os.Remove(continuationFile)
+		}
+
+		jptm.SetStatus(status)
+		jptm.ResetSourceSize() // sets source size to 0 (made to be used by setProperties command to make number of bytes transferred = 0)
+		jptm.ReportTransferDone()
+	}
+
+	mode := accessControlRecursiveMode(jptm.PropertiesToTransfer())
+	acl := plan.AccessControlList()
+
+	u, err := url.Parse(info.Source)
+	if err != nil {
+		panic("sanity check: HNS source URI did not parse.")
+	}
+	directoryURL := azbfs.NewDirectoryURL(*u, p)
+
+	// the root of the filesystem is always a directory, anything else may be a file
+	if !directoryURL.IsFileSystemRoot() {
+		props, err := directoryURL.GetProperties(jptm.Context())
+		if err != nil {
+			errorHandlerForXferSetProperties(err, jptm, transferDone)
+			return
+		}
+		if strings.EqualFold(props.XMsResourceType(), "file") {
+			err = setSingleAccessControl(jptm, directoryURL.NewFileUrl(), mode, acl)
+			if err != nil {
+				errorHandlerForXferSetProperties(err, jptm, transferDone)
+				return
+			}
+			transferDone(common.ETransferStatus.Success(), nil)
+			return
+		}
+	}
+
+	if !info.BlobFSRecursiveDelete {
+		// only the directory itself
+		err = setSingleAccessControl(jptm, directoryURL, mode, acl)
+		if err != nil {
+			errorHandlerForXferSetProperties(err, jptm, transferDone)
+			return
+		}
+		transferDone(common.ETransferStatus.Success(), nil)
+		return
+	}
+
+	// carry on from where a previous run of the job got to, if it didn't finish
+	continuation := ""
+	if b, err := ioutil.ReadFile(continuationFile); err == nil {
+		continuation = strings.TrimSpace(string(b))
+		jptm.Log(pipeline.LogInfo, fmt.Sprintf("Resuming recursive ACL change of %s", strings.Split(info.Source, "?")[0]))
+	}
+
+	failures := int64(0)
+	for {
+		resp, err := directoryURL.SetAccessControlRecursive(jptm.Context(), mode, acl, continuation, accessControlRecursiveBatchSize, true)
+		if err != nil {
+			errorHandlerForXferSetProperties(err, jptm, transferDone)
+			return
+		}
+
+		jptm.Log(pipeline.LogInfo, fmt.Sprintf("SET-ACL changed %d directories and %d files under %s, %d failed",
+			resp.DirectoriesSuccessful, resp.FilesSuccessful, strings.Split(info.Source, "?")[0], resp.FailureCount))
+		for _, entry := range resp.FailedEntries {
+			jptm.LogError(entry.Name, "SET-ACL ERROR ", errors.New(entry.ErrorMessage))
+		}
+		failures += int64(resp.FailureCount)
+
+		continuation = resp.XMsContinuation()
+		if continuation == "" {
+			break
+		}
+
+		// remember how far we got, so a resume doesn't repeat the batches that are done
+		if err := ioutil.WriteFile(continuationFile, []byte(continuation), common.DEFAULT_FILE_PERM); err != nil {
+			jptm.Log(pipeline.LogWarning, fmt.Sprintf("Couldn't save the continuation token of the recursive ACL change: %v", err))
+		}
+	}
+
+	if failures > 0 {
+		_ = os.Remove(continuationFile) // the failed paths are listed in the log; running the job again would start over anyway
+		transferDone(common.ETransferStatus.Failed(), fmt.Errorf("the ACL couldn't be changed on %d paths under this directory, see the log for the list", failures))
+		return
+	}
+	transferDone(common.ETransferStatus.Success(), nil)
+}
+
+// accessControlTarget is a file or directory, whose ACL is changed on its own
+type accessControlTarget interface {
+	GetAccessControl(ctx context.Context) (azbfs.BlobFSAccessControl, error)
+	SetAccessControl(ctx context.Context, permissions azbfs.BlobFSAccessControl) (*azbfs.PathUpdateResponse, error)
+}
+
+// setSingleAccessControl makes the change on a single path. The recursive API only applies to everything under a
+// directory, so the modify and remove modes are worked out here from the path's current ACL
+func setSingleAccessControl(jptm IJobPartTransferMgr, target accessControlTarget, mode azbfs.PathSetAccessControlRecursiveMode, acl string) error {
+	if mode != azbfs.PathSetAccessControlRecursiveModeSet {
+		current, err := target.GetAccessControl(jptm.Context())
+		if err != nil {
+			return err
+		}
+		acl = MergeADLSACL(current.ACL, acl, mode)
+	}
+
+	_, err := target.SetAccessControl(jptm.Context(), azbfs.BlobFSAccessControl{ACL: acl})
+	return err
+}
+
+func accessControlRecursiveMode(flags common.SetPropertiesFlags) azbfs.PathSetAccessControlRecursiveMode {
+	switch {
+	case flags&common.ESetPropertiesFlags.ModifyAccessControl() != 0:
+		return azbfs.PathSetAccessControlRecursiveModeModify
+	case flags&common.ESetPropertiesFlags.RemoveAccessControl() != 0:
+		return azbfs.PathSetAccessControlRecursiveModeRemove
+	default:
+		return azbfs.PathSetAccessControlRecursiveModeSet
+	}
+}
+
+// MergeADLSACL applies a modify or remove change to an ACL, in the same way the service does for the recursive API.
+// Entries are matched on their scope, type and qualifier; for remove, the change's entries need not have permissions.
+func MergeADLSACL(current string, change string, mode azbfs.PathSetAccessControlRecursiveMode) string {
+	if mode == azbfs.PathSetAccessControlRecursiveModeSet {
+		return change
+	}
+
+	entries := make([]string, 0)
+	for _, entry := range strings.Split(current, ",") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, entry := range strings.Split(change, ",") {
+		if entry == "" {
+			continue
+		}
+		key := adlsACLEntryKey(entry)
+		found := -1
+		for i, existing := range entries {
+			if adlsACLEntryKey(existing) == key {
+				found = i
+				break
+			}
+		}
+
+		switch {
+		case mode == azbfs.PathSetAccessControlRecursiveModeRemove && found >= 0:
+			entries = append(entries[:found], entries[found+1:]...)
+		case mode == azbfs.PathSetAccessControlRecursiveModeModify && found >= 0:
+			entries[found] = entry
+		case mode == azbfs.PathSetAccessControlRecursiveModeModify:
+			entries = append(entries, entry)
+		}
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// adlsACLEntryKey returns the entry without its permissions, e.g. "default:user:<oid>" for "default:user:<oid>:r-x"
+func adlsACLEntryKey(entry string) string {
+	parts := strings.Split(entry, ":")
+	scope := ""
+	if parts[0] == "default" {
+		scope = "default:"
+		parts = parts[1:]
+	}
+	if len(parts) >= 3 {
+		parts = parts[:2]
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+	return scope + strings.ToLower(parts[0]) + ":" + strings.ToLower(parts[1])
+}
@@ -27,7 +27,11 @@ func SetProperties(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer) {
 		case common.ELocation.Blob():
 			setPropertiesBlob(jptm, p)
 		case common.ELocation.BlobFS():
-			setPropertiesBlobFS(jptm, p)
+			if propertiesToTransfer := jptm.PropertiesToTransfer(); propertiesToTransfer.ShouldTransferAccessControl() {
+				setAccessControlBlobFS(jptm)
+			} else {
+				setPropertiesBlobFS(jptm, p)
+			}
 		case common.ELocation.File():
 			setPropertiesFile(jptm, p)
 		default:
@@ -0,0 +1,122 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// sasRenewalPolicy swaps SAS tokens that have a registered renewer (see common.RegisterSASRenewer) for their current
+// replacement, just before each try is sent. The SAS baked into a transfer's URLs is captured when the transfer is scheduled, which can be
+// long before the request is actually sent in a long-running job, so renewing here is what keeps such jobs alive.
+// Both the request URL and the source URL of server-side copies are considered.
+type sasRenewalPolicy struct {
+	next pipeline.Policy
+}
+
+// copySourceHeaders are the headers through which S2S requests pass their (possibly SAS-bearing) source URL.
+var copySourceHeaders = []string{"x-ms-copy-source"}
+
+func (p *sasRenewalPolicy) Do(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+	if renewed, changed := renewSASInURL(ctx, *request.URL); changed {
+		request.URL.RawQuery = renewed.RawQuery
+	}
+
+	for _, h := range copySourceHeaders {
+		raw := request.Header.Get(h)
+		if raw == "" {
+			continue
+		}
+
+		if u, err := url.Parse(raw); err == nil {
+			if renewed, changed := renewSASInURL(ctx, *u); changed {
+				request.Header.Set(h, renewed.String())
+			}
+		}
+	}
+
+	return p.next.Do(ctx, request)
+}
+
+// renewSASInURL returns u with its SAS replaced, if that SAS has a registered renewer and has since been renewed.
+// Failures are logged and the latest known SAS is used, so that the service gets the final say.
+func renewSASInURL(ctx context.Context, u url.URL) (url.URL, bool) {
+	parts := azblob.NewBlobURLParts(u)
+	sas := parts.SAS.Encode()
+	if sas == "" {
+		return u, false
+	}
+
+	renewed, ok, err := common.RenewSAS(ctx, sas)
+	if err != nil {
+		common.GetLifecycleMgr().Info("WARNING: " + err.Error())
+	}
+	if !ok || renewed == sas {
+		return u, false
+	}
+
+	u.RawQuery = replaceSASInQuery(u.RawQuery, sas, renewed)
+	return u, true
+}
+
+// replaceSASInQuery removes the parameters of oldSAS from rawQuery, preserving any non-SAS parameters (e.g. snapshot IDs),
+// and appends newSAS.
+func replaceSASInQuery(rawQuery, oldSAS, newSAS string) string {
+	oldParams, _ := url.ParseQuery(oldSAS)
+
+	kept := make([]string, 0)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		key := param
+		if i := strings.Index(param, "="); i >= 0 {
+			key = param[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if _, isSAS := oldParams[key]; !isSAS {
+			kept = append(kept, param)
+		}
+	}
+
+	if newSAS != "" {
+		kept = append(kept, newSAS)
+	}
+	return strings.Join(kept, "&")
+}
+
+func newSASRenewalPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		p := sasRenewalPolicy{next: next}
+		return p.Do
+	})
+}
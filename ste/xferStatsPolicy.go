@@ -42,6 +42,8 @@ type PipelineNetworkStats struct {
 	atomicStartSeconds         int64
 	nocopy                     common.NoCopy
 	tunerInterface             ConcurrencyTuner
+	requestsByHost             requestStatsByHost // for adaptive block sizes
+	requestLatencies           requestLatencyStats
 }
 
 func newPipelineNetworkStats(tunerInterface ConcurrencyTuner) *PipelineNetworkStats {
@@ -149,6 +151,19 @@ func (s *PipelineNetworkStats) OtherServerBusyPercentage() float32 {
 	}
 }
 
+// blockSizeObservations returns what the adaptive block size choice needs to know about requests to the given host
+func (s *PipelineNetworkStats) blockSizeObservations(host string) blockSizeObservations {
+	ob := s.requestsByHost.observations(host)
+	ob.serverBusyPercentage = s.TotalServerBusyPercentage()
+	return ob
+}
+
+// RequestLatencies returns the latency distribution of each type of request made so far
+func (s *PipelineNetworkStats) RequestLatencies() []common.RequestLatencySummary {
+	s.nocopy.Check()
+	return s.requestLatencies.summaries()
+}
+
 func (s *PipelineNetworkStats) AverageE2EMilliseconds() int {
 	s.nocopy.Check()
 	ops := atomic.LoadInt64(&s.atomicOperationCount)
@@ -171,9 +186,25 @@ func (p *xferStatsPolicy) Do(ctx context.Context, request pipeline.Request) (pip
 	resp, err := p.next.Do(ctx, request)
 
 	if p.stats != nil {
+		e2e := time.Since(start)
+		e2eMilliseconds := int64(e2e.Seconds() * 1000)
+		p.stats.tunerInterface.recordLatency(e2eMilliseconds) // the tuner needs these even before we have started, in case it's tuning to a latency target
+		if err == nil {
+			p.stats.requestsByHost.record(request.URL.Host, request.ContentLength, e2e) // likewise for adaptive block sizes
+		}
+		if !isContextCancelledError(err) {
+			// Unlike the averages below, these include the requests made before we started, since slow requests
+			// while the tuner is ramping up are often the ones that matter when looking into throttling
+			var rawResponse *http.Response
+			if resp != nil {
+				rawResponse = resp.Response()
+			}
+			p.stats.requestLatencies.record(request.Request, rawResponse, start, e2e)
+		}
+
 		if p.stats.IsStarted() {
 			atomic.AddInt64(&p.stats.atomicOperationCount, 1)
-			atomic.AddInt64(&p.stats.atomicE2ETotalMilliseconds, int64(time.Since(start).Seconds()*1000))
+			atomic.AddInt64(&p.stats.atomicE2ETotalMilliseconds, e2eMilliseconds)
 
 			if err != nil && !isContextCancelledError(err) {
 				// no response from server
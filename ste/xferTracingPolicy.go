@@ -0,0 +1,98 @@
+package ste
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tryCountContextKey = contextKey{"tryCount"}
+
+// xferTracingPolicy creates a span for each HTTP request made in the context of a job's span (which is only recorded when tracing is enabled).
+// It goes before the retry policy, so that the span covers all the tries of the request. The tries themselves
+// are counted, and recorded as events on the span, by xferTracingTryPolicy, which goes closest to the wire.
+type xferTracingPolicy struct {
+	next pipeline.Policy
+}
+
+func (p *xferTracingPolicy) Do(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return p.next.Do(ctx, request) // tracing is off, or the request isn't part of a job
+	}
+
+	ctx, span := common.Tracer().Start(ctx, requestOperationName(request.Request),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(request.Method),
+			semconv.HTTPURLKey.String(common.URLExtension{URL: *request.URL}.RedactSecretQueryParamForLogging()),
+			traceAttrClientRequestID.String(request.Header.Get("x-ms-client-request-id"))))
+	defer span.End()
+
+	tries := new(int64)
+	resp, err := p.next.Do(context.WithValue(ctx, tryCountContextKey, tries), request)
+
+	if retries := atomic.LoadInt64(tries) - 1; retries > 0 {
+		span.SetAttributes(traceAttrRetryCount.Int64(retries))
+		addTransferRetries(ctx, retries)
+	}
+	if resp != nil && resp.Response() != nil {
+		rawResponse := resp.Response()
+		span.SetAttributes(
+			semconv.HTTPStatusCodeKey.Int(rawResponse.StatusCode),
+			traceAttrServiceRequestID.String(rawResponse.Header.Get("x-ms-request-id")))
+		if rawResponse.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, rawResponse.Status)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return resp, err
+}
+
+func newXferTracingPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		r := xferTracingPolicy{next: next}
+		return r.Do
+	})
+}
+
+// xferTracingTryPolicy records each try of a request on the span created by xferTracingPolicy
+type xferTracingTryPolicy struct {
+	next pipeline.Policy
+}
+
+func (p *xferTracingTryPolicy) Do(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+	tries, ok := ctx.Value(tryCountContextKey).(*int64)
+	if !ok {
+		return p.next.Do(ctx, request) // not traced
+	}
+	try := atomic.AddInt64(tries, 1)
+
+	resp, err := p.next.Do(ctx, request)
+
+	attributes := []attribute.KeyValue{traceAttrTry.Int64(try)}
+	if resp != nil && resp.Response() != nil {
+		attributes = append(attributes, semconv.HTTPStatusCodeKey.Int(resp.Response().StatusCode))
+	}
+	if err != nil {
+		attributes = append(attributes, traceAttrError.String(err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("Try", trace.WithAttributes(attributes...))
+	return resp, err
+}
+
+func newXferTracingTryPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		r := xferTracingTryPolicy{next: next}
+		return r.Do
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type adaptiveBlockSizeSuite struct{}
+
+var _ = chk.Suite(&adaptiveBlockSizeSuite{})
+
+const testMiB = 1024 * 1024
+
+func (s *adaptiveBlockSizeSuite) TestSmallFilesUseDefault(c *chk.C) {
+	fast := blockSizeObservations{bytesPerSecondPerRequest: 500 * testMiB}
+	c.Assert(chooseAdaptiveBlockSize(1, false, 0, fast), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+	c.Assert(chooseAdaptiveBlockSize(64*testMiB, false, 0, fast), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+	c.Assert(chooseAdaptiveBlockSize(0, true, 0, blockSizeObservations{}), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+}
+
+func (s *adaptiveBlockSizeSuite) TestUploadScalesWithThroughput(c *chk.C) {
+	fileSize := int64(10 * 1024 * testMiB)
+
+	// nothing observed yet
+	c.Assert(chooseAdaptiveBlockSize(fileSize, false, 0, blockSizeObservations{}), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+
+	// 10 MiB/s per request aims for 20 MiB per request
+	ob := blockSizeObservations{bytesPerSecondPerRequest: 10 * testMiB}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, false, 0, ob), chk.Equals, int64(20*testMiB))
+
+	// rounds up to whole MiB
+	ob = blockSizeObservations{bytesPerSecondPerRequest: 10.2 * testMiB}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, false, 0, ob), chk.Equals, int64(21*testMiB))
+
+	// very fast requests are capped
+	ob = blockSizeObservations{bytesPerSecondPerRequest: 1000 * testMiB}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, false, 0, ob), chk.Equals, int64(adaptiveMaxBlockSize))
+
+	// and so is memory use
+	c.Assert(chooseAdaptiveBlockSize(fileSize, false, 16*32*testMiB, ob), chk.Equals, int64(32*testMiB))
+
+	// and the file still gets enough chunks to be done in parallel
+	c.Assert(chooseAdaptiveBlockSize(160*testMiB, false, 0, ob), chk.Equals, int64(20*testMiB))
+}
+
+func (s *adaptiveBlockSizeSuite) TestDownloadScalesWithLatency(c *chk.C) {
+	fileSize := int64(10 * 1024 * testMiB)
+
+	// low latency
+	ob := blockSizeObservations{averageLatencyMs: 50}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, true, 0, ob), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+
+	// high latency, service not busy
+	ob = blockSizeObservations{averageLatencyMs: 600}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, true, 0, ob), chk.Equals, int64(3*common.DefaultBlockBlobBlockSize))
+
+	// high latency, but it's because the service is busy
+	ob = blockSizeObservations{averageLatencyMs: 600, serverBusyPercentage: 5}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, true, 0, ob), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+
+	// throughput isn't used for downloads
+	ob = blockSizeObservations{bytesPerSecondPerRequest: 100 * testMiB}
+	c.Assert(chooseAdaptiveBlockSize(fileSize, true, 0, ob), chk.Equals, int64(common.DefaultBlockBlobBlockSize))
+}
+
+func (s *adaptiveBlockSizeSuite) TestServiceLimitsWin(c *chk.C) {
+	// 4 TiB can't be done in 50,000 blocks of 8 MiB, nor of the memory-limited size
+	fileSize := int64(4 * 1024 * 1024 * testMiB)
+	size := chooseAdaptiveBlockSize(fileSize, false, 16*8*testMiB, blockSizeObservations{})
+	c.Assert(size*common.MaxNumberOfBlocksPerBlob >= fileSize, chk.Equals, true)
+	c.Assert(size > common.DefaultBlockBlobBlockSize, chk.Equals, true)
+	c.Assert(size%testMiB, chk.Equals, int64(0)) // so that page blobs can use it too
+
+	// but never beyond the maximum block size
+	size = chooseAdaptiveBlockSize(common.MaxBlockBlobBlockSize*common.MaxNumberOfBlocksPerBlob*2, false, 0, blockSizeObservations{})
+	c.Assert(size, chk.Equals, int64(common.MaxBlockBlobBlockSize))
+}
+
+func (s *adaptiveBlockSizeSuite) TestRequestStatsByHost(c *chk.C) {
+	r := &requestStatsByHost{}
+	c.Assert(r.observations("a"), chk.Equals, blockSizeObservations{})
+
+	r.record("a", 4*testMiB, 500*time.Millisecond)
+	r.record("a", 4*testMiB, 1500*time.Millisecond)
+	r.record("a", 0, 100*time.Millisecond) // too small to count for throughput
+	r.record("b", 0, 1000*time.Millisecond)
+
+	a := r.observations("a")
+	c.Assert(a.averageLatencyMs, chk.Equals, int64(700))
+	c.Assert(a.bytesPerSecondPerRequest, chk.Equals, float64(4*testMiB))
+
+	b := r.observations("b")
+	c.Assert(b.averageLatencyMs, chk.Equals, int64(1000))
+	c.Assert(b.bytesPerSecondPerRequest, chk.Equals, float64(0))
+}
+
+func (s *adaptiveBlockSizeSuite) TestAdaptiveBlockSizeHost(c *chk.C) {
+	c.Assert(adaptiveBlockSizeHost(common.EFromTo.LocalBlob(), "/data/a", "https://acct.blob.core.windows.net/c/a"), chk.Equals, "acct.blob.core.windows.net")
+	c.Assert(adaptiveBlockSizeHost(common.EFromTo.BlobLocal(), "https://src.blob.core.windows.net/c/a", "/data/a"), chk.Equals, "src.blob.core.windows.net")
+	c.Assert(adaptiveBlockSizeHost(common.EFromTo.BlobBlob(), "https://src.blob.core.windows.net/c/a", "https://dst.blob.core.windows.net/c/a"), chk.Equals, "dst.blob.core.windows.net")
+}
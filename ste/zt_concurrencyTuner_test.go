@@ -191,3 +191,129 @@ func (s *concurrencyTunerSuite) runTest(c *chk.C, steps []tunerStep, maxConcurre
 		observedHighCpu = x.highCpuObserved
 	}
 }
+
+// observedTunerStep is like tunerStep, but with the other things that the non-default strategies look at
+type observedTunerStep struct {
+	concurrency   int
+	reason        string
+	mbpsObserved  int
+	p95Observed   int64
+	retryObserved bool
+}
+
+func (s *concurrencyTunerSuite) runObservedTest(c *chk.C, t ConcurrencyTuner, steps []observedTunerStep) {
+	observedMbps := -1 // there's no observation at first
+
+	for _, x := range steps {
+		conc, reason := t.GetRecommendedConcurrency(observedMbps, false)
+		c.Assert(conc, chk.Equals, x.concurrency)
+		c.Assert(reason, chk.Equals, x.reason)
+
+		// simulate the results of the new concurrency
+		observedMbps = x.mbpsObserved
+		t.recordLatency(x.p95Observed)
+		if x.retryObserved {
+			t.recordRetry()
+		}
+	}
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_LatencyTarget(c *chk.C) {
+	t, err := NewConcurrencyTuner("latency:200", 4, s.noMax(), true)
+	c.Assert(err, chk.IsNil)
+
+	steps := []observedTunerStep{
+		{4, concurrencyReasonInitial, 100, 50, false},
+		{20, concurrencyReasonSeeking, 400, 100, false},
+		{36, concurrencyReasonSeeking, 600, 150, false},
+		{52, concurrencyReasonSeeking, 700, 250, false},     // over the target...
+		{36, concurrencyReasonLatencyHigh, 600, 150, false}, // ... so cut back
+		{52, concurrencyReasonSeeking, 700, 150, true},      // throttling counts as over the target too
+		{36, concurrencyReasonLatencyHigh, 600, 150, false},
+		{52, concurrencyReasonSeeking, 700, 300, false},
+		{36, concurrencyReasonAtLatency, 600, 150, false}, // third cut back, so settle here
+		{36, concurrencyReasonFinished, 600, 150, false},
+	}
+	s.runObservedTest(c, t, steps)
+
+	strategy, decisions := t.GetTuningDecisions()
+	c.Assert(strategy, chk.Equals, "latency (p95 target 200 ms)")
+	c.Assert(decisions, chk.HasLen, len(steps)-1) // every step except the one after tuning finished
+	c.Assert(decisions[3], chk.DeepEquals, ConcurrencyDecision{Concurrency: 52, Reason: concurrencyReasonSeeking, Mbps: 700, P95LatencyMs: 250})
+	c.Assert(decisions[5].Retries, chk.Equals, int64(1))
+	finalReason, finalConcurrency := t.GetFinalState()
+	c.Assert(finalReason, chk.Equals, concurrencyReasonAtLatency)
+	c.Assert(finalConcurrency, chk.Equals, 36)
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_LatencyTargetFromBaselineAndMax(c *chk.C) {
+	t, err := NewConcurrencyTuner("latency", 4, 30, false)
+	c.Assert(err, chk.IsNil)
+
+	steps := []observedTunerStep{
+		{4, concurrencyReasonInitial, 100, 80, false},   // so the target is 160 ms
+		{20, concurrencyReasonSeeking, 400, 150, false}, // still under it
+		{30, concurrencyReasonHitMax, 500, 150, false},
+		{30, concurrencyReasonFinished, 500, 150, false},
+	}
+	s.runObservedTest(c, t, steps)
+
+	strategy, _ := t.GetTuningDecisions()
+	c.Assert(strategy, chk.Equals, "latency (p95 target 160 ms)")
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_Sweep(c *chk.C) {
+	t, err := NewConcurrencyTuner("sweep:16:64", 4, s.noMax(), true)
+	c.Assert(err, chk.IsNil)
+
+	steps := []observedTunerStep{
+		{4, concurrencyReasonInitial, 100, 10, false},
+		{16, concurrencyReasonSweeping, 300, 10, false},
+		{32, concurrencyReasonSweeping, 500, 20, false},
+		{48, concurrencyReasonSweeping, 450, 30, false},
+		{64, concurrencyReasonSweeping, 400, 40, false},
+		{32, concurrencyReasonAtOptimum, 500, 20, false}, // the best one
+		{32, concurrencyReasonFinished, 500, 20, false},
+	}
+	s.runObservedTest(c, t, steps)
+
+	strategy, decisions := t.GetTuningDecisions()
+	c.Assert(strategy, chk.Equals, "sweep (step 16, up to 64)")
+	c.Assert(decisions, chk.HasLen, 6)
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_SweepStillRisingAtEnd(c *chk.C) {
+	t, err := NewConcurrencyTuner("sweep:16:32", 4, s.noMax(), true)
+	c.Assert(err, chk.IsNil)
+
+	steps := []observedTunerStep{
+		{4, concurrencyReasonInitial, 100, 10, false},
+		{16, concurrencyReasonSweeping, 300, 10, false},
+		{32, concurrencyReasonSweeping, 500, 20, false},
+		{32, concurrencyReasonHitMax, 500, 20, false},
+		{32, concurrencyReasonFinished, 500, 20, false},
+	}
+	s.runObservedTest(c, t, steps)
+}
+
+func (s *concurrencyTunerSuite) TestParseConcurrencyTuningStrategy(c *chk.C) {
+	valid := map[string]string{
+		"":              HillClimbingStrategyName,
+		"hill-climbing": HillClimbingStrategyName,
+		"Latency":       LatencyStrategyName,
+		"latency:250":   "latency (p95 target 250 ms)",
+		"sweep":         "sweep (step 16, up to 256)",
+		"sweep:8":       "sweep (step 8, up to 256)",
+		"sweep:8:100":   "sweep (step 8, up to 100)",
+	}
+	for spec, expected := range valid {
+		strategy, err := parseConcurrencyTuningStrategy(spec, false)
+		c.Assert(err, chk.IsNil, chk.Commentf(spec))
+		c.Assert(strategy.description(), chk.Equals, expected)
+	}
+
+	for _, spec := range []string{"fastest", "hill-climbing:2", "latency:abc", "latency:0", "sweep:1:2:3"} {
+		_, err := parseConcurrencyTuningStrategy(spec, false)
+		c.Assert(err, chk.NotNil, chk.Commentf(spec))
+	}
+}
@@ -41,6 +41,7 @@ func (s *perfAdvisorSuite) TestPerfAdvisor(c *chk.C) {
 	concNotTuned := EAdviceType.ConcurrencyNotTuned()
 	concHitMax := EAdviceType.ConcurrencyHitUpperLimit()
 	concCpu := EAdviceType.ConcurrencyHighCpu()
+	concLatency := EAdviceType.ConcurrencyLatencyTarget()
 	netIsBottleneck := EAdviceType.NetworkIsBottleneck()
 	netOK := EAdviceType.NetworkNotBottleneck()
 	mbpsCapped := EAdviceType.MbpsCapped()
@@ -105,6 +106,7 @@ func (s *perfAdvisorSuite) TestPerfAdvisor(c *chk.C) {
 		{"combinedAzFiles", 0.5, 0.5, 0.5, 0, concurrencyReasonAtOptimum, azFilesNormal, 0, 1000, 0, otherBusy, netOK, none, none},
 		{"notVmSize      ", 0, 8, 0, 0, concurrencyReasonAtOptimum, normal, 0, 10500, 16, throughput, netOK, none, none},
 		{"smallFilesOK   ", 0, 8, 0, 0, concurrencyReasonAtOptimum, small, 0, 10500, 0, throughput, netOK, none, none},
+		{"latencyTarget  ", 0, 0, 0, 0, concurrencyReasonAtLatency, normal, 0, 1000, 0, concLatency, none, none, none},
 	}
 
 	// Run the tests, asserting that for each case, the given inputs produces the expected output
@@ -156,3 +158,28 @@ func (s *perfAdvisorSuite) assertAdviceMatches(c *chk.C, caseName string, obtain
 
 // TODO: for conciseness, we don't check the Title or Reason of the advice objects that are generated.
 //    Should we?
+
+func (s *perfAdvisorSuite) TestPerfAdvisorShowsTuningDecisions(c *chk.C) {
+	decisions := []ConcurrencyDecision{
+		{Concurrency: 4, Reason: concurrencyReasonInitial, Mbps: 100, P95LatencyMs: 20},
+		{Concurrency: 16, Reason: concurrencyReasonSweeping, Mbps: 300, P95LatencyMs: 25, Retries: 2},
+		{Concurrency: 16, Reason: concurrencyReasonAtOptimum, Mbps: 300, P95LatencyMs: 25},
+	}
+	a := &PerformanceAdvisor{
+		mbps:                        300,
+		finalConcurrencyTunerReason: concurrencyReasonAtOptimum,
+		finalConcurrency:            16,
+		tuningStrategy:              "sweep (step 16, up to 16)",
+		tuningDecisions:             decisions,
+		avgBytesPerFile:             8 * 1024 * 1024,
+	}
+	obtained := a.GetAdvice()
+	c.Assert(obtained, chk.HasLen, 2)
+	s.assertAdviceMatches(c, "sweep", obtained, 0, EAdviceType.NetworkIsBottleneck())
+	s.assertAdviceMatches(c, "sweep", obtained, 1, EAdviceType.ConcurrencyTuningDecisions())
+	c.Assert(obtained[1].Reason, chk.Matches, ".*sweep \\(step 16, up to 16\\).*16 connections \\(sweeping\\): 300 Mbps, p95 latency 25 ms, 2 retries.*")
+
+	// nothing extra for the default strategy
+	a.tuningStrategy = HillClimbingStrategyName
+	c.Assert(a.GetAdvice(), chk.HasLen, 1)
+}
@@ -0,0 +1,403 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type planMigrationSuite struct {
+	oldPlanFolder string
+}
+
+var _ = chk.Suite(&planMigrationSuite{})
+
+func (s *planMigrationSuite) SetUpTest(c *chk.C) {
+	s.oldPlanFolder = common.AzcopyJobPlanFolder
+	common.AzcopyJobPlanFolder = c.MkDir()
+}
+
+func (s *planMigrationSuite) TearDownTest(c *chk.C) {
+	common.AzcopyJobPlanFolder = s.oldPlanFolder
+}
+
+// createPlan writes a plan of the current version, with its first transfer marked as failed.
+func createPlan(c *chk.C, jobID common.JobID, partNum common.PartNumber) JobPartPlanFileName {
+	planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), partNum, DataSchemaVersion))
+	planFile.Create(common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		PartNum:         partNum,
+		IsFinalPart:     true,
+		FromTo:          common.EFromTo.LocalBlob(),
+		SourceRoot:      common.ResourceString{Value: "/data"},
+		DestinationRoot: common.ResourceString{Value: "https://acct.blob.core.windows.net/container"},
+		Transfers: common.Transfers{List: []common.CopyTransfer{
+			{Source: "/a", Destination: "/a", EntityType: common.EEntityType.File(), SourceSize: 1},
+			{Source: "/b", Destination: "/b", EntityType: common.EEntityType.File(), SourceSize: 2},
+		}},
+	})
+
+	mmf := planFile.Map()
+	mmf.Plan().Transfer(0).SetTransferStatus(common.ETransferStatus.Failed(), true)
+	mmf.Plan().Transfer(0).SetErrorCode(403, true)
+	mmf.Unmap()
+	return planFile
+}
+
+// writeAsVersion rewrites a current plan file as if it had been written with the given version.
+func writeAsVersion(c *chk.C, planFile JobPartPlanFileName, version common.Version) string {
+	data, err := os.ReadFile(planFile.GetJobPartPlanPath())
+	c.Assert(err, chk.IsNil)
+	binary.LittleEndian.PutUint32(data, uint32(version))
+	c.Assert(os.Remove(planFile.GetJobPartPlanPath()), chk.IsNil)
+
+	jobID, partNum, _ := planFile.Parse()
+	name := filepath.Join(common.AzcopyJobPlanFolder, fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), partNum, version))
+	c.Assert(os.WriteFile(name, data, common.DEFAULT_FILE_PERM), chk.IsNil)
+	return name
+}
+
+func (s *planMigrationSuite) TestMigrationChain(c *chk.C) {
+	// pretend that the previous version only differed in its version number
+	previous := DataSchemaVersion - 1
+	oldMigration, hadMigration := planMigrations[previous]
+	planMigrations[previous] = func(old []byte) ([]byte, error) {
+		binary.LittleEndian.PutUint32(old, uint32(previous+1))
+		return old, nil
+	}
+	defer func() {
+		if hadMigration {
+			planMigrations[previous] = oldMigration
+		} else {
+			delete(planMigrations, previous)
+		}
+	}()
+
+	jobID := common.NewJobID()
+	planFile := createPlan(c, jobID, 0)
+	oldName := writeAsVersion(c, planFile, previous)
+
+	migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 1)
+
+	// the migrated plan is usable, and kept the transfers' progress
+	c.Assert(planFile.Exists(), chk.Equals, true)
+	mmf := planFile.Map()
+	c.Assert(mmf.Plan().Version, chk.Equals, DataSchemaVersion)
+	c.Assert(mmf.Plan().Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(mmf.Plan().Transfer(0).ErrorCode(), chk.Equals, int32(403))
+	c.Assert(mmf.Plan().Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+	mmf.Unmap()
+
+	// the original is kept, and isn't migrated again
+	_, err = os.Stat(oldName)
+	c.Assert(err, chk.IsNil)
+	migrated, err = MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 0)
+}
+
+func (s *planMigrationSuite) TestUnsupportedVersions(c *chk.C) {
+	// there is no way back from a newer version
+	jobID := common.NewJobID()
+	writeAsVersion(c, createPlan(c, jobID, 0), DataSchemaVersion+1)
+	_, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.NotNil)
+
+	// nor from a version that predates migrations
+	jobID = common.NewJobID()
+	writeAsVersion(c, createPlan(c, jobID, 0), 1)
+	_, err = MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.NotNil)
+
+	// a file whose header disagrees with its name is not trusted
+	_, err = migratePlan([]byte{1, 0, 0, 0}, 2)
+	c.Assert(err, chk.NotNil)
+}
+
+// writeV18Plan writes a plan with the layout of version 18, whose roots were held in the header.
+func writeV18Plan(c *chk.C, jobID common.JobID, command string, sourceRoot, destinationRoot, destExtraQuery string, transfers [][2]string) string {
+	data := make([]byte, planV18HeaderSize)
+	binary.LittleEndian.PutUint32(data, 18)
+	*(*common.JobID)(unsafe.Pointer(&data[16])) = jobID
+	for i, root := range []string{sourceRoot, "", destinationRoot, destExtraQuery} {
+		offset := planV18RootsOffset + i*(2+planV18RootSize)
+		binary.LittleEndian.PutUint16(data[offset:], uint16(len(root)))
+		copy(data[offset+2:], root)
+	}
+	binary.LittleEndian.PutUint32(data[planV18CommandStringOffset:], uint32(len(command)))
+	binary.LittleEndian.PutUint32(data[planV18NumTransfersOffset:], uint32(len(transfers)))
+
+	data = append(data, command...)
+	data = append(data, make([]byte, alignTo8(int64(len(data)))-int64(len(data)))...)
+
+	stringsOffset := int64(len(data)) + int64(len(transfers))*planV18TransferSize
+	var strs []byte
+	for i, t := range transfers {
+		transfer := make([]byte, planV18TransferSize)
+		binary.LittleEndian.PutUint64(transfer, uint64(stringsOffset+int64(len(strs))))
+		binary.LittleEndian.PutUint16(transfer[8:], uint16(len(t[0])))
+		binary.LittleEndian.PutUint16(transfer[10:], uint16(len(t[1])))
+		binary.LittleEndian.PutUint32(transfer[64:], uint32(common.ETransferStatus.Started()))
+		if i == 0 {
+			binary.LittleEndian.PutUint32(transfer[64:], uint32(common.ETransferStatus.Success()))
+		}
+		data = append(data, transfer...)
+		strs = append(strs, t[0]+t[1]...)
+	}
+	data = append(data, strs...)
+
+	name := filepath.Join(common.AzcopyJobPlanFolder, fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, 18))
+	c.Assert(os.WriteFile(name, data, common.DEFAULT_FILE_PERM), chk.IsNil)
+	return name
+}
+
+func (s *planMigrationSuite) TestMigrationFromV18(c *chk.C) {
+	jobID := common.NewJobID()
+	writeV18Plan(c, jobID, "copy /data https://acct.blob.core.windows.net/container",
+		"/data", "https://acct.blob.core.windows.net/container", "snapshot=x",
+		[][2]string{{"/a", "/a"}, {"/dir/b", "/dir/b"}})
+
+	migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 1)
+
+	planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, DataSchemaVersion))
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.JobID, chk.Equals, jobID)
+	c.Assert(plan.CommandString(), chk.Equals, "copy /data https://acct.blob.core.windows.net/container")
+	c.Assert(plan.SourceRoot(), chk.Equals, "/data")
+	c.Assert(plan.SourceExtraQuery(), chk.Equals, "")
+	c.Assert(plan.DestinationRoot(), chk.Equals, "https://acct.blob.core.windows.net/container")
+	c.Assert(plan.DestExtraQuery(), chk.Equals, "snapshot=x")
+
+	c.Assert(plan.NumTransfers, chk.Equals, uint32(2))
+	src, dst, _ := plan.TransferSrcDstStrings(1)
+	c.Assert(src, chk.Equals, "/data/dir/b")
+	c.Assert(dst, chk.Equals, "https://acct.blob.core.windows.net/container/dir/b?snapshot=x")
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+}
+
+func (s *planMigrationSuite) TestLongRoots(c *chk.C) {
+	// roots are no longer limited to the 1000 bytes that the header used to hold
+	sourceRoot := "/" + strings.Repeat("d", 3000)
+	extraQuery := "sv=2020-10-02&" + strings.Repeat("x", 2000)
+
+	jobID := common.NewJobID()
+	planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, DataSchemaVersion))
+	planFile.Create(common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		IsFinalPart:     true,
+		CommandString:   "copy",
+		FromTo:          common.EFromTo.LocalBlob(),
+		SourceRoot:      common.ResourceString{Value: sourceRoot},
+		DestinationRoot: common.ResourceString{Value: "https://acct.blob.core.windows.net/container", ExtraQuery: extraQuery},
+		Transfers: common.Transfers{List: []common.CopyTransfer{
+			{Source: "/a", Destination: "/a", EntityType: common.EEntityType.File(), SourceSize: 1},
+		}},
+	})
+
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.CommandString(), chk.Equals, "copy")
+	c.Assert(plan.SourceRoot(), chk.Equals, sourceRoot)
+	c.Assert(plan.DestExtraQuery(), chk.Equals, extraQuery)
+	src, dst, _ := plan.TransferSrcDstStrings(0)
+	c.Assert(src, chk.Equals, sourceRoot+"/a")
+	c.Assert(dst, chk.Equals, "https://acct.blob.core.windows.net/container/a?"+extraQuery)
+}
+
+func (s *planMigrationSuite) TestMigrationFromV19(c *chk.C) {
+	jobID := common.NewJobID()
+	planFile := createPlan(c, jobID, 0)
+
+	// a version 19 plan is the same, but without the ACL length at the end of the header, so everything after it is 8 bytes earlier
+	data, err := os.ReadFile(planFile.GetJobPartPlanPath())
+	c.Assert(err, chk.IsNil)
+	c.Assert(os.Remove(planFile.GetJobPartPlanPath()), chk.IsNil)
+	data = append(data[:planV19HeaderSize:planV19HeaderSize], data[planV19HeaderSize+8:]...)
+	binary.LittleEndian.PutUint32(data, 19)
+
+	stringsLength := int64(binary.LittleEndian.Uint32(data[planV19CommandStringOffset:]))
+	for i := 0; i < 4; i++ {
+		stringsLength += int64(binary.LittleEndian.Uint32(data[planV19RootsOffset+4*i:]))
+	}
+	transfersOffset := alignTo8(planV19HeaderSize + stringsLength)
+	for i := int64(0); i < int64(binary.LittleEndian.Uint32(data[planV19NumTransfersOffset:])); i++ {
+		transfer := data[transfersOffset+i*planV19TransferSize:]
+		binary.LittleEndian.PutUint64(transfer, binary.LittleEndian.Uint64(transfer)-8)
+	}
+	name := filepath.Join(common.AzcopyJobPlanFolder, fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, 19))
+	c.Assert(os.WriteFile(name, data, common.DEFAULT_FILE_PERM), chk.IsNil)
+
+	migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 1)
+
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.Version, chk.Equals, DataSchemaVersion)
+	c.Assert(plan.AccessControlList(), chk.Equals, "")
+	c.Assert(plan.SourceRoot(), chk.Equals, "/data")
+	src, dst, _ := plan.TransferSrcDstStrings(1)
+	c.Assert(src, chk.Equals, "/data/b")
+	c.Assert(dst, chk.Equals, "https://acct.blob.core.windows.net/container/b")
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+}
+
+func (s *planMigrationSuite) TestAccessControlList(c *chk.C) {
+	acl := "user::rwx,user:" + strings.Repeat("a", 36) + ":r-x,group::r-x,other::---"
+
+	jobID := common.NewJobID()
+	planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, DataSchemaVersion))
+	planFile.Create(common.CopyJobPartOrderRequest{
+		JobID:              jobID,
+		IsFinalPart:        true,
+		CommandString:      "acl set",
+		FromTo:             common.EFromTo.BlobFSNone(),
+		SourceRoot:         common.ResourceString{Value: "https://acct.dfs.core.windows.net/fs/dir"},
+		SetPropertiesFlags: common.ESetPropertiesFlags.SetAccessControl(),
+		BlobAttributes:     common.BlobTransferAttributes{AccessControlList: acl},
+		Transfers: common.Transfers{List: []common.CopyTransfer{
+			{Source: "", EntityType: common.EEntityType.File()},
+		}},
+	})
+
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.AccessControlList(), chk.Equals, acl)
+	c.Assert(plan.CommandString(), chk.Equals, "acl set")
+	src, _, _ := plan.TransferSrcDstStrings(0)
+	c.Assert(src, chk.Equals, "https://acct.dfs.core.windows.net/fs/dir")
+}
+
+func (s *planMigrationSuite) TestMigrationFromV20(c *chk.C) {
+	// a version 20 plan has the same layout, with padding where PreserveXattrs is
+	jobID := common.NewJobID()
+	planFile := createPlan(c, jobID, 0)
+	writeAsVersion(c, planFile, 20)
+
+	migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 1)
+
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.Version, chk.Equals, DataSchemaVersion)
+	c.Assert(plan.PreserveXattrs, chk.Equals, false)
+	src, _, _ := plan.TransferSrcDstStrings(1)
+	c.Assert(src, chk.Equals, "/data/b")
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Failed())
+}
+
+func (s *planMigrationSuite) TestMigrationFromV21(c *chk.C) {
+	// a version 21 plan has the same layout, with padding where AdaptiveBlockSize is
+	jobID := common.NewJobID()
+	planFile := createPlan(c, jobID, 0)
+	writeAsVersion(c, planFile, 21)
+
+	migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(migrated, chk.Equals, 1)
+
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+	c.Assert(plan.Version, chk.Equals, DataSchemaVersion)
+	c.Assert(plan.AdaptiveBlockSize, chk.Equals, false)
+	src, _, _ := plan.TransferSrcDstStrings(1)
+	c.Assert(src, chk.Equals, "/data/b")
+}
+
+// TestMigrationFromRealPlans migrates plan files that were written by the versions of AzCopy that used each older layout.
+// testdata/planMigration holds one plan of each version, all for the same job: a recursive upload of three files,
+// of which the first succeeded, the second failed with a 403, and the third hadn't started.
+func (s *planMigrationSuite) TestMigrationFromRealPlans(c *chk.C) {
+	jobID, err := common.ParseJobID("5d6c2f0e-8a1b-4c3d-9e7f-0a1b2c3d4e5f")
+	c.Assert(err, chk.IsNil)
+	modified := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC).UnixNano()
+
+	for _, version := range []common.Version{18, 19, 20, 21} {
+		comment := chk.Commentf("plan version %d", version)
+		common.AzcopyJobPlanFolder = c.MkDir()
+		name := fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, version)
+		data, err := os.ReadFile(filepath.Join("testdata", "planMigration", name))
+		c.Assert(err, chk.IsNil, comment)
+		c.Assert(os.WriteFile(filepath.Join(common.AzcopyJobPlanFolder, name), data, common.DEFAULT_FILE_PERM), chk.IsNil, comment)
+
+		migrated, err := MigrateJobPlanFiles(common.AzcopyJobPlanFolder, jobID)
+		c.Assert(err, chk.IsNil, comment)
+		c.Assert(migrated, chk.Equals, 1, comment)
+
+		planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), 0, DataSchemaVersion))
+		mmf := planFile.Map()
+		plan := mmf.Plan()
+		c.Assert(plan.Version, chk.Equals, DataSchemaVersion, comment)
+		c.Assert(plan.JobID, chk.Equals, jobID, comment)
+		c.Assert(plan.IsFinalPart, chk.Equals, true, comment)
+		c.Assert(plan.FromTo, chk.Equals, common.EFromTo.LocalBlob(), comment)
+		c.Assert(plan.CommandString(), chk.Equals, "copy /data https://acct.blob.core.windows.net/container --recursive", comment)
+		c.Assert(plan.SourceRoot(), chk.Equals, "/data", comment)
+		c.Assert(plan.DestinationRoot(), chk.Equals, "https://acct.blob.core.windows.net/container", comment)
+		c.Assert(plan.AccessControlList(), chk.Equals, "", comment)
+		c.Assert(plan.PreserveXattrs, chk.Equals, false, comment)
+		c.Assert(plan.AdaptiveBlockSize, chk.Equals, false, comment)
+
+		c.Assert(plan.NumTransfers, chk.Equals, uint32(3), comment)
+		expected := []struct {
+			src, dst string
+			size     int64
+			status   common.TransferStatus
+		}{
+			{"/data/a.txt", "https://acct.blob.core.windows.net/container/a.txt", 10, common.ETransferStatus.Success()},
+			{"/data/dir/b.txt", "https://acct.blob.core.windows.net/container/dir/b.txt", 20, common.ETransferStatus.Failed()},
+			{"/data/dir/c d.txt", "https://acct.blob.core.windows.net/container/dir/c%20d.txt", 30, common.ETransferStatus.Started()},
+		}
+		for i, e := range expected {
+			src, dst, _ := plan.TransferSrcDstStrings(uint32(i))
+			c.Assert(src, chk.Equals, e.src, comment)
+			c.Assert(dst, chk.Equals, e.dst, comment)
+			transfer := plan.Transfer(uint32(i))
+			c.Assert(transfer.SourceSize, chk.Equals, e.size, comment)
+			c.Assert(transfer.ModifiedTime, chk.Equals, modified, comment)
+			c.Assert(transfer.TransferStatus(), chk.Equals, e.status, comment)
+		}
+		c.Assert(plan.Transfer(1).ErrorCode(), chk.Equals, int32(403), comment)
+		mmf.Unmap()
+	}
+}
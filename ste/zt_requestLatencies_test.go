@@ -0,0 +1,156 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type requestLatenciesSuite struct{}
+
+var _ = chk.Suite(&requestLatenciesSuite{})
+
+func (s *requestLatenciesSuite) TestBucketsCoverValuesClosely(c *chk.C) {
+	for _, ms := range []int64{0, 1, 31, 32, 63, 64, 65, 100, 1000, 12345, 999999, 1 << 30} {
+		i := latencyBucketIndex(ms)
+		highest := latencyBucketHighestValue(i)
+		c.Assert(highest >= ms, chk.Equals, true, chk.Commentf("%d", ms))
+		c.Assert(float64(highest-ms) <= float64(ms)/latencySubBucketCount, chk.Equals, true, chk.Commentf("%d", ms))
+		if i > 0 {
+			c.Assert(latencyBucketHighestValue(i-1) < ms, chk.Equals, true, chk.Commentf("%d", ms))
+		}
+	}
+
+	// small values are exact
+	for ms := int64(0); ms < 2*latencySubBucketCount; ms++ {
+		c.Assert(latencyBucketHighestValue(latencyBucketIndex(ms)), chk.Equals, ms)
+	}
+
+	// huge ones are clamped
+	c.Assert(latencyBucketIndex(1<<62), chk.Equals, latencyBucketCount-1)
+	c.Assert(latencyBucketIndex(-5), chk.Equals, 0)
+}
+
+func (s *requestLatenciesSuite) TestPercentiles(c *chk.C) {
+	h := &latencyHistogram{}
+	c.Assert(h.percentile(50), chk.Equals, int64(0))
+
+	for ms := int64(1); ms <= 1000; ms++ {
+		h.record(ms)
+	}
+	within := func(actual, expected int64) {
+		c.Assert(actual >= expected && actual <= expected+expected/latencySubBucketCount, chk.Equals, true, chk.Commentf("%d vs %d", actual, expected))
+	}
+	within(h.percentile(50), 500)
+	within(h.percentile(95), 950)
+	within(h.percentile(99), 990)
+	c.Assert(h.percentile(100), chk.Equals, int64(1000))
+
+	// the tail is never reported as more than the slowest request
+	h = &latencyHistogram{}
+	h.record(1001)
+	c.Assert(h.percentile(99), chk.Equals, int64(1001))
+}
+
+func (s *requestLatenciesSuite) TestSlowestRequestsAreKept(c *chk.C) {
+	r := &requestLatencyStats{}
+	for ms := 1; ms <= 20; ms++ {
+		req, _ := http.NewRequest(http.MethodPut, "https://acct.blob.core.windows.net/c/b?comp=block&blockid=x&sig=secret", nil)
+		req.Header.Set("x-ms-client-request-id", "client-"+strconv.Itoa(ms))
+		resp := &http.Response{StatusCode: http.StatusCreated, Header: http.Header{}}
+		resp.Header.Set("x-ms-request-id", "service-"+strconv.Itoa(ms))
+		r.record(req, resp, time.Now(), time.Duration(ms)*time.Millisecond)
+	}
+	req, _ := http.NewRequest(http.MethodPut, "https://acct.blob.core.windows.net/c/b?comp=blocklist", nil)
+	r.record(req, nil, time.Now(), 5*time.Millisecond)
+
+	summaries := r.summaries()
+	c.Assert(summaries, chk.HasLen, 2)
+	c.Assert(summaries[0].Operation, chk.Equals, "PutBlock")
+	c.Assert(summaries[0].Count, chk.Equals, int64(20))
+	c.Assert(summaries[0].MaxMilliseconds, chk.Equals, int64(20))
+	c.Assert(summaries[0].SlowestRequests, chk.HasLen, slowestRequestsToKeep)
+	for i, sample := range summaries[0].SlowestRequests {
+		ms := 20 - i
+		c.Assert(sample.Milliseconds, chk.Equals, int64(ms))
+		c.Assert(sample.ClientRequestID, chk.Equals, "client-"+strconv.Itoa(ms))
+		c.Assert(sample.ServiceRequestID, chk.Equals, "service-"+strconv.Itoa(ms))
+		c.Assert(sample.StatusCode, chk.Equals, http.StatusCreated)
+		c.Assert(sample.URL, chk.Not(chk.Matches), ".*secret.*")
+	}
+
+	c.Assert(summaries[1].Operation, chk.Equals, "PutBlockList")
+	c.Assert(summaries[1].SlowestRequests, chk.HasLen, 1)
+	c.Assert(summaries[1].SlowestRequests[0].StatusCode, chk.Equals, 0)
+}
+
+func (s *requestLatenciesSuite) TestOperationNames(c *chk.C) {
+	testCases := []struct {
+		method   string
+		url      string
+		headers  map[string]string
+		expected string
+	}{
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=block&blockid=x", nil, "PutBlock"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=block&blockid=x", map[string]string{"x-ms-copy-source": "https://s/b"}, "PutBlockFromURL"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=blocklist", nil, "PutBlockList"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=page", nil, "PutPage"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=appendblock", nil, "AppendBlock"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-blob-type": "BlockBlob"}, "PutBlob"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-copy-source": "https://s/b", "x-ms-requires-sync": "true"}, "CopyFromURL"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-copy-source": "https://s/b", "x-ms-blob-type": "BlockBlob"}, "PutBlobFromURL"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-copy-source": "https://s/b"}, "StartCopyFromURL"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c?restype=container", nil, "CreateContainer"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=properties", nil, "SetProperties"},
+		{http.MethodPut, "https://a.blob.core.windows.net/c/b?comp=tier", nil, "SetTier"},
+		{http.MethodGet, "https://a.blob.core.windows.net/c/b", map[string]string{"x-ms-range": "bytes=0-99"}, "GetBlob range"},
+		{http.MethodGet, "https://a.blob.core.windows.net/c/b", nil, "GetBlob"},
+		{http.MethodGet, "https://a.blob.core.windows.net/c?restype=container&comp=list", nil, "ListBlobs"},
+		{http.MethodGet, "https://a.blob.core.windows.net/c/b?comp=metadata", nil, "GetMetadata"},
+		{http.MethodHead, "https://a.blob.core.windows.net/c/b", nil, "GetProperties"},
+		{http.MethodDelete, "https://a.blob.core.windows.net/c/b", nil, "Delete"},
+		{http.MethodPut, "https://a.file.core.windows.net/s/f", map[string]string{"x-ms-type": "file"}, "CreateFile"},
+		{http.MethodPut, "https://a.file.core.windows.net/s/d?restype=directory", nil, "CreateDirectory"},
+		{http.MethodPut, "https://a.file.core.windows.net/s/f?comp=range", nil, "PutRange"},
+		{http.MethodPut, "https://a.file.core.windows.net/s/f?comp=range", map[string]string{"x-ms-copy-source": "https://s/b"}, "PutRangeFromURL"},
+		{http.MethodPut, "https://a.file.core.windows.net/s/f?comp=properties", nil, "SetProperties"},
+		{http.MethodGet, "https://a.file.core.windows.net/s/f", map[string]string{"x-ms-range": "bytes=0-99"}, "GetFile range"},
+		{http.MethodPut, "https://a.dfs.core.windows.net/fs/f?resource=file", nil, "CreateFile"},
+		{http.MethodPatch, "https://a.dfs.core.windows.net/fs/f?action=append&position=0", nil, "Append"},
+		{http.MethodPatch, "https://a.dfs.core.windows.net/fs/f?action=flush&position=10", nil, "Flush"},
+		{http.MethodPatch, "https://a.dfs.core.windows.net/fs/f?action=setAccessControl", nil, "SetAccessControl"},
+		{http.MethodGet, "https://a.dfs.core.windows.net/fs/f", map[string]string{"Range": "bytes=0-99"}, "Read range"},
+		{http.MethodHead, "https://a.dfs.core.windows.net/fs/f", nil, "GetProperties"},
+	}
+
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, tc.url, nil)
+		c.Assert(err, chk.IsNil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		c.Check(requestOperationName(req), chk.Equals, tc.expected, chk.Commentf("%s %s", tc.method, tc.url))
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type sasRenewalPolicySuite struct{}
+
+var _ = chk.Suite(&sasRenewalPolicySuite{})
+
+func (s *sasRenewalPolicySuite) TestReplaceSASInQuery(c *chk.C) {
+	query := "snapshot=2020-01-01T00%3A00%3A00.0000000Z&sv=2020-10-02&se=2000-01-01T00%3A00%3A00Z&sig=old"
+	replaced := replaceSASInQuery(query, "se=2000-01-01T00%3A00%3A00Z&sig=old&sv=2020-10-02", "sv=2020-10-02&sig=new")
+	c.Assert(replaced, chk.Equals, "snapshot=2020-01-01T00%3A00%3A00.0000000Z&sv=2020-10-02&sig=new")
+}
+
+func (s *sasRenewalPolicySuite) TestExpiringSASIsSwappedIntoURL(c *chk.C) {
+	expiring := "sv=2020-10-02&se=" + url.QueryEscape(time.Now().Add(time.Minute).UTC().Format(time.RFC3339)) + "&sp=r&sig=expiring"
+	fresh := "sv=2020-10-02&se=" + url.QueryEscape(time.Now().Add(time.Hour).UTC().Format(time.RFC3339)) + "&sp=r&sig=fresh"
+	common.RegisterSASRenewer(expiring, func(ctx context.Context) (string, error) { return fresh, nil })
+
+	u, _ := url.Parse("https://acct.blob.core.windows.net/c/b?" + expiring)
+	renewed, changed := renewSASInURL(context.Background(), *u)
+	c.Assert(changed, chk.Equals, true)
+	c.Assert(renewed.Query().Get("sig"), chk.Equals, "fresh")
+	c.Assert(renewed.Path, chk.Equals, "/c/b")
+
+	// a SAS nobody registered is left alone
+	u, _ = url.Parse("https://acct.blob.core.windows.net/c/b?sv=2020-10-02&sig=unknown")
+	_, changed = renewSASInURL(context.Background(), *u)
+	c.Assert(changed, chk.Equals, false)
+}
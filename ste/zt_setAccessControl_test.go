@@ -0,0 +1,49 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
+	chk "gopkg.in/check.v1"
+)
+
+type setAccessControlSuite struct{}
+
+var _ = chk.Suite(&setAccessControlSuite{})
+
+func (s *setAccessControlSuite) TestMergeADLSACL(c *chk.C) {
+	current := "user::rwx,user:1111:r-x,group::r-x,mask::r-x,other::---,default:user:1111:r-x"
+
+	// modify updates the entries that are there, and adds the others
+	c.Assert(MergeADLSACL(current, "user:1111:rwx,user:2222:r--", azbfs.PathSetAccessControlRecursiveModeModify), chk.Equals,
+		"user::rwx,user:1111:rwx,group::r-x,mask::r-x,other::---,default:user:1111:r-x,user:2222:r--")
+
+	// default entries are distinct from access entries
+	c.Assert(MergeADLSACL(current, "default:user:1111:---", azbfs.PathSetAccessControlRecursiveModeModify), chk.Equals,
+		"user::rwx,user:1111:r-x,group::r-x,mask::r-x,other::---,default:user:1111:---")
+
+	// remove matches on the type and id alone
+	c.Assert(MergeADLSACL(current, "user:1111,default:user:1111,user:3333", azbfs.PathSetAccessControlRecursiveModeRemove), chk.Equals,
+		"user::rwx,group::r-x,mask::r-x,other::---")
+
+	// set replaces the lot
+	c.Assert(MergeADLSACL(current, "user::rwx,group::---,other::---", azbfs.PathSetAccessControlRecursiveModeSet), chk.Equals,
+		"user::rwx,group::---,other::---")
+}
@@ -0,0 +1,155 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	chk "gopkg.in/check.v1"
+)
+
+type tracingSuite struct{}
+
+var _ = chk.Suite(&tracingSuite{})
+
+// recordSpans makes the global tracer record spans in memory, until the returned func is called
+func recordSpans() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder, func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) }
+}
+
+// newTracedTestPipeline returns a pipeline whose first try of each request gets a 503, and whose second gets the given status
+func newTracedTestPipeline(status int) pipeline.Pipeline {
+	retryOnce := pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			resp, err := next.Do(ctx, request)
+			if err == nil && resp.Response().StatusCode == http.StatusServiceUnavailable {
+				resp, err = next.Do(ctx, request)
+			}
+			return resp, err
+		}
+	})
+	tries := 0
+	sender := pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			tries++
+			resp := &http.Response{StatusCode: status, Header: http.Header{}}
+			if tries == 1 {
+				resp.StatusCode = http.StatusServiceUnavailable
+			}
+			resp.Header.Set("x-ms-request-id", "service-id")
+			return pipeline.NewHTTPResponse(resp), nil
+		}
+	})
+	return pipeline.NewPipeline([]pipeline.Factory{newXferTracingPolicyFactory(), retryOnce, newXferTracingTryPolicyFactory()},
+		pipeline.Options{HTTPSender: sender})
+}
+
+func mustParseURL(c *chk.C, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	c.Assert(err, chk.IsNil)
+	return u
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, a := range span.Attributes() {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func (s *tracingSuite) TestRequestSpansAreChildrenOfTransfer(c *chk.C) {
+	recorder, stop := recordSpans()
+	defer stop()
+
+	ctx, transfer := common.Tracer().Start(context.Background(), "Transfer")
+	ctx = context.WithValue(ctx, transferRetryCountContextKey, new(int64))
+
+	request, err := pipeline.NewRequest(http.MethodPut, *mustParseURL(c, "https://acct.blob.core.windows.net/c/b?comp=block&blockid=x&sig=secret"), nil)
+	c.Assert(err, chk.IsNil)
+	request.Header.Set("x-ms-client-request-id", "client-id")
+	_, err = newTracedTestPipeline(http.StatusCreated).Do(ctx, nil, request)
+	c.Assert(err, chk.IsNil)
+	endTransferSpan(ctx, common.ETransferStatus.Success(), 0)
+
+	ended := recorder.Ended()
+	c.Assert(ended, chk.HasLen, 2)
+	requestSpan, transferSpan := ended[0], ended[1]
+
+	c.Assert(requestSpan.Name(), chk.Equals, "PutBlock")
+	c.Assert(requestSpan.SpanKind(), chk.Equals, trace.SpanKindClient)
+	c.Assert(requestSpan.Parent().SpanID(), chk.Equals, transfer.SpanContext().SpanID())
+	c.Assert(attributeOf(requestSpan, traceAttrRetryCount).AsInt64(), chk.Equals, int64(1))
+	c.Assert(attributeOf(requestSpan, "http.status_code").AsInt64(), chk.Equals, int64(http.StatusCreated))
+	c.Assert(attributeOf(requestSpan, traceAttrClientRequestID).AsString(), chk.Equals, "client-id")
+	c.Assert(attributeOf(requestSpan, traceAttrServiceRequestID).AsString(), chk.Equals, "service-id")
+	c.Assert(attributeOf(requestSpan, "http.url").AsString(), chk.Not(chk.Matches), ".*secret.*")
+	c.Assert(requestSpan.Events(), chk.HasLen, 2)
+
+	c.Assert(transferSpan.Name(), chk.Equals, "Transfer")
+	c.Assert(attributeOf(transferSpan, traceAttrRetryCount).AsInt64(), chk.Equals, int64(1))
+	c.Assert(attributeOf(transferSpan, traceAttrStatus).AsString(), chk.Equals, common.ETransferStatus.Success().String())
+}
+
+func (s *tracingSuite) TestFailedRequestsAndTransfersAreErrors(c *chk.C) {
+	recorder, stop := recordSpans()
+	defer stop()
+
+	ctx, _ := common.Tracer().Start(context.Background(), "Transfer")
+	ctx = context.WithValue(ctx, transferRetryCountContextKey, new(int64))
+
+	request, err := pipeline.NewRequest(http.MethodGet, *mustParseURL(c, "https://acct.blob.core.windows.net/c/b"), nil)
+	c.Assert(err, chk.IsNil)
+	_, err = newTracedTestPipeline(http.StatusNotFound).Do(ctx, nil, request)
+	c.Assert(err, chk.IsNil)
+	endTransferSpan(ctx, common.ETransferStatus.Failed(), http.StatusNotFound)
+
+	ended := recorder.Ended()
+	c.Assert(ended, chk.HasLen, 2)
+	c.Assert(ended[0].Status().Code.String(), chk.Equals, "Error")
+	c.Assert(ended[1].Status().Code.String(), chk.Equals, "Error")
+	c.Assert(attributeOf(ended[1], traceAttrErrorCode).AsInt64(), chk.Equals, int64(http.StatusNotFound))
+}
+
+func (s *tracingSuite) TestNothingIsTracedOutsideOfJobs(c *chk.C) {
+	recorder, stop := recordSpans()
+	defer stop()
+
+	request, err := pipeline.NewRequest(http.MethodGet, *mustParseURL(c, "https://acct.blob.core.windows.net/c/b"), nil)
+	c.Assert(err, chk.IsNil)
+	_, err = newTracedTestPipeline(http.StatusOK).Do(context.Background(), nil, request)
+	c.Assert(err, chk.IsNil)
+	endTransferSpan(context.Background(), common.ETransferStatus.Success(), 0)
+
+	c.Assert(recorder.Ended(), chk.HasLen, 0)
+}
@@ -0,0 +1,60 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type xattrsSuite struct{}
+
+var _ = chk.Suite(&xattrsSuite{})
+
+func (s *xattrsSuite) TestDeleteXattrsSidecar(c *chk.C) {
+	status := http.StatusAccepted
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, chk.Equals, http.MethodDelete)
+		deleted = r.URL.Path
+		if status == http.StatusNotFound {
+			w.Header().Set("x-ms-error-code", string(azblob.ServiceCodeBlobNotFound))
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+
+	blob := server.URL + "/devstoreaccount1/container/dir/file?sv=2020-10-02&sig=secret"
+	c.Assert(deleteXattrsSidecar(context.Background(), blob, p), chk.IsNil)
+	c.Assert(deleted, chk.Equals, "/devstoreaccount1/container/dir/file"+common.XattrsSidecarSuffix)
+
+	// there's usually no sidecar to delete
+	status = http.StatusNotFound
+	c.Assert(deleteXattrsSidecar(context.Background(), blob, p), chk.IsNil)
+
+	status = http.StatusForbidden
+	c.Assert(deleteXattrsSidecar(context.Background(), blob, p), chk.NotNil)
+}